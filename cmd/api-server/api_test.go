@@ -5,9 +5,15 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 func setupTestRouter() *gin.Engine {
@@ -21,6 +27,25 @@ func setupTestRouter() *gin.Engine {
 	router.POST("/kv/set", apiServer.Set)
 	router.GET("/kv/get/:key", apiServer.Get)
 	router.DELETE("/kv/delete/:key", apiServer.Delete)
+	router.POST("/kv/undelete/:key", apiServer.Undelete)
+	router.GET("/kv/deleted", apiServer.ListDeleted)
+	router.GET("/kv/history/:key", apiServer.History)
+	router.POST("/kv/crdt/:key", apiServer.CrdtUpdate)
+	router.GET("/kv/crdt/:key", apiServer.CrdtGet)
+	router.POST("/kv/schemas", apiServer.RegisterSchema)
+	router.GET("/kv/schemas", apiServer.ListSchemas)
+	router.DELETE("/kv/schemas", apiServer.DeleteSchema)
+	router.GET("/kv/export", apiServer.Export)
+	router.POST("/kv/import", apiServer.Import)
+	router.GET("/admin/backup", apiServer.Backup)
+	router.POST("/admin/restore", apiServer.Restore)
+	router.GET("/admin/quotas", apiServer.QuotaUsage)
+	router.GET("/admin/hotkeys", apiServer.TopKeys)
+	router.GET("/admin/replication", apiServer.ReplicationStatus)
+	router.POST("/admin/promote", apiServer.Promote)
+	router.POST("/admin/reshard", apiServer.Reshard)
+	router.GET("/admin/reshard", apiServer.ReshardStatus)
+	router.GET("/admin/quorum", apiServer.QuorumStatus)
 
 	return router
 }
@@ -186,3 +211,552 @@ func TestInvalidJSON(t *testing.T) {
 		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
 	}
 }
+
+func TestBackupEndpoint(t *testing.T) {
+	router := setupTestRouter()
+
+	req, _ := http.NewRequest("GET", "/admin/backup", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Will fail due to no gRPC connection
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestRestoreEndpoint(t *testing.T) {
+	router := setupTestRouter()
+
+	tests := []struct {
+		name           string
+		mode           string
+		expectedStatus int
+	}{
+		{
+			name:           "Default mode",
+			mode:           "",
+			expectedStatus: http.StatusInternalServerError, // Will fail due to no gRPC connection
+		},
+		{
+			name:           "Merge mode",
+			mode:           "?mode=merge",
+			expectedStatus: http.StatusInternalServerError, // Will fail due to no gRPC connection
+		},
+		{
+			name:           "Invalid mode",
+			mode:           "?mode=append",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := bytes.NewBufferString(`{"key":"k","value":"v","checksum":1}` + "\n")
+			req, _ := http.NewRequest("POST", "/admin/restore"+tt.mode, body)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestExportImportEndpoints(t *testing.T) {
+	router := setupTestRouter()
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		expectedStatus int
+	}{
+		{
+			name:           "Export JSON Lines",
+			method:         "GET",
+			url:            "/kv/export?format=jsonl&prefix=app/",
+			expectedStatus: http.StatusInternalServerError, // Will fail due to no gRPC connection
+		},
+		{
+			name:           "Export unknown format",
+			method:         "GET",
+			url:            "/kv/export?format=xml",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Import CSV",
+			method:         "POST",
+			url:            "/kv/import?format=csv",
+			expectedStatus: http.StatusInternalServerError, // Will fail due to no gRPC connection
+		},
+		{
+			name:           "Import unknown format",
+			method:         "POST",
+			url:            "/kv/import?format=xml",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.url, bytes.NewBufferString("key,value\na,1\n"))
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestUndeleteEndpoint(t *testing.T) {
+	router := setupTestRouter()
+
+	tests := []struct {
+		name           string
+		key            string
+		expectedStatus int
+	}{
+		{
+			name:           "Valid key",
+			key:            "test-key",
+			expectedStatus: http.StatusInternalServerError, // Will fail due to no gRPC connection
+		},
+		{
+			name:           "Empty key",
+			key:            "",
+			expectedStatus: http.StatusNotFound, // No route match for /kv/undelete/
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/kv/undelete/"+tt.key, nil)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestListDeletedEndpoint(t *testing.T) {
+	router := setupTestRouter()
+
+	req, _ := http.NewRequest("GET", "/kv/deleted?prefix=app/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Will fail due to no gRPC connection
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestHistoryEndpoint(t *testing.T) {
+	router := setupTestRouter()
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+	}{
+		{
+			name:           "Valid key",
+			url:            "/kv/history/test-key",
+			expectedStatus: http.StatusInternalServerError, // Will fail due to no gRPC connection
+		},
+		{
+			name:           "Valid limit",
+			url:            "/kv/history/test-key?limit=5",
+			expectedStatus: http.StatusInternalServerError, // Will fail due to no gRPC connection
+		},
+		{
+			name:           "Invalid limit",
+			url:            "/kv/history/test-key?limit=-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Empty key",
+			url:            "/kv/history/",
+			expectedStatus: http.StatusNotFound, // No route match for /kv/history/
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.url, nil)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestRequestContext_ForwardsClientIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		header     string
+		remoteAddr string
+		expected   string
+	}{
+		{
+			name:       "Client ID header",
+			header:     "billing-service",
+			remoteAddr: "10.0.0.1:1234",
+			expected:   "billing-service",
+		},
+		{
+			name:       "Fallback to client IP",
+			header:     "",
+			remoteAddr: "10.0.0.1:1234",
+			expected:   "10.0.0.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request, _ = http.NewRequest("GET", "/", nil)
+			c.Request.RemoteAddr = tt.remoteAddr
+			if tt.header != "" {
+				c.Request.Header.Set(clientIDHeader, tt.header)
+			}
+
+			md, _ := metadata.FromOutgoingContext(requestContext(c))
+			if got := md.Get(clientIDMetadataKey); len(got) != 1 || got[0] != tt.expected {
+				t.Errorf("forwarded identity = %v, expected %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQuotaUsageEndpoint(t *testing.T) {
+	router := setupTestRouter()
+
+	req, _ := http.NewRequest("GET", "/admin/quotas", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// Will fail due to no gRPC connection
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestGRPCError_InvalidArgument(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		reason         string
+		expectedStatus int
+	}{
+		{"Empty key", "EMPTY_KEY", http.StatusBadRequest},
+		{"Invalid key format", "INVALID_KEY_FORMAT", http.StatusBadRequest},
+		{"Key too large", "KEY_TOO_LARGE", http.StatusRequestEntityTooLarge},
+		{"Value too large", "VALUE_TOO_LARGE", http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, _ := status.New(codes.InvalidArgument, "rejected").WithDetails(
+				&errdetails.ErrorInfo{Reason: tt.reason},
+				&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "key", Description: "rejected"}}},
+			)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			grpcError(c, st.Err())
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.reason) {
+				t.Errorf("Expected reason in body, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGRPCError_QuotaExceeded(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	st, _ := status.New(codes.ResourceExhausted, "quota exceeded").WithDetails(&errdetails.QuotaFailure{
+		Violations: []*errdetails.QuotaFailure_Violation{{Subject: "quota:tenant-a", Description: "max_keys 10 reached"}},
+	})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	grpcError(c, st.Err())
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "quota:tenant-a") {
+		t.Errorf("Expected quota violation in body, got %s", w.Body.String())
+	}
+}
+
+func TestGRPCError_StatusMapping(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		code           codes.Code
+		expectedStatus int
+	}{
+		{codes.Canceled, statusClientClosedRequest},
+		{codes.Unknown, http.StatusInternalServerError},
+		{codes.InvalidArgument, http.StatusBadRequest},
+		{codes.DeadlineExceeded, http.StatusGatewayTimeout},
+		{codes.NotFound, http.StatusNotFound},
+		{codes.AlreadyExists, http.StatusConflict},
+		{codes.PermissionDenied, http.StatusForbidden},
+		{codes.ResourceExhausted, http.StatusTooManyRequests},
+		{codes.FailedPrecondition, http.StatusConflict},
+		{codes.Aborted, http.StatusConflict},
+		{codes.OutOfRange, http.StatusBadRequest},
+		{codes.Unimplemented, http.StatusNotImplemented},
+		{codes.Internal, http.StatusInternalServerError},
+		{codes.Unavailable, http.StatusServiceUnavailable},
+		{codes.DataLoss, http.StatusInternalServerError},
+		{codes.Unauthenticated, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			grpcError(c, status.Error(tt.code, "failed"))
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestGRPCError_Details(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		err      error
+		expected map[string]any
+	}{
+		{
+			name: "Missing key",
+			err: func() error {
+				st, _ := status.New(codes.NotFound, "Key 'a' not found").WithDetails(
+					&errdetails.ErrorInfo{Reason: "KEY_NOT_FOUND"},
+					&errdetails.ResourceInfo{ResourceType: "key", ResourceName: "a"},
+				)
+				return st.Err()
+			}(),
+			expected: map[string]any{
+				"success":  false,
+				"message":  "Key 'a' not found",
+				"reason":   "KEY_NOT_FOUND",
+				"resource": map[string]any{"type": "key", "name": "a"},
+			},
+		},
+		{
+			name: "Read-only replica",
+			err: func() error {
+				st, _ := status.New(codes.FailedPrecondition, "read-only replica of kv1:50051").WithDetails(
+					&errdetails.ErrorInfo{Reason: "READ_ONLY_REPLICA", Metadata: map[string]string{"primary": "kv1:50051"}},
+					&errdetails.PreconditionFailure{Violations: []*errdetails.PreconditionFailure_Violation{
+						{Type: "READ_ONLY_REPLICA", Subject: "kv1:50051", Description: "read-only replica of kv1:50051"},
+					}},
+				)
+				return st.Err()
+			}(),
+			expected: map[string]any{
+				"reason":   "READ_ONLY_REPLICA",
+				"metadata": map[string]any{"primary": "kv1:50051"},
+				"precondition_violations": []any{
+					map[string]any{"type": "READ_ONLY_REPLICA", "subject": "kv1:50051", "description": "read-only replica of kv1:50051"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			grpcError(c, tt.err)
+
+			var body map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			for field, want := range tt.expected {
+				if !reflect.DeepEqual(body[field], want) {
+					t.Errorf("%s = %v, expected %v", field, body[field], want)
+				}
+			}
+		})
+	}
+}
+
+func TestSchemaEndpoints(t *testing.T) {
+	router := setupTestRouter()
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "Register schema",
+			method:         "POST",
+			url:            "/kv/schemas",
+			body:           `{"prefix":"config/","schema":{"type":"object"}}`,
+			expectedStatus: http.StatusInternalServerError, // Will fail due to no gRPC connection
+		},
+		{
+			name:           "Register without schema",
+			method:         "POST",
+			url:            "/kv/schemas",
+			body:           `{"prefix":"config/"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "List schemas",
+			method:         "GET",
+			url:            "/kv/schemas",
+			expectedStatus: http.StatusInternalServerError, // Will fail due to no gRPC connection
+		},
+		{
+			name:           "Delete schema",
+			method:         "DELETE",
+			url:            "/kv/schemas?prefix=config/",
+			expectedStatus: http.StatusInternalServerError, // Will fail due to no gRPC connection
+		},
+		{
+			name:           "Delete without prefix",
+			method:         "DELETE",
+			url:            "/kv/schemas",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.url, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestTopKeysEndpoint(t *testing.T) {
+	router := setupTestRouter()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{
+			name:           "Default limit",
+			query:          "",
+			expectedStatus: http.StatusInternalServerError, // Will fail due to no gRPC connection
+		},
+		{
+			name:           "Invalid limit",
+			query:          "?limit=-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/admin/hotkeys"+tt.query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestReplicationEndpoints(t *testing.T) {
+	router := setupTestRouter()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"Status", "GET", "/admin/replication"},
+		{"Promote", "POST", "/admin/promote"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			// Will fail due to no gRPC connection
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+			}
+		})
+	}
+}
+
+func TestCrdtEndpoints(t *testing.T) {
+	router := setupTestRouter()
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+	}{
+		{"Valid update", "POST", `{"type":"pn-counter","op":"increment","amount":2}`, http.StatusInternalServerError}, // Will fail due to no gRPC connection
+		{"Unknown type", "POST", `{"type":"counter","op":"increment"}`, http.StatusBadRequest},
+		{"Unknown operation", "POST", `{"type":"or-set","op":"insert","value":"x"}`, http.StatusBadRequest},
+		{"Missing operation", "POST", `{"type":"or-set"}`, http.StatusBadRequest},
+		{"Read", "GET", "", http.StatusInternalServerError}, // Will fail due to no gRPC connection
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, "/kv/crdt/visits", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+)
+
+// restoreBatchSize is the number of entries forwarded in each restore message
+const restoreBatchSize = 100
+
+// BackupEntry represents one line of a JSON Lines backup file. Keys written
+// by quorum writes keep their version, and CRDT keys their type.
+type BackupEntry struct {
+	Key      string            `json:"key"`
+	Value    string            `json:"value"`
+	Checksum uint32            `json:"checksum"`
+	Version  map[string]uint64 `json:"version,omitempty"`
+	Crdt     string            `json:"crdt,omitempty"`
+}
+
+// BackupSchema represents a schema line of a JSON Lines backup file
+type BackupSchema struct {
+	Prefix string          `json:"prefix"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// backupLine is either an entry or a schema; a line is a schema when it
+// carries one
+type backupLine struct {
+	BackupEntry
+	BackupSchema
+}
+
+// RestoreResponse represents the JSON response for restoring a backup
+type RestoreResponse struct {
+	Success      bool   `json:"success"`
+	KeysRestored int64  `json:"keys_restored"`
+	Message      string `json:"message"`
+}
+
+// restoreModes maps the mode query parameter to its gRPC value
+var restoreModes = map[string]proto.RestoreMode{
+	"replace": proto.RestoreMode_RESTORE_MODE_REPLACE,
+	"merge":   proto.RestoreMode_RESTORE_MODE_MERGE,
+}
+
+// Backup handles GET /admin/backup by streaming a snapshot as JSON Lines.
+// The snapshots of the shards are taken at the same time and written one
+// after the other, preceded by the registered schemas.
+func (s *APIServer) Backup(c *gin.Context) {
+	// Check if gRPC client is available (for testing)
+	r := s.layout()
+	if len(r.shards) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+	if r.migration != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "resharding is in progress; retry once it has finished"})
+		return
+	}
+	if s.quorum.enabled() {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": quorumUnsupported})
+		return
+	}
+
+	// Receive the first chunk of every shard before committing to a status
+	// code so that errors raised at the start of the backup are still
+	// reported cleanly
+	streams, firsts, err := openStreams(requestContext(c), r.shards, func(ctx context.Context, backend *shard) (grpc.ServerStreamingClient[proto.BackupChunk], error) {
+		return backend.client.Backup(ctx, &proto.BackupRequest{})
+	})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="kvstore-backup.jsonl"`)
+	c.Status(http.StatusOK)
+
+	// Every shard holds the same schemas, so each prefix is written once
+	enc := json.NewEncoder(c.Writer)
+	written := make(map[string]bool)
+	for _, first := range firsts {
+		if first == nil {
+			continue
+		}
+		for _, schema := range first.Schemas {
+			if written[schema.Prefix] {
+				continue
+			}
+			written[schema.Prefix] = true
+			if err := enc.Encode(BackupSchema{Prefix: schema.Prefix, Schema: json.RawMessage(schema.Schema)}); err != nil {
+				log.Printf("Backup aborted while writing response: %v", err)
+				panic(http.ErrAbortHandler)
+			}
+		}
+	}
+	for i, stream := range streams {
+		chunk := firsts[i]
+		for chunk != nil {
+			for _, entry := range chunk.Entries {
+				line := BackupEntry{
+					Key:      entry.Key,
+					Value:    entry.Value,
+					Checksum: entry.Checksum,
+					Version:  versionFromProto(entry.Version),
+					Crdt:     crdtTypeName(entry.Crdt),
+				}
+				if err := enc.Encode(line); err != nil {
+					log.Printf("Backup aborted while writing response: %v", err)
+					panic(http.ErrAbortHandler)
+				}
+			}
+			c.Writer.Flush()
+
+			chunk, err = stream.Recv()
+			if err != nil && err != io.EOF {
+				// Abort the connection so a truncated backup is never mistaken for a complete one
+				log.Printf("Backup aborted mid-stream: %v", err)
+				panic(http.ErrAbortHandler)
+			}
+		}
+	}
+}
+
+// Restore handles POST /admin/restore with a JSON Lines backup as the body.
+// Each entry is sent to the shard that owns its key and each schema to every
+// shard; in replace mode every shard is replaced, including those that
+// receive no entries.
+func (s *APIServer) Restore(c *gin.Context) {
+	mode, ok := restoreModes[c.DefaultQuery("mode", "replace")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be 'replace' or 'merge'"})
+		return
+	}
+
+	// Hold off a layout change until the write is done
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
+	// Check if gRPC client is available (for testing)
+	r := s.layout()
+	if len(r.shards) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+	if r.migration != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "resharding is in progress; retry once it has finished"})
+		return
+	}
+	if s.quorum.enabled() {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": quorumUnsupported})
+		return
+	}
+
+	// Cancelling the streams makes the servers discard everything staged so far
+	ctx, cancel := context.WithCancel(requestContext(c))
+	defer cancel()
+
+	streams := make([]grpc.ClientStreamingClient[proto.RestoreRequest, proto.RestoreResponse], len(r.shards))
+	err := fanOut(ctx, r.shards, func(ctx context.Context, i int, backend *shard) error {
+		var err error
+		streams[i], err = backend.client.Restore(ctx)
+		return err
+	})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	// The first message to each shard carries the mode
+	pending := make([]*proto.RestoreRequest, len(r.shards))
+	for i := range pending {
+		pending[i] = &proto.RestoreRequest{Mode: mode}
+	}
+
+	dec := json.NewDecoder(c.Request.Body)
+decode:
+	for line := 1; ; line++ {
+		var entry backupLine
+		err := dec.Decode(&entry)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid backup entry", "line": line, "detail": err.Error()})
+			return
+		}
+		if entry.Schema != nil {
+			for _, req := range pending {
+				req.Schemas = append(req.Schemas, &proto.KeySchema{Prefix: entry.Prefix, Schema: string(entry.Schema)})
+			}
+			continue
+		}
+
+		i := 0
+		if len(r.shards) > 1 {
+			i = r.ring.locate(entry.Key)
+		}
+		crdt, ok := crdtTypes[entry.Crdt]
+		if !ok && entry.Crdt != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid backup entry", "line": line, "detail": fmt.Sprintf("unknown CRDT type %q", entry.Crdt)})
+			return
+		}
+		req := pending[i]
+		req.Entries = append(req.Entries, &proto.BackupEntry{
+			Key:      entry.Key,
+			Value:    entry.Value,
+			Checksum: entry.Checksum,
+			Version:  versionToProto(entry.Version),
+			Crdt:     crdt,
+		})
+		if len(req.Entries) == restoreBatchSize {
+			if err := streams[i].Send(req); err != nil {
+				break decode // the real error is returned by CloseAndRecv
+			}
+			pending[i] = &proto.RestoreRequest{}
+		}
+	}
+
+	// Always send the final batches, even if empty, so the mode reaches every server
+	for i, stream := range streams {
+		if err := stream.Send(pending[i]); err != nil && err != io.EOF {
+			grpcError(c, err)
+			return
+		}
+	}
+
+	var restored int64
+	for _, stream := range streams {
+		grpcResp, err := stream.CloseAndRecv()
+		if err != nil {
+			grpcError(c, err)
+			return
+		}
+		restored += grpcResp.KeysRestored
+	}
+
+	c.JSON(http.StatusOK, RestoreResponse{
+		Success:      true,
+		KeysRestored: restored,
+		Message:      fmt.Sprintf("Restored %d keys", restored),
+	})
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// crdtTypes maps the CRDT type names of the API to their gRPC values
+var crdtTypes = map[string]proto.CrdtType{
+	"lww-register": proto.CrdtType_CRDT_TYPE_LWW_REGISTER,
+	"g-counter":    proto.CrdtType_CRDT_TYPE_G_COUNTER,
+	"pn-counter":   proto.CrdtType_CRDT_TYPE_PN_COUNTER,
+	"or-set":       proto.CrdtType_CRDT_TYPE_OR_SET,
+}
+
+// crdtOps maps the CRDT operation names of the API to their gRPC values
+var crdtOps = map[string]proto.CrdtOp{
+	"assign":    proto.CrdtOp_CRDT_OP_ASSIGN,
+	"increment": proto.CrdtOp_CRDT_OP_INCREMENT,
+	"decrement": proto.CrdtOp_CRDT_OP_DECREMENT,
+	"add":       proto.CrdtOp_CRDT_OP_ADD,
+	"remove":    proto.CrdtOp_CRDT_OP_REMOVE,
+}
+
+// crdtTypeName returns the API name of a CRDT type
+func crdtTypeName(t proto.CrdtType) string {
+	for name, value := range crdtTypes {
+		if value == t {
+			return name
+		}
+	}
+	return ""
+}
+
+// CrdtUpdateRequest represents the JSON request body for updating a CRDT
+type CrdtUpdateRequest struct {
+	Type   string `json:"type" binding:"required"`
+	Op     string `json:"op" binding:"required"`
+	Value  string `json:"value"`
+	Amount uint64 `json:"amount"`
+}
+
+// CrdtResponse represents the JSON response with the value of a CRDT
+type CrdtResponse struct {
+	Success  bool     `json:"success"`
+	Type     string   `json:"type,omitempty"`
+	Value    string   `json:"value,omitempty"`
+	Count    *int64   `json:"count,omitempty"`
+	Elements []string `json:"elements,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// crdtResponse builds the JSON response for a CRDT of type t
+func crdtResponse(success bool, message string, t proto.CrdtType, value string, count int64, elements []string) CrdtResponse {
+	resp := CrdtResponse{Success: success, Message: message, Type: crdtTypeName(t)}
+	switch t {
+	case proto.CrdtType_CRDT_TYPE_LWW_REGISTER:
+		resp.Value = value
+	case proto.CrdtType_CRDT_TYPE_G_COUNTER, proto.CrdtType_CRDT_TYPE_PN_COUNTER:
+		resp.Count = &count
+	case proto.CrdtType_CRDT_TYPE_OR_SET:
+		resp.Elements = append([]string{}, elements...)
+	}
+	return resp
+}
+
+// CrdtUpdate handles POST /kv/crdt/:key
+func (s *APIServer) CrdtUpdate(c *gin.Context) {
+	key := c.Param("key")
+	var req CrdtUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	crdtType, ok := crdtTypes[req.Type]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be 'lww-register', 'g-counter', 'pn-counter' or 'or-set'"})
+		return
+	}
+	op, ok := crdtOps[req.Op]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "op must be 'assign', 'increment', 'decrement', 'add' or 'remove'"})
+		return
+	}
+	if s.quorum.enabled() {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "CRDTs are " + quorumUnsupported})
+		return
+	}
+
+	// Hold off a layout change until the write is done
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
+	// Check if gRPC client is available (for testing)
+	r := s.layout()
+	if len(r.shards) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+	backend := r.owner(key)
+
+	// While resharding, keep the copier from overwriting the new state
+	if r.migration != nil {
+		defer r.migration.claim(key)()
+	}
+
+	// Call gRPC service
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
+	defer cancel()
+
+	grpcResp, err := backend.client.CrdtUpdate(ctx, &proto.CrdtUpdateRequest{
+		Key:    key,
+		Type:   crdtType,
+		Op:     op,
+		Value:  req.Value,
+		Amount: req.Amount,
+	})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, crdtResponse(grpcResp.Success, grpcResp.Message, grpcResp.Type, grpcResp.Value, grpcResp.Count, grpcResp.Elements))
+}
+
+// CrdtGet handles GET /kv/crdt/:key
+func (s *APIServer) CrdtGet(c *gin.Context) {
+	key := c.Param("key")
+	if s.quorum.enabled() {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "CRDTs are " + quorumUnsupported})
+		return
+	}
+
+	// Check if gRPC client is available (for testing)
+	backend := s.shardFor(key)
+	if backend == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+
+	// Call gRPC service
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
+	defer cancel()
+
+	grpcResp, err := backend.client.CrdtGet(ctx, &proto.CrdtGetRequest{Key: key})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if !grpcResp.Success {
+		status = http.StatusNotFound
+	}
+	c.JSON(status, crdtResponse(grpcResp.Success, grpcResp.Message, grpcResp.Type, grpcResp.Value, grpcResp.Count, grpcResp.Elements))
+}
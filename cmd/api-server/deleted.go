@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UndeleteResponse represents the JSON response for restoring a deleted key
+type UndeleteResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// DeletedKey represents a soft-deleted key and its retention window
+type DeletedKey struct {
+	Key       string    `json:"key"`
+	DeletedAt time.Time `json:"deleted_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ListDeletedResponse represents the JSON response for listing deleted keys
+type ListDeletedResponse struct {
+	Success bool         `json:"success"`
+	Keys    []DeletedKey `json:"keys"`
+	Message string       `json:"message"`
+}
+
+// Undelete handles POST /kv/undelete/:key
+func (s *APIServer) Undelete(c *gin.Context) {
+	key := c.Param("key")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key parameter is required"})
+		return
+	}
+
+	// Check if gRPC client is available (for testing)
+	backend := s.shardFor(key)
+	if backend == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+
+	// Call gRPC service
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
+	defer cancel()
+
+	grpcResp, err := backend.client.Undelete(ctx, &proto.UndeleteRequest{Key: key})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if !grpcResp.Success {
+		status = http.StatusNotFound
+	}
+
+	c.JSON(status, UndeleteResponse{
+		Success: grpcResp.Success,
+		Message: grpcResp.Message,
+	})
+}
+
+// ListDeleted handles GET /kv/deleted?prefix=... by merging the deleted
+// keys of every shard
+func (s *APIServer) ListDeleted(c *gin.Context) {
+	// Check if gRPC client is available (for testing)
+	shards := s.backends()
+	if len(shards) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+
+	// Call gRPC service
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
+	defer cancel()
+
+	responses := make([]*proto.ListDeletedResponse, len(shards))
+	err := fanOut(ctx, shards, func(ctx context.Context, i int, backend *shard) error {
+		var err error
+		responses[i], err = backend.client.ListDeleted(ctx, &proto.ListDeletedRequest{Prefix: c.Query("prefix")})
+		return err
+	})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	// While resharding a key may briefly be on two shards
+	keys := make([]DeletedKey, 0)
+	seen := make(map[string]bool)
+	for _, resp := range responses {
+		for _, k := range resp.Keys {
+			if seen[k.Key] {
+				continue
+			}
+			seen[k.Key] = true
+			keys = append(keys, DeletedKey{
+				Key:       k.Key,
+				DeletedAt: time.Unix(0, k.DeletedAt).UTC(),
+				ExpiresAt: time.Unix(0, k.ExpiresAt).UTC(),
+			})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+
+	c.JSON(http.StatusOK, ListDeletedResponse{
+		Success: true,
+		Keys:    keys,
+		Message: fmt.Sprintf("Found %d deleted keys", len(keys)),
+	})
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// statusClientClosedRequest is the non-standard HTTP status of a request
+// cancelled by its client
+const statusClientClosedRequest = 499
+
+// httpStatuses maps every gRPC status code to the HTTP status it is
+// reported as
+var httpStatuses = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           statusClientClosedRequest,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusConflict,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// tooLargeReasons are the ErrorInfo reasons of writes rejected for their size
+var tooLargeReasons = map[string]bool{
+	"KEY_TOO_LARGE":   true,
+	"VALUE_TOO_LARGE": true,
+}
+
+// httpStatus returns the HTTP status a gRPC status code is reported as
+func httpStatus(code codes.Code) int {
+	if s, ok := httpStatuses[code]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// notFound reports whether a backend call failed because what it named
+// does not exist. Servers in legacy status mode answer with success=false
+// instead, which callers keep handling too.
+func notFound(err error) bool {
+	return status.Code(err) == codes.NotFound
+}
+
+// grpcError writes the JSON error response for a failed gRPC call. Field and
+// quota violations carried in the status details are passed through so
+// clients can tell which rule or limit they hit. The body also carries the
+// success and message fields of a successful response, so that clients
+// reading those keep working.
+func grpcError(c *gin.Context, err error) {
+	st := status.Convert(err)
+	httpStatus := httpStatus(st.Code())
+
+	body := gin.H{"success": false, "message": st.Message(), "error": err.Error()}
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.ErrorInfo:
+			body["reason"] = d.Reason
+			if len(d.Metadata) > 0 {
+				body["metadata"] = d.Metadata
+			}
+			if st.Code() == codes.InvalidArgument && tooLargeReasons[d.Reason] {
+				httpStatus = http.StatusRequestEntityTooLarge
+			}
+		case *errdetails.BadRequest:
+			violations := make([]gin.H, 0, len(d.FieldViolations))
+			for _, v := range d.FieldViolations {
+				violations = append(violations, gin.H{"field": v.Field, "description": v.Description})
+			}
+			body["field_violations"] = violations
+		case *errdetails.QuotaFailure:
+			violations := make([]gin.H, 0, len(d.Violations))
+			for _, v := range d.Violations {
+				violations = append(violations, gin.H{"subject": v.Subject, "description": v.Description})
+			}
+			body["quota_violations"] = violations
+		case *errdetails.PreconditionFailure:
+			violations := make([]gin.H, 0, len(d.Violations))
+			for _, v := range d.Violations {
+				violations = append(violations, gin.H{"type": v.Type, "subject": v.Subject, "description": v.Description})
+			}
+			body["precondition_violations"] = violations
+		case *errdetails.ResourceInfo:
+			body["resource"] = gin.H{"type": d.ResourceType, "name": d.ResourceName}
+		}
+	}
+
+	c.JSON(httpStatus, body)
+}
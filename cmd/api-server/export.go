@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+)
+
+// importReadSize is the size of the body pieces forwarded to the import stream
+const importReadSize = 32 * 1024
+
+// csvHeaderLine is the header row that starts every CSV export
+var csvHeaderLine = []byte("key,value\n")
+
+// dataFormats maps the format query parameter to its gRPC value
+var dataFormats = map[string]proto.DataFormat{
+	"jsonl": proto.DataFormat_DATA_FORMAT_JSONL,
+	"csv":   proto.DataFormat_DATA_FORMAT_CSV,
+}
+
+// exportContentTypes maps each export format to the content type it is served as
+var exportContentTypes = map[proto.DataFormat]string{
+	proto.DataFormat_DATA_FORMAT_JSONL: "application/x-ndjson",
+	proto.DataFormat_DATA_FORMAT_CSV:   "text/csv",
+}
+
+// ImportError represents a row that could not be imported
+type ImportError struct {
+	Line  int64  `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportProgress represents one line of the JSON Lines progress report for an import
+type ImportProgress struct {
+	RowsRead     int64         `json:"rows_read"`
+	RowsImported int64         `json:"rows_imported"`
+	RowsFailed   int64         `json:"rows_failed"`
+	Errors       []ImportError `json:"errors,omitempty"`
+	Done         bool          `json:"done"`
+}
+
+// Export handles GET /kv/export?format=jsonl|csv&prefix=... Every shard
+// exports its keys in key order and the shards are written one after the
+// other.
+func (s *APIServer) Export(c *gin.Context) {
+	format, ok := dataFormats[c.DefaultQuery("format", "jsonl")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be 'jsonl' or 'csv'"})
+		return
+	}
+
+	// Check if gRPC client is available (for testing)
+	r := s.layout()
+	if len(r.shards) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+	if r.migration != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "resharding is in progress; retry once it has finished"})
+		return
+	}
+	if s.quorum.enabled() {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": quorumUnsupported})
+		return
+	}
+
+	// Receive the first chunk of every shard before committing to a status code
+	streams, firsts, err := openStreams(requestContext(c), r.shards, func(ctx context.Context, backend *shard) (grpc.ServerStreamingClient[proto.ExportChunk], error) {
+		return backend.client.Export(ctx, &proto.ExportRequest{
+			Format: format,
+			Prefix: c.Query("prefix"),
+		})
+	})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", exportContentTypes[format])
+	c.Status(http.StatusOK)
+
+	for i, stream := range streams {
+		chunk := firsts[i]
+		if chunk != nil && i > 0 && format == proto.DataFormat_DATA_FORMAT_CSV {
+			// Only the first shard's header row is kept
+			chunk.Data = bytes.TrimPrefix(chunk.Data, csvHeaderLine)
+		}
+
+		for chunk != nil {
+			if _, err := c.Writer.Write(chunk.Data); err != nil {
+				log.Printf("Export aborted while writing response: %v", err)
+				panic(http.ErrAbortHandler)
+			}
+			c.Writer.Flush()
+
+			chunk, err = stream.Recv()
+			if err != nil && err != io.EOF {
+				log.Printf("Export aborted mid-stream: %v", err)
+				panic(http.ErrAbortHandler)
+			}
+		}
+	}
+}
+
+// Import handles POST /kv/import?format=jsonl|csv. The request body is
+// streamed to the backend and progress is streamed back as JSON Lines, the
+// last line having "done": true. With several shards the rows are split
+// between them by importSharded.
+func (s *APIServer) Import(c *gin.Context) {
+	format, ok := dataFormats[c.DefaultQuery("format", "jsonl")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be 'jsonl' or 'csv'"})
+		return
+	}
+
+	// Hold off a layout change until the write is done
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
+	// Check if gRPC client is available (for testing)
+	r := s.layout()
+	if len(r.shards) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+	if r.migration != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "resharding is in progress; retry once it has finished"})
+		return
+	}
+	if s.quorum.enabled() {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": quorumUnsupported})
+		return
+	}
+	if len(r.shards) > 1 {
+		s.importSharded(c, format, r)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(requestContext(c))
+	defer cancel()
+
+	stream, err := r.shards[0].client.Import(ctx)
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	// Progress is written while the body is still being read
+	http.NewResponseController(c.Writer).EnableFullDuplex()
+
+	go func() {
+		if err := stream.Send(&proto.ImportRequest{Format: format}); err != nil {
+			return
+		}
+		buf := make([]byte, importReadSize)
+		for {
+			n, err := c.Request.Body.Read(buf)
+			if n > 0 {
+				if err := stream.Send(&proto.ImportRequest{Data: bytes.Clone(buf[:n])}); err != nil {
+					return
+				}
+			}
+			if err == io.EOF {
+				stream.CloseSend()
+				return
+			}
+			if err != nil {
+				log.Printf("Import aborted while reading request: %v", err)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	// Receive the first report before committing to a status code
+	progress, err := stream.Recv()
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	enc := json.NewEncoder(c.Writer)
+	for {
+		report := ImportProgress{
+			RowsRead:     progress.RowsRead,
+			RowsImported: progress.RowsImported,
+			RowsFailed:   progress.RowsFailed,
+			Done:         progress.Done,
+		}
+		for _, e := range progress.Errors {
+			report.Errors = append(report.Errors, ImportError{Line: e.Line, Error: e.Error})
+		}
+		if err := enc.Encode(report); err != nil {
+			log.Printf("Import aborted while writing response: %v", err)
+			panic(http.ErrAbortHandler)
+		}
+		c.Writer.Flush()
+
+		if progress.Done {
+			return
+		}
+
+		progress, err = stream.Recv()
+		if err != nil {
+			log.Printf("Import aborted mid-stream: %v", err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/pwntato/Censys/proto"
+)
+
+// handoff asks another server to hold a write or delete for a replica
+// that could not be reached and deliver it once the replica is back, so a
+// brief outage leaves nothing to repair. The servers following the key's
+// replicas on the ring are tried first, then the write's coordinator. A
+// hinted write does not count towards W.
+func (s *APIServer) handoff(ctx context.Context, r *routing, coordinator, target *shard, hint *proto.StoreHintRequest) {
+	hint.Target = target.addr
+	holders := append(r.replicas(hint.Key, len(r.shards))[s.quorum.n:], coordinator)
+
+	var err error
+	for _, holder := range holders {
+		if _, err = holder.replication.StoreHint(ctx, hint); err == nil {
+			s.quorumStats.hintsStored.Add(1)
+			return
+		}
+	}
+	log.Printf("No server could hold the write of key '%s' for %s: %v", hint.Key, target.addr, err)
+	s.quorumStats.hintFailures.Add(1)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HistoryEntry represents a recorded change to a key
+type HistoryEntry struct {
+	Revision  uint64    `json:"revision"`
+	Timestamp time.Time `json:"timestamp"`
+	Operation string    `json:"operation"`
+	Value     string    `json:"value,omitempty"`
+	Client    string    `json:"client"`
+}
+
+// HistoryResponse represents the JSON response for the history of a key
+type HistoryResponse struct {
+	Success bool           `json:"success"`
+	Entries []HistoryEntry `json:"entries"`
+	Message string         `json:"message"`
+}
+
+// History handles GET /kv/history/:key?limit=N
+func (s *APIServer) History(c *gin.Context) {
+	key := c.Param("key")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key parameter is required"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a non-negative integer"})
+		return
+	}
+
+	// Check if gRPC client is available (for testing)
+	backend := s.shardFor(key)
+	if backend == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+
+	// Call gRPC service
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
+	defer cancel()
+
+	grpcResp, err := backend.client.History(ctx, &proto.HistoryRequest{Key: key, Limit: int32(limit)})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if !grpcResp.Success {
+		status = http.StatusNotFound
+	}
+
+	entries := make([]HistoryEntry, 0, len(grpcResp.Entries))
+	for _, e := range grpcResp.Entries {
+		entries = append(entries, HistoryEntry{
+			Revision:  e.Revision,
+			Timestamp: time.Unix(0, e.Timestamp).UTC(),
+			Operation: strings.ToLower(strings.TrimPrefix(e.Op.String(), "MUTATION_OP_")),
+			Value:     e.Value,
+			Client:    e.Client,
+		})
+	}
+
+	c.JSON(status, HistoryResponse{
+		Success: grpcResp.Success,
+		Entries: entries,
+		Message: grpcResp.Message,
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HotKey represents the estimated access counts for a key
+type HotKey struct {
+	Key    string `json:"key"`
+	Reads  int64  `json:"reads"`
+	Writes int64  `json:"writes"`
+}
+
+// TopKeysResponse represents the JSON response for the most accessed keys
+type TopKeysResponse struct {
+	Success    bool     `json:"success"`
+	Keys       []HotKey `json:"keys"`
+	SampleRate float64  `json:"sample_rate"`
+	Message    string   `json:"message"`
+}
+
+// TopKeys handles GET /admin/hotkeys?limit=N by merging the hot keys of
+// every shard
+func (s *APIServer) TopKeys(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a non-negative integer"})
+		return
+	}
+
+	// Check if gRPC client is available (for testing)
+	shards := s.backends()
+	if len(shards) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+
+	// Call gRPC service
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
+	defer cancel()
+
+	responses := make([]*proto.TopKeysResponse, len(shards))
+	err = fanOut(ctx, shards, func(ctx context.Context, i int, backend *shard) error {
+		var err error
+		responses[i], err = backend.client.TopKeys(ctx, &proto.TopKeysRequest{Limit: int32(limit)})
+		return err
+	})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	// Each key lives on one shard, so the busiest keys overall are among
+	// the busiest of some shard
+	keys := make([]HotKey, 0)
+	for _, resp := range responses {
+		for _, k := range resp.Keys {
+			keys = append(keys, HotKey{Key: k.Key, Reads: k.Reads, Writes: k.Writes})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ti, tj := keys[i].Reads+keys[i].Writes, keys[j].Reads+keys[j].Writes
+		if ti != tj {
+			return ti > tj
+		}
+		return keys[i].Key < keys[j].Key
+	})
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	c.JSON(http.StatusOK, TopKeysResponse{
+		Success:    true,
+		Keys:       keys,
+		SampleRate: responses[0].SampleRate,
+		Message:    fmt.Sprintf("Found %d hot keys", len(keys)),
+	})
+}
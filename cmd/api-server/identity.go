@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// clientIDHeader is the HTTP header callers use to identify themselves
+	clientIDHeader = "X-Client-ID"
+
+	// clientIDMetadataKey is the gRPC metadata key the identity is forwarded under
+	clientIDMetadataKey = "x-client-id"
+)
+
+// requestContext returns the context for a backend call made on behalf of an
+// HTTP request. It carries the caller's identity: the X-Client-ID header
+// when set, otherwise the caller's IP address.
+func requestContext(c *gin.Context) context.Context {
+	id := c.GetHeader(clientIDHeader)
+	if id == "" {
+		id = c.ClientIP()
+	}
+	return metadata.AppendToOutgoingContext(c.Request.Context(), clientIDMetadataKey, id)
+}
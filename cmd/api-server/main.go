@@ -5,49 +5,85 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/pwntato/Censys/internal/certs"
 	"github.com/pwntato/Censys/proto"
 
 	"github.com/gin-gonic/gin"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 )
 
-// APIServer handles HTTP requests and forwards them to the gRPC service
+// APIServer handles HTTP requests and forwards them to the gRPC services of
+// one or more kvstore-servers, each holding the keys of one shard
 type APIServer struct {
-	grpcClient proto.KeyValueStoreClient
+	routing      atomic.Pointer[routing]
+	virtualNodes int
+
+	// writes is held for reading by requests that write through the
+	// layout, so that a resharding starts only once they have finished
+	writes sync.RWMutex
+
+	// reshardMu serializes changes to the layout; dialed holds the
+	// connection to every backend ever used, by address
+	reshardMu sync.Mutex
+	dialed    map[string]*shard
+	reshard   *migration // the latest resharding, nil if none was started
+
+	// quorum replicates every key across several shards when enabled;
+	// quorumStats counts the repairs it makes
+	quorum      quorum
+	quorumStats quorumStats
+
+	// pending counts the replica writes and read repairs that outlive
+	// their request, for shutdown to wait for
+	pending sync.WaitGroup
 }
 
-// NewAPIServer creates a new API server instance
-func NewAPIServer(grpcAddr string) (*APIServer, error) {
-	// Connect to the gRPC server
-	conn, err := grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+// NewAPIServer creates a new API server instance sharding keys across the
+// backends at grpcAddrs
+func NewAPIServer(grpcAddrs []string, virtualNodes int) (*APIServer, error) {
+	s := &APIServer{virtualNodes: virtualNodes, dialed: make(map[string]*shard)}
+
+	// Connect to the gRPC servers
+	shards, err := s.dial(grpcAddrs)
 	if err != nil {
 		return nil, err
 	}
 
-	client := proto.NewKeyValueStoreClient(conn)
-	return &APIServer{grpcClient: client}, nil
+	s.routing.Store(&routing{shards: shards, ring: newHashRing(grpcAddrs, virtualNodes)})
+	return s, nil
 }
 
 // SetRequest represents the JSON request body for setting a key-value pair
 type SetRequest struct {
 	Key   string `json:"key" binding:"required"`
 	Value string `json:"value" binding:"required"`
+
+	// Version is the version last read, for a quorum write
+	Version map[string]uint64 `json:"version,omitempty"`
 }
 
 // SetResponse represents the JSON response for setting a key-value pair
 type SetResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
+	Success bool              `json:"success"`
+	Message string            `json:"message"`
+	Version map[string]uint64 `json:"version,omitempty"`
 }
 
 // GetResponse represents the JSON response for getting a value
 type GetResponse struct {
-	Success bool   `json:"success"`
-	Value   string `json:"value,omitempty"`
-	Message string `json:"message"`
+	Success  bool              `json:"success"`
+	Value    string            `json:"value,omitempty"`
+	Message  string            `json:"message"`
+	Version  map[string]uint64 `json:"version,omitempty"`
+	Siblings []Sibling         `json:"siblings,omitempty"`
 }
 
 // DeleteResponse represents the JSON response for deleting a key
@@ -63,23 +99,38 @@ func (s *APIServer) Set(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if s.quorum.enabled() {
+		s.quorumSet(c, req)
+		return
+	}
+
+	// Hold off a layout change until the write is done
+	s.writes.RLock()
+	defer s.writes.RUnlock()
 
 	// Check if gRPC client is available (for testing)
-	if s.grpcClient == nil {
+	r := s.layout()
+	if len(r.shards) == 0 {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
 		return
 	}
+	backend := r.owner(req.Key)
+
+	// While resharding, keep the copier from overwriting the new value
+	if r.migration != nil {
+		defer r.migration.claim(req.Key)()
+	}
 
 	// Call gRPC service
-	ctx, cancel := context.WithTimeout(c, 5*time.Second)
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
 	defer cancel()
 
-	grpcResp, err := s.grpcClient.Set(ctx, &proto.SetRequest{
+	grpcResp, err := backend.client.Set(ctx, &proto.SetRequest{
 		Key:   req.Key,
 		Value: req.Value,
 	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		grpcError(c, err)
 		return
 	}
 
@@ -101,20 +152,32 @@ func (s *APIServer) Get(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "key parameter is required"})
 		return
 	}
+	if s.quorum.enabled() {
+		s.quorumGet(c, key)
+		return
+	}
 
 	// Check if gRPC client is available (for testing)
-	if s.grpcClient == nil {
+	r := s.layout()
+	if len(r.shards) == 0 {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
 		return
 	}
+	backend := r.owner(key)
 
 	// Call gRPC service
-	ctx, cancel := context.WithTimeout(c, 5*time.Second)
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
 	defer cancel()
 
-	grpcResp, err := s.grpcClient.Get(ctx, &proto.GetRequest{Key: key})
+	grpcResp, err := backend.client.Get(ctx, &proto.GetRequest{Key: key})
+	if r.migration != nil && (notFound(err) || err == nil && !grpcResp.Success) {
+		// While resharding, the key may not have been copied to its new owner yet
+		if old := r.migration.previous.owner(key); old != backend {
+			grpcResp, err = old.client.Get(ctx, &proto.GetRequest{Key: key})
+		}
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		grpcError(c, err)
 		return
 	}
 
@@ -137,20 +200,47 @@ func (s *APIServer) Delete(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "key parameter is required"})
 		return
 	}
+	if s.quorum.enabled() {
+		s.quorumDelete(c, key)
+		return
+	}
+
+	// Hold off a layout change until the write is done
+	s.writes.RLock()
+	defer s.writes.RUnlock()
 
 	// Check if gRPC client is available (for testing)
-	if s.grpcClient == nil {
+	r := s.layout()
+	if len(r.shards) == 0 {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
 		return
 	}
+	backend := r.owner(key)
+
+	// While resharding, keep the copier from bringing the key back
+	if r.migration != nil {
+		defer r.migration.claim(key)()
+	}
 
 	// Call gRPC service
-	ctx, cancel := context.WithTimeout(c, 5*time.Second)
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
 	defer cancel()
 
-	grpcResp, err := s.grpcClient.Delete(ctx, &proto.DeleteRequest{Key: key})
+	grpcResp, err := backend.client.Delete(ctx, &proto.DeleteRequest{Key: key})
+	if r.migration != nil && (err == nil || notFound(err)) {
+		// The key may also still be on its old owner
+		if old := r.migration.previous.owner(key); old != backend {
+			oldResp, oldErr := old.client.Delete(ctx, &proto.DeleteRequest{Key: key})
+			switch {
+			case oldErr != nil && !notFound(oldErr):
+				err = oldErr
+			case err != nil || !grpcResp.Success:
+				grpcResp, err = oldResp, oldErr
+			}
+		}
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		grpcError(c, err)
 		return
 	}
 
@@ -165,16 +255,63 @@ func (s *APIServer) Delete(c *gin.Context) {
 	})
 }
 
-// Health handles GET /health
+// Health handles GET /health. The API server is healthy when every backend
+// reports itself serving through the gRPC health service; the status of each
+// is listed by address.
 func (s *APIServer) Health(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"success": true, "status": "healthy"})
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
+	defer cancel()
+
+	shards := s.backends()
+	statuses := make([]string, len(shards))
+	fanOut(ctx, shards, func(ctx context.Context, i int, backend *shard) error {
+		resp, err := backend.health.Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			statuses[i] = status.Code(err).String()
+		} else {
+			statuses[i] = resp.Status.String()
+		}
+		return nil
+	})
+
+	healthy := true
+	backends := make(map[string]string, len(shards))
+	for i, backend := range shards {
+		backends[backend.addr] = statuses[i]
+		healthy = healthy && statuses[i] == healthpb.HealthCheckResponse_SERVING.String()
+	}
+	if !healthy {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"success": false, "status": "unhealthy", "backends": backends})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "status": "healthy", "backends": backends})
 }
 
 func main() {
-	// Get gRPC server address from environment variable, default to kvstore-server:50051
-	grpcAddr := os.Getenv("GRPC_SERVER_ADDRESS")
-	if grpcAddr == "" {
-		grpcAddr = "kvstore-server:50051"
+	// Get the gRPC server addresses from environment variables: a list of
+	// shards, or a single server defaulting to kvstore-server:50051
+	grpcAddrs := os.Getenv("GRPC_SERVER_ADDRESSES")
+	if grpcAddrs == "" {
+		grpcAddrs = os.Getenv("GRPC_SERVER_ADDRESS")
+	}
+	if grpcAddrs == "" {
+		grpcAddrs = "kvstore-server:50051"
+	}
+	addrs, err := parseShardAddresses(grpcAddrs)
+	if err != nil {
+		log.Fatalf("Invalid GRPC_SERVER_ADDRESSES: %v", err)
+	}
+
+	virtualNodes := defaultVirtualNodes
+	if v := os.Getenv("SHARD_VIRTUAL_NODES"); v != "" {
+		if virtualNodes, err = strconv.Atoi(v); err != nil || virtualNodes <= 0 {
+			log.Fatalf("Invalid SHARD_VIRTUAL_NODES: must be a positive integer")
+		}
+	}
+
+	q, err := quorumFromEnv(len(addrs))
+	if err != nil {
+		log.Fatalf("Invalid quorum configuration: %v", err)
 	}
 
 	// Get API port from environment variable, default to 8080
@@ -183,11 +320,40 @@ func main() {
 		port = "8080"
 	}
 
+	shutdownTimeout := defaultShutdownTimeout
+	if v := os.Getenv("API_SHUTDOWN_TIMEOUT"); v != "" {
+		if shutdownTimeout, err = time.ParseDuration(v); err != nil || shutdownTimeout <= 0 {
+			log.Fatalf("Invalid API_SHUTDOWN_TIMEOUT: must be a positive duration")
+		}
+	}
+
+	// Certificates are reloaded when they change until shutdown
+	watching, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	backendCerts, err := backendTLSFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if backendCerts != nil {
+		useBackendTLS(backendCerts, os.Getenv("GRPC_TLS_SERVER_NAME"))
+		go backendCerts.Watch(watching, certs.ReloadInterval)
+		log.Printf("Connecting to kvstore servers over TLS")
+	}
+	httpsCerts, err := httpsFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
 	// Create API server
-	apiServer, err := NewAPIServer(grpcAddr)
+	apiServer, err := NewAPIServer(addrs, virtualNodes)
 	if err != nil {
 		log.Fatalf("Failed to create API server: %v", err)
 	}
+	log.Printf("Sharding keys across %d kvstore servers", len(addrs))
+	if q.enabled() {
+		apiServer.quorum = q
+		log.Printf("Replicating every key to %d servers (R=%d, W=%d)", q.n, q.r, q.w)
+	}
 
 	// Set Gin to release mode for production
 	gin.SetMode(gin.ReleaseMode)
@@ -199,7 +365,7 @@ func main() {
 	router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
 		c.Header("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, X-Client-ID")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -214,10 +380,54 @@ func main() {
 	router.POST("/kv/set", apiServer.Set)
 	router.GET("/kv/get/:key", apiServer.Get)
 	router.DELETE("/kv/delete/:key", apiServer.Delete)
+	router.POST("/kv/undelete/:key", apiServer.Undelete)
+	router.GET("/kv/deleted", apiServer.ListDeleted)
+	router.GET("/kv/history/:key", apiServer.History)
+	router.POST("/kv/crdt/:key", apiServer.CrdtUpdate)
+	router.GET("/kv/crdt/:key", apiServer.CrdtGet)
+	router.POST("/kv/schemas", apiServer.RegisterSchema)
+	router.GET("/kv/schemas", apiServer.ListSchemas)
+	router.DELETE("/kv/schemas", apiServer.DeleteSchema)
+	router.GET("/kv/export", apiServer.Export)
+	router.POST("/kv/import", apiServer.Import)
+	router.GET("/admin/backup", apiServer.Backup)
+	router.POST("/admin/restore", apiServer.Restore)
+	router.GET("/admin/quotas", apiServer.QuotaUsage)
+	router.GET("/admin/hotkeys", apiServer.TopKeys)
+	router.GET("/admin/replication", apiServer.ReplicationStatus)
+	router.POST("/admin/promote", apiServer.Promote)
+	router.POST("/admin/reshard", apiServer.Reshard)
+	router.GET("/admin/reshard", apiServer.ReshardStatus)
+	router.GET("/admin/quorum", apiServer.QuorumStatus)
+
+	// Start server, over HTTPS when a certificate is configured
+	srv := &http.Server{Addr: ":" + port, Handler: router}
+	served := make(chan error, 1)
+	if httpsCerts != nil {
+		srv.TLSConfig = httpsCerts.ServerConfig()
+		go httpsCerts.Watch(watching, certs.ReloadInterval)
+		log.Printf("API server starting on :%s over HTTPS", port)
+		go func() {
+			served <- srv.ListenAndServeTLS("", "")
+		}()
+	} else {
+		log.Printf("API server starting on :%s", port)
+		go func() {
+			served <- srv.ListenAndServe()
+		}()
+	}
 
-	// Start server
-	log.Printf("API server starting on :%s", port)
-	if err := router.Run(":" + port); err != nil {
+	// On SIGINT or SIGTERM, requests in flight are drained before exiting
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-served:
 		log.Fatalf("Failed to start API server: %v", err)
+	case sig := <-signals:
+		log.Printf("Received %v, draining requests for up to %v", sig, shutdownTimeout)
+	}
+	if err := apiServer.shutdown(srv, shutdownTimeout); err != nil {
+		log.Printf("Requests still in flight after %v were cut off: %v", shutdownTimeout, err)
 	}
+	log.Printf("API server stopped")
 }
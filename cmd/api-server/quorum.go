@@ -0,0 +1,521 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// quorumUnsupported is the error of the endpoints that move whole shards,
+// which would see every key once per replica
+const quorumUnsupported = "not available with quorum replication"
+
+// quorum is how keys are replicated in a leaderless deployment: every key
+// is stored on n shards, and reads and writes succeed once r and w of them
+// respond. It is disabled when n is at most 1.
+type quorum struct {
+	n, r, w int
+}
+
+// enabled reports whether keys are replicated across shards
+func (q quorum) enabled() bool {
+	return q.n > 1
+}
+
+// quorumFromEnv reads QUORUM_N, QUORUM_R and QUORUM_W for a deployment of
+// the given number of shards. R and W default to a majority of N.
+func quorumFromEnv(shards int) (quorum, error) {
+	read := func(name string, def int) (int, error) {
+		v := os.Getenv(name)
+		if v == "" {
+			return def, nil
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("%s must be a positive integer", name)
+		}
+		return n, nil
+	}
+
+	var q quorum
+	var err error
+	if q.n, err = read("QUORUM_N", 1); err != nil {
+		return quorum{}, err
+	}
+	if q.n > shards {
+		return quorum{}, fmt.Errorf("QUORUM_N is %d but there are only %d kvstore servers", q.n, shards)
+	}
+	if q.r, err = read("QUORUM_R", q.n/2+1); err != nil {
+		return quorum{}, err
+	}
+	if q.w, err = read("QUORUM_W", q.n/2+1); err != nil {
+		return quorum{}, err
+	}
+	if q.r > q.n || q.w > q.n {
+		return quorum{}, fmt.Errorf("QUORUM_R and QUORUM_W must not exceed QUORUM_N")
+	}
+	return q, nil
+}
+
+// level reads a per-request override of R or W from the query parameter
+// name: "one", "quorum", "all" or a number of replicas
+func (q quorum) level(c *gin.Context, name string, def int) (int, error) {
+	v, ok := c.GetQuery(name)
+	if !ok {
+		return def, nil
+	}
+	switch v {
+	case "one":
+		return 1, nil
+	case "quorum":
+		return q.n/2 + 1, nil
+	case "all":
+		return q.n, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 || n > q.n {
+		return 0, fmt.Errorf("%s must be one, quorum, all or a number from 1 to %d", name, q.n)
+	}
+	return n, nil
+}
+
+// preference returns the first n distinct shards found walking the ring
+// clockwise from key
+func (r *hashRing) preference(key string, n int) []int {
+	h := ringHash(key)
+	start := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+
+	var shards []int
+	for i := 0; i < len(r.points) && len(shards) < n; i++ {
+		shard := r.points[(start+i)%len(r.points)].shard
+		if !slices.Contains(shards, shard) {
+			shards = append(shards, shard)
+		}
+	}
+	return shards
+}
+
+// replicas returns the n shards that hold key, its owner first
+func (r *routing) replicas(key string, n int) []*shard {
+	indexes := r.ring.preference(key, n)
+	shards := make([]*shard, len(indexes))
+	for i, index := range indexes {
+		shards[i] = r.shards[index]
+	}
+	return shards
+}
+
+// versionFromProto converts a version vector for JSON
+func versionFromProto(entries []*proto.VersionEntry) map[string]uint64 {
+	if len(entries) == 0 {
+		return nil
+	}
+	v := make(map[string]uint64, len(entries))
+	for _, e := range entries {
+		v[e.Node] = e.Counter
+	}
+	return v
+}
+
+// versionToProto converts a version vector from JSON
+func versionToProto(v map[string]uint64) []*proto.VersionEntry {
+	entries := make([]*proto.VersionEntry, 0, len(v))
+	for node, counter := range v {
+		entries = append(entries, &proto.VersionEntry{Node: node, Counter: counter})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Node < entries[j].Node })
+	return entries
+}
+
+// descends reports whether version a has seen every write b has
+func descends(a, b map[string]uint64) bool {
+	for node, counter := range b {
+		if a[node] < counter {
+			return false
+		}
+	}
+	return true
+}
+
+// sameVersion reports whether a and b are the same version
+func sameVersion(a, b map[string]uint64) bool {
+	return descends(a, b) && descends(b, a)
+}
+
+// Sibling is one of several conflicting values of a key. A deleted sibling
+// is a delete that conflicts with the other values.
+type Sibling struct {
+	Value   string            `json:"value"`
+	Version map[string]uint64 `json:"version,omitempty"`
+	Deleted bool              `json:"deleted,omitempty"`
+}
+
+// unavailable reports whether err means the backend could not be reached,
+// rather than that it refused the request
+func unavailable(err error) bool {
+	code := status.Code(err)
+	return code == codes.Unavailable || code == codes.DeadlineExceeded
+}
+
+// quorumSet writes a key to its replicas. The first replica that can be
+// reached coordinates the write and assigns its version; the others are
+// sent that version and the response is written once W replicas have
+// acknowledged it. The remaining writes carry on in the background.
+func (s *APIServer) quorumSet(c *gin.Context, req SetRequest) {
+	w, err := s.quorum.level(c, "w", s.quorum.w)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if gRPC client is available (for testing)
+	r := s.layout()
+	if len(r.shards) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+	replicas := r.replicas(req.Key, s.quorum.n)
+
+	// The writes outlive the request once W replicas have acknowledged
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(requestContext(c)), 5*time.Second)
+
+	var coordinated *proto.SetResponse
+	var coordinator *shard
+	for _, backend := range replicas {
+		coordinated, err = backend.client.Set(ctx, &proto.SetRequest{
+			Key:         req.Key,
+			Value:       req.Value,
+			Version:     versionToProto(req.Version),
+			Coordinator: backend.addr,
+		})
+		if err == nil {
+			coordinator = backend
+			break
+		}
+		if !unavailable(err) {
+			break
+		}
+	}
+	if coordinator == nil {
+		cancel()
+		if unavailable(err) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "no replica of the key is available: " + err.Error()})
+			return
+		}
+		grpcError(c, err)
+		return
+	}
+
+	acks := make(chan error, len(replicas)-1)
+	var wg sync.WaitGroup
+	for _, backend := range replicas {
+		if backend == coordinator {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			replicated := &proto.SetRequest{Key: req.Key, Value: req.Value, Version: coordinated.Version}
+			_, err := backend.client.Set(ctx, replicated)
+			acks <- err
+			if unavailable(err) {
+				s.handoff(ctx, r, coordinator, backend, &proto.StoreHintRequest{Key: req.Key, Value: req.Value, Version: coordinated.Version})
+			}
+		}()
+	}
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		wg.Wait()
+		cancel()
+	}()
+
+	acked := 1
+	var lastErr error
+	for i := 0; i < len(replicas)-1 && acked < w; i++ {
+		if err := <-acks; err != nil {
+			lastErr = err
+		} else {
+			acked++
+		}
+	}
+	if acked < w {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   fmt.Sprintf("only %d of %d replicas acknowledged the write: %v", acked, w, lastErr),
+			"version": versionFromProto(coordinated.Version),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SetResponse{
+		Success: true,
+		Message: coordinated.Message,
+		Version: versionFromProto(coordinated.Version),
+	})
+}
+
+// replicaReply is the answer of one replica to a read
+type replicaReply struct {
+	backend *shard
+	resp    *proto.GetResponse
+	err     error
+}
+
+// readReplicas sends a read to every replica of key and returns once r
+// have answered, along with the last error seen. The answers of the other
+// replicas are returned by more, which waits for all of them.
+func readReplicas(ctx context.Context, replicas []*shard, key string, r int) (got []replicaReply, more func() []replicaReply, err error) {
+	replies := make(chan replicaReply, len(replicas))
+	for _, backend := range replicas {
+		go func() {
+			resp, err := backend.client.Get(ctx, &proto.GetRequest{Key: key, IncludeDeleted: true})
+			if notFound(err) {
+				// A replica that has never seen the key has answered
+				resp, err = &proto.GetResponse{Message: status.Convert(err).Message()}, nil
+			}
+			replies <- replicaReply{backend: backend, resp: resp, err: err}
+		}()
+	}
+
+	received := 0
+	for ; received < len(replicas) && len(got) < r; received++ {
+		reply := <-replies
+		if reply.err != nil {
+			err = reply.err
+			continue
+		}
+		got = append(got, reply)
+	}
+
+	more = func() []replicaReply {
+		var late []replicaReply
+		for ; received < len(replicas); received++ {
+			if reply := <-replies; reply.err == nil {
+				late = append(late, reply)
+			}
+		}
+		return late
+	}
+	return got, more, err
+}
+
+// latest returns the values and deletes among replies that no other one
+// supersedes: a single one unless replicas accepted conflicting writes. A
+// delete is compared by its version like a value, so a replica that missed
+// it does not bring the key back.
+func latest(replies []replicaReply) []Sibling {
+	var found []Sibling
+	for _, reply := range replies {
+		if reply.resp.Success || reply.resp.Deleted {
+			found = append(found, Sibling{Value: reply.resp.Value, Version: versionFromProto(reply.resp.Version), Deleted: reply.resp.Deleted})
+		}
+	}
+
+	var siblings []Sibling
+	for i, a := range found {
+		superseded := false
+		for j, b := range found {
+			if sameVersion(a.Version, b.Version) {
+				// The same write, or a conflict under one version; keep the first copy of each value
+				if a.Value == b.Value && a.Deleted == b.Deleted && j < i {
+					superseded = true
+				}
+				continue
+			}
+			if descends(b.Version, a.Version) {
+				superseded = true
+			}
+		}
+		if !superseded {
+			siblings = append(siblings, a)
+		}
+	}
+	sort.Slice(siblings, func(i, j int) bool { return siblings[i].Value < siblings[j].Value })
+	return siblings
+}
+
+// quorumGet reads a key from its replicas and answers once R of them have
+// responded. Values superseded by a newer version are ignored; if several
+// values remain, the replicas accepted conflicting writes and they are
+// all returned with 300 Multiple Choices, along with a version that
+// descends from each of them for the write that resolves the conflict.
+// A key whose newest version is a delete is not found. Replicas found
+// holding an older value are repaired in the background.
+func (s *APIServer) quorumGet(c *gin.Context, key string) {
+	rq, err := s.quorum.level(c, "r", s.quorum.r)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if gRPC client is available (for testing)
+	r := s.layout()
+	if len(r.shards) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+
+	// The reads outlive the request, so that late replicas can be repaired
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(requestContext(c)), 5*time.Second)
+	replies, more, err := readReplicas(ctx, r.replicas(key, s.quorum.n), key, rq)
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		defer cancel()
+		s.readRepair(ctx, key, append(replies, more()...))
+	}()
+
+	if len(replies) < rq {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": fmt.Sprintf("only %d of %d replicas answered: %v", len(replies), rq, err)})
+		return
+	}
+
+	siblings := latest(replies)
+	switch {
+	case len(siblings) == 0:
+		c.JSON(http.StatusNotFound, GetResponse{
+			Success: false,
+			Message: fmt.Sprintf("Key '%s' not found", key),
+		})
+	case len(siblings) == 1 && siblings[0].Deleted:
+		c.JSON(http.StatusNotFound, GetResponse{
+			Success: false,
+			Message: fmt.Sprintf("Key '%s' not found", key),
+			Version: siblings[0].Version,
+		})
+	case len(siblings) == 1:
+		c.JSON(http.StatusOK, GetResponse{
+			Success: true,
+			Value:   siblings[0].Value,
+			Message: fmt.Sprintf("Key '%s' retrieved successfully", key),
+			Version: siblings[0].Version,
+		})
+	default:
+		merged := make(map[string]uint64)
+		for _, sibling := range siblings {
+			for node, counter := range sibling.Version {
+				merged[node] = max(merged[node], counter)
+			}
+		}
+		c.JSON(http.StatusMultipleChoices, GetResponse{
+			Success:  false,
+			Message:  fmt.Sprintf("Key '%s' has %d conflicting values", key, len(siblings)),
+			Version:  merged,
+			Siblings: siblings,
+		})
+	}
+}
+
+// quorumDelete deletes a key from its replicas the way quorumSet writes
+// it: the first replica that can be reached coordinates the delete and
+// assigns its version, which the others record with the deletion so that
+// an older write cannot bring the key back. It answers once W replicas
+// have acknowledged the delete; the delete is handed off for replicas that
+// cannot be reached.
+func (s *APIServer) quorumDelete(c *gin.Context, key string) {
+	w, err := s.quorum.level(c, "w", s.quorum.w)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if gRPC client is available (for testing)
+	r := s.layout()
+	if len(r.shards) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+	replicas := r.replicas(key, s.quorum.n)
+
+	// The deletes outlive the request once W replicas have acknowledged
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(requestContext(c)), 5*time.Second)
+
+	var coordinated *proto.DeleteResponse
+	var coordinator *shard
+	for _, backend := range replicas {
+		coordinated, err = backend.client.Delete(ctx, &proto.DeleteRequest{Key: key, Coordinator: backend.addr})
+		if err == nil {
+			coordinator = backend
+			break
+		}
+		if !unavailable(err) {
+			break
+		}
+	}
+	if coordinator == nil {
+		cancel()
+		if unavailable(err) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "no replica of the key is available: " + err.Error()})
+			return
+		}
+		grpcError(c, err)
+		return
+	}
+
+	type reply struct {
+		found bool
+		err   error
+	}
+	replies := make(chan reply, len(replicas)-1)
+	var wg sync.WaitGroup
+	for _, backend := range replicas {
+		if backend == coordinator {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := backend.client.Delete(ctx, &proto.DeleteRequest{Key: key, Version: coordinated.Version})
+			replies <- reply{found: err == nil && resp.Success, err: err}
+			if unavailable(err) {
+				s.handoff(ctx, r, coordinator, backend, &proto.StoreHintRequest{Key: key, Version: coordinated.Version, Delete: true})
+			}
+		}()
+	}
+	s.pending.Add(1)
+	go func() {
+		defer s.pending.Done()
+		wg.Wait()
+		cancel()
+	}()
+
+	acked, found := 1, coordinated.Success
+	var lastErr error
+	for i := 0; i < len(replicas)-1 && acked < w; i++ {
+		reply := <-replies
+		if reply.err != nil {
+			lastErr = reply.err
+			continue
+		}
+		acked++
+		found = found || reply.found
+	}
+	if acked < w {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": fmt.Sprintf("only %d of %d replicas acknowledged the delete: %v", acked, w, lastErr)})
+		return
+	}
+
+	if !found {
+		c.JSON(http.StatusNotFound, DeleteResponse{
+			Success: false,
+			Message: fmt.Sprintf("Key '%s' not found", key),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, DeleteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Key '%s' deleted successfully", key),
+	})
+}
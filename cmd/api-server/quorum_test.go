@@ -0,0 +1,371 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// startQuorumRouter serves n fake backends and a router replicating every
+// key to q.n of them
+func startQuorumRouter(t *testing.T, n int, q quorum) (*gin.Engine, []*fakeBackend) {
+	t.Helper()
+
+	addrs, backends := startFakeBackends(t, n)
+	router, apiServer := newShardedRouter(t, addrs)
+	apiServer.quorum = q
+	return router, backends
+}
+
+// holders returns the backends that hold key
+func holders(backends []*fakeBackend, key string) []*fakeBackend {
+	var found []*fakeBackend
+	for _, b := range backends {
+		if _, ok := b.keys()[key]; ok {
+			found = append(found, b)
+		}
+	}
+	return found
+}
+
+// holding returns the backends holding value under key
+func holding(backends []*fakeBackend, key, value string) []*fakeBackend {
+	var found []*fakeBackend
+	for _, b := range backends {
+		if b.keys()[key] == value {
+			found = append(found, b)
+		}
+	}
+	return found
+}
+
+func TestHashRingPreference(t *testing.T) {
+	addrs := []string{"kv1:50051", "kv2:50051", "kv3:50051", "kv4:50051"}
+	ring := newHashRing(addrs, defaultVirtualNodes)
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		shards := ring.preference(key, 3)
+		if len(shards) != 3 || shards[0] != ring.locate(key) {
+			t.Fatalf("preference(%s) = %v, expected 3 shards led by %d", key, shards, ring.locate(key))
+		}
+		if shards[0] == shards[1] || shards[1] == shards[2] || shards[0] == shards[2] {
+			t.Fatalf("preference(%s) = %v repeats a shard", key, shards)
+		}
+	}
+}
+
+func TestQuorumLevel(t *testing.T) {
+	q := quorum{n: 3, r: 2, w: 2}
+	tests := []struct {
+		query   string
+		want    int
+		wantErr bool
+	}{
+		{"", 2, false},
+		{"?w=one", 1, false},
+		{"?w=quorum", 2, false},
+		{"?w=all", 3, false},
+		{"?w=3", 3, false},
+		{"?w=4", 0, true},
+		{"?w=0", 0, true},
+		{"?w=most", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request, _ = http.NewRequest("POST", "/kv/set"+tt.query, nil)
+			got, err := q.level(c, "w", q.w)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("level() = %d, %v; expected %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuorumWriteAndRead(t *testing.T) {
+	router, backends := startQuorumRouter(t, 4, quorum{n: 3, r: 2, w: 2})
+
+	req, _ := http.NewRequest("POST", "/kv/set?w=all", strings.NewReader(`{"key":"k","value":"v1"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Set status %d: %s", w.Code, w.Body.String())
+	}
+	var set SetResponse
+	json.Unmarshal(w.Body.Bytes(), &set)
+	if len(set.Version) != 1 {
+		t.Errorf("Set version = %v, expected one coordinator entry", set.Version)
+	}
+
+	// The key is on exactly its three replicas
+	if found := holders(backends, "k"); len(found) != 3 {
+		t.Fatalf("key is on %d backends, expected 3", len(found))
+	}
+
+	req, _ = http.NewRequest("GET", "/kv/get/k", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var got GetResponse
+	json.Unmarshal(w.Body.Bytes(), &got)
+	if w.Code != http.StatusOK || got.Value != "v1" || len(got.Version) != 1 {
+		t.Errorf("Get status %d = %+v", w.Code, got)
+	}
+
+	// With one replica down a majority still answers, but not all of them
+	holders(backends, "k")[0].server.Stop()
+
+	for _, tt := range []struct {
+		query string
+		want  int
+	}{
+		{"", http.StatusOK},
+		{"?w=all", http.StatusServiceUnavailable},
+	} {
+		req, _ = http.NewRequest("POST", "/kv/set"+tt.query, strings.NewReader(`{"key":"k","value":"v2"}`))
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("Set%s with a replica down status %d, expected %d: %s", tt.query, w.Code, tt.want, w.Body.String())
+		}
+	}
+	req, _ = http.NewRequest("GET", "/kv/get/k?r=all", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Get?r=all with a replica down status %d", w.Code)
+	}
+}
+
+func TestQuorumDelete(t *testing.T) {
+	router, backends := startQuorumRouter(t, 4, quorum{n: 3, r: 2, w: 2})
+	del := func() (int, DeleteResponse) {
+		req, _ := http.NewRequest("DELETE", "/kv/delete/k?w=all", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp DeleteResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	req, _ := http.NewRequest("POST", "/kv/set?w=all", strings.NewReader(`{"key":"k","value":"v1"}`))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	replicas := holders(backends, "k")
+
+	if code, resp := del(); code != http.StatusOK || !resp.Success {
+		t.Fatalf("Delete status %d = %+v", code, resp)
+	}
+	if found := holders(backends, "k"); len(found) != 0 {
+		t.Errorf("key is still on %d backends after the delete", len(found))
+	}
+
+	// Every replica records the deletion under the coordinator's version
+	var versions []map[string]uint64
+	for _, b := range replicas {
+		b.mu.Lock()
+		versions = append(versions, versionFromProto(b.versions["k"]))
+		b.mu.Unlock()
+	}
+	for i, version := range versions {
+		if len(version) != 1 || !sameVersion(version, versions[0]) {
+			t.Errorf("replica %d recorded the deletion at %v, expected %v", i, version, versions[0])
+		}
+	}
+
+	if code, _ := del(); code != http.StatusNotFound {
+		t.Errorf("Delete of a deleted key status %d, expected 404", code)
+	}
+}
+
+func TestQuorumRead_Versions(t *testing.T) {
+	router, backends := startQuorumRouter(t, 3, quorum{n: 3, r: 3, w: 3})
+
+	store := func(b *fakeBackend, value string, version ...*proto.VersionEntry) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.data["k"] = value
+		b.versions["k"] = version
+	}
+	get := func() (int, GetResponse) {
+		req, _ := http.NewRequest("GET", "/kv/get/k", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp GetResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	// A newer version supersedes the ones it descends from
+	store(backends[0], "old", &proto.VersionEntry{Node: "a", Counter: 1})
+	store(backends[1], "new", &proto.VersionEntry{Node: "a", Counter: 2})
+	if code, resp := get(); code != http.StatusOK || resp.Value != "new" {
+		t.Errorf("Get status %d = %+v, expected the newest value", code, resp)
+	}
+	waitFor(t, "read repair", func() bool { return len(holding(backends, "k", "new")) == 3 })
+
+	// Concurrent versions are both returned, with a version covering them
+	store(backends[2], "other", &proto.VersionEntry{Node: "b", Counter: 1})
+	code, resp := get()
+	if code != http.StatusMultipleChoices || len(resp.Siblings) != 2 {
+		t.Fatalf("Get status %d = %+v, expected two siblings", code, resp)
+	}
+	if resp.Version["a"] != 2 || resp.Version["b"] != 1 {
+		t.Errorf("merged version = %v", resp.Version)
+	}
+}
+
+func TestQuorumReadRepair(t *testing.T) {
+	router, backends := startQuorumRouter(t, 3, quorum{n: 3, r: 2, w: 2})
+	backends[0].data["k"], backends[0].versions["k"] = "old", []*proto.VersionEntry{{Node: "a", Counter: 1}}
+	backends[1].data["k"], backends[1].versions["k"] = "new", []*proto.VersionEntry{{Node: "a", Counter: 2}}
+
+	// Every replica ends up with the newest value, whichever two answered first
+	req, _ := http.NewRequest("GET", "/kv/get/k", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	waitFor(t, "read repair", func() bool { return len(holding(backends, "k", "new")) == 3 })
+	for i, b := range backends {
+		if value := b.keys()["k"]; value != "new" {
+			t.Errorf("backend %d holds %q after read repair", i, value)
+		}
+	}
+
+	var status QuorumStatusResponse
+	waitFor(t, "the repairs to be counted", func() bool {
+		req, _ := http.NewRequest("GET", "/admin/quorum", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		json.Unmarshal(w.Body.Bytes(), &status)
+		return status.ReadRepairs == 2
+	})
+	if !status.Enabled || status.N != 3 || status.ReadRepairFailures != 0 {
+		t.Errorf("QuorumStatus() = %+v", status)
+	}
+
+	// Conflicting values are left for the client to resolve
+	backends[2].mu.Lock()
+	backends[2].data["k"], backends[2].versions["k"] = "other", []*proto.VersionEntry{{Node: "b", Counter: 1}}
+	backends[2].mu.Unlock()
+	req, _ = http.NewRequest("GET", "/kv/get/k?r=all", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if value := backends[0].keys()["k"]; value != "new" {
+		t.Errorf("read repair overwrote a conflict with %q", value)
+	}
+}
+
+func TestQuorumReadRepair_Deletes(t *testing.T) {
+	router, backends := startQuorumRouter(t, 3, quorum{n: 3, r: 2, w: 2})
+	store := func(b *fakeBackend, value string, version ...*proto.VersionEntry) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if value == "" {
+			delete(b.data, "k")
+		} else {
+			b.data["k"] = value
+		}
+		b.versions["k"] = version
+	}
+	get := func() (int, GetResponse) {
+		req, _ := http.NewRequest("GET", "/kv/get/k?r=all", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var resp GetResponse
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	// A delete supersedes the value it descends from, on the replicas that
+	// missed it too
+	store(backends[0], "", &proto.VersionEntry{Node: "a", Counter: 2})
+	store(backends[1], "old", &proto.VersionEntry{Node: "a", Counter: 1})
+	store(backends[2], "old", &proto.VersionEntry{Node: "a", Counter: 1})
+	if code, resp := get(); code != http.StatusNotFound || resp.Version["a"] != 2 {
+		t.Errorf("Get status %d = %+v, expected the key deleted at {a:2}", code, resp)
+	}
+	waitFor(t, "read repair", func() bool { return len(holders(backends, "k")) == 0 })
+	for i, b := range backends {
+		b.mu.Lock()
+		version := versionFromProto(b.versions["k"])
+		b.mu.Unlock()
+		if version["a"] != 2 {
+			t.Errorf("backend %d recorded the deletion at %v, expected {a:2}", i, version)
+		}
+	}
+
+	// A value written after the delete supersedes it
+	store(backends[1], "new", &proto.VersionEntry{Node: "a", Counter: 3})
+	if code, resp := get(); code != http.StatusOK || resp.Value != "new" {
+		t.Errorf("Get status %d = %+v, expected the value written after the delete", code, resp)
+	}
+	waitFor(t, "read repair", func() bool { return len(holding(backends, "k", "new")) == 3 })
+
+	// A delete concurrent with a value is returned as a sibling
+	store(backends[2], "", &proto.VersionEntry{Node: "b", Counter: 1})
+	code, resp := get()
+	if code != http.StatusMultipleChoices || len(resp.Siblings) != 2 || !resp.Siblings[0].Deleted || resp.Siblings[1].Value != "new" {
+		t.Errorf("Get status %d = %+v, expected the delete and the value as siblings", code, resp)
+	}
+}
+
+func TestQuorumHintedHandoff(t *testing.T) {
+	router, backends := startQuorumRouter(t, 4, quorum{n: 3, r: 2, w: 2})
+	set := func(path, value string) {
+		req, _ := http.NewRequest("POST", path, strings.NewReader(`{"key":"k","value":"`+value+`"}`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Set(%s) status %d: %s", value, w.Code, w.Body.String())
+		}
+	}
+
+	// Every replica acknowledges the first write, so that none is still
+	// answering it when one is stopped
+	set("/kv/set?w=all", "v1")
+	replicas := holders(backends, "k")
+	var spare *fakeBackend
+	for _, b := range backends {
+		if !slices.Contains(replicas, b) {
+			spare = b
+		}
+	}
+
+	// The write for the replica that is down is held by the next server
+	down := replicas[2]
+	down.server.Stop()
+	set("/kv/set", "v2")
+	var status QuorumStatusResponse
+	waitFor(t, "a hinted handoff", func() bool {
+		req, _ := http.NewRequest("GET", "/admin/quorum", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		json.Unmarshal(w.Body.Bytes(), &status)
+		return status.HintedHandoffs == 1
+	})
+	if status.HintedHandoffFailures != 0 {
+		t.Errorf("QuorumStatus() = %+v", status)
+	}
+	hints := spare.heldHints()
+	if len(hints) != 1 || hints[0].Target != down.addr || hints[0].Key != "k" || hints[0].Value != "v2" || len(hints[0].Version) == 0 {
+		t.Errorf("hints = %v, expected the write of v2 for %s", hints, down.addr)
+	}
+
+	// and so is a delete
+	req, _ := http.NewRequest("DELETE", "/kv/delete/k", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Delete status %d: %s", w.Code, w.Body.String())
+	}
+	waitFor(t, "the delete to be handed off", func() bool { return len(spare.heldHints()) == 2 })
+	if hint := spare.heldHints()[1]; hint.Target != down.addr || hint.Key != "k" || !hint.Delete || len(hint.Version) == 0 {
+		t.Errorf("hint = %v, expected the delete of k for %s", hint, down.addr)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuotaStatus represents the limits and consumption of a quota
+type QuotaStatus struct {
+	Name          string `json:"name"`
+	Prefix        string `json:"prefix,omitempty"`
+	Client        string `json:"client,omitempty"`
+	MaxKeys       int64  `json:"max_keys"`
+	MaxBytes      int64  `json:"max_bytes"`
+	MaxValueBytes int64  `json:"max_value_bytes"`
+	Keys          int64  `json:"keys"`
+	Bytes         int64  `json:"bytes"`
+}
+
+// QuotaUsageResponse represents the JSON response for quota consumption
+type QuotaUsageResponse struct {
+	Success bool          `json:"success"`
+	Quotas  []QuotaStatus `json:"quotas"`
+	Message string        `json:"message"`
+}
+
+// QuotaUsage handles GET /admin/quotas. Each shard enforces its quotas on
+// its own keys; the usage reported is the sum across shards.
+func (s *APIServer) QuotaUsage(c *gin.Context) {
+	// Check if gRPC client is available (for testing)
+	shards := s.backends()
+	if len(shards) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+
+	// Call gRPC service
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
+	defer cancel()
+
+	responses := make([]*proto.QuotaUsageResponse, len(shards))
+	err := fanOut(ctx, shards, func(ctx context.Context, i int, backend *shard) error {
+		var err error
+		responses[i], err = backend.client.QuotaUsage(ctx, &proto.QuotaUsageRequest{})
+		return err
+	})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	quotas := make([]QuotaStatus, 0)
+	byName := make(map[string]int)
+	for _, resp := range responses {
+		for _, q := range resp.Quotas {
+			if i, ok := byName[q.Name]; ok {
+				quotas[i].Keys += q.Keys
+				quotas[i].Bytes += q.Bytes
+				continue
+			}
+			byName[q.Name] = len(quotas)
+			quotas = append(quotas, QuotaStatus{
+				Name:          q.Name,
+				Prefix:        q.Prefix,
+				Client:        q.Client,
+				MaxKeys:       q.MaxKeys,
+				MaxBytes:      q.MaxBytes,
+				MaxValueBytes: q.MaxValueBytes,
+				Keys:          q.Keys,
+				Bytes:         q.Bytes,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, QuotaUsageResponse{
+		Success: true,
+		Quotas:  quotas,
+		Message: fmt.Sprintf("%d quotas configured", len(quotas)),
+	})
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// quorumStats counts the repairs made by quorum reads and the writes
+// handed off for unreachable replicas
+type quorumStats struct {
+	readRepairs        atomic.Int64
+	readRepairFailures atomic.Int64
+	hintsStored        atomic.Int64
+	hintFailures       atomic.Int64
+}
+
+// QuorumStatusResponse represents the JSON response describing quorum
+// replication
+type QuorumStatusResponse struct {
+	Enabled               bool  `json:"enabled"`
+	N                     int   `json:"n"`
+	R                     int   `json:"r"`
+	W                     int   `json:"w"`
+	ReadRepairs           int64 `json:"read_repairs"`
+	ReadRepairFailures    int64 `json:"read_repair_failures"`
+	HintedHandoffs        int64 `json:"hinted_handoffs"`
+	HintedHandoffFailures int64 `json:"hinted_handoff_failures"`
+}
+
+// QuorumStatus handles GET /admin/quorum
+func (s *APIServer) QuorumStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, QuorumStatusResponse{
+		Enabled:               s.quorum.enabled(),
+		N:                     max(s.quorum.n, 1),
+		R:                     max(s.quorum.r, 1),
+		W:                     max(s.quorum.w, 1),
+		ReadRepairs:           s.quorumStats.readRepairs.Load(),
+		ReadRepairFailures:    s.quorumStats.readRepairFailures.Load(),
+		HintedHandoffs:        s.quorumStats.hintsStored.Load(),
+		HintedHandoffFailures: s.quorumStats.hintFailures.Load(),
+	})
+}
+
+// readRepair writes the newest value among the replies of a read to the
+// replicas that answered with an older one or none, or deletes the key
+// from them when the newest version is a delete. Nothing is repaired when
+// the replies hold conflicting values, which only a client can resolve.
+func (s *APIServer) readRepair(ctx context.Context, key string, replies []replicaReply) {
+	siblings := latest(replies)
+	if len(siblings) != 1 {
+		return
+	}
+	newest := siblings[0]
+
+	var wg sync.WaitGroup
+	for _, reply := range replies {
+		resp := reply.resp
+		if (resp.Success || resp.Deleted) && resp.Deleted == newest.Deleted && resp.Value == newest.Value && sameVersion(versionFromProto(resp.Version), newest.Version) {
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			if newest.Deleted {
+				_, err = reply.backend.client.Delete(ctx, &proto.DeleteRequest{Key: key, Version: versionToProto(newest.Version)})
+			} else {
+				_, err = reply.backend.client.Set(ctx, &proto.SetRequest{Key: key, Value: newest.Value, Version: versionToProto(newest.Version)})
+			}
+			if err != nil {
+				log.Printf("Read repair of key '%s' on %s failed: %v", key, reply.backend.addr, err)
+				s.quorumStats.readRepairFailures.Add(1)
+				return
+			}
+			s.quorumStats.readRepairs.Add(1)
+		}()
+	}
+	wg.Wait()
+}
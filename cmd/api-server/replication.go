@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReplicationStatusResponse represents the JSON response for the
+// replication role and lag of the kvstore server
+type ReplicationStatusResponse struct {
+	Role            string     `json:"role"`
+	Primary         string     `json:"primary,omitempty"`
+	Connected       bool       `json:"connected"`
+	AppliedRevision uint64     `json:"applied_revision"`
+	PrimaryRevision uint64     `json:"primary_revision"`
+	LagRevisions    uint64     `json:"lag_revisions"`
+	LagSeconds      float64    `json:"lag_seconds"`
+	LastContact     *time.Time `json:"last_contact,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	Replicas        int32      `json:"replicas"`
+
+	// Anti-entropy against the primary, for a replica
+	AntiEntropyRounds int64      `json:"anti_entropy_rounds"`
+	KeysRepaired      int64      `json:"keys_repaired"`
+	LastAntiEntropy   *time.Time `json:"last_anti_entropy,omitempty"`
+	AntiEntropyError  string     `json:"anti_entropy_error,omitempty"`
+
+	// Writes held for other servers that could not be reached
+	HintsPending   int64 `json:"hints_pending"`
+	HintsStored    int64 `json:"hints_stored"`
+	HintsDelivered int64 `json:"hints_delivered"`
+	HintsExpired   int64 `json:"hints_expired"`
+	HintsDropped   int64 `json:"hints_dropped"`
+}
+
+// PromoteResponse represents the JSON response for promoting a replica
+type PromoteResponse struct {
+	Success  bool   `json:"success"`
+	Revision uint64 `json:"revision"`
+	Message  string `json:"message"`
+}
+
+// ReplicationStatus handles GET /admin/replication?shard=...
+func (s *APIServer) ReplicationStatus(c *gin.Context) {
+	// Check if gRPC client is available (for testing)
+	if len(s.backends()) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+	backend := s.selectShard(c)
+	if backend == nil {
+		return
+	}
+
+	// Call gRPC service
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
+	defer cancel()
+
+	grpcResp, err := backend.replication.ReplicationStatus(ctx, &proto.ReplicationStatusRequest{})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	resp := ReplicationStatusResponse{
+		Role:            grpcResp.Role,
+		Primary:         grpcResp.Primary,
+		Connected:       grpcResp.Connected,
+		AppliedRevision: grpcResp.AppliedRevision,
+		PrimaryRevision: grpcResp.PrimaryRevision,
+		LagRevisions:    grpcResp.LagRevisions,
+		LagSeconds:      grpcResp.LagSeconds,
+		LastError:       grpcResp.LastError,
+		Replicas:        grpcResp.Replicas,
+
+		AntiEntropyRounds: grpcResp.AntiEntropyRounds,
+		KeysRepaired:      grpcResp.KeysRepaired,
+		AntiEntropyError:  grpcResp.AntiEntropyError,
+
+		HintsPending:   grpcResp.HintsPending,
+		HintsStored:    grpcResp.HintsStored,
+		HintsDelivered: grpcResp.HintsDelivered,
+		HintsExpired:   grpcResp.HintsExpired,
+		HintsDropped:   grpcResp.HintsDropped,
+	}
+	if grpcResp.LastContact != 0 {
+		lastContact := time.Unix(0, grpcResp.LastContact).UTC()
+		resp.LastContact = &lastContact
+	}
+	if grpcResp.LastAntiEntropy != 0 {
+		lastAntiEntropy := time.Unix(0, grpcResp.LastAntiEntropy).UTC()
+		resp.LastAntiEntropy = &lastAntiEntropy
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Promote handles POST /admin/promote?shard=...
+func (s *APIServer) Promote(c *gin.Context) {
+	// Check if gRPC client is available (for testing)
+	if len(s.backends()) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+	backend := s.selectShard(c)
+	if backend == nil {
+		return
+	}
+
+	// Call gRPC service
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
+	defer cancel()
+
+	grpcResp, err := backend.replication.Promote(ctx, &proto.PromoteRequest{})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if !grpcResp.Success {
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, PromoteResponse{
+		Success:  grpcResp.Success,
+		Revision: grpcResp.Revision,
+		Message:  grpcResp.Message,
+	})
+}
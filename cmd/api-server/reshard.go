@@ -0,0 +1,459 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/metadata"
+)
+
+// migrationStripes is the number of locks keys are spread over while
+// resharding, so that writes to different keys rarely wait on each other
+const migrationStripes = 256
+
+// Resharding states
+const (
+	reshardCopying  = "copying"
+	reshardCleaning = "cleaning up"
+	reshardDone     = "done"
+	reshardFailed   = "failed"
+)
+
+// ReshardRequest represents the JSON request body for changing the shards
+type ReshardRequest struct {
+	Shards []string `json:"shards" binding:"required"`
+}
+
+// ReshardStatus represents the JSON response describing a resharding
+type ReshardStatus struct {
+	State          string     `json:"state"`
+	Shards         []string   `json:"shards"`
+	PreviousShards []string   `json:"previous_shards"`
+	StartedAt      time.Time  `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	KeysScanned    int64      `json:"keys_scanned"`
+	KeysCopied     int64      `json:"keys_copied"`
+	KeysRemoved    int64      `json:"keys_removed"`
+	Error          string     `json:"error,omitempty"`
+}
+
+// migration moves keys from a previous layout to the one it is attached
+// to. Until it is done, writes go to the new owners while reads fall back
+// to the previous ones, and keys written during the migration are never
+// overwritten by the copy.
+type migration struct {
+	previous *routing
+	target   []string
+	stripes  [migrationStripes]sync.Mutex
+
+	mu         sync.Mutex
+	written    map[string]bool // keys written since the migration started
+	state      string
+	startedAt  time.Time
+	finishedAt time.Time
+	scanned    int64
+	copied     int64
+	removed    int64
+	err        error
+}
+
+// newMigration starts a migration from previous to the shards at target
+func newMigration(previous *routing, target []string) *migration {
+	return &migration{
+		previous:  previous,
+		target:    target,
+		written:   make(map[string]bool),
+		state:     reshardCopying,
+		startedAt: time.Now(),
+	}
+}
+
+// stripe returns the lock guarding key
+func (m *migration) stripe(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % migrationStripes)
+}
+
+// claim marks key as written, so it is no longer copied, and holds its
+// lock until the returned function is called
+func (m *migration) claim(key string) func() {
+	stripe := &m.stripes[m.stripe(key)]
+	stripe.Lock()
+	m.mu.Lock()
+	m.written[key] = true
+	m.mu.Unlock()
+	return stripe.Unlock
+}
+
+// running reports whether the migration is still making progress
+func (m *migration) running() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state == reshardCopying || m.state == reshardCleaning
+}
+
+// setState moves the migration on to state, recording when it finished
+func (m *migration) setState(state string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state, m.err = state, err
+	if state == reshardDone || state == reshardFailed {
+		m.finishedAt = time.Now()
+	}
+}
+
+// restart resumes a failed migration. Keys written so far stay claimed.
+func (m *migration) restart() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state, m.err = reshardCopying, nil
+	m.scanned, m.copied, m.removed = 0, 0, 0
+	m.finishedAt = time.Time{}
+}
+
+// count adds n to one of the migration's counters
+func (m *migration) count(counter *int64, n int64) {
+	m.mu.Lock()
+	*counter += n
+	m.mu.Unlock()
+}
+
+// status describes the migration
+func (m *migration) status() ReshardStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := ReshardStatus{
+		State:          m.state,
+		Shards:         m.target,
+		PreviousShards: make([]string, 0, len(m.previous.shards)),
+		StartedAt:      m.startedAt.UTC(),
+		KeysScanned:    m.scanned,
+		KeysCopied:     m.copied,
+		KeysRemoved:    m.removed,
+	}
+	for _, backend := range m.previous.shards {
+		status.PreviousShards = append(status.PreviousShards, backend.addr)
+	}
+	if !m.finishedAt.IsZero() {
+		finished := m.finishedAt.UTC()
+		status.FinishedAt = &finished
+	}
+	if m.err != nil {
+		status.Error = m.err.Error()
+	}
+	return status
+}
+
+// dial returns a connection to each of addrs, reusing those already open.
+// The caller must hold reshardMu unless the server is not yet serving.
+func (s *APIServer) dial(addrs []string) ([]*shard, error) {
+	shards := make([]*shard, len(addrs))
+	for i, addr := range addrs {
+		backend, ok := s.dialed[addr]
+		if !ok {
+			var err error
+			if backend, err = dialShard(addr); err != nil {
+				return nil, err
+			}
+			s.dialed[addr] = backend
+		}
+		shards[i] = backend
+	}
+	return shards, nil
+}
+
+// sameShards reports whether shards are exactly the backends at addrs, in
+// any order
+func sameShards(shards []*shard, addrs []string) bool {
+	if len(shards) != len(addrs) {
+		return false
+	}
+	for _, backend := range shards {
+		if !slices.Contains(addrs, backend.addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// copySchemas registers the schemas of the current shards on the shards
+// joining the layout, so that they validate writes from the start
+func copySchemas(ctx context.Context, current, joining []*shard) error {
+	var added []*shard
+	for _, backend := range joining {
+		if !slices.Contains(current, backend) {
+			added = append(added, backend)
+		}
+	}
+	if len(current) == 0 || len(added) == 0 {
+		return nil
+	}
+
+	resp, err := current[0].client.ListSchemas(ctx, &proto.ListSchemasRequest{})
+	if err != nil {
+		return err
+	}
+	return fanOut(ctx, added, func(ctx context.Context, i int, backend *shard) error {
+		for _, schema := range resp.Schemas {
+			resp, err := backend.client.RegisterSchema(ctx, &proto.RegisterSchemaRequest{Prefix: schema.Prefix, Schema: schema.Schema})
+			if err != nil {
+				return err
+			}
+			if !resp.Success {
+				return fmt.Errorf("backend %s: %s", backend.addr, resp.Message)
+			}
+		}
+		return nil
+	})
+}
+
+// Reshard handles POST /admin/reshard by moving the keyspace onto the
+// listed shards. The keys that change owner are copied in the background;
+// the response describes the resharding as it starts. A resharding that
+// failed is resumed by sending the same list again.
+func (s *APIServer) Reshard(c *gin.Context) {
+	var req ReshardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	addrs, err := parseShardAddresses(strings.Join(req.Shards, ","))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.reshardMu.Lock()
+	defer s.reshardMu.Unlock()
+
+	// Check if gRPC client is available (for testing)
+	current := s.layout()
+	if len(current.shards) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+	if s.quorum.enabled() {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": quorumUnsupported})
+		return
+	}
+
+	// A resharding runs until its old shards are cleaned up, after the switch
+	if s.reshard != nil && s.reshard.running() {
+		c.JSON(http.StatusConflict, gin.H{"error": "resharding is already in progress"})
+		return
+	}
+
+	if m := current.migration; m != nil {
+		if !sameShards(current.shards, addrs) {
+			c.JSON(http.StatusConflict, gin.H{"error": "the failed resharding to " + strings.Join(m.target, ",") + " must be retried first"})
+			return
+		}
+		m.restart()
+		go s.migrate(current)
+		c.JSON(http.StatusAccepted, m.status())
+		return
+	}
+	if sameShards(current.shards, addrs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "shards already match the current layout"})
+		return
+	}
+
+	shards, err := s.dial(addrs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
+	defer cancel()
+	if err := copySchemas(ctx, current.shards, shards); err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	next := &routing{
+		shards:    shards,
+		ring:      newHashRing(addrs, s.virtualNodes),
+		migration: newMigration(current, addrs),
+	}
+
+	// Writes routed by the current layout finish first, so the copy sees them
+	s.writes.Lock()
+	s.routing.Store(next)
+	s.writes.Unlock()
+
+	s.reshard = next.migration
+	go s.migrate(next)
+	c.JSON(http.StatusAccepted, next.migration.status())
+}
+
+// ReshardStatus handles GET /admin/reshard
+func (s *APIServer) ReshardStatus(c *gin.Context) {
+	s.reshardMu.Lock()
+	m := s.reshard
+	s.reshardMu.Unlock()
+
+	if m == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no resharding has been started"})
+		return
+	}
+	c.JSON(http.StatusOK, m.status())
+}
+
+// migrate copies every key of next's migration that changes owner, then
+// switches to next alone and removes the moved keys from their old shards
+func (s *APIServer) migrate(next *routing) {
+	m := next.migration
+	ctx := metadata.AppendToOutgoingContext(context.Background(), clientIDMetadataKey, "reshard")
+
+	moved := make([][]string, len(m.previous.shards))
+	err := fanOut(ctx, m.previous.shards, func(ctx context.Context, i int, source *shard) error {
+		var err error
+		moved[i], err = m.copyFrom(ctx, source, next)
+		return err
+	})
+	if err != nil {
+		log.Printf("Resharding failed: %v", err)
+		m.setState(reshardFailed, err)
+		return
+	}
+
+	// Every key is on its new owner, so reads no longer need the old ones
+	s.routing.Store(&routing{shards: next.shards, ring: next.ring})
+	m.setState(reshardCleaning, nil)
+
+	for i, source := range m.previous.shards {
+		// A shard that left the layout keeps its data
+		if !slices.Contains(next.shards, source) {
+			continue
+		}
+		for _, key := range moved[i] {
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			_, err := source.client.Delete(ctx, &proto.DeleteRequest{Key: key})
+			cancel()
+			if err != nil && !notFound(err) {
+				err = fmt.Errorf("removing %s from %s: %v", key, source.addr, err)
+				log.Printf("Resharding failed: %v", err)
+				m.setState(reshardFailed, err)
+				return
+			}
+			m.count(&m.removed, 1)
+		}
+	}
+
+	m.setState(reshardDone, nil)
+	log.Printf("Resharding onto %s done", strings.Join(m.target, ","))
+}
+
+// copyFrom copies the keys of source that next assigns to another shard,
+// and returns them
+func (m *migration) copyFrom(ctx context.Context, source *shard, next *routing) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := source.client.Backup(ctx, &proto.BackupRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %v", source.addr, err)
+	}
+
+	var moved []string
+	batches := make(map[*shard][]*proto.BackupEntry)
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %v", source.addr, err)
+		}
+
+		m.count(&m.scanned, int64(len(chunk.Entries)))
+		for _, entry := range chunk.Entries {
+			dest := next.owner(entry.Key)
+			if dest == source {
+				continue
+			}
+			moved = append(moved, entry.Key)
+			batches[dest] = append(batches[dest], entry)
+			if len(batches[dest]) == restoreBatchSize {
+				if err := m.copyBatch(ctx, dest, batches[dest]); err != nil {
+					return nil, err
+				}
+				batches[dest] = nil
+			}
+		}
+	}
+
+	for dest, entries := range batches {
+		if err := m.copyBatch(ctx, dest, entries); err != nil {
+			return nil, err
+		}
+	}
+	return moved, nil
+}
+
+// copyBatch merges entries into dest, leaving out keys written since the
+// migration started. The keys' locks are held until dest has them.
+func (m *migration) copyBatch(ctx context.Context, dest *shard, entries []*proto.BackupEntry) error {
+	// Locks are taken in order so that two batches cannot deadlock
+	stripes := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		stripes = append(stripes, m.stripe(entry.Key))
+	}
+	sort.Ints(stripes)
+	stripes = slices.Compact(stripes)
+	for _, i := range stripes {
+		m.stripes[i].Lock()
+	}
+	defer func() {
+		for _, i := range stripes {
+			m.stripes[i].Unlock()
+		}
+	}()
+
+	m.mu.Lock()
+	pending := make([]*proto.BackupEntry, 0, len(entries))
+	for _, entry := range entries {
+		if !m.written[entry.Key] {
+			pending = append(pending, entry)
+		}
+	}
+	m.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	stream, err := dest.client.Restore(ctx)
+	if err != nil {
+		return fmt.Errorf("copying to %s: %v", dest.addr, err)
+	}
+	// A failed send is reported by CloseAndRecv
+	stream.Send(&proto.RestoreRequest{Mode: proto.RestoreMode_RESTORE_MODE_MERGE, Entries: pending})
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return fmt.Errorf("copying to %s: %v", dest.addr, err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("copying to %s: %s", dest.addr, resp.Message)
+	}
+
+	m.count(&m.copied, resp.KeysRestored)
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/status"
+)
+
+// RegisterSchemaRequest represents the JSON request body for registering a schema
+type RegisterSchemaRequest struct {
+	Prefix string          `json:"prefix"`
+	Schema json.RawMessage `json:"schema" binding:"required"`
+}
+
+// SchemaResponse represents the JSON response for registering or deleting a schema
+type SchemaResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message"`
+}
+
+// KeySchema represents a JSON Schema and the key prefix it applies to
+type KeySchema struct {
+	Prefix string          `json:"prefix"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// ListSchemasResponse represents the JSON response for listing schemas
+type ListSchemasResponse struct {
+	Success bool        `json:"success"`
+	Schemas []KeySchema `json:"schemas"`
+	Message string      `json:"message"`
+}
+
+// RegisterSchema handles POST /kv/schemas. Keys under a prefix are spread
+// over every shard, so the schema is registered on all of them.
+func (s *APIServer) RegisterSchema(c *gin.Context) {
+	var req RegisterSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if gRPC client is available (for testing)
+	shards := s.backends()
+	if len(shards) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+
+	// Call gRPC service
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
+	defer cancel()
+
+	responses := make([]*proto.RegisterSchemaResponse, len(shards))
+	err := fanOut(ctx, shards, func(ctx context.Context, i int, backend *shard) error {
+		var err error
+		responses[i], err = backend.client.RegisterSchema(ctx, &proto.RegisterSchemaRequest{
+			Prefix: req.Prefix,
+			Schema: string(req.Schema),
+		})
+		return err
+	})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SchemaResponse{
+		Success: responses[0].Success,
+		Message: responses[0].Message,
+	})
+}
+
+// ListSchemas handles GET /kv/schemas. A prefix registered on only some
+// shards is still listed, with the schema of the first shard holding it.
+func (s *APIServer) ListSchemas(c *gin.Context) {
+	// Check if gRPC client is available (for testing)
+	shards := s.backends()
+	if len(shards) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+
+	// Call gRPC service
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
+	defer cancel()
+
+	responses := make([]*proto.ListSchemasResponse, len(shards))
+	err := fanOut(ctx, shards, func(ctx context.Context, i int, backend *shard) error {
+		var err error
+		responses[i], err = backend.client.ListSchemas(ctx, &proto.ListSchemasRequest{})
+		return err
+	})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	schemas := make([]KeySchema, 0)
+	seen := make(map[string]bool)
+	for _, resp := range responses {
+		for _, ks := range resp.Schemas {
+			if !seen[ks.Prefix] {
+				seen[ks.Prefix] = true
+				schemas = append(schemas, KeySchema{Prefix: ks.Prefix, Schema: json.RawMessage(ks.Schema)})
+			}
+		}
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Prefix < schemas[j].Prefix })
+
+	c.JSON(http.StatusOK, ListSchemasResponse{
+		Success: true,
+		Schemas: schemas,
+		Message: fmt.Sprintf("Found %d schemas", len(schemas)),
+	})
+}
+
+// DeleteSchema handles DELETE /kv/schemas?prefix=... on every shard
+func (s *APIServer) DeleteSchema(c *gin.Context) {
+	prefix, ok := c.GetQuery("prefix")
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prefix parameter is required"})
+		return
+	}
+
+	// Check if gRPC client is available (for testing)
+	shards := s.backends()
+	if len(shards) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "gRPC client not available"})
+		return
+	}
+
+	// Call gRPC service
+	ctx, cancel := context.WithTimeout(requestContext(c), 5*time.Second)
+	defer cancel()
+
+	responses := make([]*proto.DeleteSchemaResponse, len(shards))
+	err := fanOut(ctx, shards, func(ctx context.Context, i int, backend *shard) error {
+		var err error
+		responses[i], err = backend.client.DeleteSchema(ctx, &proto.DeleteSchemaRequest{Prefix: prefix})
+		if notFound(err) {
+			// Only some shards may still have had the schema
+			responses[i], err = &proto.DeleteSchemaResponse{Message: status.Convert(err).Message()}, nil
+		}
+		return err
+	})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	// The schema counts as deleted if any shard still had it
+	grpcResp := responses[0]
+	for _, resp := range responses {
+		if resp.Success {
+			grpcResp = resp
+			break
+		}
+	}
+
+	status := http.StatusOK
+	if !grpcResp.Success {
+		status = http.StatusNotFound
+	}
+
+	c.JSON(status, SchemaResponse{
+		Success: grpcResp.Success,
+		Message: grpcResp.Message,
+	})
+}
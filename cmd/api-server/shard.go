@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// defaultVirtualNodes is how many points each shard gets on the hash ring.
+// More points spread keys more evenly at the cost of a larger ring.
+const defaultVirtualNodes = 128
+
+// shard is one kvstore-server backend
+type shard struct {
+	addr        string
+	conn        *grpc.ClientConn
+	client      proto.KeyValueStoreClient
+	replication proto.ReplicationClient
+	health      healthpb.HealthClient
+}
+
+// ringPoint is a position on the hash ring owned by a shard
+type ringPoint struct {
+	hash  uint64
+	shard int
+}
+
+// hashRing assigns keys to shards by consistent hashing. Each shard is
+// placed at several points derived from its address, and a key belongs to
+// the first point at or after its own hash, so adding or removing a shard
+// only moves the keys next to that shard's points.
+type hashRing struct {
+	points []ringPoint
+}
+
+// ringHash hashes s onto the ring. FNV alone clusters similar inputs such
+// as "addr#1" and "addr#2", so its output is passed through a 64-bit mixer.
+func ringHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// newHashRing places virtualNodes points for each of the shard addresses
+func newHashRing(addrs []string, virtualNodes int) *hashRing {
+	r := &hashRing{points: make([]ringPoint, 0, len(addrs)*virtualNodes)}
+	for i, addr := range addrs {
+		for v := 0; v < virtualNodes; v++ {
+			r.points = append(r.points, ringPoint{hash: ringHash(fmt.Sprintf("%s#%d", addr, v)), shard: i})
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i].hash < r.points[j].hash })
+	return r
+}
+
+// locate returns the index of the shard that owns key
+func (r *hashRing) locate(key string) int {
+	h := ringHash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].shard
+}
+
+// parseShardAddresses splits a comma-separated list of backend addresses
+func parseShardAddresses(list string) ([]string, error) {
+	var addrs []string
+	seen := make(map[string]bool)
+	for _, addr := range strings.Split(list, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if seen[addr] {
+			return nil, fmt.Errorf("backend %s is listed twice", addr)
+		}
+		seen[addr] = true
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no backend addresses given")
+	}
+	return addrs, nil
+}
+
+// dialShard connects to a backend
+func dialShard(addr string) (*shard, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(backendCredentials))
+	if err != nil {
+		return nil, fmt.Errorf("backend %s: %v", addr, err)
+	}
+	return &shard{
+		addr:        addr,
+		conn:        conn,
+		client:      proto.NewKeyValueStoreClient(conn),
+		replication: proto.NewReplicationClient(conn),
+		health:      healthpb.NewHealthClient(conn),
+	}, nil
+}
+
+// routing is the layout of the keyspace over the shards. It is replaced as
+// a whole, so every request is served from one consistent layout.
+type routing struct {
+	shards []*shard
+	ring   *hashRing
+
+	// migration is set while keys move to this layout from the previous one
+	migration *migration
+}
+
+// owner returns the shard that owns key
+func (r *routing) owner(key string) *shard {
+	if len(r.shards) == 1 {
+		return r.shards[0]
+	}
+	return r.shards[r.ring.locate(key)]
+}
+
+// layout returns the current routing, which is empty when no backend is
+// configured
+func (s *APIServer) layout() *routing {
+	if r := s.routing.Load(); r != nil {
+		return r
+	}
+	return &routing{}
+}
+
+// shardFor returns the shard that owns key, or nil when no backend is
+// configured
+func (s *APIServer) shardFor(key string) *shard {
+	r := s.layout()
+	if len(r.shards) == 0 {
+		return nil
+	}
+	return r.owner(key)
+}
+
+// backends returns every shard that may hold keys: the shards of the
+// current layout and, while resharding, those being migrated away from
+func (s *APIServer) backends() []*shard {
+	r := s.layout()
+	if r.migration == nil {
+		return r.shards
+	}
+
+	shards := append([]*shard(nil), r.shards...)
+	for _, old := range r.migration.previous.shards {
+		if !slices.Contains(shards, old) {
+			shards = append(shards, old)
+		}
+	}
+	return shards
+}
+
+// fanOut calls fn for every shard concurrently and returns the first error.
+// fn receives the shard's index so it can store its result without locking.
+func fanOut(ctx context.Context, shards []*shard, fn func(ctx context.Context, i int, backend *shard) error) error {
+	errs := make([]error, len(shards))
+	var wg sync.WaitGroup
+	for i, backend := range shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = fn(ctx, i, backend)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectShard returns the backend named by the shard query parameter,
+// which may be left out when there is a single backend. It writes a 400
+// response and returns nil when no backend matches.
+func (s *APIServer) selectShard(c *gin.Context) *shard {
+	shards := s.backends()
+	addr, ok := c.GetQuery("shard")
+	if !ok && len(shards) == 1 {
+		return shards[0]
+	}
+	for _, backend := range shards {
+		if backend.addr == addr {
+			return backend
+		}
+	}
+
+	addrs := make([]string, 0, len(shards))
+	for _, backend := range shards {
+		addrs = append(addrs, backend.addr)
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "shard parameter must be one of: " + strings.Join(addrs, ", ")})
+	return nil
+}
+
+// openStreams starts a server-streaming call on every shard and receives
+// its first message, so that errors raised at the start of any of them are
+// reported before a response is written. The first message of a stream
+// that ended straight away is nil.
+func openStreams[T any](ctx context.Context, shards []*shard, open func(ctx context.Context, backend *shard) (grpc.ServerStreamingClient[T], error)) ([]grpc.ServerStreamingClient[T], []*T, error) {
+	streams := make([]grpc.ServerStreamingClient[T], len(shards))
+	firsts := make([]*T, len(shards))
+	err := fanOut(ctx, shards, func(ctx context.Context, i int, backend *shard) error {
+		stream, err := open(ctx, backend)
+		if err != nil {
+			return err
+		}
+		first, err := stream.Recv()
+		if err != nil && err != io.EOF {
+			return err
+		}
+		streams[i], firsts[i] = stream, first
+		return nil
+	})
+	return streams, firsts, err
+}
@@ -0,0 +1,835 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pwntato/Censys/internal/certs"
+	"github.com/pwntato/Censys/internal/certs/certstest"
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// fakeBackend is an in-memory kvstore-server with just enough of the API
+// to exercise routing and fan-out
+type fakeBackend struct {
+	proto.UnimplementedKeyValueStoreServer
+	server  *grpc.Server
+	mu      sync.Mutex
+	data    map[string]string
+	schemas map[string]string
+
+	// versions holds the version vector of quorum writes; a coordinated
+	// write gets a fresh version from writes
+	versions map[string][]*proto.VersionEntry
+	writes   uint64
+
+	// addr is where the backend is served; hints are the writes it holds
+	// for other backends
+	addr  string
+	hints []*proto.StoreHintRequest
+
+	// legacy answers with success=false for missing keys instead of
+	// NotFound, as servers in legacy status mode do
+	legacy bool
+
+	health *health.Server
+
+	// hold, when set, is called by Get before it reads the key
+	hold func()
+}
+
+// fakeReplication serves the hinted handoff of a fake backend
+type fakeReplication struct {
+	proto.UnimplementedReplicationServer
+	backend *fakeBackend
+}
+
+func (f *fakeReplication) StoreHint(ctx context.Context, req *proto.StoreHintRequest) (*proto.StoreHintResponse, error) {
+	f.backend.mu.Lock()
+	defer f.backend.mu.Unlock()
+	f.backend.hints = append(f.backend.hints, req)
+	return &proto.StoreHintResponse{Success: true}, nil
+}
+
+// heldHints returns the hints the backend holds
+func (f *fakeBackend) heldHints() []*proto.StoreHintRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.hints)
+}
+
+// keys returns a copy of the backend's data
+func (f *fakeBackend) keys() map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return maps.Clone(f.data)
+}
+
+func (f *fakeBackend) Set(ctx context.Context, req *proto.SetRequest) (*proto.SetResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	version := req.Version
+	if req.Coordinator != "" {
+		f.writes++
+		version = []*proto.VersionEntry{{Node: req.Coordinator, Counter: f.writes}}
+	}
+	f.data[req.Key] = req.Value
+	f.versions[req.Key] = version
+	return &proto.SetResponse{Success: true, Version: version}, nil
+}
+
+func (f *fakeBackend) Get(ctx context.Context, req *proto.GetRequest) (*proto.GetResponse, error) {
+	if f.hold != nil {
+		f.hold()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, ok := f.data[req.Key]
+	if version := f.versions[req.Key]; !ok && version != nil && req.IncludeDeleted {
+		return &proto.GetResponse{Deleted: true, Version: version}, nil
+	}
+	if !ok && !f.legacy {
+		return nil, status.Errorf(codes.NotFound, "Key '%s' not found", req.Key)
+	}
+	return &proto.GetResponse{Success: ok, Value: value, Version: f.versions[req.Key]}, nil
+}
+
+func (f *fakeBackend) Delete(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.data[req.Key]
+	version := req.Version
+	if req.Coordinator != "" {
+		f.writes++
+		version = []*proto.VersionEntry{{Node: req.Coordinator, Counter: f.writes}}
+	}
+	if !ok && version == nil && !f.legacy {
+		return nil, status.Errorf(codes.NotFound, "Key '%s' not found", req.Key)
+	}
+	delete(f.data, req.Key)
+	f.versions[req.Key] = version
+	return &proto.DeleteResponse{Success: ok, Version: version}, nil
+}
+
+func (f *fakeBackend) Backup(req *proto.BackupRequest, stream grpc.ServerStreamingServer[proto.BackupChunk]) error {
+	chunk := &proto.BackupChunk{}
+	f.mu.Lock()
+	for prefix, schema := range f.schemas {
+		chunk.Schemas = append(chunk.Schemas, &proto.KeySchema{Prefix: prefix, Schema: schema})
+	}
+	for key, value := range f.data {
+		chunk.Entries = append(chunk.Entries, &proto.BackupEntry{Key: key, Value: value, Version: f.versions[key]})
+	}
+	f.mu.Unlock()
+	return stream.Send(chunk)
+}
+
+// Restore merges the entries it receives; replace mode is not supported
+func (f *fakeBackend) Restore(stream grpc.ClientStreamingServer[proto.RestoreRequest, proto.RestoreResponse]) error {
+	var restored int64
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&proto.RestoreResponse{Success: true, KeysRestored: restored})
+		}
+		if err != nil {
+			return err
+		}
+		for _, schema := range req.Schemas {
+			f.RegisterSchema(stream.Context(), &proto.RegisterSchemaRequest{Prefix: schema.Prefix, Schema: schema.Schema})
+		}
+		for _, entry := range req.Entries {
+			f.Set(stream.Context(), &proto.SetRequest{Key: entry.Key, Value: entry.Value, Version: entry.Version})
+			restored++
+		}
+	}
+}
+
+func (f *fakeBackend) RegisterSchema(ctx context.Context, req *proto.RegisterSchemaRequest) (*proto.RegisterSchemaResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.schemas[req.Prefix] = req.Schema
+	return &proto.RegisterSchemaResponse{Success: true}, nil
+}
+
+func (f *fakeBackend) ListSchemas(ctx context.Context, req *proto.ListSchemasRequest) (*proto.ListSchemasResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &proto.ListSchemasResponse{Success: true}
+	for prefix, schema := range f.schemas {
+		resp.Schemas = append(resp.Schemas, &proto.KeySchema{Prefix: prefix, Schema: schema})
+	}
+	return resp, nil
+}
+
+func (f *fakeBackend) Export(req *proto.ExportRequest, stream grpc.ServerStreamingServer[proto.ExportChunk]) error {
+	f.mu.Lock()
+	keys := make([]string, 0, len(f.data))
+	for key := range f.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "{\"key\":%q,\"value\":%q}\n", key, f.data[key])
+	}
+	f.mu.Unlock()
+	return stream.Send(&proto.ExportChunk{Data: buf.Bytes()})
+}
+
+// Import stores JSON Lines rows, rejecting those with an empty value
+func (f *fakeBackend) Import(stream grpc.BidiStreamingServer[proto.ImportRequest, proto.ImportProgress]) error {
+	var doc bytes.Buffer
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		doc.Write(req.Data)
+	}
+
+	progress := &proto.ImportProgress{Done: true}
+	scanner := bufio.NewScanner(&doc)
+	for line := int64(1); scanner.Scan(); line++ {
+		var row struct{ Key, Value string }
+		json.Unmarshal(scanner.Bytes(), &row)
+		progress.RowsRead++
+		if row.Value == "" {
+			progress.RowsFailed++
+			progress.Errors = append(progress.Errors, &proto.ImportError{Line: line, Error: "empty value"})
+			continue
+		}
+		f.Set(stream.Context(), &proto.SetRequest{Key: row.Key, Value: row.Value})
+		progress.RowsImported++
+	}
+	return stream.Send(progress)
+}
+
+// startFakeBackends serves n fake backends with the server options opts and
+// returns their addresses
+func startFakeBackends(t *testing.T, n int, opts ...grpc.ServerOption) ([]string, []*fakeBackend) {
+	t.Helper()
+
+	backends := make([]*fakeBackend, n)
+	addrs := make([]string, n)
+	for i := range backends {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Listen() error = %v", err)
+		}
+		server := grpc.NewServer(opts...)
+		backends[i] = &fakeBackend{
+			server:   server,
+			data:     make(map[string]string),
+			schemas:  make(map[string]string),
+			versions: make(map[string][]*proto.VersionEntry),
+			addr:     lis.Addr().String(),
+			health:   health.NewServer(),
+		}
+		proto.RegisterKeyValueStoreServer(server, backends[i])
+		proto.RegisterReplicationServer(server, &fakeReplication{backend: backends[i]})
+		healthpb.RegisterHealthServer(server, backends[i].health)
+		go server.Serve(lis)
+		t.Cleanup(server.Stop)
+		addrs[i] = backends[i].addr
+	}
+	return addrs, backends
+}
+
+// newShardedRouter returns a router sharding across the backends at addrs
+func newShardedRouter(t *testing.T, addrs []string) (*gin.Engine, *APIServer) {
+	t.Helper()
+
+	apiServer, err := NewAPIServer(addrs, defaultVirtualNodes)
+	if err != nil {
+		t.Fatalf("NewAPIServer() error = %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/health", apiServer.Health)
+	router.POST("/kv/set", apiServer.Set)
+	router.GET("/kv/get/:key", apiServer.Get)
+	router.DELETE("/kv/delete/:key", apiServer.Delete)
+	router.GET("/kv/export", apiServer.Export)
+	router.POST("/kv/import", apiServer.Import)
+	router.GET("/admin/backup", apiServer.Backup)
+	router.POST("/admin/restore", apiServer.Restore)
+	router.POST("/admin/reshard", apiServer.Reshard)
+	router.GET("/admin/reshard", apiServer.ReshardStatus)
+	router.GET("/admin/quorum", apiServer.QuorumStatus)
+	return router, apiServer
+}
+
+// startShardedRouter serves n fake backends and a router sharding across them
+func startShardedRouter(t *testing.T, n int) (*gin.Engine, []*fakeBackend) {
+	t.Helper()
+
+	addrs, backends := startFakeBackends(t, n)
+	router, _ := newShardedRouter(t, addrs)
+	return router, backends
+}
+
+// setKeys writes key-0 to key-(n-1) through router
+func setKeys(t *testing.T, router *gin.Engine, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		body := fmt.Sprintf(`{"key":"key-%d","value":"v%d"}`, i, i)
+		req, _ := http.NewRequest("POST", "/kv/set", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Set key-%d status %d: %s", i, w.Code, w.Body.String())
+		}
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after five seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHashRing(t *testing.T) {
+	addrs := []string{"kv1:50051", "kv2:50051", "kv3:50051"}
+	ring := newHashRing(addrs, defaultVirtualNodes)
+
+	const keys = 30000
+	counts := make([]int, len(addrs))
+	owners := make([]int, keys)
+	for i := range owners {
+		owners[i] = ring.locate(fmt.Sprintf("key-%d", i))
+		counts[owners[i]]++
+	}
+	for i, count := range counts {
+		if count < keys/len(addrs)*3/4 || count > keys/len(addrs)*5/4 {
+			t.Errorf("shard %s owns %d of %d keys", addrs[i], count, keys)
+		}
+	}
+
+	// Adding a shard only moves keys onto the new shard, about a quarter of them
+	grown := newHashRing(append(addrs, "kv4:50051"), defaultVirtualNodes)
+	moved := 0
+	for i, owner := range owners {
+		switch now := grown.locate(fmt.Sprintf("key-%d", i)); now {
+		case owner:
+		case 3:
+			moved++
+		default:
+			t.Fatalf("key-%d moved from shard %d to %d", i, owner, now)
+		}
+	}
+	if moved < keys/6 || moved > keys/3 {
+		t.Errorf("%d of %d keys moved to the new shard", moved, keys)
+	}
+}
+
+func TestParseShardAddresses(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		want    int
+		wantErr bool
+	}{
+		{"Single", "kvstore-server:50051", 1, false},
+		{"List", "kv1:50051, kv2:50051,,kv3:50051", 3, false},
+		{"Duplicate", "kv1:50051,kv1:50051", 0, true},
+		{"Empty", " , ", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addrs, err := parseShardAddresses(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseShardAddresses() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(addrs) != tt.want {
+				t.Errorf("parseShardAddresses() = %v", addrs)
+			}
+		})
+	}
+}
+
+func TestShardedRouting(t *testing.T) {
+	router, backends := startShardedRouter(t, 3)
+
+	const keys = 60
+	setKeys(t, router, keys)
+
+	// Every key landed on exactly one shard and can be read back
+	total := 0
+	for i, b := range backends {
+		if len(b.data) == 0 {
+			t.Errorf("shard %d received no keys", i)
+		}
+		total += len(b.data)
+	}
+	if total != keys {
+		t.Errorf("shards hold %d keys, expected %d", total, keys)
+	}
+	for i := 0; i < keys; i++ {
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/kv/get/key-%d", i), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("Get key-%d status %d", i, w.Code)
+		}
+	}
+
+	// Export fans out to every shard
+	req, _ := http.NewRequest("GET", "/kv/export", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if lines := strings.Count(w.Body.String(), "\n"); w.Code != http.StatusOK || lines != keys {
+		t.Errorf("Export status %d with %d rows, expected %d", w.Code, lines, keys)
+	}
+}
+
+func TestShardedRouting_MissingKeys(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		t.Run(fmt.Sprintf("legacy=%v", legacy), func(t *testing.T) {
+			router, backends := startShardedRouter(t, 2)
+			for _, b := range backends {
+				b.legacy = legacy
+			}
+
+			for _, method := range []string{"GET", "DELETE"} {
+				path := "/kv/get/missing"
+				if method == "DELETE" {
+					path = "/kv/delete/missing"
+				}
+				req, _ := http.NewRequest(method, path, nil)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				var body map[string]any
+				json.Unmarshal(w.Body.Bytes(), &body)
+				if w.Code != http.StatusNotFound || body["success"] != false {
+					t.Errorf("%s of a missing key = %d %s, expected 404", method, w.Code, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestShardedHealth(t *testing.T) {
+	addrs, backends := startFakeBackends(t, 3)
+	router, _ := newShardedRouter(t, addrs)
+
+	health := func() (int, map[string]any) {
+		req, _ := http.NewRequest("GET", "/health", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		var body struct {
+			Status   string         `json:"status"`
+			Backends map[string]any `json:"backends"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body.Backends
+	}
+
+	code, statuses := health()
+	if code != http.StatusOK || len(statuses) != 3 {
+		t.Errorf("Health with every backend serving = %d %v", code, statuses)
+	}
+
+	// A backend still starting up makes the API server unhealthy
+	backends[1].health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	code, statuses = health()
+	if code != http.StatusServiceUnavailable || statuses[addrs[1]] != "NOT_SERVING" || statuses[addrs[0]] != "SERVING" {
+		t.Errorf("Health with a backend not serving = %d %v", code, statuses)
+	}
+
+	// As does one that cannot be reached
+	backends[1].health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	backends[2].server.Stop()
+	code, statuses = health()
+	if code != http.StatusServiceUnavailable || statuses[addrs[2]] != codes.Unavailable.String() {
+		t.Errorf("Health with a backend down = %d %v", code, statuses)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		wantErr bool
+	}{
+		{"Requests in flight finish", 10 * time.Second, false},
+		{"Requests in flight are cut off", 50 * time.Millisecond, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addrs, backends := startFakeBackends(t, 1)
+			router, apiServer := newShardedRouter(t, addrs)
+			backends[0].data["a"] = "1"
+			started, release := make(chan struct{}, 1), make(chan struct{})
+			backends[0].hold = func() {
+				started <- struct{}{}
+				<-release
+			}
+
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("Listen() error = %v", err)
+			}
+			srv := &http.Server{Handler: router}
+			go srv.Serve(lis)
+			url := "http://" + lis.Addr().String() + "/kv/get/a"
+			client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+			type result struct {
+				code int
+				err  error
+			}
+			got := make(chan result, 1)
+			go func() {
+				resp, err := client.Get(url)
+				if err != nil {
+					got <- result{err: err}
+					return
+				}
+				resp.Body.Close()
+				got <- result{code: resp.StatusCode}
+			}()
+			<-started
+
+			stopped := make(chan error, 1)
+			go func() {
+				stopped <- apiServer.shutdown(srv, tt.timeout)
+			}()
+
+			// New connections are refused at once
+			waitFor(t, "new connections to be refused", func() bool {
+				resp, err := client.Get(url)
+				if err == nil {
+					resp.Body.Close()
+				}
+				return err != nil
+			})
+
+			if tt.wantErr {
+				if err := <-stopped; err == nil {
+					t.Error("shutdown() = nil with a request still in flight")
+				}
+				if r := <-got; r.err == nil {
+					t.Errorf("request in flight got %d, expected it to be cut off", r.code)
+				}
+				close(release)
+				return
+			}
+
+			select {
+			case err := <-stopped:
+				t.Fatalf("shutdown() = %v before the request in flight finished", err)
+			case <-time.After(50 * time.Millisecond):
+			}
+			close(release)
+			if r := <-got; r.err != nil || r.code != http.StatusOK {
+				t.Errorf("request in flight = %d, %v, expected 200", r.code, r.err)
+			}
+			if err := <-stopped; err != nil {
+				t.Errorf("shutdown() error = %v", err)
+			}
+			if state := apiServer.dialed[addrs[0]].conn.GetState(); state != connectivity.Shutdown {
+				t.Errorf("backend connection is %v after shutdown", state)
+			}
+		})
+	}
+}
+
+func TestShardedTLS(t *testing.T) {
+	files := certstest.Write(t, t.TempDir(), "kvstore-server", "api-server", "stranger")
+	load := func(f certs.Files) *certs.Reloader {
+		r, err := certs.Load(f)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		return r
+	}
+
+	// The backends only accept clients with a certificate from the authority
+	addrs, _ := startFakeBackends(t, 2, grpc.Creds(credentials.NewTLS(load(files["kvstore-server"]).ServerConfig())))
+	t.Cleanup(func() { backendCredentials = insecure.NewCredentials() })
+
+	tests := []struct {
+		name           string
+		client         certs.Files
+		expectedStatus int
+	}{
+		{"Client certificate", files["api-server"], http.StatusOK},
+		{"No client certificate", certs.Files{CA: files["api-server"].CA}, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useBackendTLS(load(tt.client), "kvstore-server")
+			router, _ := newShardedRouter(t, addrs)
+
+			// The API is served over HTTPS with its own certificate
+			srv := httptest.NewUnstartedServer(router)
+			srv.TLS = load(certs.Files{Cert: files["api-server"].Cert, Key: files["api-server"].Key}).ServerConfig()
+			srv.StartTLS()
+			t.Cleanup(srv.Close)
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: load(certs.Files{CA: tt.client.CA}).ClientConfig("api-server")}}
+
+			resp, err := client.Get(srv.URL + "/health")
+			if err != nil {
+				t.Fatalf("GET /health over HTTPS error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("GET /health = %d, expected %d", resp.StatusCode, tt.expectedStatus)
+			}
+		})
+	}
+
+	// A client that does not trust the API server's authority is refused
+	strangers := &http.Client{Transport: &http.Transport{TLSClientConfig: load(certs.Files{CA: files["stranger"].Cert}).ClientConfig("api-server")}}
+	srv := httptest.NewUnstartedServer(gin.New())
+	srv.TLS = load(certs.Files{Cert: files["api-server"].Cert, Key: files["api-server"].Key}).ServerConfig()
+	srv.StartTLS()
+	defer srv.Close()
+	if _, err := strangers.Get(srv.URL + "/health"); err == nil {
+		t.Error("GET /health succeeded without trusting the server's certificate")
+	}
+}
+
+func TestShardedImport(t *testing.T) {
+	router, backends := startShardedRouter(t, 3)
+
+	var doc strings.Builder
+	for i := 1; i <= 20; i++ {
+		value := fmt.Sprintf("v%d", i)
+		if i == 7 || i == 15 {
+			value = ""
+		}
+		fmt.Fprintf(&doc, "{\"key\":\"row-%d\",\"value\":%q}\n", i, value)
+		if i == 10 {
+			doc.WriteString("\n") // blank lines still count towards line numbers
+		}
+	}
+
+	req, _ := http.NewRequest("POST", "/kv/import", strings.NewReader(doc.String()))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Import status %d: %s", w.Code, w.Body.String())
+	}
+
+	var last ImportProgress
+	var errs []ImportError
+	dec := json.NewDecoder(w.Body)
+	for dec.More() {
+		last = ImportProgress{}
+		if err := dec.Decode(&last); err != nil {
+			t.Fatalf("invalid progress line: %v", err)
+		}
+		errs = append(errs, last.Errors...)
+	}
+
+	if !last.Done || last.RowsRead != 20 || last.RowsImported != 18 || last.RowsFailed != 2 {
+		t.Errorf("final progress = %+v", last)
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+	if len(errs) != 2 || errs[0].Line != 7 || errs[1].Line != 16 {
+		t.Errorf("errors = %+v, expected lines 7 and 16 of the document", errs)
+	}
+
+	total := 0
+	for _, b := range backends {
+		total += len(b.data)
+	}
+	if total != 18 {
+		t.Errorf("shards hold %d keys, expected 18", total)
+	}
+}
+
+func TestShardedBackupAndRestore(t *testing.T) {
+	router, backends := startShardedRouter(t, 3)
+	setKeys(t, router, 30)
+	for _, b := range backends {
+		b.schemas["user:"] = `{"type":"object"}`
+	}
+	versioned := holders(backends, "key-0")[0]
+	versioned.versions["key-0"] = []*proto.VersionEntry{{Node: "kv1", Counter: 3}}
+
+	req, _ := http.NewRequest("GET", "/admin/backup", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Backup status %d: %s", w.Code, w.Body.String())
+	}
+	backup := w.Body.String()
+	if lines := strings.Split(strings.TrimSpace(backup), "\n"); len(lines) != 31 || lines[0] != `{"prefix":"user:","schema":{"type":"object"}}` {
+		t.Fatalf("backup has %d lines starting with %s, expected the schema once and 30 entries", len(lines), lines[0])
+	}
+
+	// Restore into a fresh set of shards
+	router, backends = startShardedRouter(t, 3)
+	req, _ = http.NewRequest("POST", "/admin/restore?mode=merge", strings.NewReader(backup))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Restore status %d: %s", w.Code, w.Body.String())
+	}
+
+	total := 0
+	for i, b := range backends {
+		total += len(b.data)
+		if b.schemas["user:"] != `{"type":"object"}` {
+			t.Errorf("shard %d schemas = %v, expected the backed up schema", i, b.schemas)
+		}
+	}
+	if total != 30 {
+		t.Errorf("shards hold %d keys, expected 30", total)
+	}
+
+	// Versions survive the round trip
+	restored := holders(backends, "key-0")[0]
+	if version := versionFromProto(restored.versions["key-0"]); version["kv1"] != 3 {
+		t.Errorf("restored version of key-0 = %v, expected {kv1:3}", version)
+	}
+}
+
+func TestReshard(t *testing.T) {
+	addrs, backends := startFakeBackends(t, 3)
+	router, _ := newShardedRouter(t, addrs[:2])
+	backends[0].schemas["user:"] = `{"type": "object"}`
+
+	const keys = 90
+	setKeys(t, router, keys)
+
+	reshard := func(addrs []string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(ReshardRequest{Shards: addrs})
+		req, _ := http.NewRequest("POST", "/admin/reshard", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	if w := reshard(addrs[:2]); w.Code != http.StatusBadRequest {
+		t.Errorf("Reshard to the current layout status %d, expected 400", w.Code)
+	}
+	if w := reshard(addrs); w.Code != http.StatusAccepted {
+		t.Fatalf("Reshard status %d: %s", w.Code, w.Body.String())
+	}
+
+	var status ReshardStatus
+	waitFor(t, "the resharding to finish", func() bool {
+		req, _ := http.NewRequest("GET", "/admin/reshard", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		json.Unmarshal(w.Body.Bytes(), &status)
+		return status.State == reshardDone || status.State == reshardFailed
+	})
+	if status.State != reshardDone || status.KeysScanned != keys || status.KeysCopied == 0 || status.KeysCopied != status.KeysRemoved {
+		t.Fatalf("status = %+v", status)
+	}
+
+	// Every key now lives only on its owner in the new layout
+	ring := newHashRing(addrs, defaultVirtualNodes)
+	total := 0
+	for i, b := range backends {
+		for key := range b.keys() {
+			if owner := ring.locate(key); owner != i {
+				t.Errorf("%s is on shard %d, expected shard %d", key, i, owner)
+			}
+			total++
+		}
+	}
+	if total != keys || int64(len(backends[2].keys())) != status.KeysCopied {
+		t.Errorf("shards hold %d keys, %d on the new shard; expected %d, %d", total, len(backends[2].keys()), keys, status.KeysCopied)
+	}
+	if backends[2].schemas["user:"] == "" {
+		t.Error("schemas were not copied to the new shard")
+	}
+}
+
+func TestReshard_DoubleRead(t *testing.T) {
+	addrs, backends := startFakeBackends(t, 2)
+	router, apiServer := newShardedRouter(t, addrs[:1])
+	setKeys(t, router, 20)
+
+	// Freeze a migration onto both shards before any key is copied
+	current := apiServer.layout()
+	shards, _ := apiServer.dial(addrs)
+	next := &routing{shards: shards, ring: newHashRing(addrs, defaultVirtualNodes), migration: newMigration(current, addrs)}
+	apiServer.routing.Store(next)
+
+	moving := ""
+	for i := 0; i < 20 && moving == ""; i++ {
+		if key := fmt.Sprintf("key-%d", i); next.ring.locate(key) == 1 {
+			moving = key
+		}
+	}
+	if moving == "" {
+		t.Fatal("no key moves to the new shard")
+	}
+
+	// Reads fall back to the old owner
+	req, _ := http.NewRequest("GET", "/kv/get/"+moving, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Get %s during migration status %d", moving, w.Code)
+	}
+
+	// Writes go to the new owner and are never overwritten by the copy
+	req, _ = http.NewRequest("POST", "/kv/set", strings.NewReader(`{"key":"`+moving+`","value":"new"}`))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if got := backends[1].keys()[moving]; got != "new" {
+		t.Fatalf("new owner has %s = %q", moving, got)
+	}
+	if err := next.migration.copyBatch(context.Background(), shards[1], []*proto.BackupEntry{{Key: moving, Value: "old"}}); err != nil {
+		t.Fatalf("copyBatch() error = %v", err)
+	}
+	if got := backends[1].keys()[moving]; got != "new" {
+		t.Errorf("copy overwrote %s with %q", moving, got)
+	}
+
+	// Deletes reach both owners
+	req, _ = http.NewRequest("DELETE", "/kv/delete/"+moving, nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	_, onOld := backends[0].keys()[moving]
+	_, onNew := backends[1].keys()[moving]
+	if w.Code != http.StatusOK || onOld || onNew {
+		t.Errorf("Delete %s status %d, left on old: %v, new: %v", moving, w.Code, onOld, onNew)
+	}
+}
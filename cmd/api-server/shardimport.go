@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+)
+
+// lineMapping records that the row starting on shardLine of a shard's
+// import stream came from line of the original document
+type lineMapping struct {
+	shardLine int64
+	line      int64
+}
+
+// importSplitter reads an import document and forwards each row to the
+// shard that owns its key, on one import stream per shard. Rows are sent
+// on as they were written where possible, so that the shards validate them
+// exactly as they would a document sent to them directly.
+type importSplitter struct {
+	format  proto.DataFormat
+	ring    *hashRing
+	streams []grpc.BidiStreamingClient[proto.ImportRequest, proto.ImportProgress]
+	bufs    []bytes.Buffer
+	lines   []int64 // lines sent to each shard so far
+
+	mu      sync.Mutex
+	mapping [][]lineMapping
+	local   proto.ImportProgress // rows that failed before reaching a shard
+}
+
+// newImportSplitter creates a splitter writing to streams, one per shard
+func newImportSplitter(format proto.DataFormat, ring *hashRing, streams []grpc.BidiStreamingClient[proto.ImportRequest, proto.ImportProgress]) *importSplitter {
+	return &importSplitter{
+		format:  format,
+		ring:    ring,
+		streams: streams,
+		bufs:    make([]bytes.Buffer, len(streams)),
+		lines:   make([]int64, len(streams)),
+		mapping: make([][]lineMapping, len(streams)),
+	}
+}
+
+// run splits body across the shards and closes every stream once the body
+// is exhausted. It calls cancel if the body cannot be read.
+func (sp *importSplitter) run(body io.Reader, cancel context.CancelFunc) {
+	for _, stream := range sp.streams {
+		if err := stream.Send(&proto.ImportRequest{Format: sp.format}); err != nil {
+			return
+		}
+	}
+
+	if sp.format == proto.DataFormat_DATA_FORMAT_CSV {
+		// Each shard sees a header, so its first row is never taken for one
+		for i := range sp.bufs {
+			sp.bufs[i].Write(csvHeaderLine)
+			sp.lines[i] = 1
+		}
+	}
+
+	var err error
+	if sp.format == proto.DataFormat_DATA_FORMAT_CSV {
+		err = sp.splitCSV(body)
+	} else {
+		err = sp.splitJSONL(body)
+	}
+	if errors.Is(err, errStreamClosed) {
+		return // the failing shard's error is reported by its receiver
+	}
+	if err != nil {
+		log.Printf("Import aborted while reading request: %v", err)
+		cancel()
+		return
+	}
+
+	for i, stream := range sp.streams {
+		if err := sp.flush(i); err != nil {
+			return
+		}
+		stream.CloseSend()
+	}
+}
+
+// errStreamClosed is returned when a shard's import stream has failed
+var errStreamClosed = errors.New("import stream closed")
+
+// splitJSONL forwards each non-blank line to the shard owning its key.
+// Lines without a readable key go to the first shard, which reports them.
+func (sp *importSplitter) splitJSONL(body io.Reader) error {
+	r := bufio.NewReader(body)
+	for line := int64(1); ; line++ {
+		text, err := r.ReadString('\n')
+		if err == io.EOF && text == "" {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		var row struct {
+			Key *string `json:"key"`
+		}
+		i := 0
+		if json.Unmarshal([]byte(text), &row) == nil && row.Key != nil {
+			i = sp.ring.locate(*row.Key)
+		}
+		if err := sp.write(i, line, []byte(text+"\n")); err != nil {
+			return err
+		}
+	}
+}
+
+// splitCSV forwards each record to the shard owning its key, skipping a
+// leading key,value header. Records that cannot be parsed are reported
+// here, as there is no key to route them by.
+func (sp *importSplitter) splitCSV(body io.Reader) error {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	first := true
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			sp.mu.Lock()
+			sp.local.RowsRead++
+			sp.local.RowsFailed++
+			sp.local.Errors = append(sp.local.Errors, &proto.ImportError{Line: int64(parseErr.StartLine), Error: parseErr.Err.Error()})
+			sp.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		line, _ := reader.FieldPos(0)
+		isHeader := first && len(fields) == 2 && fields[0] == "key" && fields[1] == "value"
+		first = false
+		if isHeader {
+			continue
+		}
+
+		buf.Reset()
+		writer.Write(fields)
+		writer.Flush()
+		if err := sp.write(sp.ring.locate(fields[0]), int64(line), buf.Bytes()); err != nil {
+			return err
+		}
+	}
+}
+
+// write queues a row that starts on line of the document for shard i
+func (sp *importSplitter) write(i int, line int64, row []byte) error {
+	sp.mu.Lock()
+	sp.mapping[i] = append(sp.mapping[i], lineMapping{shardLine: sp.lines[i] + 1, line: line})
+	sp.mu.Unlock()
+
+	sp.lines[i] += int64(bytes.Count(row, []byte{'\n'}))
+	sp.bufs[i].Write(row)
+	if sp.bufs[i].Len() >= importReadSize {
+		return sp.flush(i)
+	}
+	return nil
+}
+
+// flush sends the rows queued for shard i
+func (sp *importSplitter) flush(i int) error {
+	if sp.bufs[i].Len() == 0 {
+		return nil
+	}
+	err := sp.streams[i].Send(&proto.ImportRequest{Data: bytes.Clone(sp.bufs[i].Bytes())})
+	sp.bufs[i].Reset()
+	if err != nil {
+		return errStreamClosed
+	}
+	return nil
+}
+
+// originalLine maps a line reported by shard i back to the document
+func (sp *importSplitter) originalLine(i int, shardLine int64) int64 {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	mapping := sp.mapping[i]
+	j := sort.Search(len(mapping), func(j int) bool { return mapping[j].shardLine > shardLine })
+	if j == 0 {
+		return shardLine
+	}
+	m := mapping[j-1]
+	return m.line + shardLine - m.shardLine
+}
+
+// takeLocal returns the rows that failed before reaching a shard, and the
+// errors found since the last call
+func (sp *importSplitter) takeLocal() (rowsRead, rowsFailed int64, errs []*proto.ImportError) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	errs, sp.local.Errors = sp.local.Errors, nil
+	return sp.local.RowsRead, sp.local.RowsFailed, errs
+}
+
+// shardProgress is a progress report received from one shard
+type shardProgress struct {
+	shard    int
+	progress *proto.ImportProgress
+	err      error
+}
+
+// importSharded handles an import across several shards. The shards'
+// progress is summed into a single report, with errors pointing at lines
+// of the original document.
+func (s *APIServer) importSharded(c *gin.Context, format proto.DataFormat, r *routing) {
+	ctx, cancel := context.WithCancel(requestContext(c))
+	defer cancel()
+
+	streams := make([]grpc.BidiStreamingClient[proto.ImportRequest, proto.ImportProgress], len(r.shards))
+	err := fanOut(ctx, r.shards, func(ctx context.Context, i int, backend *shard) error {
+		var err error
+		streams[i], err = backend.client.Import(ctx)
+		return err
+	})
+	if err != nil {
+		grpcError(c, err)
+		return
+	}
+
+	// Progress is written while the body is still being read
+	http.NewResponseController(c.Writer).EnableFullDuplex()
+
+	splitter := newImportSplitter(format, r.ring, streams)
+	go splitter.run(c.Request.Body, cancel)
+
+	reports := make(chan shardProgress)
+	for i, stream := range streams {
+		go func() {
+			for {
+				progress, err := stream.Recv()
+				select {
+				case reports <- shardProgress{shard: i, progress: progress, err: err}:
+				case <-ctx.Done():
+					return
+				}
+				if err != nil || progress.Done {
+					return
+				}
+			}
+		}()
+	}
+
+	latest := make([]*proto.ImportProgress, len(streams))
+	enc := json.NewEncoder(c.Writer)
+	for done, started := 0, false; done < len(streams); {
+		r := <-reports
+		if r.err != nil {
+			// Report the failure cleanly if nothing has been written yet
+			if !started {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": r.err.Error()})
+				return
+			}
+			log.Printf("Import aborted mid-stream: %v", r.err)
+			panic(http.ErrAbortHandler)
+		}
+		if r.progress.Done {
+			done++
+		}
+		latest[r.shard] = r.progress
+
+		var report ImportProgress
+		for _, e := range r.progress.Errors {
+			report.Errors = append(report.Errors, ImportError{Line: splitter.originalLine(r.shard, e.Line), Error: e.Error})
+		}
+		rowsRead, rowsFailed, localErrs := splitter.takeLocal()
+		report.RowsRead, report.RowsFailed = rowsRead, rowsFailed
+		for _, e := range localErrs {
+			report.Errors = append(report.Errors, ImportError{Line: e.Line, Error: e.Error})
+		}
+		for _, p := range latest {
+			if p != nil {
+				report.RowsRead += p.RowsRead
+				report.RowsImported += p.RowsImported
+				report.RowsFailed += p.RowsFailed
+			}
+		}
+		report.Done = done == len(streams)
+		sort.Slice(report.Errors, func(i, j int) bool { return report.Errors[i].Line < report.Errors[j].Line })
+
+		if !started {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Status(http.StatusOK)
+			started = true
+		}
+		if err := enc.Encode(report); err != nil {
+			log.Printf("Import aborted while writing response: %v", err)
+			panic(http.ErrAbortHandler)
+		}
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// defaultShutdownTimeout is how long requests in flight at shutdown are
+// given to finish before they are cut off
+const defaultShutdownTimeout = 25 * time.Second
+
+// shutdown stops srv gracefully. It stops accepting connections at once and
+// waits until timeout for the requests in flight, and for the replica writes
+// and read repairs that outlive them, before closing the connections to the
+// backends. An error means some were still running when they were cut off.
+func (s *APIServer) shutdown(srv *http.Server, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := srv.Shutdown(ctx)
+	if err != nil {
+		srv.Close()
+	}
+
+	pending := make(chan struct{})
+	go func() {
+		s.pending.Wait()
+		close(pending)
+	}()
+	select {
+	case <-pending:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.close()
+	return err
+}
+
+// close closes the connection to every backend ever dialed
+func (s *APIServer) close() {
+	s.reshardMu.Lock()
+	defer s.reshardMu.Unlock()
+	for _, backend := range s.dialed {
+		backend.conn.Close()
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pwntato/Censys/internal/certs"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// backendCredentials are the transport credentials the kvstore servers are
+// dialed with
+var backendCredentials = insecure.NewCredentials()
+
+// backendTLSFromEnv loads GRPC_TLS_CA, the authorities the kvstore servers'
+// certificates are verified against, and GRPC_TLS_CERT and GRPC_TLS_KEY, the
+// client certificate presented to them. It returns nil when none is set.
+func backendTLSFromEnv() (*certs.Reloader, error) {
+	files := certs.Files{
+		Cert: os.Getenv("GRPC_TLS_CERT"),
+		Key:  os.Getenv("GRPC_TLS_KEY"),
+		CA:   os.Getenv("GRPC_TLS_CA"),
+	}
+	if files == (certs.Files{}) {
+		return nil, nil
+	}
+	r, err := certs.Load(files)
+	if err != nil {
+		return nil, fmt.Errorf("GRPC_TLS: %v", err)
+	}
+	return r, nil
+}
+
+// useBackendTLS makes the kvstore servers be dialed over TLS. Their
+// certificates must be issued for serverName, or for the host they are
+// dialed at when it is empty.
+func useBackendTLS(r *certs.Reloader, serverName string) {
+	backendCredentials = credentials.NewTLS(r.ClientConfig(serverName))
+}
+
+// httpsFromEnv loads API_TLS_CERT and API_TLS_KEY, the certificate served
+// over HTTPS, and API_TLS_CLIENT_CA, the authorities that must have issued
+// the certificates of callers when set. It returns nil when HTTPS is not
+// configured.
+func httpsFromEnv() (*certs.Reloader, error) {
+	files := certs.Files{
+		Cert: os.Getenv("API_TLS_CERT"),
+		Key:  os.Getenv("API_TLS_KEY"),
+		CA:   os.Getenv("API_TLS_CLIENT_CA"),
+	}
+	if files == (certs.Files{}) {
+		return nil, nil
+	}
+	if files.Cert == "" {
+		return nil, fmt.Errorf("API_TLS_CLIENT_CA needs API_TLS_CERT and API_TLS_KEY")
+	}
+	r, err := certs.Load(files)
+	if err != nil {
+		return nil, fmt.Errorf("API_TLS: %v", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pwntato/Censys/internal/certs"
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// newMirror creates a mirror between the kvstore servers at source and
+// target
+func newMirror(source, target *grpc.ClientConn) *mirror {
+	return &mirror{
+		source:      source.Target(),
+		target:      target.Target(),
+		feed:        proto.NewChangeFeedClient(source),
+		sourceKV:    proto.NewKeyValueStoreClient(source),
+		targetKV:    proto.NewKeyValueStoreClient(target),
+		consumer:    defaultConsumer,
+		ackInterval: defaultAckInterval,
+		resumed:     make(chan struct{}),
+	}
+}
+
+// Status handles GET /status
+func (m *mirror) Status(c *gin.Context) {
+	c.JSON(http.StatusOK, m.status())
+}
+
+// Pause handles POST /pause
+func (m *mirror) Pause(c *gin.Context) {
+	if !m.pause() {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": "Mirror is already paused"})
+		return
+	}
+	log.Printf("Mirroring paused at cursor %d", m.cursor())
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Mirror paused"})
+}
+
+// Resume handles POST /resume
+func (m *mirror) Resume(c *gin.Context) {
+	if !m.resume() {
+		c.JSON(http.StatusConflict, gin.H{"success": false, "message": "Mirror is not paused"})
+		return
+	}
+	log.Printf("Mirroring resumed")
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Mirror resumed"})
+}
+
+// Health handles GET /health
+func (m *mirror) Health(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"success": true, "status": "healthy"})
+}
+
+// newRouter routes the mirror's control and status endpoints
+func newRouter(m *mirror) *gin.Engine {
+	router := gin.Default()
+	router.GET("/health", m.Health)
+	router.GET("/status", m.Status)
+	router.POST("/pause", m.Pause)
+	router.POST("/resume", m.Resume)
+	return router
+}
+
+func main() {
+	sourceAddr, targetAddr := os.Getenv("MIRROR_SOURCE"), os.Getenv("MIRROR_TARGET")
+	if sourceAddr == "" || targetAddr == "" {
+		log.Fatalf("Invalid configuration: MIRROR_SOURCE and MIRROR_TARGET are required")
+	}
+	if sourceAddr == targetAddr {
+		log.Fatalf("Invalid configuration: MIRROR_SOURCE and MIRROR_TARGET must differ")
+	}
+
+	// Both clusters are dialed over TLS when any certificate is configured
+	creds := insecure.NewCredentials()
+	files := certs.Files{Cert: os.Getenv("MIRROR_TLS_CERT"), Key: os.Getenv("MIRROR_TLS_KEY"), CA: os.Getenv("MIRROR_TLS_CA")}
+	if files != (certs.Files{}) {
+		certificates, err := certs.Load(files)
+		if err != nil {
+			log.Fatalf("Invalid MIRROR_TLS configuration: %v", err)
+		}
+		go certificates.Watch(context.Background(), certs.ReloadInterval)
+		creds = credentials.NewTLS(certificates.ClientConfig(os.Getenv("MIRROR_TLS_SERVER_NAME")))
+		log.Printf("Connecting to both clusters over TLS")
+	}
+
+	source, err := grpc.NewClient(sourceAddr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		log.Fatalf("Invalid MIRROR_SOURCE %q: %v", sourceAddr, err)
+	}
+	defer source.Close()
+	target, err := grpc.NewClient(targetAddr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		log.Fatalf("Invalid MIRROR_TARGET %q: %v", targetAddr, err)
+	}
+	defer target.Close()
+
+	m := newMirror(source, target)
+	for _, prefix := range strings.Split(os.Getenv("MIRROR_PREFIXES"), ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			m.prefixes = append(m.prefixes, prefix)
+		}
+	}
+	if v := os.Getenv("MIRROR_CONSUMER"); v != "" {
+		m.consumer = v
+	}
+	if v := os.Getenv("MIRROR_CONFLICT_POLICY"); v != "" {
+		policy, ok := conflictPolicies[v]
+		if !ok {
+			log.Fatalf("Invalid MIRROR_CONFLICT_POLICY %q: must be source-wins, target-wins or newest-wins", v)
+		}
+		m.policy = policy
+	}
+	if v := os.Getenv("MIRROR_ACK_INTERVAL"); v != "" {
+		if m.ackInterval, err = time.ParseDuration(v); err != nil || m.ackInterval <= 0 {
+			log.Fatalf("Invalid MIRROR_ACK_INTERVAL: must be a positive duration")
+		}
+	}
+	port := os.Getenv("MIRROR_PORT")
+	if port == "" {
+		port = "8090"
+	}
+
+	if len(m.prefixes) == 0 {
+		log.Printf("Mirroring every key from %s to %s", sourceAddr, targetAddr)
+	} else {
+		log.Printf("Mirroring keys under %s from %s to %s", strings.Join(m.prefixes, ", "), sourceAddr, targetAddr)
+	}
+	go m.run(context.Background())
+
+	gin.SetMode(gin.ReleaseMode)
+	log.Printf("Mirror control API starting on :%s", port)
+	if err := newRouter(m).Run(":" + port); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+}
@@ -0,0 +1,592 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Defaults for the mirror
+const (
+	defaultConsumer    = "kvstore-mirror"
+	defaultAckInterval = time.Second
+
+	// retryInterval is how long the mirror waits before reconnecting after
+	// a failure
+	retryInterval = time.Second
+)
+
+// clientIDMetadataKey is the gRPC metadata key the mirror identifies its
+// writes to the target under
+const clientIDMetadataKey = "x-client-id"
+
+// conflictPolicy decides whether a change from the source overwrites a key
+// that was written on the target by someone other than the mirror
+type conflictPolicy int
+
+const (
+	// sourceWins always applies the source's change
+	sourceWins conflictPolicy = iota
+	// targetWins leaves keys written on the target alone
+	targetWins
+	// newestWins applies the change only if it was committed after the
+	// target's own write
+	newestWins
+)
+
+// conflictPolicies maps the configured names to policies
+var conflictPolicies = map[string]conflictPolicy{
+	"source-wins": sourceWins,
+	"target-wins": targetWins,
+	"newest-wins": newestWins,
+}
+
+func (p conflictPolicy) String() string {
+	for name, policy := range conflictPolicies {
+		if policy == p {
+			return name
+		}
+	}
+	return fmt.Sprintf("conflictPolicy(%d)", int(p))
+}
+
+// mirror continuously copies the changes to a set of key prefixes from a
+// source kvstore server to a target one. It follows the source's change
+// feed as a named consumer, so after a restart it resumes from the last
+// change it acknowledged; a consumer the source does not know yet starts
+// with a copy of the source's current contents.
+type mirror struct {
+	source, target string
+	feed           proto.ChangeFeedClient
+	sourceKV       proto.KeyValueStoreClient
+	targetKV       proto.KeyValueStoreClient
+
+	prefixes    []string // mirrored key prefixes; every key when empty
+	consumer    string   // consumer name on the source and client identity on the target
+	policy      conflictPolicy
+	ackInterval time.Duration
+
+	mu          sync.Mutex
+	paused      bool
+	resumed     chan struct{} // closed when the mirror is resumed
+	stopStream  context.CancelFunc
+	connected   bool
+	applied     uint64 // cursor of the last change processed
+	acked       uint64
+	latest      uint64 // latest cursor on the source, as last seen
+	behindSince time.Time
+	lastChange  time.Time // commit time of the last change processed
+	lastError   string
+	stats       mirrorStats
+}
+
+// mirrorStats counts what the mirror did with the changes it processed
+type mirrorStats struct {
+	Copied    int64 `json:"copied"`
+	Deleted   int64 `json:"deleted"`
+	Conflicts int64 `json:"conflicts"`
+	Rejected  int64 `json:"rejected"`
+	Snapshots int64 `json:"snapshots"`
+}
+
+// MirrorStatus is the JSON status of the mirror, with its lag behind the
+// source
+type MirrorStatus struct {
+	Source         string     `json:"source"`
+	Target         string     `json:"target"`
+	Prefixes       []string   `json:"prefixes"`
+	Consumer       string     `json:"consumer"`
+	ConflictPolicy string     `json:"conflict_policy"`
+	State          string     `json:"state"`
+	Cursor         uint64     `json:"cursor"`
+	AckedCursor    uint64     `json:"acked_cursor"`
+	LatestCursor   uint64     `json:"latest_cursor"`
+	LagChanges     uint64     `json:"lag_changes"`
+	LagSeconds     float64    `json:"lag_seconds"`
+	LastChange     *time.Time `json:"last_change,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	mirrorStats
+}
+
+// mirrored reports whether a key is under one of the mirrored prefixes
+func (m *mirror) mirrored(key string) bool {
+	if len(m.prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// context returns ctx carrying the mirror's identity
+func (m *mirror) context(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, clientIDMetadataKey, m.consumer)
+}
+
+// run mirrors changes until ctx is cancelled, reconnecting after every
+// failure and waiting while paused
+func (m *mirror) run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.runAcks(ctx)
+	}()
+	defer wg.Wait()
+
+	for {
+		streamCtx, ok := m.waitRunning(ctx)
+		if !ok {
+			return
+		}
+		err := m.follow(streamCtx)
+		m.flushAck(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if streamCtx.Err() != nil {
+			// Paused
+			continue
+		}
+		log.Printf("Mirroring from %s interrupted: %v", m.source, err)
+		m.disconnected(err)
+
+		select {
+		case <-time.After(retryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// waitRunning waits until the mirror is not paused and returns a context
+// for following the source that pausing cancels
+func (m *mirror) waitRunning(ctx context.Context) (context.Context, bool) {
+	for {
+		m.mu.Lock()
+		if !m.paused {
+			streamCtx, cancel := context.WithCancel(ctx)
+			m.stopStream = cancel
+			m.mu.Unlock()
+			return streamCtx, true
+		}
+		resumed := m.resumed
+		m.mu.Unlock()
+
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return nil, false
+		}
+	}
+}
+
+// pause stops mirroring after the change being applied, and acknowledges
+// what was mirrored so far. It returns false if the mirror was already
+// paused.
+func (m *mirror) pause() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.paused {
+		return false
+	}
+	m.paused = true
+	m.resumed = make(chan struct{})
+	if m.stopStream != nil {
+		m.stopStream()
+	}
+	return true
+}
+
+// resume continues mirroring from the last change processed. It returns
+// false if the mirror was not paused.
+func (m *mirror) resume() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.paused {
+		return false
+	}
+	m.paused = false
+	close(m.resumed)
+	return true
+}
+
+// follow copies the source's changes to the target until the stream fails
+// or ctx is cancelled. A consumer unknown to the source, or one whose
+// cursor is older than the changes the source still holds, starts over
+// from a copy of the source's contents.
+func (m *mirror) follow(ctx context.Context) error {
+	if m.policy != sourceWins {
+		// Conflicts are detected from the target's history
+		_, err := m.targetKV.History(ctx, &proto.HistoryRequest{Key: "", Limit: 1})
+		if status.Code(err) == codes.FailedPrecondition {
+			return fmt.Errorf("the %s conflict policy needs change history enabled on the target: %v", m.policy, err)
+		}
+	}
+
+	cursors, err := m.feed.ListCursors(ctx, &proto.ListCursorsRequest{})
+	if err != nil {
+		return err
+	}
+	m.observeLatest(cursors.Latest)
+	known := false
+	for _, c := range cursors.Consumers {
+		if c.Consumer == m.consumer {
+			known = true
+			m.mu.Lock()
+			m.applied, m.acked = c.Cursor, c.Cursor
+			m.mu.Unlock()
+		}
+	}
+	if !known {
+		if err := m.copySnapshot(ctx, cursors.Latest); err != nil {
+			return err
+		}
+	}
+
+	err = m.stream(ctx)
+	if status.Code(err) == codes.OutOfRange {
+		log.Printf("Changes after cursor %d are no longer held by %s, copying its contents again", m.cursor(), m.source)
+		if err := m.copySnapshot(ctx, m.latestCursor()); err != nil {
+			return err
+		}
+		err = m.stream(ctx)
+	}
+	return err
+}
+
+// copySnapshot copies the mirrored keys the source holds to the target and
+// acknowledges the changes up to latest, which must have been read before
+// the copy started; changes made during the copy are mirrored again.
+func (m *mirror) copySnapshot(ctx context.Context, latest uint64) error {
+	log.Printf("Copying the contents of %s at cursor %d", m.source, latest)
+
+	stream, err := m.sourceKV.Backup(ctx, &proto.BackupRequest{})
+	if err != nil {
+		return err
+	}
+	copied := 0
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for _, entry := range chunk.Entries {
+			if entry.Crdt != proto.CrdtType_CRDT_TYPE_NONE || !m.mirrored(entry.Key) {
+				continue
+			}
+			e := &proto.ChangeEvent{Op: proto.MutationOp_MUTATION_OP_SET, Key: entry.Key, Value: entry.Value}
+			if err := m.apply(ctx, e); err != nil {
+				return err
+			}
+			copied++
+		}
+	}
+
+	if _, err := m.feed.Ack(ctx, &proto.ChangeAckRequest{Consumer: m.consumer, Cursor: latest}); err != nil {
+		return fmt.Errorf("failed to acknowledge the copy: %w", err)
+	}
+	m.mu.Lock()
+	m.applied, m.acked = latest, latest
+	m.stats.Snapshots++
+	m.mu.Unlock()
+	log.Printf("Copied %d keys from %s", copied, m.source)
+	return nil
+}
+
+// stream subscribes to the source's changes after the consumer's
+// acknowledged cursor and applies them until the stream fails
+func (m *mirror) stream(ctx context.Context) error {
+	stream, err := m.feed.Subscribe(ctx, &proto.ChangeSubscribeRequest{Consumer: m.consumer})
+	if err != nil {
+		return err
+	}
+	log.Printf("Mirroring %s to %s after cursor %d", m.source, m.target, m.cursor())
+
+	for {
+		e, err := stream.Recv()
+		if err == io.EOF {
+			return fmt.Errorf("source closed the stream")
+		}
+		if err != nil {
+			return err
+		}
+		if err := m.apply(ctx, e); err != nil {
+			return err
+		}
+		m.processed(e)
+	}
+}
+
+// apply makes a change on the target, unless it is outside the mirrored
+// prefixes or loses to the target's own write. Changes the target refuses
+// are counted and skipped; other failures are returned, to be retried.
+func (m *mirror) apply(ctx context.Context, e *proto.ChangeEvent) error {
+	switch e.Op {
+	case proto.MutationOp_MUTATION_OP_SET, proto.MutationOp_MUTATION_OP_DELETE:
+		// CRDT keys are merged between sites by their own peers
+		if e.Crdt != proto.CrdtType_CRDT_TYPE_NONE || !m.mirrored(e.Key) {
+			return nil
+		}
+	case proto.MutationOp_MUTATION_OP_CLEAR:
+		return m.clear(ctx, e)
+	default:
+		// Schemas are configuration of each deployment
+		return nil
+	}
+
+	wins, err := m.wins(ctx, e.Key, e.Timestamp)
+	if err != nil {
+		return err
+	}
+	if !wins {
+		m.count(func(s *mirrorStats) { s.Conflicts++ })
+		return nil
+	}
+
+	ctx = m.context(ctx)
+	if e.Op == proto.MutationOp_MUTATION_OP_SET {
+		_, err = m.targetKV.Set(ctx, &proto.SetRequest{Key: e.Key, Value: e.Value})
+	} else {
+		// A key already missing from the target is as good as deleted
+		_, err = m.targetKV.Delete(ctx, &proto.DeleteRequest{Key: e.Key})
+		if status.Code(err) == codes.NotFound {
+			err = nil
+		}
+	}
+	if retryable(err) {
+		return err
+	}
+	if err != nil {
+		log.Printf("Target refused the change to key '%s' at cursor %d: %v", e.Key, e.Cursor, err)
+		m.count(func(s *mirrorStats) { s.Rejected++ })
+		return nil
+	}
+	if e.Op == proto.MutationOp_MUTATION_OP_SET {
+		m.count(func(s *mirrorStats) { s.Copied++ })
+	} else {
+		m.count(func(s *mirrorStats) { s.Deleted++ })
+	}
+	return nil
+}
+
+// clear mirrors the source being cleared, as when it was recovered to an
+// earlier point in time, by deleting the mirrored keys on the target. The
+// source records its remaining keys right after.
+func (m *mirror) clear(ctx context.Context, e *proto.ChangeEvent) error {
+	stream, err := m.targetKV.Backup(ctx, &proto.BackupRequest{})
+	if err != nil {
+		return err
+	}
+	var keys []string
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for _, entry := range chunk.Entries {
+			if entry.Crdt == proto.CrdtType_CRDT_TYPE_NONE && m.mirrored(entry.Key) {
+				keys = append(keys, entry.Key)
+			}
+		}
+	}
+
+	for _, key := range keys {
+		del := &proto.ChangeEvent{Cursor: e.Cursor, Timestamp: e.Timestamp, Op: proto.MutationOp_MUTATION_OP_DELETE, Key: key}
+		if err := m.apply(ctx, del); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wins reports whether a change committed on the source at timestamp may
+// overwrite key on the target under the conflict policy
+func (m *mirror) wins(ctx context.Context, key string, timestamp int64) (bool, error) {
+	if m.policy == sourceWins {
+		return true, nil
+	}
+
+	resp, err := m.targetKV.History(ctx, &proto.HistoryRequest{Key: key, Limit: 1})
+	if status.Code(err) == codes.NotFound {
+		// The target never wrote the key
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if len(resp.Entries) == 0 || resp.Entries[0].Client == m.consumer {
+		return true, nil
+	}
+	if m.policy == newestWins {
+		return resp.Entries[0].Timestamp < timestamp, nil
+	}
+	return false, nil
+}
+
+// retryable reports whether a failed write may succeed if tried again
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.OK, codes.InvalidArgument, codes.FailedPrecondition, codes.ResourceExhausted, codes.PermissionDenied, codes.DataLoss:
+		return false
+	}
+	return true
+}
+
+// processed records that a change was mirrored
+func (m *mirror) processed(e *proto.ChangeEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.connected = true
+	m.lastError = ""
+	m.applied = e.Cursor
+	m.lastChange = time.Unix(0, e.Timestamp)
+	m.observeLatestLocked(e.Cursor)
+}
+
+// observeLatest records the latest cursor seen on the source
+func (m *mirror) observeLatest(latest uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.connected = true
+	m.observeLatestLocked(latest)
+}
+
+// observeLatestLocked is observeLatest with m.mu held
+func (m *mirror) observeLatestLocked(latest uint64) {
+	m.latest = max(m.latest, latest)
+	switch {
+	case m.applied >= m.latest:
+		m.behindSince = time.Time{}
+	case m.behindSince.IsZero():
+		m.behindSince = time.Now()
+	}
+}
+
+// disconnected records why following the source stopped
+func (m *mirror) disconnected(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.connected = false
+	m.lastError = err.Error()
+}
+
+// count updates the statistics
+func (m *mirror) count(update func(*mirrorStats)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	update(&m.stats)
+}
+
+// cursor returns the cursor of the last change processed
+func (m *mirror) cursor() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.applied
+}
+
+// latestCursor returns the latest cursor seen on the source
+func (m *mirror) latestCursor() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.latest
+}
+
+// runAcks acknowledges the mirrored changes to the source and refreshes
+// the source's latest cursor every ack interval, until ctx is cancelled
+func (m *mirror) runAcks(ctx context.Context) {
+	ticker := time.NewTicker(m.ackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.flushAck(ctx)
+			if resp, err := m.feed.ListCursors(ctx, &proto.ListCursorsRequest{}); err == nil {
+				m.observeLatest(resp.Latest)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// flushAck acknowledges the changes processed since the last
+// acknowledgement. Changes processed but not acknowledged when the mirror
+// stops are mirrored again after it restarts.
+func (m *mirror) flushAck(ctx context.Context) {
+	m.mu.Lock()
+	applied, acked := m.applied, m.acked
+	m.mu.Unlock()
+	if applied <= acked {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if _, err := m.feed.Ack(ctx, &proto.ChangeAckRequest{Consumer: m.consumer, Cursor: applied}); err != nil {
+		log.Printf("Failed to acknowledge cursor %d to %s: %v", applied, m.source, err)
+		return
+	}
+	m.mu.Lock()
+	m.acked = max(m.acked, applied)
+	m.mu.Unlock()
+}
+
+// status reports the mirror's configuration, progress and lag
+func (m *mirror) status() MirrorStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := MirrorStatus{
+		Source:         m.source,
+		Target:         m.target,
+		Prefixes:       m.prefixes,
+		Consumer:       m.consumer,
+		ConflictPolicy: m.policy.String(),
+		State:          "running",
+		Cursor:         m.applied,
+		AckedCursor:    m.acked,
+		LatestCursor:   max(m.latest, m.applied),
+		LastError:      m.lastError,
+		mirrorStats:    m.stats,
+	}
+	switch {
+	case m.paused:
+		s.State = "paused"
+	case !m.connected:
+		s.State = "disconnected"
+	}
+	s.LagChanges = s.LatestCursor - s.Cursor
+	if !m.behindSince.IsZero() && s.LagChanges > 0 {
+		s.LagSeconds = time.Since(m.behindSince).Seconds()
+	}
+	if !m.lastChange.IsZero() {
+		lastChange := m.lastChange
+		s.LastChange = &lastChange
+	}
+	if s.Prefixes == nil {
+		s.Prefixes = []string{}
+	}
+	return s
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeSource serves a backup of its data and a change feed
+type fakeSource struct {
+	proto.UnimplementedKeyValueStoreServer
+	proto.UnimplementedChangeFeedServer
+
+	mu      sync.Mutex
+	data    map[string]string
+	events  []*proto.ChangeEvent
+	cursors map[string]uint64
+	notify  chan struct{}
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{data: make(map[string]string), cursors: make(map[string]uint64), notify: make(chan struct{})}
+}
+
+// change records a change to the source's data, committed at ts
+func (f *fakeSource) change(op proto.MutationOp, key, value string, ts time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if op == proto.MutationOp_MUTATION_OP_SET {
+		f.data[key] = value
+	} else {
+		delete(f.data, key)
+	}
+	f.events = append(f.events, &proto.ChangeEvent{
+		Cursor:    uint64(len(f.events) + 1),
+		Timestamp: ts.UnixNano(),
+		Op:        op,
+		Key:       key,
+		Value:     value,
+	})
+	close(f.notify)
+	f.notify = make(chan struct{})
+}
+
+func (f *fakeSource) acked(consumer string) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cursors[consumer]
+}
+
+func (f *fakeSource) Backup(req *proto.BackupRequest, stream grpc.ServerStreamingServer[proto.BackupChunk]) error {
+	f.mu.Lock()
+	chunk := &proto.BackupChunk{}
+	for key, value := range f.data {
+		chunk.Entries = append(chunk.Entries, &proto.BackupEntry{Key: key, Value: value})
+	}
+	f.mu.Unlock()
+	return stream.Send(chunk)
+}
+
+func (f *fakeSource) Subscribe(req *proto.ChangeSubscribeRequest, stream grpc.ServerStreamingServer[proto.ChangeEvent]) error {
+	from := f.acked(req.Consumer) + 1
+	for {
+		f.mu.Lock()
+		events, notify := f.events[min(from-1, uint64(len(f.events))):], f.notify
+		f.mu.Unlock()
+
+		for _, e := range events {
+			if err := stream.Send(e); err != nil {
+				return err
+			}
+			from = e.Cursor + 1
+		}
+		select {
+		case <-notify:
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (f *fakeSource) Ack(ctx context.Context, req *proto.ChangeAckRequest) (*proto.ChangeAckResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cursors[req.Consumer] = req.Cursor
+	return &proto.ChangeAckResponse{Success: true}, nil
+}
+
+func (f *fakeSource) ListCursors(ctx context.Context, req *proto.ListCursorsRequest) (*proto.ListCursorsResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	resp := &proto.ListCursorsResponse{Latest: uint64(len(f.events))}
+	for consumer, cursor := range f.cursors {
+		resp.Consumers = append(resp.Consumers, &proto.ConsumerCursor{Consumer: consumer, Cursor: cursor})
+	}
+	return resp, nil
+}
+
+// fakeTarget stores writes and the last writer of every key
+type fakeTarget struct {
+	proto.UnimplementedKeyValueStoreServer
+
+	mu        sync.Mutex
+	data      map[string]string
+	writers   map[string]*proto.HistoryEntry
+	noHistory bool
+}
+
+func newFakeTarget() *fakeTarget {
+	return &fakeTarget{data: make(map[string]string), writers: make(map[string]*proto.HistoryEntry)}
+}
+
+// write records a write by client at ts
+func (f *fakeTarget) write(key, value, client string, ts time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data[key] = value
+	f.writers[key] = &proto.HistoryEntry{Timestamp: ts.UnixNano(), Value: value, Client: client}
+}
+
+func (f *fakeTarget) get(key string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	value, ok := f.data[key]
+	return value, ok
+}
+
+func client(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ids := md.Get(clientIDMetadataKey); len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
+func (f *fakeTarget) Set(ctx context.Context, req *proto.SetRequest) (*proto.SetResponse, error) {
+	f.write(req.Key, req.Value, client(ctx), time.Now())
+	return &proto.SetResponse{Success: true}, nil
+}
+
+func (f *fakeTarget) Delete(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.data[req.Key]; !ok {
+		return nil, status.Errorf(codes.NotFound, "Key '%s' not found", req.Key)
+	}
+	delete(f.data, req.Key)
+	f.writers[req.Key] = &proto.HistoryEntry{Timestamp: time.Now().UnixNano(), Op: proto.MutationOp_MUTATION_OP_DELETE, Client: client(ctx)}
+	return &proto.DeleteResponse{Success: true}, nil
+}
+
+func (f *fakeTarget) History(ctx context.Context, req *proto.HistoryRequest) (*proto.HistoryResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.noHistory {
+		return nil, status.Error(codes.FailedPrecondition, "history is not enabled")
+	}
+	if entry, ok := f.writers[req.Key]; ok {
+		return &proto.HistoryResponse{Success: true, Entries: []*proto.HistoryEntry{entry}}, nil
+	}
+	return nil, status.Errorf(codes.NotFound, "No history for key '%s'", req.Key)
+}
+
+// dial serves the registered services on an in-memory listener and
+// returns a connection to it
+func dial(t *testing.T, register func(*grpc.Server)) *grpc.ClientConn {
+	t.Helper()
+
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	register(server)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial test server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// startMirror runs a mirror from source to target until the test ends
+func startMirror(t *testing.T, source *fakeSource, target *fakeTarget, configure func(*mirror)) *mirror {
+	t.Helper()
+
+	m := newMirror(
+		dial(t, func(s *grpc.Server) {
+			proto.RegisterKeyValueStoreServer(s, source)
+			proto.RegisterChangeFeedServer(s, source)
+		}),
+		dial(t, func(s *grpc.Server) { proto.RegisterKeyValueStoreServer(s, target) }),
+	)
+	m.ackInterval = 10 * time.Millisecond
+	if configure != nil {
+		configure(m)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return m
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMirror_CopiesAndFollowsPrefixes(t *testing.T) {
+	source, target := newFakeSource(), newFakeTarget()
+	now := time.Now()
+	source.change(proto.MutationOp_MUTATION_OP_SET, "app/a", "1", now)
+	source.change(proto.MutationOp_MUTATION_OP_SET, "other/x", "2", now)
+
+	m := startMirror(t, source, target, func(m *mirror) { m.prefixes = []string{"app/"} })
+
+	// A new consumer starts with a copy of the source
+	waitFor(t, "the copy", func() bool { return source.acked(defaultConsumer) == 2 })
+	if value, _ := target.get("app/a"); value != "1" {
+		t.Errorf("app/a = %q after the copy, expected 1", value)
+	}
+
+	source.change(proto.MutationOp_MUTATION_OP_SET, "app/b", "3", now)
+	source.change(proto.MutationOp_MUTATION_OP_DELETE, "app/a", "", now)
+	source.change(proto.MutationOp_MUTATION_OP_SET, "other/y", "4", now)
+	waitFor(t, "the changes to be acknowledged", func() bool { return source.acked(defaultConsumer) == 5 })
+
+	target.mu.Lock()
+	data := target.data
+	if len(data) != 1 || data["app/b"] != "3" {
+		t.Errorf("target data = %v, expected only app/b=3", data)
+	}
+	target.mu.Unlock()
+
+	s := m.status()
+	if s.State != "running" || s.Cursor != 5 || s.LagChanges != 0 || s.Copied != 2 || s.Deleted != 1 || s.Snapshots != 1 {
+		t.Errorf("status() = %+v", s)
+	}
+}
+
+func TestMirror_ConflictPolicies(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name      string
+		policy    conflictPolicy
+		committed time.Time // when the source's change was committed
+		want      string
+	}{
+		{"Source wins", sourceWins, now.Add(-time.Minute), "source"},
+		{"Target wins", targetWins, now.Add(time.Minute), "local"},
+		{"Newest wins, source newer", newestWins, now.Add(time.Minute), "source"},
+		{"Newest wins, target newer", newestWins, now.Add(-time.Minute), "local"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, target := newFakeSource(), newFakeTarget()
+			source.cursors[defaultConsumer] = 0
+			target.write("app/k", "local", "alice", now)
+			// Keys last written by the mirror itself are always updated
+			target.write("app/m", "old", defaultConsumer, now)
+
+			m := startMirror(t, source, target, func(m *mirror) { m.policy = tt.policy })
+			source.change(proto.MutationOp_MUTATION_OP_SET, "app/k", "source", tt.committed)
+			source.change(proto.MutationOp_MUTATION_OP_SET, "app/m", "new", tt.committed)
+			waitFor(t, "the changes", func() bool { return m.cursor() == 2 })
+
+			if value, _ := target.get("app/k"); value != tt.want {
+				t.Errorf("app/k = %q, expected %q", value, tt.want)
+			}
+			if value, _ := target.get("app/m"); value != "new" {
+				t.Errorf("app/m = %q, expected new", value)
+			}
+			if conflicts := m.status().Conflicts; (tt.want == "local") != (conflicts == 1) {
+				t.Errorf("Conflicts = %d", conflicts)
+			}
+		})
+	}
+}
+
+func TestMirror_ConflictPolicyNeedsHistory(t *testing.T) {
+	source, target := newFakeSource(), newFakeTarget()
+	target.noHistory = true
+
+	m := startMirror(t, source, target, func(m *mirror) { m.policy = targetWins })
+	waitFor(t, "the failure", func() bool { return m.status().LastError != "" })
+	if s := m.status(); s.State != "disconnected" || !strings.Contains(s.LastError, "history") {
+		t.Errorf("status() = %+v", s)
+	}
+}
+
+func TestMirror_PauseResume(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	source, target := newFakeSource(), newFakeTarget()
+	m := startMirror(t, source, target, nil)
+	router := newRouter(m)
+	waitFor(t, "the copy", func() bool { return m.status().Snapshots == 1 })
+
+	post := func(path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		return w.Code
+	}
+	statusOf := func() MirrorStatus {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+		var s MirrorStatus
+		if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil {
+			t.Fatalf("invalid status %s: %v", w.Body, err)
+		}
+		return s
+	}
+
+	if code := post("/pause"); code != http.StatusOK {
+		t.Fatalf("POST /pause = %d", code)
+	}
+	if code := post("/pause"); code != http.StatusConflict {
+		t.Errorf("second POST /pause = %d, expected 409", code)
+	}
+
+	// Nothing is mirrored while paused, and the lag shows it
+	source.change(proto.MutationOp_MUTATION_OP_SET, "k", "v", time.Now())
+	waitFor(t, "the lag", func() bool { return statusOf().LagChanges == 1 })
+	if _, ok := target.get("k"); ok {
+		t.Error("a change was mirrored while paused")
+	}
+	if s := statusOf(); s.State != "paused" {
+		t.Errorf("State = %q, expected paused", s.State)
+	}
+
+	if code := post("/resume"); code != http.StatusOK {
+		t.Fatalf("POST /resume = %d", code)
+	}
+	waitFor(t, "the change", func() bool {
+		_, ok := target.get("k")
+		return ok
+	})
+	if code := post("/resume"); code != http.StatusConflict {
+		t.Errorf("second POST /resume = %d, expected 409", code)
+	}
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Shape of the Merkle tree used for anti-entropy
+const (
+	// merkleFanout is the number of children of every inner node
+	merkleFanout = 16
+
+	// merkleDepth is the level of the leaves, the root being level 0
+	merkleDepth = 3
+
+	// merkleLeaves is the number of key ranges the tree is built from
+	merkleLeaves = merkleFanout * merkleFanout * merkleFanout
+)
+
+// antiEntropyCatchUp is how long a replica waits to apply the revision its
+// primary's records were read at before comparing them with its own
+const antiEntropyCatchUp = time.Second
+
+// antiEntropyClient is the client identity recorded on repairs
+const antiEntropyClient = "anti-entropy"
+
+// errReplicaMoved is returned when the replica is not at the revision the
+// primary's records were read at, so a difference may be a write that has
+// not reached one side yet rather than divergence
+var errReplicaMoved = errors.New("replica is not at the primary's revision")
+
+// merkleTree hashes a store's records by key range. Keys are spread over
+// the leaves by a hash of the key; a leaf combines the hashes of its
+// records and an inner node hashes its children. Stores holding the same
+// records have the same tree, and the ranges where they differ are found
+// by descending only into the nodes whose hashes differ.
+type merkleTree struct {
+	revision uint64
+	levels   [][]uint64 // levels[0] holds the root, levels[merkleDepth] the leaves
+}
+
+// merkleLeaf returns the leaf covering key
+func merkleLeaf(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % merkleLeaves)
+}
+
+// entryHash hashes a record together with its key. The stored value is
+// hashed rather than its checksum, so a corrupted value shows up too.
+func entryHash(key, value string) uint64 {
+	h := sha256.New()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(value))
+	return binary.LittleEndian.Uint64(h.Sum(nil))
+}
+
+// merkleTree hashes the store's records at its current revision. Records
+// are combined into their leaf by XOR, so the order they are visited in
+// does not matter.
+func (k *kvStore) merkleTree() *merkleTree {
+	leaves := make([]uint64, merkleLeaves)
+	k.mu.RLock()
+	for key, rec := range k.data {
+		leaves[merkleLeaf(key)] ^= entryHash(key, rec.value)
+	}
+	revision := k.revision
+	k.mu.RUnlock()
+
+	t := &merkleTree{revision: revision, levels: make([][]uint64, merkleDepth+1)}
+	t.levels[merkleDepth] = leaves
+	buf := make([]byte, 8*merkleFanout)
+	for level := merkleDepth - 1; level >= 0; level-- {
+		children := t.levels[level+1]
+		nodes := make([]uint64, len(children)/merkleFanout)
+		for i := range nodes {
+			for j, child := range children[i*merkleFanout : (i+1)*merkleFanout] {
+				binary.LittleEndian.PutUint64(buf[8*j:], child)
+			}
+			h := fnv.New64a()
+			h.Write(buf)
+			nodes[i] = h.Sum64()
+		}
+		t.levels[level] = nodes
+	}
+	return t
+}
+
+// roundTree returns the tree of the round that started at revision, or
+// hashes a fresh one for a new round. Only the latest round's tree is
+// kept; a replica whose tree was replaced by another round's gets the
+// fresh tree, whose revision tells it to start over.
+func (r *replicationServer) roundTree(revision uint64) *merkleTree {
+	r.treeMu.Lock()
+	defer r.treeMu.Unlock()
+
+	if revision == 0 || r.tree == nil || r.tree.revision != revision {
+		r.tree = r.store.merkleTree()
+	}
+	return r.tree
+}
+
+// MerkleTree returns the hashes of the requested nodes of this server's
+// Merkle tree
+func (r *replicationServer) MerkleTree(ctx context.Context, req *proto.MerkleTreeRequest) (*proto.MerkleTreeResponse, error) {
+	if req.Level > merkleDepth {
+		return nil, status.Errorf(codes.InvalidArgument, "level must be at most %d", merkleDepth)
+	}
+
+	tree := r.roundTree(req.Revision)
+	nodes := tree.levels[req.Level]
+	resp := &proto.MerkleTreeResponse{Revision: tree.revision, Hashes: make([]uint64, len(req.Nodes))}
+	for i, node := range req.Nodes {
+		if int(node) >= len(nodes) {
+			return nil, status.Errorf(codes.InvalidArgument, "level %d has no node %d", req.Level, node)
+		}
+		resp.Hashes[i] = nodes[node]
+	}
+	return resp, nil
+}
+
+// MerkleLeaves returns the records under the requested leaves of this
+// server's Merkle tree, in key order
+func (r *replicationServer) MerkleLeaves(ctx context.Context, req *proto.MerkleLeavesRequest) (*proto.MerkleLeavesResponse, error) {
+	leaves := make(map[int]bool, len(req.Leaves))
+	for _, leaf := range req.Leaves {
+		if leaf >= merkleLeaves {
+			return nil, status.Errorf(codes.InvalidArgument, "leaf must be less than %d", merkleLeaves)
+		}
+		leaves[int(leaf)] = true
+	}
+
+	k := r.store
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	resp := &proto.MerkleLeavesResponse{Revision: k.revision}
+	for key, rec := range k.data {
+		if leaves[merkleLeaf(key)] {
+			resp.Entries = append(resp.Entries, &proto.BackupEntry{Key: key, Value: rec.value, Checksum: rec.checksum, Version: rec.version.proto(), Crdt: rec.crdt})
+		}
+	}
+	sort.Slice(resp.Entries, func(i, j int) bool { return resp.Entries[i].Key < resp.Entries[j].Key })
+	return resp, nil
+}
+
+// runAntiEntropy compares the replica with its primary every interval and
+// repairs the keys that differ, until ctx is cancelled
+func (k *kvStore) runAntiEntropy(ctx context.Context, client proto.ReplicationClient, interval time.Duration) {
+	rs := k.replica
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		repaired, err := k.antiEntropyRound(ctx, client)
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, errReplicaMoved) {
+			continue // compared again next round
+		}
+		if err != nil {
+			log.Printf("Anti-entropy against %s failed: %v", rs.primary, err)
+		} else if repaired > 0 {
+			log.Printf("Anti-entropy repaired %d keys that differed from %s", repaired, rs.primary)
+		}
+		rs.antiEntropyDone(repaired, err)
+	}
+}
+
+// antiEntropyRound descends the primary's Merkle tree from the root,
+// following only the nodes whose hashes differ from the replica's, then
+// repairs the keys under the leaves that differ. Each side hashes its tree
+// once, both at the revision the primary's root was read at. It returns
+// the number of keys repaired.
+func (k *kvStore) antiEntropyRound(ctx context.Context, client proto.ReplicationClient) (int, error) {
+	var tree *merkleTree
+	var revision uint64
+	nodes := []uint32{0}
+	for level := uint32(0); ; level++ {
+		remote, err := client.MerkleTree(ctx, &proto.MerkleTreeRequest{Level: level, Nodes: nodes, Revision: revision})
+		if err != nil {
+			return 0, err
+		}
+		if len(remote.Hashes) != len(nodes) {
+			return 0, fmt.Errorf("primary returned %d hashes for %d nodes", len(remote.Hashes), len(nodes))
+		}
+		if tree == nil {
+			revision = remote.Revision
+			if err := k.waitForRevision(ctx, revision); err != nil {
+				return 0, err
+			}
+			if tree = k.merkleTree(); tree.revision != revision {
+				return 0, errReplicaMoved
+			}
+		} else if remote.Revision != revision {
+			return 0, errReplicaMoved
+		}
+
+		local := tree.levels[level]
+		var differing []uint32
+		for i, node := range nodes {
+			if remote.Hashes[i] != local[node] {
+				differing = append(differing, node)
+			}
+		}
+		if len(differing) == 0 {
+			return 0, nil
+		}
+		if level == merkleDepth {
+			nodes = differing
+			break
+		}
+
+		nodes = make([]uint32, 0, len(differing)*merkleFanout)
+		for _, node := range differing {
+			for child := uint32(0); child < merkleFanout; child++ {
+				nodes = append(nodes, node*merkleFanout+child)
+			}
+		}
+	}
+
+	remote, err := client.MerkleLeaves(ctx, &proto.MerkleLeavesRequest{Leaves: nodes})
+	if err != nil {
+		return 0, err
+	}
+	return k.repairLeaves(ctx, remote.Revision, nodes, remote.Entries)
+}
+
+// waitForRevision gives the replica, which is usually just behind its
+// primary, a moment to catch up to revision. It does not report whether it
+// did; the caller checks the revision under the lock it needs anyway.
+func (k *kvStore) waitForRevision(ctx context.Context, revision uint64) error {
+	deadline := time.Now().Add(antiEntropyCatchUp)
+	for k.currentRevision() < revision && time.Now().Before(deadline) {
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// repairMutation builds the mutation that restores the primary's entry
+func repairMutation(entry *proto.BackupEntry) *proto.Mutation {
+	m := setMutation(entry.Key, entry.Value)
+	m.Version = entry.Version
+	m.Crdt = entry.Crdt
+	return m
+}
+
+// repairLeaves makes the records under leaves match the primary's entries,
+// which were read at revision. The repairs are applied at the replica's
+// current revision, which must be that same revision.
+func (k *kvStore) repairLeaves(ctx context.Context, revision uint64, leaves []uint32, entries []*proto.BackupEntry) (int, error) {
+	want := make(map[string]*proto.BackupEntry, len(entries))
+	for _, entry := range entries {
+		if entry.Checksum != checksumOf(entry.Key, entry.Value) {
+			return 0, fmt.Errorf("primary's record for key '%s' failed checksum verification", entry.Key)
+		}
+		want[entry.Key] = entry
+	}
+	inLeaves := make(map[int]bool, len(leaves))
+	for _, leaf := range leaves {
+		inLeaves[int(leaf)] = true
+	}
+
+	if err := k.waitForRevision(ctx, revision); err != nil {
+		return 0, err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.revision != revision {
+		return 0, errReplicaMoved
+	}
+
+	var repairs []*proto.Mutation
+	for key, rec := range k.data {
+		if !inLeaves[merkleLeaf(key)] {
+			continue
+		}
+		entry, ok := want[key]
+		switch {
+		case !ok:
+			repairs = append(repairs, &proto.Mutation{Op: proto.MutationOp_MUTATION_OP_DELETE, Key: key})
+		case rec.value != entry.Value || rec.checksum != entry.Checksum || rec.crdt != entry.Crdt:
+			repairs = append(repairs, repairMutation(entry))
+		}
+	}
+	for key, entry := range want {
+		if _, ok := k.data[key]; !ok {
+			repairs = append(repairs, repairMutation(entry))
+		}
+	}
+	sort.Slice(repairs, func(i, j int) bool { return repairs[i].Key < repairs[j].Key })
+
+	now := time.Now().UnixNano()
+	for _, m := range repairs {
+		m.Revision = k.revision
+		m.Timestamp = now
+		m.Client = antiEntropyClient
+	}
+	if err := k.applyLocally(repairs); err != nil {
+		return 0, err
+	}
+	return len(repairs), nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMerkleTree(t *testing.T) {
+	ctx := context.Background()
+	a, b := NewKVStore(), NewKVStore()
+	for i := 0; i < 100; i++ {
+		a.Set(ctx, &proto.SetRequest{Key: fmt.Sprintf("key-%d", i), Value: "v"})
+	}
+	for i := 99; i >= 0; i-- {
+		b.Set(ctx, &proto.SetRequest{Key: fmt.Sprintf("key-%d", i), Value: "v"})
+	}
+
+	// The same records give the same tree, whatever order they were written in
+	if a.merkleTree().levels[0][0] != b.merkleTree().levels[0][0] {
+		t.Fatal("stores with the same records have different roots")
+	}
+
+	// A changed value changes one leaf and the nodes above it
+	b.Set(ctx, &proto.SetRequest{Key: "key-42", Value: "w"})
+	ta, tb := a.merkleTree(), b.merkleTree()
+	leaf := merkleLeaf("key-42")
+	for level := merkleDepth; level >= 0; level-- {
+		differing := 0
+		for i := range ta.levels[level] {
+			if ta.levels[level][i] != tb.levels[level][i] {
+				differing++
+			}
+		}
+		if differing != 1 || ta.levels[level][leaf] == tb.levels[level][leaf] {
+			t.Errorf("level %d: %d nodes differ, expected only node %d", level, differing, leaf)
+		}
+		leaf /= merkleFanout
+	}
+}
+
+func TestAntiEntropy_RepairsDivergence(t *testing.T) {
+	ctx := context.Background()
+	primary, replica := NewKVStore(), NewKVStore()
+	replica.antiEntropyInterval = 20 * time.Millisecond
+	startTestReplica(t, replica, startTestPrimary(t, primary))
+
+	for i := 0; i < 200; i++ {
+		primary.Set(ctx, &proto.SetRequest{Key: fmt.Sprintf("key-%d", i), Value: fmt.Sprintf("v%d", i)})
+	}
+	waitFor(t, "the replica to catch up", func() bool { return caughtUp(primary, replica) })
+
+	// Diverge the replica behind replication's back
+	replica.mu.Lock()
+	replica.data["key-1"] = record{value: "corrupted", checksum: replica.data["key-1"].checksum}
+	delete(replica.data, "key-2")
+	replica.data["ghost"] = record{value: "x", checksum: checksumOf("ghost", "x")}
+	replica.mu.Unlock()
+
+	srv := &replicationServer{store: replica}
+	waitFor(t, "anti-entropy to repair the replica", func() bool {
+		resp, _ := srv.ReplicationStatus(ctx, &proto.ReplicationStatusRequest{})
+		return resp.KeysRepaired == 3
+	})
+
+	if primary.merkleTree().levels[0][0] != replica.merkleTree().levels[0][0] {
+		t.Error("replica still differs from the primary")
+	}
+	if resp, _ := replica.Get(ctx, &proto.GetRequest{Key: "key-1"}); resp == nil || resp.Value != "v1" {
+		t.Errorf("replica Get(key-1) = %v", resp)
+	}
+	if _, err := replica.Get(ctx, &proto.GetRequest{Key: "ghost"}); status.Code(err) != codes.NotFound {
+		t.Error("replica kept a key the primary does not have")
+	}
+	if replica.currentRevision() != primary.currentRevision() {
+		t.Errorf("repairs moved the replica to revision %d, primary is at %d", replica.currentRevision(), primary.currentRevision())
+	}
+
+	resp, _ := srv.ReplicationStatus(ctx, &proto.ReplicationStatusRequest{})
+	if resp.AntiEntropyRounds == 0 || resp.LastAntiEntropy == 0 || resp.AntiEntropyError != "" {
+		t.Errorf("ReplicationStatus() = %v", resp)
+	}
+}
+
+func TestMerkleTree_OneTreePerRound(t *testing.T) {
+	ctx := context.Background()
+	store := NewKVStore()
+	srv := &replicationServer{store: store}
+	store.Set(ctx, &proto.SetRequest{Key: "key", Value: "v1"})
+
+	root, err := srv.MerkleTree(ctx, &proto.MerkleTreeRequest{Nodes: []uint32{0}})
+	if err != nil {
+		t.Fatalf("MerkleTree() error = %v", err)
+	}
+	store.Set(ctx, &proto.SetRequest{Key: "key", Value: "v2"})
+
+	// The rest of the round reads the tree the root came from
+	leaf := uint32(merkleLeaf("key"))
+	resp, err := srv.MerkleTree(ctx, &proto.MerkleTreeRequest{Level: merkleDepth, Nodes: []uint32{leaf}, Revision: root.Revision})
+	if err != nil {
+		t.Fatalf("MerkleTree() error = %v", err)
+	}
+	if resp.Revision != root.Revision || resp.Hashes[0] != entryHash("key", "v1") {
+		t.Errorf("MerkleTree() within a round = %v, expected the tree at revision %d", resp, root.Revision)
+	}
+
+	// A new round starts from the current records
+	resp, _ = srv.MerkleTree(ctx, &proto.MerkleTreeRequest{Level: merkleDepth, Nodes: []uint32{leaf}})
+	if resp.Revision != store.currentRevision() || resp.Hashes[0] != entryHash("key", "v2") {
+		t.Errorf("MerkleTree() for a new round = %v, expected the tree at revision %d", resp, store.currentRevision())
+	}
+}
+
+func TestMerkleTree_InvalidRequest(t *testing.T) {
+	srv := &replicationServer{store: NewKVStore()}
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"Level too deep", func() error {
+			_, err := srv.MerkleTree(context.Background(), &proto.MerkleTreeRequest{Level: merkleDepth + 1})
+			return err
+		}},
+		{"Node out of range", func() error {
+			_, err := srv.MerkleTree(context.Background(), &proto.MerkleTreeRequest{Level: 1, Nodes: []uint32{merkleFanout}})
+			return err
+		}},
+		{"Leaf out of range", func() error {
+			_, err := srv.MerkleLeaves(context.Background(), &proto.MerkleLeavesRequest{Leaves: []uint32{merkleLeaves}})
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
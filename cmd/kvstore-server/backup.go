@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// backupBatchSize is the number of records sent in each backup chunk
+const backupBatchSize = 100
+
+// snapshot returns a point-in-time copy of every record in the store
+func (k *kvStore) snapshot() map[string]record {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.copyData()
+}
+
+// copyData returns a copy of every record in the store. The caller must
+// hold k.mu.
+func (k *kvStore) copyData() map[string]record {
+	snap := make(map[string]record, len(k.data))
+	for key, rec := range k.data {
+		snap[key] = rec
+	}
+	return snap
+}
+
+// Backup streams a consistent snapshot of the store in key order. The
+// registered schemas are sent with the first chunk.
+func (k *kvStore) Backup(req *proto.BackupRequest, stream grpc.ServerStreamingServer[proto.BackupChunk]) error {
+	k.mu.RLock()
+	snap := k.copyData()
+	schemas := k.schemaList()
+	k.mu.RUnlock()
+
+	keys := make([]string, 0, len(snap))
+	for key := range snap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	chunk := &proto.BackupChunk{Schemas: schemas}
+	for _, key := range keys {
+		rec := snap[key]
+		// Refuse to carry corruption into a backup
+		if !rec.verify(key) {
+			return status.Errorf(codes.DataLoss, "key '%s' failed checksum verification", key)
+		}
+
+		chunk.Entries = append(chunk.Entries, &proto.BackupEntry{
+			Key:      key,
+			Value:    rec.value,
+			Checksum: rec.checksum,
+			Version:  rec.version.proto(),
+			Crdt:     rec.crdt,
+		})
+		if len(chunk.Entries) == backupBatchSize {
+			if err := stream.Send(chunk); err != nil {
+				return err
+			}
+			chunk = &proto.BackupChunk{}
+		}
+	}
+	if len(chunk.Entries) > 0 || len(chunk.Schemas) > 0 {
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Backup streamed %d keys and %d schemas", len(keys), len(schemas))
+	return nil
+}
+
+// Restore loads a snapshot streamed by the client. Nothing is applied until
+// the whole stream has been received, every entry has passed checksum
+// verification and every value matches the schemas it will be stored under,
+// so a failed restore leaves the store untouched. A replace restores the
+// snapshot's schemas in place of the registered ones; a merge registers
+// them over those for the same prefixes.
+func (k *kvStore) Restore(stream grpc.ClientStreamingServer[proto.RestoreRequest, proto.RestoreResponse]) error {
+	var staged []*proto.Mutation
+	schemas := make(map[string]*keySchema)
+	mode := proto.RestoreMode_RESTORE_MODE_REPLACE
+	received := false
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if !received {
+			mode = req.Mode
+			received = true
+			if mode != proto.RestoreMode_RESTORE_MODE_REPLACE && mode != proto.RestoreMode_RESTORE_MODE_MERGE {
+				return status.Errorf(codes.InvalidArgument, "unknown restore mode %v", mode)
+			}
+		}
+
+		for _, s := range req.Schemas {
+			compiled, err := compileSchema(s.Schema)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "invalid schema for prefix '%s' in backup: %v", s.Prefix, err)
+			}
+			schemas[s.Prefix] = &keySchema{source: compactJSON(s.Schema), compiled: compiled}
+		}
+		for _, entry := range req.Entries {
+			rec := record{value: entry.Value, checksum: entry.Checksum}
+			if !rec.verify(entry.Key) {
+				return status.Errorf(codes.DataLoss, "backup entry for key '%s' failed checksum verification", entry.Key)
+			}
+			m := setMutation(entry.Key, entry.Value)
+			m.Version = entry.Version
+			m.Crdt = entry.Crdt
+			staged = append(staged, m)
+		}
+	}
+
+	if !received {
+		return status.Error(codes.InvalidArgument, "restore stream was empty")
+	}
+
+	k.lockWrite()
+	err := k.commitRestore(stream.Context(), mode, schemas, staged)
+	k.unlockWrite()
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Restored %d keys", len(staged))
+	if len(schemas) > 0 {
+		message = fmt.Sprintf("Restored %d keys and %d schemas", len(staged), len(schemas))
+	}
+	log.Printf("Restore loaded %d keys and %d schemas (%v)", len(staged), len(schemas), mode)
+	return stream.SendAndClose(&proto.RestoreResponse{
+		Success:      true,
+		KeysRestored: int64(len(staged)),
+		Message:      message,
+	})
+}
+
+// commitRestore checks the restored records against the schemas they will
+// be stored under and commits them with the schemas. A replace is recorded
+// as a clear and the removal of every registered schema, followed by the
+// restored schemas and records. The caller must hold the store locked with
+// lockWrite.
+func (k *kvStore) commitRestore(ctx context.Context, mode proto.RestoreMode, schemas map[string]*keySchema, records []*proto.Mutation) error {
+	effective := schemas
+	if mode == proto.RestoreMode_RESTORE_MODE_MERGE {
+		effective = make(map[string]*keySchema, len(k.schemas)+len(schemas))
+		for prefix, s := range k.schemas {
+			effective[prefix] = s
+		}
+		for prefix, s := range schemas {
+			effective[prefix] = s
+		}
+	}
+	for _, m := range records {
+		// A CRDT's value is its encoded state, which schemas do not apply to
+		if m.Crdt != proto.CrdtType_CRDT_TYPE_NONE {
+			continue
+		}
+		if err := checkSchemaIn(effective, m.Key, m.Value); err != nil {
+			return err
+		}
+	}
+
+	var mutations []*proto.Mutation
+	if mode == proto.RestoreMode_RESTORE_MODE_REPLACE {
+		mutations = append(mutations, &proto.Mutation{Op: proto.MutationOp_MUTATION_OP_CLEAR})
+		for _, s := range k.schemaList() {
+			mutations = append(mutations, &proto.Mutation{Op: proto.MutationOp_MUTATION_OP_DELETE_SCHEMA, Key: s.Prefix})
+		}
+	}
+	prefixes := make([]string, 0, len(schemas))
+	for prefix := range schemas {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		mutations = append(mutations, &proto.Mutation{Op: proto.MutationOp_MUTATION_OP_SET_SCHEMA, Key: prefix, Value: schemas[prefix].source})
+	}
+	return k.commit(ctx, append(mutations, records...)...)
+}
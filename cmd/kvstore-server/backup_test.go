@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// testServer is a store served over an in-memory connection, with a
+// client connected to it
+type testServer struct {
+	proto.KeyValueStoreClient
+	server *grpc.Server
+	conn   *grpc.ClientConn
+	lis    *bufconn.Listener
+}
+
+// testServerConfig is what the options of startTestServer set
+type testServerConfig struct {
+	serverOptions []grpc.ServerOption
+	services      []func(*grpc.Server)
+	clientCreds   credentials.TransportCredentials
+}
+
+// testServerOption configures the server started by startTestServer
+type testServerOption func(*testServerConfig)
+
+// withServerOptions creates the server with the given options, such as
+// interceptors or credentials
+func withServerOptions(opts ...grpc.ServerOption) testServerOption {
+	return func(c *testServerConfig) {
+		c.serverOptions = append(c.serverOptions, opts...)
+	}
+}
+
+// withService registers more services on the server before it starts
+func withService(register func(*grpc.Server)) testServerOption {
+	return func(c *testServerConfig) {
+		c.services = append(c.services, register)
+	}
+}
+
+// withClientCreds connects the client with the given credentials rather
+// than none
+func withClientCreds(creds credentials.TransportCredentials) testServerOption {
+	return func(c *testServerConfig) {
+		c.clientCreds = creds
+	}
+}
+
+// startTestServer serves the given store over an in-memory connection and
+// returns a client for it
+func startTestServer(t *testing.T, store *kvStore, opts ...testServerOption) *testServer {
+	t.Helper()
+
+	config := testServerConfig{clientCreds: insecure.NewCredentials()}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	lis := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer(config.serverOptions...)
+	proto.RegisterKeyValueStoreServer(grpcServer, store)
+	for _, register := range config.services {
+		register(grpcServer)
+	}
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	ts := &testServer{server: grpcServer, lis: lis}
+	ts.conn = ts.dial(t, config.clientCreds)
+	ts.KeyValueStoreClient = proto.NewKeyValueStoreClient(ts.conn)
+	return ts
+}
+
+// dial opens another connection to the server with the given credentials
+func (ts *testServer) dial(t *testing.T, creds credentials.TransportCredentials) *grpc.ClientConn {
+	t.Helper()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ts.lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(creds),
+	)
+	if err != nil {
+		t.Fatalf("Failed to dial test server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// collectBackup reads a whole backup stream into a slice
+func collectBackup(t *testing.T, client proto.KeyValueStoreClient) []*proto.BackupEntry {
+	t.Helper()
+
+	stream, err := client.Backup(context.Background(), &proto.BackupRequest{})
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+
+	var entries []*proto.BackupEntry
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("Backup() stream error = %v", err)
+		}
+		entries = append(entries, chunk.Entries...)
+	}
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	source := NewKVStore()
+	for i := 0; i < 250; i++ {
+		key := "key-" + string(rune('a'+i%26)) + string(rune('a'+i/26))
+		source.Set(ctx, &proto.SetRequest{Key: key, Value: "value-" + key})
+	}
+
+	entries := collectBackup(t, startTestServer(t, source))
+	if len(entries) != 250 {
+		t.Fatalf("Backup() returned %d entries, expected 250", len(entries))
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i-1].Key >= entries[i].Key {
+			t.Fatalf("Backup() entries not in key order at %d", i)
+		}
+	}
+
+	target := NewKVStore()
+	target.Set(ctx, &proto.SetRequest{Key: "stale-key", Value: "stale-value"})
+	client := startTestServer(t, target)
+
+	stream, err := client.Restore(ctx)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	for start := 0; start < len(entries); start += backupBatchSize {
+		end := min(start+backupBatchSize, len(entries))
+		if err := stream.Send(&proto.RestoreRequest{Entries: entries[start:end]}); err != nil {
+			t.Fatalf("Restore() send error = %v", err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if !resp.Success || resp.KeysRestored != 250 {
+		t.Errorf("Restore() = %v, expected 250 keys restored", resp)
+	}
+
+	if _, exists := target.data["stale-key"]; exists {
+		t.Errorf("replace restore kept a key that was not in the backup")
+	}
+	for _, entry := range entries {
+		got, err := target.Get(ctx, &proto.GetRequest{Key: entry.Key})
+		if err != nil || !got.Success || got.Value != entry.Value {
+			t.Errorf("Get(%s) after restore = %v, %v", entry.Key, got, err)
+		}
+	}
+}
+
+func TestRestore_Merge(t *testing.T) {
+	ctx := context.Background()
+	store := NewKVStore()
+	store.Set(ctx, &proto.SetRequest{Key: "kept", Value: "old"})
+	store.Set(ctx, &proto.SetRequest{Key: "overwritten", Value: "old"})
+	client := startTestServer(t, store)
+
+	stream, err := client.Restore(ctx)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	stream.Send(&proto.RestoreRequest{
+		Mode: proto.RestoreMode_RESTORE_MODE_MERGE,
+		Entries: []*proto.BackupEntry{
+			{Key: "overwritten", Value: "new", Checksum: checksumOf("overwritten", "new")},
+			{Key: "added", Value: "new", Checksum: checksumOf("added", "new")},
+		},
+	})
+	if _, err := stream.CloseAndRecv(); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	expected := map[string]string{"kept": "old", "overwritten": "new", "added": "new"}
+	for key, value := range expected {
+		if got := store.data[key].value; got != value {
+			t.Errorf("after merge %s = %q, expected %q", key, got, value)
+		}
+	}
+}
+
+func TestRestore_RejectsCorruptEntry(t *testing.T) {
+	ctx := context.Background()
+	store := NewKVStore()
+	store.Set(ctx, &proto.SetRequest{Key: "existing", Value: "value"})
+	client := startTestServer(t, store)
+
+	stream, err := client.Restore(ctx)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	stream.Send(&proto.RestoreRequest{
+		Entries: []*proto.BackupEntry{
+			{Key: "good", Value: "value", Checksum: checksumOf("good", "value")},
+			{Key: "bad", Value: "value", Checksum: 12345},
+		},
+	})
+	_, err = stream.CloseAndRecv()
+	if status.Code(err) != codes.DataLoss {
+		t.Fatalf("Restore() error = %v, expected DataLoss", err)
+	}
+
+	// A failed restore must leave the store untouched
+	if len(store.data) != 1 || store.data["existing"].value != "value" {
+		t.Errorf("store modified by failed restore: %v", store.data)
+	}
+}
+
+func TestRestore_RejectsEmptyStream(t *testing.T) {
+	store := NewKVStore()
+	store.Set(context.Background(), &proto.SetRequest{Key: "existing", Value: "value"})
+	client := startTestServer(t, store)
+
+	stream, err := client.Restore(context.Background())
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	_, err = stream.CloseAndRecv()
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Restore() error = %v, expected InvalidArgument", err)
+	}
+	if len(store.data) != 1 {
+		t.Errorf("empty restore stream wiped the store")
+	}
+}
+
+func TestBackupAndRestore_Schemas(t *testing.T) {
+	ctx := context.Background()
+	portSchema := `{"type":"object","required":["port"]}`
+
+	source := NewKVStore()
+	source.RegisterSchema(ctx, &proto.RegisterSchemaRequest{Prefix: "config/", Schema: portSchema})
+	source.Set(ctx, &proto.SetRequest{Key: "config/db", Value: `{"port":5432}`})
+
+	stream, err := startTestServer(t, source).Backup(ctx, &proto.BackupRequest{})
+	if err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Backup() stream error = %v", err)
+	}
+	if len(first.Schemas) != 1 || first.Schemas[0].Prefix != "config/" {
+		t.Fatalf("first backup chunk carries schemas %v", first.Schemas)
+	}
+
+	tests := []struct {
+		name     string
+		mode     proto.RestoreMode
+		expected []string
+	}{
+		{"Replace", proto.RestoreMode_RESTORE_MODE_REPLACE, []string{"config/"}},
+		{"Merge", proto.RestoreMode_RESTORE_MODE_MERGE, []string{"config/", "other/"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := NewKVStore()
+			target.RegisterSchema(ctx, &proto.RegisterSchemaRequest{Prefix: "other/", Schema: `{"type":"string"}`})
+
+			restore, err := startTestServer(t, target).Restore(ctx)
+			if err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+			restore.Send(&proto.RestoreRequest{Mode: tt.mode, Entries: first.Entries, Schemas: first.Schemas})
+			if _, err := restore.CloseAndRecv(); err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+
+			var prefixes []string
+			for _, s := range target.schemaList() {
+				prefixes = append(prefixes, s.Prefix)
+			}
+			if !reflect.DeepEqual(prefixes, tt.expected) {
+				t.Errorf("schemas after restore = %v, expected %v", prefixes, tt.expected)
+			}
+			if target.data["config/db"].value != `{"port":5432}` {
+				t.Errorf("record not restored: %v", target.data)
+			}
+		})
+	}
+}
+
+func TestRestore_RejectsSchemaViolation(t *testing.T) {
+	ctx := context.Background()
+	store := NewKVStore()
+	store.RegisterSchema(ctx, &proto.RegisterSchemaRequest{Prefix: "config/", Schema: `{"type":"object"}`})
+	store.Set(ctx, &proto.SetRequest{Key: "existing", Value: "value"})
+	client := startTestServer(t, store)
+
+	tests := []struct {
+		name    string
+		request *proto.RestoreRequest
+	}{
+		{
+			name: "Registered schema",
+			request: &proto.RestoreRequest{
+				Mode:    proto.RestoreMode_RESTORE_MODE_MERGE,
+				Entries: []*proto.BackupEntry{{Key: "config/db", Value: "not json", Checksum: checksumOf("config/db", "not json")}},
+			},
+		},
+		{
+			name: "Schema restored with the record",
+			request: &proto.RestoreRequest{
+				Entries: []*proto.BackupEntry{{Key: "app/name", Value: "42", Checksum: checksumOf("app/name", "42")}},
+				Schemas: []*proto.KeySchema{{Prefix: "app/", Schema: `{"type":"string"}`}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.Restore(ctx)
+			if err != nil {
+				t.Fatalf("Restore() error = %v", err)
+			}
+			stream.Send(tt.request)
+			if _, err := stream.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+				t.Fatalf("Restore() error = %v, expected InvalidArgument", err)
+			}
+			if len(store.data) != 1 || len(store.schemas) != 1 {
+				t.Errorf("store modified by failed restore: %v, %d schemas", store.data, len(store.schemas))
+			}
+		})
+	}
+}
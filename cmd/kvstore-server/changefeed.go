@@ -0,0 +1,530 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// Files of the change feed inside the data directory
+const (
+	changeLogFile = "changes.log"
+	cursorsFile   = "cursors.json"
+)
+
+// Defaults for the change feed
+const (
+	// defaultChangeBacklog is how many recent changes are kept in memory.
+	// Older ones are read back from the change log, or are gone without a
+	// data directory.
+	defaultChangeBacklog = 10000
+
+	// changeIndexInterval is how many changes apart the change log offsets
+	// kept to find old changes are
+	changeIndexInterval = 1024
+
+	// changeRetryInterval is how often changes held back by a failed write
+	// are tried again when no new change comes to try them
+	changeRetryInterval = time.Second
+)
+
+// errEnoughChanges stops reading the change log once a batch is full
+var errEnoughChanges = errors.New("enough changes read")
+
+// historyPosition orders the mutations applied to a store: by revision, then
+// by order within the revision, as a snapshot or a repair applies several
+// mutations at the same revision
+type historyPosition struct {
+	revision uint64
+	n        int
+}
+
+// next returns the position of a mutation applied at revision after the
+// one at p
+func (p historyPosition) next(revision uint64) historyPosition {
+	if revision == p.revision {
+		return historyPosition{revision: revision, n: p.n + 1}
+	}
+	return historyPosition{revision: revision}
+}
+
+// after reports whether p comes after q
+func (p historyPosition) after(q historyPosition) bool {
+	return p.revision > q.revision || p.revision == q.revision && p.n > q.n
+}
+
+// changeLog records every mutation applied to the store as a change with a
+// cursor, its position in the feed, and the cursors consumers have
+// acknowledged. With a data directory, changes are appended to a log and
+// cursors saved next to it. Replaying the mutation log or the raft log on
+// startup applies mutations that were already recorded; they are
+// recognised by their position in the store's history and not recorded
+// again, so every change keeps its cursor across restarts.
+//
+// The change log is not synced on every append: the mutations are durable
+// in the log replayed on startup, which records again the changes that a
+// crash cut off the end of the change log. A change that cannot be written
+// is removed from the log and held back, with every change after it, until
+// it can be; the feed falls behind the store meanwhile but never has a gap.
+type changeLog struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File // nil without a data directory
+	size     int64
+	next     uint64 // cursor of the next change
+	applied  historyPosition
+	recorded historyPosition
+	backlog  int
+	recent   []*proto.ChangeEvent
+	index    []int64              // index[i] is the offset of the change with cursor i*changeIndexInterval+1
+	notify   chan struct{}        // closed when changes are added
+	pending  []*proto.ChangeEvent // changes applied but not written yet, oldest first
+	failed   error                // why the oldest pending change could not be written
+
+	cursorsMu   sync.Mutex
+	cursorsPath string
+	cursors     map[string]uint64
+}
+
+// newChangeLog creates a change feed kept in memory, holding the last
+// backlog changes
+func newChangeLog(backlog int) *changeLog {
+	return &changeLog{
+		next:    1,
+		backlog: backlog,
+		notify:  make(chan struct{}),
+		cursors: make(map[string]uint64),
+	}
+}
+
+// openChangeLog opens the change log and consumer cursors in dir, creating
+// them if needed
+func openChangeLog(dir string, backlog int) (*changeLog, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	c := newChangeLog(backlog)
+	c.path = filepath.Join(dir, changeLogFile)
+	c.cursorsPath = filepath.Join(dir, cursorsFile)
+
+	validSize, err := readFrames(c.path, func(payload []byte, offset, end int64) error {
+		e := &proto.ChangeEvent{}
+		if err := protobuf.Unmarshal(payload, e); err != nil {
+			return fmt.Errorf("%w: undecodable change at offset %d: %v", errLogCorrupt, offset, err)
+		}
+		if e.Cursor != c.next {
+			return fmt.Errorf("%w: change at offset %d has cursor %d, expected %d", errLogCorrupt, offset, e.Cursor, c.next)
+		}
+		c.add(e, offset)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", c.path, err)
+	}
+	if info, err := os.Stat(c.path); err == nil && info.Size() > validSize {
+		log.Printf("Discarding %d bytes of partially written changes at the end of %s", info.Size()-validSize, c.path)
+		if err := os.Truncate(c.path, validSize); err != nil {
+			return nil, err
+		}
+	}
+	c.size = validSize
+	if c.file, err = os.OpenFile(c.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(c.cursorsPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &c.cursors); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", c.cursorsPath, err)
+		}
+	}
+	return c, nil
+}
+
+// add indexes a change stored at offset in the change log. The caller must
+// hold c.mu.
+func (c *changeLog) add(e *proto.ChangeEvent, offset int64) {
+	c.recorded = c.recorded.next(e.Revision)
+	if (e.Cursor-1)%changeIndexInterval == 0 {
+		c.index = append(c.index, offset)
+	}
+	if c.backlog > 0 {
+		c.recent = append(c.recent, e)
+		if len(c.recent) > c.backlog {
+			// Trim by copying, so the backing array does not grow forever
+			c.recent = append(c.recent[:0:0], c.recent[len(c.recent)-c.backlog:]...)
+		}
+	}
+	c.next = e.Cursor + 1
+}
+
+// append records a change at the end of the feed and wakes up the
+// subscribers waiting for it. A change that cannot be written is not
+// recorded, and whatever part of it reached the log is cut off again. The
+// caller must hold c.mu.
+func (c *changeLog) append(e *proto.ChangeEvent) error {
+	e.Cursor = c.next
+	offset := c.size
+	if c.file != nil {
+		frame, err := appendFrame(nil, e)
+		if err == nil {
+			_, err = c.file.Write(frame)
+		}
+		if err != nil {
+			if truncErr := c.file.Truncate(c.size); truncErr != nil {
+				// Opening the log on the next start discards the partial frame
+				log.Printf("Failed to remove a partially written change from %s: %v", c.path, truncErr)
+			}
+			return err
+		}
+		c.size += int64(len(frame))
+	}
+	c.add(e, offset)
+
+	close(c.notify)
+	c.notify = make(chan struct{})
+	return nil
+}
+
+// failure returns the error that holds changes back from the feed, or nil
+// while every change is recorded
+func (c *changeLog) failure() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.failed
+}
+
+// observe records a mutation the store applied, unless it was already
+// recorded before a restart. The caller must hold k.mu for writing, which
+// keeps changes in the order they were applied.
+func (c *changeLog) observe(m *proto.Mutation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.applied = c.applied.next(m.Revision)
+	if !c.applied.after(c.recorded) {
+		return
+	}
+	c.pending = append(c.pending, &proto.ChangeEvent{
+		Revision:  m.Revision,
+		Timestamp: m.Timestamp,
+		Op:        m.Op,
+		Key:       m.Key,
+		Value:     m.Value,
+		Client:    m.Client,
+		Crdt:      m.Crdt,
+	})
+	c.flush()
+}
+
+// flush writes the pending changes in order, stopping at the first that
+// cannot be written so that no later change overtakes it. The caller must
+// hold c.mu.
+func (c *changeLog) flush() {
+	for len(c.pending) > 0 {
+		if err := c.append(c.pending[0]); err != nil {
+			if c.failed == nil {
+				log.Printf("Failed to append change %d to %s; holding back %d changes until it can be written: %v", c.next, c.path, len(c.pending), err)
+			}
+			c.failed = fmt.Errorf("failed to record change %d: %w", c.next, err)
+			return
+		}
+		c.pending[0] = nil
+		c.pending = c.pending[1:]
+	}
+	if c.failed != nil {
+		log.Printf("Recording changes in %s again, up to change %d", c.path, c.next-1)
+		c.failed = nil
+	}
+}
+
+// runRetry retries the changes held back by a failed write every interval
+// until stop is closed, and hands the outcome to report so that the state of
+// the feed can be published
+func (c *changeLog) runRetry(interval time.Duration, report func(error), stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			c.flush()
+			err := c.failed
+			c.mu.Unlock()
+			report(err)
+		}
+	}
+}
+
+// rewind records the contents of a store recovered to an earlier revision
+// than the feed has reached, as a clear followed by every key, so that
+// consumers can rebuild from it. Later mutations are recorded from the
+// recovered revision on. The caller must hold k.mu for writing.
+func (c *changeLog) rewind(revision uint64, data map[string]record) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The recovered contents come after the changes replayed before them
+	c.flush()
+	if c.failed != nil {
+		return c.failed
+	}
+
+	now := time.Now().UnixNano()
+	events := []*proto.ChangeEvent{{Revision: revision, Timestamp: now, Op: proto.MutationOp_MUTATION_OP_CLEAR}}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		events = append(events, &proto.ChangeEvent{
+			Revision:  revision,
+			Timestamp: now,
+			Op:        proto.MutationOp_MUTATION_OP_SET,
+			Key:       key,
+			Value:     data[key].value,
+			Client:    data[key].owner,
+			Crdt:      data[key].crdt,
+		})
+	}
+	for _, e := range events {
+		if err := c.append(e); err != nil {
+			return fmt.Errorf("failed to record the recovered contents in %s: %w", c.path, err)
+		}
+	}
+	c.applied = c.recorded
+	return nil
+}
+
+// read returns up to limit changes from cursor from on. When there are none
+// yet, it returns a channel that is closed once there are.
+func (c *changeLog) read(from uint64, limit int) ([]*proto.ChangeEvent, <-chan struct{}, error) {
+	c.mu.Lock()
+	if from >= c.next {
+		defer c.mu.Unlock()
+		return nil, c.notify, nil
+	}
+	if len(c.recent) > 0 && from >= c.recent[0].Cursor {
+		defer c.mu.Unlock()
+		i := int(from - c.recent[0].Cursor)
+		return append([]*proto.ChangeEvent(nil), c.recent[i:min(i+limit, len(c.recent))]...), nil, nil
+	}
+	if c.file == nil {
+		defer c.mu.Unlock()
+		return nil, nil, status.Errorf(codes.OutOfRange, "changes before cursor %d are no longer available", c.next-uint64(len(c.recent)))
+	}
+	path, start := c.path, c.index[(from-1)/changeIndexInterval]
+	c.mu.Unlock()
+
+	// Changes are never rewritten, so the log can be read while appended to
+	var events []*proto.ChangeEvent
+	_, err := readFramesFrom(path, start, func(payload []byte, offset, end int64) error {
+		e := &proto.ChangeEvent{}
+		if err := protobuf.Unmarshal(payload, e); err != nil {
+			return fmt.Errorf("%w: undecodable change at offset %d: %v", errLogCorrupt, offset, err)
+		}
+		if e.Cursor >= from {
+			events = append(events, e)
+		}
+		if len(events) == limit {
+			return errEnoughChanges
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errEnoughChanges) {
+		return nil, nil, status.Errorf(codes.Internal, "failed to read changes: %v", err)
+	}
+	return events, nil, nil
+}
+
+// close flushes and closes the change log file, if any
+func (c *changeLog) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	// Changes still held back are recorded from the mutations on the next
+	// start if this last attempt fails
+	c.flush()
+	// Changes are written without a sync of their own
+	err := c.file.Sync()
+	if closeErr := c.file.Close(); err == nil {
+		err = closeErr
+	}
+	c.file = nil
+	return err
+}
+
+// latest returns the cursor of the latest change, 0 if there is none
+func (c *changeLog) latest() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.next - 1
+}
+
+// acked returns the last cursor a consumer acknowledged, 0 if none
+func (c *changeLog) acked(consumer string) uint64 {
+	c.cursorsMu.Lock()
+	defer c.cursorsMu.Unlock()
+	return c.cursors[consumer]
+}
+
+// ack durably records that a consumer has processed every change up to
+// cursor. Cursors only move forward.
+func (c *changeLog) ack(consumer string, cursor uint64) error {
+	if latest := c.latest(); cursor > latest {
+		return status.Errorf(codes.InvalidArgument, "cursor %d is past the latest change, %d", cursor, latest)
+	}
+
+	c.cursorsMu.Lock()
+	defer c.cursorsMu.Unlock()
+
+	previous, ok := c.cursors[consumer]
+	if cursor < previous {
+		return status.Errorf(codes.FailedPrecondition, "consumer '%s' already acknowledged cursor %d", consumer, previous)
+	}
+	if ok && cursor == previous {
+		return nil
+	}
+	c.cursors[consumer] = cursor
+	if err := c.saveCursors(); err != nil {
+		if ok {
+			c.cursors[consumer] = previous
+		} else {
+			delete(c.cursors, consumer)
+		}
+		return status.Errorf(codes.Internal, "failed to save cursors: %v", err)
+	}
+	return nil
+}
+
+// saveCursors replaces the cursors file with the current cursors, so that
+// an acknowledgement survives a crash once it returns. The caller must hold
+// c.cursorsMu.
+func (c *changeLog) saveCursors() error {
+	if c.cursorsPath == "" {
+		return nil
+	}
+	data, err := json.Marshal(c.cursors)
+	if err != nil {
+		return err
+	}
+
+	tmp := c.cursorsPath + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.cursorsPath)
+}
+
+// changeFeedServer serves the ChangeFeed service for a store
+type changeFeedServer struct {
+	proto.UnimplementedChangeFeedServer
+	store *kvStore
+}
+
+// Subscribe streams changes in cursor order, starting at req.FromCursor or
+// just after the consumer's acknowledged cursor, and keeps following new
+// changes until the client goes away
+func (s *changeFeedServer) Subscribe(req *proto.ChangeSubscribeRequest, stream grpc.ServerStreamingServer[proto.ChangeEvent]) error {
+	c := s.store.changes
+	from := req.FromCursor
+	if from == 0 {
+		if req.Consumer == "" {
+			return status.Error(codes.InvalidArgument, "a consumer or a cursor to start from is required")
+		}
+		from = c.acked(req.Consumer) + 1
+	}
+
+	for {
+		events, wait, err := c.read(from, backupBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			if err := stream.Send(e); err != nil {
+				return err
+			}
+			from = e.Cursor + 1
+		}
+		if wait == nil {
+			continue
+		}
+		select {
+		case <-wait:
+		case <-stream.Context().Done():
+			return nil
+		case <-s.store.closing:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+	}
+}
+
+// Ack records the last change a consumer has processed, to resume after on
+// its next Subscribe
+func (s *changeFeedServer) Ack(ctx context.Context, req *proto.ChangeAckRequest) (*proto.ChangeAckResponse, error) {
+	if req.Consumer == "" {
+		return nil, status.Error(codes.InvalidArgument, "consumer is required")
+	}
+	if err := s.store.changes.ack(req.Consumer, req.Cursor); err != nil {
+		return nil, err
+	}
+	return &proto.ChangeAckResponse{
+		Success: true,
+		Message: fmt.Sprintf("Consumer '%s' acknowledged cursor %d", req.Consumer, req.Cursor),
+	}, nil
+}
+
+// ListCursors returns every consumer's acknowledged cursor, by name
+func (s *changeFeedServer) ListCursors(ctx context.Context, req *proto.ListCursorsRequest) (*proto.ListCursorsResponse, error) {
+	c := s.store.changes
+	resp := &proto.ListCursorsResponse{Latest: c.latest()}
+
+	c.cursorsMu.Lock()
+	for consumer, cursor := range c.cursors {
+		resp.Consumers = append(resp.Consumers, &proto.ConsumerCursor{
+			Consumer: consumer,
+			Cursor:   cursor,
+			Lag:      resp.Latest - min(cursor, resp.Latest),
+		})
+	}
+	c.cursorsMu.Unlock()
+
+	sort.Slice(resp.Consumers, func(i, j int) bool { return resp.Consumers[i].Consumer < resp.Consumers[j].Consumer })
+	return resp, nil
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startChangeFeed serves the change feed of a store and returns a client
+// for it
+func startChangeFeed(t *testing.T, store *kvStore) proto.ChangeFeedClient {
+	t.Helper()
+
+	ts := startTestServer(t, store, withService(func(s *grpc.Server) {
+		proto.RegisterChangeFeedServer(s, &changeFeedServer{store: store})
+	}))
+	return proto.NewChangeFeedClient(ts.conn)
+}
+
+// receiveChanges reads n changes from a subscription
+func receiveChanges(t *testing.T, stream grpc.ServerStreamingClient[proto.ChangeEvent], n int) []*proto.ChangeEvent {
+	t.Helper()
+
+	var events []*proto.ChangeEvent
+	for len(events) < n {
+		e, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv() error = %v", err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+// describeChange formats a change for comparisons
+func describeChange(e *proto.ChangeEvent) string {
+	switch e.Op {
+	case proto.MutationOp_MUTATION_OP_SET:
+		return "set " + e.Key + "=" + e.Value
+	case proto.MutationOp_MUTATION_OP_DELETE:
+		return "delete " + e.Key
+	case proto.MutationOp_MUTATION_OP_CLEAR:
+		return "clear"
+	}
+	return e.Op.String()
+}
+
+// expectChanges checks the cursors and contents of a run of changes
+func expectChanges(t *testing.T, events []*proto.ChangeEvent, first uint64, want ...string) {
+	t.Helper()
+
+	if len(events) != len(want) {
+		t.Fatalf("got %d changes, expected %d", len(events), len(want))
+	}
+	for i, e := range events {
+		if e.Cursor != first+uint64(i) || describeChange(e) != want[i] {
+			t.Errorf("change %d = cursor %d %q, expected cursor %d %q", i, e.Cursor, describeChange(e), first+uint64(i), want[i])
+		}
+	}
+}
+
+func TestChangeFeed_Subscribe(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	store := NewKVStore()
+	client := startChangeFeed(t, store)
+
+	store.Set(ctx, &proto.SetRequest{Key: "a", Value: "1"})
+	store.Set(ctx, &proto.SetRequest{Key: "b", Value: "2"})
+	store.Delete(ctx, &proto.DeleteRequest{Key: "a"})
+
+	stream, err := client.Subscribe(ctx, &proto.ChangeSubscribeRequest{FromCursor: 2})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	events := receiveChanges(t, stream, 2)
+	expectChanges(t, events, 2, "set b=2", "delete a")
+	if events[1].Revision != 3 {
+		t.Errorf("Revision = %d, expected 3", events[1].Revision)
+	}
+
+	// The subscription follows later writes
+	store.Set(ctx, &proto.SetRequest{Key: "c", Value: "3"})
+	expectChanges(t, receiveChanges(t, stream, 1), 4, "set c=3")
+}
+
+func TestChangeFeed_ResumeAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	store := openTestStore(t, dir, recoveryTarget{})
+	store.Set(ctx, &proto.SetRequest{Key: "a", Value: "1"})
+	store.Set(ctx, &proto.SetRequest{Key: "b", Value: "2"})
+	store.Set(ctx, &proto.SetRequest{Key: "c", Value: "3"})
+
+	client := startChangeFeed(t, store)
+	stream, err := client.Subscribe(ctx, &proto.ChangeSubscribeRequest{Consumer: "indexer"})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	expectChanges(t, receiveChanges(t, stream, 2), 1, "set a=1", "set b=2")
+	if _, err := client.Ack(ctx, &proto.ChangeAckRequest{Consumer: "indexer", Cursor: 2}); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	store.Close()
+
+	// Replaying the mutation log records nothing twice
+	reopened := openTestStore(t, dir, recoveryTarget{})
+	if latest := reopened.changes.latest(); latest != 3 {
+		t.Fatalf("latest cursor after restart = %d, expected 3", latest)
+	}
+	reopened.Set(ctx, &proto.SetRequest{Key: "d", Value: "4"})
+
+	client = startChangeFeed(t, reopened)
+	stream, err = client.Subscribe(ctx, &proto.ChangeSubscribeRequest{Consumer: "indexer"})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	expectChanges(t, receiveChanges(t, stream, 2), 3, "set c=3", "set d=4")
+
+	resp, err := client.ListCursors(ctx, &proto.ListCursorsRequest{})
+	if err != nil {
+		t.Fatalf("ListCursors() error = %v", err)
+	}
+	if resp.Latest != 4 || len(resp.Consumers) != 1 || resp.Consumers[0].Cursor != 2 || resp.Consumers[0].Lag != 2 {
+		t.Errorf("ListCursors() = %v", resp)
+	}
+	reopened.Close()
+
+	// Changes beyond the in-memory backlog are read back from the log
+	changes, err := openChangeLog(dir, 1)
+	if err != nil {
+		t.Fatalf("openChangeLog() error = %v", err)
+	}
+	defer changes.close()
+	events, _, err := changes.read(2, 2)
+	if err != nil {
+		t.Fatalf("read() error = %v", err)
+	}
+	expectChanges(t, events, 2, "set b=2", "set c=3")
+}
+
+func TestChangeFeed_RecordsRecovery(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store := openTestStore(t, dir, recoveryTarget{})
+	store.Set(ctx, &proto.SetRequest{Key: "b", Value: "1"})
+	store.Set(ctx, &proto.SetRequest{Key: "a", Value: "2"})
+	store.Set(ctx, &proto.SetRequest{Key: "b", Value: "3"})
+	store.Close()
+
+	// Recovering to an earlier revision records the recovered contents
+	recovered := openTestStore(t, dir, recoveryTarget{revision: 2})
+	recovered.Set(ctx, &proto.SetRequest{Key: "c", Value: "4"})
+	events, _, err := recovered.changes.read(4, 10)
+	if err != nil {
+		t.Fatalf("read() error = %v", err)
+	}
+	expectChanges(t, events, 4, "clear", "set a=2", "set b=1", "set c=4")
+	if events[0].Revision != 2 || events[3].Revision != 3 {
+		t.Errorf("revisions = %d and %d, expected 2 and 3", events[0].Revision, events[3].Revision)
+	}
+	recovered.Close()
+
+	reopened := openTestStore(t, dir, recoveryTarget{})
+	if latest := reopened.changes.latest(); latest != 7 {
+		t.Errorf("latest cursor after restart = %d, expected 7", latest)
+	}
+}
+
+// swapChangeLogFile replaces the file changes are written to, closing the
+// one it replaces
+func swapChangeLogFile(t *testing.T, store *kvStore, file *os.File) {
+	t.Helper()
+
+	store.changes.mu.Lock()
+	previous := store.changes.file
+	store.changes.file = file
+	store.changes.mu.Unlock()
+	previous.Close()
+}
+
+// breakChangeLog swaps in a read-only handle, so that no change can be
+// written until a writable one is swapped back in
+func breakChangeLog(t *testing.T, store *kvStore) {
+	t.Helper()
+
+	readOnly, err := os.Open(store.changes.path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	swapChangeLogFile(t, store, readOnly)
+}
+
+func TestChangeFeed_FailedAppend(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store := openTestStore(t, dir, recoveryTarget{})
+	store.Set(ctx, &proto.SetRequest{Key: "a", Value: "1"})
+	breakChangeLog(t, store)
+
+	// Writes are still made, and their changes held back in order
+	for _, key := range []string{"b", "c"} {
+		if _, err := store.Set(ctx, &proto.SetRequest{Key: key, Value: key}); err != nil {
+			t.Errorf("Set(%s) with a failing change log error = %v", key, err)
+		}
+	}
+	if latest := store.changes.latest(); latest != 1 {
+		t.Errorf("latest cursor = %d after a failed change, expected 1", latest)
+	}
+	if store.changes.failure() == nil {
+		t.Error("failure() = nil with changes held back")
+	}
+
+	// Once the log can be written again, the retry catches the feed up
+	writable, err := os.OpenFile(store.changes.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	swapChangeLogFile(t, store, writable)
+	stop := make(chan struct{})
+	defer close(stop)
+	reports := make(chan error, 1)
+	go store.changes.runRetry(10*time.Millisecond, func(err error) {
+		select {
+		case reports <- err:
+		default:
+		}
+	}, stop)
+	waitFor(t, "the feed to catch up", func() bool {
+		select {
+		case err := <-reports:
+			return err == nil
+		default:
+			return false
+		}
+	})
+	store.Set(ctx, &proto.SetRequest{Key: "d", Value: "d"})
+	events, _, err := store.changes.read(1, 10)
+	if err != nil {
+		t.Fatalf("read() error = %v", err)
+	}
+	expectChanges(t, events, 1, "set a=1", "set b=b", "set c=c", "set d=d")
+
+	// Changes still held back at shutdown are recorded on the next start
+	breakChangeLog(t, store)
+	store.Set(ctx, &proto.SetRequest{Key: "e", Value: "e"})
+	store.Close()
+
+	reopened := openTestStore(t, dir, recoveryTarget{})
+	reopened.Set(ctx, &proto.SetRequest{Key: "f", Value: "f"})
+	events, _, err = reopened.changes.read(1, 10)
+	if err != nil {
+		t.Fatalf("read() error = %v", err)
+	}
+	expectChanges(t, events, 1, "set a=1", "set b=b", "set c=c", "set d=d", "set e=e", "set f=f")
+}
+
+func TestChangeFeed_Errors(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	store := NewKVStore()
+	store.changes = newChangeLog(1)
+	store.Set(ctx, &proto.SetRequest{Key: "a", Value: "1"})
+	store.Set(ctx, &proto.SetRequest{Key: "b", Value: "2"})
+	client := startChangeFeed(t, store)
+	client.Ack(ctx, &proto.ChangeAckRequest{Consumer: "indexer", Cursor: 2})
+
+	subscribe := func(req *proto.ChangeSubscribeRequest) error {
+		stream, err := client.Subscribe(ctx, req)
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		return err
+	}
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"Subscribe without consumer or cursor", func() error {
+			return subscribe(&proto.ChangeSubscribeRequest{})
+		}, codes.InvalidArgument},
+		{"Subscribe before the backlog", func() error {
+			return subscribe(&proto.ChangeSubscribeRequest{FromCursor: 1})
+		}, codes.OutOfRange},
+		{"Ack without consumer", func() error {
+			_, err := client.Ack(ctx, &proto.ChangeAckRequest{Cursor: 1})
+			return err
+		}, codes.InvalidArgument},
+		{"Ack past the latest change", func() error {
+			_, err := client.Ack(ctx, &proto.ChangeAckRequest{Consumer: "other", Cursor: 3})
+			return err
+		}, codes.InvalidArgument},
+		{"Ack moving backwards", func() error {
+			_, err := client.Ack(ctx, &proto.ChangeAckRequest{Consumer: "indexer", Cursor: 1})
+			return err
+		}, codes.FailedPrecondition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); status.Code(err) != tt.want {
+				t.Errorf("error = %v, expected %v", err, tt.want)
+			}
+		})
+	}
+}
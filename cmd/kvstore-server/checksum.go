@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"sort"
+
+	"github.com/pwntato/Censys/proto"
+)
+
+// crc32cTable is the Castagnoli polynomial table used for all record checksums
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// checksumOf computes the CRC32C checksum of a key-value pair. The key is
+// covered as well as the value so that a value attached to the wrong key is
+// detected too.
+func checksumOf(key, value string) uint32 {
+	crc := crc32.Update(0, crc32cTable, []byte(key))
+	crc = crc32.Update(crc, crc32cTable, []byte{0})
+	return crc32.Update(crc, crc32cTable, []byte(value))
+}
+
+// verify reports whether the record still matches the checksum it was written with
+func (r record) verify(key string) bool {
+	return r.checksum == checksumOf(key, r.value)
+}
+
+// Verify scrubs the whole store and reports every key whose record is corrupt
+func (k *kvStore) Verify(ctx context.Context, req *proto.VerifyRequest) (*proto.VerifyResponse, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var corrupt []string
+	for key, rec := range k.data {
+		if !rec.verify(key) {
+			corrupt = append(corrupt, key)
+		}
+	}
+	sort.Strings(corrupt)
+
+	if len(corrupt) > 0 {
+		log.Printf("Verify found %d corrupt keys out of %d", len(corrupt), len(k.data))
+	}
+
+	return &proto.VerifyResponse{
+		Success:     len(corrupt) == 0,
+		KeysScanned: int64(len(k.data)),
+		CorruptKeys: corrupt,
+		Message:     fmt.Sprintf("Scanned %d keys, found %d corrupt", len(k.data), len(corrupt)),
+	}, nil
+}
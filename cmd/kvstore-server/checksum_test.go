@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestChecksum_DetectsCorruption(t *testing.T) {
+	store := NewKVStore()
+	ctx := context.Background()
+
+	store.Set(ctx, &proto.SetRequest{Key: "good-key", Value: "good-value"})
+	store.Set(ctx, &proto.SetRequest{Key: "bad-key", Value: "bad-value"})
+
+	// Simulate bit-rot by changing the value without updating its checksum
+	rec := store.data["bad-key"]
+	rec.value = "bad-valuf"
+	store.data["bad-key"] = rec
+
+	resp, err := store.Get(ctx, &proto.GetRequest{Key: "good-key"})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !resp.Success || resp.Value != "good-value" {
+		t.Errorf("Get() = %v, expected good-value", resp)
+	}
+
+	_, err = store.Get(ctx, &proto.GetRequest{Key: "bad-key"})
+	if status.Code(err) != codes.DataLoss {
+		t.Errorf("Get() of corrupt key error = %v, expected DataLoss", err)
+	}
+}
+
+func TestChecksum_CoversKey(t *testing.T) {
+	if checksumOf("a", "bc") == checksumOf("ab", "c") {
+		t.Errorf("checksum should distinguish key and value boundaries")
+	}
+
+	rec := record{value: "value", checksum: checksumOf("key-1", "value")}
+	if rec.verify("key-2") {
+		t.Errorf("record verified against the wrong key")
+	}
+}
+
+func TestKVStore_Verify(t *testing.T) {
+	store := NewKVStore()
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c"} {
+		store.Set(ctx, &proto.SetRequest{Key: key, Value: "value-" + key})
+	}
+
+	resp, err := store.Verify(ctx, &proto.VerifyRequest{})
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !resp.Success || resp.KeysScanned != 3 || len(resp.CorruptKeys) != 0 {
+		t.Errorf("Verify() on clean store = %v", resp)
+	}
+
+	rec := store.data["b"]
+	rec.checksum ^= 1
+	store.data["b"] = rec
+
+	resp, err = store.Verify(ctx, &proto.VerifyRequest{})
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if resp.Success {
+		t.Errorf("Verify() success = true, expected false")
+	}
+	if len(resp.CorruptKeys) != 1 || resp.CorruptKeys[0] != "b" {
+		t.Errorf("Verify() corrupt keys = %v, expected [b]", resp.CorruptKeys)
+	}
+}
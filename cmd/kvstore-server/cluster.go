@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// defaultRaftSnapshotEntries is how many applied entries the raft log grows
+// by before it is compacted into a snapshot
+const defaultRaftSnapshotEntries = 10000
+
+// forwardedMetadataKey marks a write forwarded by a follower. It is never
+// forwarded again, so a stale view of the leader cannot bounce it around.
+const forwardedMetadataKey = "x-raft-forwarded-by"
+
+// forwardedWrites are the unary write RPCs a follower forwards to the
+// leader, with a constructor for each one's response
+var forwardedWrites = map[string]func() protobuf.Message{
+	proto.KeyValueStore_Set_FullMethodName:            func() protobuf.Message { return &proto.SetResponse{} },
+	proto.KeyValueStore_Delete_FullMethodName:         func() protobuf.Message { return &proto.DeleteResponse{} },
+	proto.KeyValueStore_Undelete_FullMethodName:       func() protobuf.Message { return &proto.UndeleteResponse{} },
+	proto.KeyValueStore_RegisterSchema_FullMethodName: func() protobuf.Message { return &proto.RegisterSchemaResponse{} },
+	proto.KeyValueStore_DeleteSchema_FullMethodName:   func() protobuf.Message { return &proto.DeleteSchemaResponse{} },
+}
+
+// streamingWrites are the streaming write RPCs, which a follower rejects
+// with the leader's address instead of forwarding
+var streamingWrites = map[string]bool{
+	proto.KeyValueStore_Restore_FullMethodName: true,
+	proto.KeyValueStore_Import_FullMethodName:  true,
+}
+
+// raftNodeFromEnv creates the cluster member described by KVSTORE_RAFT_ID
+// and KVSTORE_RAFT_PEERS, or returns nil when the server runs standalone.
+// The node keeps its log in dataDir, which is required.
+func raftNodeFromEnv(dataDir string) (*raftNode, error) {
+	id := os.Getenv("KVSTORE_RAFT_ID")
+	if id == "" {
+		return nil, nil
+	}
+	if dataDir == "" {
+		return nil, fmt.Errorf("KVSTORE_DATA_DIR is required when KVSTORE_RAFT_ID is set")
+	}
+
+	peers, err := parseRaftPeers(os.Getenv("KVSTORE_RAFT_PEERS"))
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := envDuration("KVSTORE_RAFT_ELECTION_TIMEOUT", time.Second)
+	if err != nil || timeout == 0 {
+		return nil, fmt.Errorf("invalid KVSTORE_RAFT_ELECTION_TIMEOUT %q", os.Getenv("KVSTORE_RAFT_ELECTION_TIMEOUT"))
+	}
+	snapshotEntries, err := envInt("KVSTORE_RAFT_SNAPSHOT_ENTRIES", defaultRaftSnapshotEntries)
+	if err != nil || snapshotEntries < 0 {
+		return nil, fmt.Errorf("invalid KVSTORE_RAFT_SNAPSHOT_ENTRIES %q", os.Getenv("KVSTORE_RAFT_SNAPSHOT_ENTRIES"))
+	}
+
+	return newRaftNode(id, peers, dataDir, raftConfig{
+		electionTimeout:   timeout,
+		heartbeatInterval: timeout / 5,
+		snapshotEntries:   uint64(snapshotEntries),
+	})
+}
+
+// commitReplicated proposes a group of mutations to the cluster and waits
+// for a majority to accept them. k.mu is released meanwhile, so reads and
+// the applier carry on while other writes wait on k.writes. Whatever the
+// applier has not reached yet once the entry commits is applied before
+// returning. The caller must hold the store locked with lockWrite.
+func (k *kvStore) commitReplicated(ctx context.Context, mutations []*proto.Mutation) error {
+	k.mu.Unlock()
+	index, term, err := k.raft.propose(mutations)
+	if err == nil {
+		err = k.raft.waitCommitted(ctx, index, term)
+	}
+	k.mu.Lock()
+	if err != nil {
+		return err
+	}
+	k.applyCommitted()
+	return nil
+}
+
+// applyCommitted applies the committed raft log entries the store has not
+// seen yet, loading the snapshot first if they start before it. Revisions
+// are assigned here, in log order, so that every node numbers the same
+// mutations the same way. The caller must hold k.mu for writing.
+func (k *kvStore) applyCommitted() {
+	snap, entries := k.raft.committedAfter(k.appliedIndex)
+	if snap != nil {
+		k.loadRaftSnapshot(snap)
+	}
+
+	for _, e := range entries {
+		// A leader appends a whole group at once, so an entry of a later
+		// term means the rest of a partial group was never committed
+		if len(k.raftGroup) > 0 && e.Term != k.raftGroupTerm {
+			log.Printf("Dropping %d mutations of a raft entry group cut short by a new leader", len(k.raftGroup))
+			k.raftGroup = nil
+		}
+		k.raftGroup = append(k.raftGroup, e.Mutations...)
+		k.raftGroupTerm = e.Term
+		k.appliedIndex = e.Index
+		if e.Partial {
+			continue
+		}
+
+		for _, m := range k.raftGroup {
+			// The log entry is shared with the replicators, so number a copy
+			m = protobuf.Clone(m).(*proto.Mutation)
+			m.Revision = k.revision + 1
+			k.applyMutation(m)
+		}
+		k.raftGroup = nil
+	}
+}
+
+// loadRaftSnapshot replaces the store's contents with a raft snapshot. The
+// caller must hold k.mu for writing.
+func (k *kvStore) loadRaftSnapshot(snap *proto.RaftSnapshot) {
+	// The snapshot is shared with the replicators, so load a copy
+	mutations := make([]*proto.Mutation, len(snap.Mutations))
+	for i, m := range snap.Mutations {
+		mutations[i] = protobuf.Clone(m).(*proto.Mutation)
+	}
+	if err := k.replaceContents(snap.Revision, snap.Timestamp, mutations); err != nil {
+		log.Printf("Failed to load raft snapshot: %v", err)
+	}
+	k.appliedIndex = snap.LastIndex
+	k.raftGroup = nil
+	log.Printf("Loaded raft snapshot up to index %d at revision %d", snap.LastIndex, snap.Revision)
+}
+
+// raftSnapshot returns a snapshot of the store at the last applied entry
+// when the raft log is due to be compacted, or nil. Snapshots are only
+// taken between groups of entries. The caller must hold k.mu.
+func (k *kvStore) raftSnapshot() *proto.RaftSnapshot {
+	if len(k.raftGroup) > 0 || !k.raft.snapshotDue(k.appliedIndex) {
+		return nil
+	}
+
+	snap := &proto.RaftSnapshot{LastIndex: k.appliedIndex, Revision: k.revision, Timestamp: time.Now().UnixNano()}
+	for _, s := range k.schemaList() {
+		snap.Mutations = append(snap.Mutations, &proto.Mutation{Op: proto.MutationOp_MUTATION_OP_SET_SCHEMA, Key: s.Prefix, Value: s.Schema})
+	}
+	keys := make([]string, 0, len(k.data))
+	for key := range k.data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		rec := k.data[key]
+		snap.Mutations = append(snap.Mutations, &proto.Mutation{
+			Op:       proto.MutationOp_MUTATION_OP_SET,
+			Key:      key,
+			Value:    rec.value,
+			Checksum: rec.checksum,
+			Client:   rec.owner,
+			Version:  rec.version.proto(),
+			Crdt:     rec.crdt,
+		})
+	}
+	snap.Mutations = append(snap.Mutations, k.deletions()...)
+	return snap
+}
+
+// runApplier applies entries committed by other nodes until stop is closed,
+// compacting the raft log whenever it has grown enough
+func (k *kvStore) runApplier(stop <-chan struct{}) {
+	for {
+		select {
+		case <-k.raft.applyCh:
+			k.mu.Lock()
+			k.applyCommitted()
+			snap := k.raftSnapshot()
+			k.mu.Unlock()
+
+			if snap != nil {
+				if err := k.raft.compact(snap); err != nil {
+					log.Printf("Failed to compact the raft log: %v", err)
+				}
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// forwardWrites is a unary interceptor that sends writes received by a
+// follower on to the leader, on behalf of the original client
+func (rn *raftNode) forwardWrites(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	newResponse, isWrite := forwardedWrites[info.FullMethod]
+	if !isWrite {
+		return handler(ctx, req)
+	}
+
+	leader, isSelf := rn.leader()
+	if isSelf {
+		return handler(ctx, req)
+	}
+	if leader == nil || len(metadata.ValueFromIncomingContext(ctx, forwardedMetadataKey)) > 0 {
+		// Let the store report that this node is not the leader
+		return handler(ctx, req)
+	}
+
+	out := metadata.AppendToOutgoingContext(ctx,
+		clientIDMetadataKey, clientIdentity(ctx),
+		forwardedMetadataKey, rn.id,
+	)
+	resp := newResponse()
+	if err := leader.conn.Invoke(out, info.FullMethod, req, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// rejectFollowerStreams is a stream interceptor that turns streaming writes
+// sent to a follower away before they start
+func (rn *raftNode) rejectFollowerStreams(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if streamingWrites[info.FullMethod] {
+		if _, isSelf := rn.leader(); !isSelf {
+			rn.mu.Lock()
+			defer rn.mu.Unlock()
+			return rn.notLeaderError()
+		}
+	}
+	return handler(srv, ss)
+}
@@ -0,0 +1,420 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultCrdtSyncInterval is how often CRDT states are exchanged with peers
+const defaultCrdtSyncInterval = 5 * time.Second
+
+// crdtState is the state of a CRDT key, stored JSON-encoded as the key's
+// value. Only the fields used by the key's type are set. Maps encode with
+// sorted keys and the slices are kept sorted, so equal states always
+// encode the same way.
+type crdtState struct {
+	// LWW register: the value written last, ties broken by node
+	Value     string `json:"value,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Node      string `json:"node,omitempty"`
+
+	// Counters: the total each node has added and, for a PN-counter,
+	// subtracted
+	Increments map[string]uint64 `json:"increments,omitempty"`
+	Decrements map[string]uint64 `json:"decrements,omitempty"`
+
+	// OR-set: the unique tags of the additions of each element still
+	// present, the tags removed, and the last tag each node issued
+	Adds    map[string][]string `json:"adds,omitempty"`
+	Removed []string            `json:"removed,omitempty"`
+	Tags    map[string]uint64   `json:"tags,omitempty"`
+}
+
+// decodeCrdt parses the state stored for a CRDT key
+func decodeCrdt(value string) (*crdtState, error) {
+	s := &crdtState{}
+	if err := json.Unmarshal([]byte(value), s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// encode returns the stored form of the state
+func (s *crdtState) encode() string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+// apply performs op on a state of type t on behalf of node at time now
+func (s *crdtState) apply(t proto.CrdtType, req *proto.CrdtUpdateRequest, node string, now int64) error {
+	switch {
+	case t == proto.CrdtType_CRDT_TYPE_LWW_REGISTER && req.Op == proto.CrdtOp_CRDT_OP_ASSIGN:
+		// Never go back in time, even if the clock does
+		s.Value, s.Timestamp, s.Node = req.Value, max(now, s.Timestamp+1), node
+	case (t == proto.CrdtType_CRDT_TYPE_G_COUNTER || t == proto.CrdtType_CRDT_TYPE_PN_COUNTER) && req.Op == proto.CrdtOp_CRDT_OP_INCREMENT:
+		if s.Increments == nil {
+			s.Increments = make(map[string]uint64)
+		}
+		s.Increments[node] += req.Amount
+	case t == proto.CrdtType_CRDT_TYPE_PN_COUNTER && req.Op == proto.CrdtOp_CRDT_OP_DECREMENT:
+		if s.Decrements == nil {
+			s.Decrements = make(map[string]uint64)
+		}
+		s.Decrements[node] += req.Amount
+	case t == proto.CrdtType_CRDT_TYPE_OR_SET && req.Op == proto.CrdtOp_CRDT_OP_ADD:
+		// An emptied set keeps its tag counters, so a tag is never reissued
+		if s.Adds == nil {
+			s.Adds = make(map[string][]string)
+		}
+		if s.Tags == nil {
+			s.Tags = make(map[string]uint64)
+		}
+		s.Tags[node]++
+		s.Adds[req.Value] = sortedUnion(s.Adds[req.Value], []string{fmt.Sprintf("%s:%d", node, s.Tags[node])})
+	case t == proto.CrdtType_CRDT_TYPE_OR_SET && req.Op == proto.CrdtOp_CRDT_OP_REMOVE:
+		// Only the additions seen here are removed; one made concurrently
+		// elsewhere keeps the element
+		s.Removed = sortedUnion(s.Removed, s.Adds[req.Value])
+		delete(s.Adds, req.Value)
+	default:
+		return status.Errorf(codes.InvalidArgument, "operation %v does not apply to %v", req.Op, t)
+	}
+	return nil
+}
+
+// mergeCrdt combines two states of type t. The result does not depend on
+// the order of the arguments, so servers that have exchanged their states
+// hold the same one.
+func mergeCrdt(t proto.CrdtType, a, b *crdtState) *crdtState {
+	merged := &crdtState{}
+	switch t {
+	case proto.CrdtType_CRDT_TYPE_LWW_REGISTER:
+		winner := a
+		if b.Timestamp > a.Timestamp ||
+			b.Timestamp == a.Timestamp && (b.Node > a.Node || b.Node == a.Node && b.Value > a.Value) {
+			winner = b
+		}
+		merged.Value, merged.Timestamp, merged.Node = winner.Value, winner.Timestamp, winner.Node
+	case proto.CrdtType_CRDT_TYPE_G_COUNTER, proto.CrdtType_CRDT_TYPE_PN_COUNTER:
+		merged.Increments = mergeMax(a.Increments, b.Increments)
+		merged.Decrements = mergeMax(a.Decrements, b.Decrements)
+	case proto.CrdtType_CRDT_TYPE_OR_SET:
+		merged.Tags = mergeMax(a.Tags, b.Tags)
+		merged.Removed = sortedUnion(a.Removed, b.Removed)
+		for _, adds := range []map[string][]string{a.Adds, b.Adds} {
+			for element, tags := range adds {
+				live := sortedUnion(merged.Adds[element], tags)
+				live = slices.DeleteFunc(live, func(tag string) bool {
+					_, removed := slices.BinarySearch(merged.Removed, tag)
+					return removed
+				})
+				if len(live) == 0 {
+					delete(merged.Adds, element)
+					continue
+				}
+				if merged.Adds == nil {
+					merged.Adds = make(map[string][]string)
+				}
+				merged.Adds[element] = live
+			}
+		}
+	}
+	return merged
+}
+
+// mergeMax keeps the larger count of every node
+func mergeMax(a, b map[string]uint64) map[string]uint64 {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	merged := maps.Clone(a)
+	if merged == nil {
+		merged = make(map[string]uint64, len(b))
+	}
+	for node, n := range b {
+		merged[node] = max(merged[node], n)
+	}
+	return merged
+}
+
+// sortedUnion returns the sorted union of two sorted lists
+func sortedUnion(a, b []string) []string {
+	union := slices.Concat(a, b)
+	slices.Sort(union)
+	return slices.Compact(union)
+}
+
+// crdtReading is the value of a CRDT as returned to clients
+type crdtReading struct {
+	value    string
+	count    int64
+	elements []string
+}
+
+// read returns the value a state of type t represents
+func (s *crdtState) read(t proto.CrdtType) crdtReading {
+	switch t {
+	case proto.CrdtType_CRDT_TYPE_LWW_REGISTER:
+		return crdtReading{value: s.Value}
+	case proto.CrdtType_CRDT_TYPE_G_COUNTER, proto.CrdtType_CRDT_TYPE_PN_COUNTER:
+		var count int64
+		for _, n := range s.Increments {
+			count += int64(n)
+		}
+		for _, n := range s.Decrements {
+			count -= int64(n)
+		}
+		return crdtReading{count: count, value: fmt.Sprint(count)}
+	default:
+		elements := make([]string, 0, len(s.Adds))
+		for element := range s.Adds {
+			elements = append(elements, element)
+		}
+		sort.Strings(elements)
+		value, _ := json.Marshal(elements)
+		return crdtReading{elements: elements, value: string(value)}
+	}
+}
+
+// crdtValue returns the value a stored CRDT record represents, as plain
+// reads return it
+func crdtValue(rec record) (string, error) {
+	s, err := decodeCrdt(rec.value)
+	if err != nil {
+		return "", err
+	}
+	return s.read(rec.crdt).value, nil
+}
+
+// notCrdt is the error for a plain read or write of a CRDT key
+func notCrdt(key string, t proto.CrdtType) error {
+	return failedPrecondition(reasonCrdtTypeMismatch, key, fmt.Sprintf("key '%s' is a %v; use the CRDT operations", key, t), nil)
+}
+
+// CrdtUpdate applies an operation to a CRDT key. The first update of a key
+// declares its type.
+func (k *kvStore) CrdtUpdate(ctx context.Context, req *proto.CrdtUpdateRequest) (*proto.CrdtUpdateResponse, error) {
+	k.access.record(req.Key, true)
+
+	if k.crdtNode == "" {
+		return nil, failedPrecondition(reasonFeatureDisabled, "crdt", "CRDT mode is not enabled on this server", nil)
+	}
+	if req.Type == proto.CrdtType_CRDT_TYPE_NONE {
+		return nil, status.Error(codes.InvalidArgument, "a CRDT type is required")
+	}
+	if err := k.limits.validate(req.Key, req.Value); err != nil {
+		return nil, err
+	}
+
+	k.lockWrite()
+	defer k.unlockWrite()
+
+	state := &crdtState{}
+	if rec, exists := k.data[req.Key]; exists {
+		if rec.crdt == proto.CrdtType_CRDT_TYPE_NONE {
+			return nil, failedPrecondition(reasonCrdtTypeMismatch, req.Key, fmt.Sprintf("key '%s' holds a plain value", req.Key), nil)
+		}
+		if rec.crdt != req.Type {
+			return nil, failedPrecondition(reasonCrdtTypeMismatch, req.Key, fmt.Sprintf("key '%s' is a %v, not a %v", req.Key, rec.crdt, req.Type), nil)
+		}
+		var err error
+		if state, err = decodeCrdt(rec.value); err != nil {
+			return nil, status.Errorf(codes.DataLoss, "key '%s' has an unreadable CRDT state: %v", req.Key, err)
+		}
+	}
+	if err := state.apply(req.Type, req, k.crdtNode, time.Now().UnixNano()); err != nil {
+		return nil, err
+	}
+
+	m := setMutation(req.Key, state.encode())
+	m.Crdt = req.Type
+	if err := k.checkQuotas(req.Key, m.Value, clientIdentity(ctx)); err != nil {
+		return nil, err
+	}
+	if err := k.commit(ctx, m); err != nil {
+		return nil, err
+	}
+
+	reading := state.read(req.Type)
+	return &proto.CrdtUpdateResponse{
+		Success:  true,
+		Message:  fmt.Sprintf("Key '%s' updated successfully", req.Key),
+		Type:     req.Type,
+		Value:    reading.value,
+		Count:    reading.count,
+		Elements: reading.elements,
+	}, nil
+}
+
+// CrdtGet returns the value of a CRDT key
+func (k *kvStore) CrdtGet(ctx context.Context, req *proto.CrdtGetRequest) (*proto.CrdtGetResponse, error) {
+	k.access.record(req.Key, false)
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	rec, exists := k.data[req.Key]
+	if !exists {
+		return nil, notFound(reasonKeyNotFound, "key", req.Key, fmt.Sprintf("Key '%s' not found", req.Key))
+	}
+	if rec.crdt == proto.CrdtType_CRDT_TYPE_NONE {
+		return nil, failedPrecondition(reasonCrdtTypeMismatch, req.Key, fmt.Sprintf("key '%s' holds a plain value", req.Key), nil)
+	}
+	if !rec.verify(req.Key) {
+		log.Printf("Checksum mismatch for key '%s'", req.Key)
+		return nil, status.Errorf(codes.DataLoss, "key '%s' failed checksum verification", req.Key)
+	}
+	state, err := decodeCrdt(rec.value)
+	if err != nil {
+		return nil, status.Errorf(codes.DataLoss, "key '%s' has an unreadable CRDT state: %v", req.Key, err)
+	}
+
+	reading := state.read(rec.crdt)
+	return &proto.CrdtGetResponse{
+		Success:  true,
+		Message:  fmt.Sprintf("Key '%s' retrieved successfully", req.Key),
+		Type:     rec.crdt,
+		Value:    reading.value,
+		Count:    reading.count,
+		Elements: reading.elements,
+	}, nil
+}
+
+// CrdtSync merges the CRDT states of another server into this server's and
+// returns the result, so that a single call brings both up to date
+func (k *kvStore) CrdtSync(ctx context.Context, req *proto.CrdtSyncRequest) (*proto.CrdtSyncResponse, error) {
+	if k.crdtNode == "" {
+		return nil, failedPrecondition(reasonFeatureDisabled, "crdt", "CRDT mode is not enabled on this server", nil)
+	}
+	if _, err := k.mergeCrdts(ctx, req.Entries); err != nil {
+		return nil, err
+	}
+	return &proto.CrdtSyncResponse{Entries: k.crdtEntries()}, nil
+}
+
+// crdtEntries returns the states of every CRDT key in key order
+func (k *kvStore) crdtEntries() []*proto.CrdtEntry {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var entries []*proto.CrdtEntry
+	for key, rec := range k.data {
+		if rec.crdt != proto.CrdtType_CRDT_TYPE_NONE {
+			entries = append(entries, &proto.CrdtEntry{Key: key, Type: rec.crdt, State: rec.value})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries
+}
+
+// mergeCrdts merges states received from another server into the store and
+// returns the number of keys that changed. A state replaces a plain value
+// under the same key. When two servers declared a key as different types,
+// the type listed first in CrdtType wins everywhere.
+func (k *kvStore) mergeCrdts(ctx context.Context, entries []*proto.CrdtEntry) (int, error) {
+	remote := make([]*crdtState, len(entries))
+	for i, entry := range entries {
+		if _, ok := proto.CrdtType_name[int32(entry.Type)]; !ok || entry.Type == proto.CrdtType_CRDT_TYPE_NONE {
+			return 0, status.Errorf(codes.InvalidArgument, "key '%s' has no CRDT type", entry.Key)
+		}
+		var err error
+		if remote[i], err = decodeCrdt(entry.State); err != nil {
+			return 0, status.Errorf(codes.InvalidArgument, "key '%s' has an unreadable CRDT state: %v", entry.Key, err)
+		}
+	}
+
+	k.lockWrite()
+	defer k.unlockWrite()
+
+	var mutations []*proto.Mutation
+	for i, entry := range entries {
+		merged, t := remote[i], entry.Type
+		if rec, exists := k.data[entry.Key]; exists && rec.crdt != proto.CrdtType_CRDT_TYPE_NONE {
+			if rec.crdt < t {
+				continue
+			}
+			if rec.crdt == t {
+				local, err := decodeCrdt(rec.value)
+				if err != nil {
+					return 0, status.Errorf(codes.DataLoss, "key '%s' has an unreadable CRDT state: %v", entry.Key, err)
+				}
+				merged = mergeCrdt(t, local, remote[i])
+			}
+		}
+
+		m := setMutation(entry.Key, merged.encode())
+		m.Crdt = t
+		if rec, exists := k.data[entry.Key]; exists && rec.crdt == t && rec.value == m.Value {
+			continue
+		}
+		mutations = append(mutations, m)
+	}
+	if len(mutations) == 0 {
+		return 0, nil
+	}
+	if err := k.commit(ctx, mutations...); err != nil {
+		return 0, err
+	}
+	return len(mutations), nil
+}
+
+// startCrdtSync starts exchanging CRDT states with every peer each
+// interval in the background, until ctx is cancelled
+func (k *kvStore) startCrdtSync(ctx context.Context, peers []string, interval time.Duration) error {
+	conns := make([]*grpc.ClientConn, len(peers))
+	for i, peer := range peers {
+		conn, err := grpc.NewClient(peer, grpc.WithTransportCredentials(peerCredentials))
+		if err != nil {
+			return fmt.Errorf("invalid CRDT peer address %q: %v", peer, err)
+		}
+		conns[i] = conn
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				for _, conn := range conns {
+					conn.Close()
+				}
+				return
+			}
+
+			for i, conn := range conns {
+				if err := k.syncCrdts(ctx, proto.NewKeyValueStoreClient(conn)); err != nil && ctx.Err() == nil {
+					log.Printf("CRDT sync with %s failed: %v", peers[i], err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// syncCrdts sends this server's CRDT states to a peer and merges the
+// peer's states in return
+func (k *kvStore) syncCrdts(ctx context.Context, client proto.KeyValueStoreClient) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	resp, err := client.CrdtSync(ctx, &proto.CrdtSyncRequest{Node: k.crdtNode, Entries: k.crdtEntries()})
+	if err != nil {
+		return err
+	}
+	_, err = k.mergeCrdts(ctx, resp.Entries)
+	return err
+}
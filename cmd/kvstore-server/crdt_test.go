@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newCrdtStore returns a store in CRDT mode as node
+func newCrdtStore(node string) *kvStore {
+	k := NewKVStore()
+	k.crdtNode = node
+	return k
+}
+
+// exchange syncs the CRDT states of two stores as a peer's sync round does
+func exchange(t *testing.T, a, b *kvStore) {
+	t.Helper()
+
+	resp, err := a.CrdtSync(context.Background(), &proto.CrdtSyncRequest{Node: b.crdtNode, Entries: b.crdtEntries()})
+	if err != nil {
+		t.Fatalf("CrdtSync() error = %v", err)
+	}
+	if _, err := b.mergeCrdts(context.Background(), resp.Entries); err != nil {
+		t.Fatalf("mergeCrdts() error = %v", err)
+	}
+}
+
+func TestCrdt_ConcurrentUpdatesConverge(t *testing.T) {
+	type update struct {
+		site int
+		op   proto.CrdtOp
+		arg  string
+		n    uint64
+	}
+	tests := []struct {
+		name     string
+		crdt     proto.CrdtType
+		before   []update // applied and synced before the concurrent updates
+		updates  []update
+		value    string
+		elements []string
+	}{
+		{
+			name:    "LWW register keeps the last write",
+			crdt:    proto.CrdtType_CRDT_TYPE_LWW_REGISTER,
+			updates: []update{{0, proto.CrdtOp_CRDT_OP_ASSIGN, "first", 0}, {1, proto.CrdtOp_CRDT_OP_ASSIGN, "second", 0}},
+			value:   "second",
+		},
+		{
+			name:    "G-counter adds every site's increments",
+			crdt:    proto.CrdtType_CRDT_TYPE_G_COUNTER,
+			updates: []update{{0, proto.CrdtOp_CRDT_OP_INCREMENT, "", 2}, {1, proto.CrdtOp_CRDT_OP_INCREMENT, "", 3}, {0, proto.CrdtOp_CRDT_OP_INCREMENT, "", 1}},
+			value:   "6",
+		},
+		{
+			name:    "PN-counter subtracts decrements",
+			crdt:    proto.CrdtType_CRDT_TYPE_PN_COUNTER,
+			updates: []update{{0, proto.CrdtOp_CRDT_OP_INCREMENT, "", 5}, {1, proto.CrdtOp_CRDT_OP_DECREMENT, "", 7}},
+			value:   "-2",
+		},
+		{
+			name:     "OR-set keeps an addition the removal did not see",
+			crdt:     proto.CrdtType_CRDT_TYPE_OR_SET,
+			before:   []update{{0, proto.CrdtOp_CRDT_OP_ADD, "x", 0}, {0, proto.CrdtOp_CRDT_OP_ADD, "y", 0}, {0, proto.CrdtOp_CRDT_OP_ADD, "z", 0}},
+			updates:  []update{{1, proto.CrdtOp_CRDT_OP_REMOVE, "x", 0}, {0, proto.CrdtOp_CRDT_OP_ADD, "x", 0}, {1, proto.CrdtOp_CRDT_OP_REMOVE, "y", 0}},
+			value:    `["x","z"]`,
+			elements: []string{"x", "z"},
+		},
+		{
+			name:     "OR-set re-adds an element to an emptied set",
+			crdt:     proto.CrdtType_CRDT_TYPE_OR_SET,
+			before:   []update{{0, proto.CrdtOp_CRDT_OP_ADD, "x", 0}, {0, proto.CrdtOp_CRDT_OP_REMOVE, "x", 0}},
+			updates:  []update{{0, proto.CrdtOp_CRDT_OP_ADD, "x", 0}},
+			value:    `["x"]`,
+			elements: []string{"x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			sites := []*kvStore{newCrdtStore("site-a"), newCrdtStore("site-b")}
+			apply := func(updates []update) {
+				for _, u := range updates {
+					_, err := sites[u.site].CrdtUpdate(ctx, &proto.CrdtUpdateRequest{Key: "k", Type: tt.crdt, Op: u.op, Value: u.arg, Amount: u.n})
+					if err != nil {
+						t.Fatalf("CrdtUpdate(%v) error = %v", u.op, err)
+					}
+					time.Sleep(time.Millisecond) // order the register's writes
+				}
+			}
+
+			apply(tt.before)
+			exchange(t, sites[0], sites[1])
+			apply(tt.updates)
+			exchange(t, sites[0], sites[1])
+
+			for i, site := range sites {
+				resp, err := site.CrdtGet(ctx, &proto.CrdtGetRequest{Key: "k"})
+				if err != nil {
+					t.Fatalf("CrdtGet() error = %v", err)
+				}
+				if resp.Value != tt.value || !slices.Equal(resp.Elements, tt.elements) {
+					t.Errorf("site %d CrdtGet() = %v, expected %s", i, resp, tt.value)
+				}
+				if got, _ := site.Get(ctx, &proto.GetRequest{Key: "k"}); got.Value != tt.value {
+					t.Errorf("site %d Get() = %q, expected %q", i, got.Value, tt.value)
+				}
+			}
+			if a, b := sites[0].crdtEntries(), sites[1].crdtEntries(); a[0].State != b[0].State {
+				t.Errorf("sites hold different states:\n%s\n%s", a[0].State, b[0].State)
+			}
+
+			// Syncing again changes nothing
+			revision := sites[0].currentRevision()
+			exchange(t, sites[1], sites[0])
+			if sites[0].currentRevision() != revision {
+				t.Error("a second sync committed changes")
+			}
+		})
+	}
+}
+
+func TestCrdt_Errors(t *testing.T) {
+	ctx := context.Background()
+	k := newCrdtStore("site-a")
+	k.Set(ctx, &proto.SetRequest{Key: "plain", Value: "v"})
+	k.CrdtUpdate(ctx, &proto.CrdtUpdateRequest{Key: "counter", Type: proto.CrdtType_CRDT_TYPE_G_COUNTER, Op: proto.CrdtOp_CRDT_OP_INCREMENT, Amount: 1})
+
+	tests := []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"CRDT mode disabled", func() error {
+			_, err := NewKVStore().CrdtUpdate(ctx, &proto.CrdtUpdateRequest{Key: "k", Type: proto.CrdtType_CRDT_TYPE_G_COUNTER})
+			return err
+		}, codes.FailedPrecondition},
+		{"Missing type", func() error {
+			_, err := k.CrdtUpdate(ctx, &proto.CrdtUpdateRequest{Key: "k"})
+			return err
+		}, codes.InvalidArgument},
+		{"Operation of another type", func() error {
+			_, err := k.CrdtUpdate(ctx, &proto.CrdtUpdateRequest{Key: "counter", Type: proto.CrdtType_CRDT_TYPE_G_COUNTER, Op: proto.CrdtOp_CRDT_OP_DECREMENT})
+			return err
+		}, codes.InvalidArgument},
+		{"Type differs from the declared one", func() error {
+			_, err := k.CrdtUpdate(ctx, &proto.CrdtUpdateRequest{Key: "counter", Type: proto.CrdtType_CRDT_TYPE_PN_COUNTER, Op: proto.CrdtOp_CRDT_OP_INCREMENT})
+			return err
+		}, codes.FailedPrecondition},
+		{"CRDT update of a plain value", func() error {
+			_, err := k.CrdtUpdate(ctx, &proto.CrdtUpdateRequest{Key: "plain", Type: proto.CrdtType_CRDT_TYPE_G_COUNTER, Op: proto.CrdtOp_CRDT_OP_INCREMENT})
+			return err
+		}, codes.FailedPrecondition},
+		{"CRDT read of a plain value", func() error {
+			_, err := k.CrdtGet(ctx, &proto.CrdtGetRequest{Key: "plain"})
+			return err
+		}, codes.FailedPrecondition},
+		{"Plain write of a CRDT", func() error {
+			_, err := k.Set(ctx, &proto.SetRequest{Key: "counter", Value: "7"})
+			return err
+		}, codes.FailedPrecondition},
+		{"Delete of a CRDT", func() error {
+			_, err := k.Delete(ctx, &proto.DeleteRequest{Key: "counter"})
+			return err
+		}, codes.FailedPrecondition},
+		{"Sync of an untyped state", func() error {
+			_, err := k.CrdtSync(ctx, &proto.CrdtSyncRequest{Entries: []*proto.CrdtEntry{{Key: "k", State: "{}"}}})
+			return err
+		}, codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); status.Code(err) != tt.want {
+				t.Errorf("error = %v, expected %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrdt_SyncWithPeers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, b := newCrdtStore("site-a"), newCrdtStore("site-b")
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterKeyValueStoreServer(server, b)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	// A plain value is replaced by a CRDT state from another site
+	b.Set(ctx, &proto.SetRequest{Key: "visits", Value: "stale"})
+	a.CrdtUpdate(ctx, &proto.CrdtUpdateRequest{Key: "visits", Type: proto.CrdtType_CRDT_TYPE_PN_COUNTER, Op: proto.CrdtOp_CRDT_OP_INCREMENT, Amount: 4})
+	b.CrdtUpdate(ctx, &proto.CrdtUpdateRequest{Key: "tags", Type: proto.CrdtType_CRDT_TYPE_OR_SET, Op: proto.CrdtOp_CRDT_OP_ADD, Value: "red"})
+
+	if err := a.startCrdtSync(ctx, []string{lis.Addr().String()}, 20*time.Millisecond); err != nil {
+		t.Fatalf("startCrdtSync() error = %v", err)
+	}
+	waitFor(t, "the sites to sync", func() bool {
+		visits, _ := b.Get(ctx, &proto.GetRequest{Key: "visits"})
+		tags, _ := a.Get(ctx, &proto.GetRequest{Key: "tags"})
+		return visits.Value == "4" && tags.Value == `["red"]`
+	})
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Reasons reported in the ErrorInfo of a request for something that does
+// not exist, or that the server cannot do in its current state
+const (
+	reasonKeyNotFound        = "KEY_NOT_FOUND"
+	reasonDeletedKeyNotFound = "DELETED_KEY_NOT_FOUND"
+	reasonSchemaNotFound     = "SCHEMA_NOT_FOUND"
+	reasonHistoryNotFound    = "HISTORY_NOT_FOUND"
+	reasonAlreadyPrimary     = "ALREADY_PRIMARY"
+	reasonReadOnlyReplica    = "READ_ONLY_REPLICA"
+	reasonFeatureDisabled    = "FEATURE_DISABLED"
+	reasonCrdtTypeMismatch   = "CRDT_TYPE_MISMATCH"
+)
+
+// legacyReasons are the errors that servers used to report as a response
+// with success=false, and still do in legacy status mode
+var legacyReasons = map[string]bool{
+	reasonKeyNotFound:        true,
+	reasonDeletedKeyNotFound: true,
+	reasonSchemaNotFound:     true,
+	reasonHistoryNotFound:    true,
+	reasonAlreadyPrimary:     true,
+}
+
+// notFound builds a NotFound error naming the missing resource
+func notFound(reason, resourceType, name, description string) error {
+	st, err := status.New(codes.NotFound, description).WithDetails(
+		&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain},
+		&errdetails.ResourceInfo{ResourceType: resourceType, ResourceName: name, Description: description},
+	)
+	if err != nil {
+		return status.Error(codes.NotFound, description)
+	}
+	return st.Err()
+}
+
+// failedPrecondition builds a FailedPrecondition error for a request the
+// server cannot serve in its current state. metadata is reported in the
+// ErrorInfo.
+func failedPrecondition(reason, subject, description string, metadata map[string]string) error {
+	st, err := status.New(codes.FailedPrecondition, description).WithDetails(
+		&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain, Metadata: metadata},
+		&errdetails.PreconditionFailure{Violations: []*errdetails.PreconditionFailure_Violation{
+			{Type: reason, Subject: subject, Description: description},
+		}},
+	)
+	if err != nil {
+		return status.Error(codes.FailedPrecondition, description)
+	}
+	return st.Err()
+}
+
+// legacyStatus is a unary interceptor for servers in legacy status mode.
+// It turns the errors clients written before canonical status codes expect
+// as a response with success=false back into one: the method's response,
+// with its message field set to the error's and the ErrorInfo metadata in
+// the fields of the same name.
+func legacyStatus(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err == nil {
+		return resp, nil
+	}
+	st := status.Convert(err)
+	var reason *errdetails.ErrorInfo
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*errdetails.ErrorInfo); ok && legacyReasons[d.Reason] {
+			reason = d
+		}
+	}
+	if reason == nil {
+		return nil, err
+	}
+
+	// The response type is found from the method's descriptor
+	name := strings.ReplaceAll(strings.TrimPrefix(info.FullMethod, "/"), "/", ".")
+	desc, lookupErr := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if lookupErr != nil {
+		return nil, err
+	}
+	method, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, err
+	}
+	msgType, lookupErr := protoregistry.GlobalTypes.FindMessageByName(method.Output().FullName())
+	if lookupErr != nil {
+		return nil, err
+	}
+
+	msg := msgType.New()
+	fields := msg.Descriptor().Fields()
+	if field := fields.ByName("message"); field != nil && field.Kind() == protoreflect.StringKind {
+		msg.Set(field, protoreflect.ValueOfString(st.Message()))
+	}
+	for key, value := range reason.Metadata {
+		field := fields.ByName(protoreflect.Name(key))
+		if field == nil || field.Cardinality() == protoreflect.Repeated {
+			continue
+		}
+		switch field.Kind() {
+		case protoreflect.StringKind:
+			msg.Set(field, protoreflect.ValueOfString(value))
+		case protoreflect.Uint64Kind:
+			if n, parseErr := strconv.ParseUint(value, 10, 64); parseErr == nil {
+				msg.Set(field, protoreflect.ValueOfUint64(n))
+			}
+		}
+	}
+	return msg.Interface(), nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrors_Details(t *testing.T) {
+	ctx := context.Background()
+	store := NewKVStore()
+	store.crdtNode = "site-a"
+	store.Set(ctx, &proto.SetRequest{Key: "plain", Value: "v"})
+
+	tests := []struct {
+		name     string
+		call     func() error
+		code     codes.Code
+		reason   string
+		resource string // name of the missing resource, for NotFound
+		subject  string // subject of the violated precondition, for FailedPrecondition
+	}{
+		{"Get of a missing key", func() error {
+			_, err := store.Get(ctx, &proto.GetRequest{Key: "missing"})
+			return err
+		}, codes.NotFound, reasonKeyNotFound, "missing", ""},
+		{"Delete of a missing key", func() error {
+			_, err := store.Delete(ctx, &proto.DeleteRequest{Key: "missing"})
+			return err
+		}, codes.NotFound, reasonKeyNotFound, "missing", ""},
+		{"Delete of a missing schema", func() error {
+			_, err := store.DeleteSchema(ctx, &proto.DeleteSchemaRequest{Prefix: "app/"})
+			return err
+		}, codes.NotFound, reasonSchemaNotFound, "app/", ""},
+		{"Undelete with soft delete disabled", func() error {
+			_, err := store.Undelete(ctx, &proto.UndeleteRequest{Key: "missing"})
+			return err
+		}, codes.FailedPrecondition, reasonFeatureDisabled, "", "soft-delete"},
+		{"CRDT read of a plain value", func() error {
+			_, err := store.CrdtGet(ctx, &proto.CrdtGetRequest{Key: "plain"})
+			return err
+		}, codes.FailedPrecondition, reasonCrdtTypeMismatch, "", "plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(tt.call())
+			if st.Code() != tt.code {
+				t.Fatalf("error = %v, expected %v", st.Err(), tt.code)
+			}
+
+			var reason, resource, subject string
+			for _, detail := range st.Details() {
+				switch d := detail.(type) {
+				case *errdetails.ErrorInfo:
+					reason = d.Reason
+				case *errdetails.ResourceInfo:
+					resource = d.ResourceName
+				case *errdetails.PreconditionFailure:
+					subject = d.Violations[0].Subject
+				}
+			}
+			if reason != tt.reason || resource != tt.resource || subject != tt.subject {
+				t.Errorf("details = reason %q, resource %q, subject %q", reason, resource, subject)
+			}
+		})
+	}
+}
+
+func TestLegacyStatus(t *testing.T) {
+	ctx := context.Background()
+	store := NewKVStore()
+	store.limits.maxValueBytes = 4
+
+	ts := startTestServer(t, store,
+		withServerOptions(grpc.ChainUnaryInterceptor(legacyStatus)),
+		withService(func(s *grpc.Server) {
+			proto.RegisterReplicationServer(s, &replicationServer{store: store})
+		}),
+	)
+	client, conn := ts.KeyValueStoreClient, ts.conn
+	store.Set(ctx, &proto.SetRequest{Key: "a", Value: "1"})
+
+	// Missing keys are unsuccessful responses again
+	get, err := client.Get(ctx, &proto.GetRequest{Key: "missing"})
+	if err != nil || get.Success || get.Message != "Key 'missing' not found" {
+		t.Errorf("Get() of a missing key = %v, %v", get, err)
+	}
+	del, err := client.Delete(ctx, &proto.DeleteRequest{Key: "missing"})
+	if err != nil || del.Success {
+		t.Errorf("Delete() of a missing key = %v, %v", del, err)
+	}
+	promote, err := proto.NewReplicationClient(conn).Promote(ctx, &proto.PromoteRequest{})
+	if err != nil || promote.Success || promote.Revision != 1 {
+		t.Errorf("Promote() of a primary = %v, %v", promote, err)
+	}
+
+	// Other errors are unchanged
+	if _, err := client.Set(ctx, &proto.SetRequest{Key: "b", Value: "too long"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Set() error = %v, expected InvalidArgument", err)
+	}
+	if _, err := client.History(ctx, &proto.HistoryRequest{Key: "a"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("History() error = %v, expected FailedPrecondition", err)
+	}
+}
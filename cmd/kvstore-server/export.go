@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// exportFlushSize is the number of bytes buffered before an export chunk is sent
+	exportFlushSize = 32 * 1024
+
+	// importBatchSize is the number of rows committed together, and
+	// processed between progress reports
+	importBatchSize = 500
+
+	// maxImportLineBytes bounds a JSON Lines row, line break included, so
+	// that a document without line breaks cannot exhaust memory
+	maxImportLineBytes = 16 << 20
+)
+
+// csvHeader is the header row written on export and skipped on import
+var csvHeader = []string{"key", "value"}
+
+// jsonRow is the JSON Lines representation of a key-value pair. Pointers are
+// used so that a missing field can be told apart from an empty one.
+type jsonRow struct {
+	Key   *string `json:"key"`
+	Value *string `json:"value"`
+}
+
+// Export streams the keyspace, or the keys under a prefix, in key order
+func (k *kvStore) Export(req *proto.ExportRequest, stream grpc.ServerStreamingServer[proto.ExportChunk]) error {
+	if req.Format != proto.DataFormat_DATA_FORMAT_JSONL && req.Format != proto.DataFormat_DATA_FORMAT_CSV {
+		return status.Errorf(codes.InvalidArgument, "unknown export format %v", req.Format)
+	}
+
+	snap := k.snapshot()
+	keys := make([]string, 0, len(snap))
+	for key := range snap {
+		if strings.HasPrefix(key, req.Prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	csvWriter := csv.NewWriter(&buf)
+	flush := func() error {
+		csvWriter.Flush()
+		if buf.Len() == 0 {
+			return nil
+		}
+		err := stream.Send(&proto.ExportChunk{Data: bytes.Clone(buf.Bytes())})
+		buf.Reset()
+		return err
+	}
+
+	if req.Format == proto.DataFormat_DATA_FORMAT_CSV {
+		csvWriter.Write(csvHeader)
+	}
+
+	for _, key := range keys {
+		rec := snap[key]
+		if !rec.verify(key) {
+			return status.Errorf(codes.DataLoss, "key '%s' failed checksum verification", key)
+		}
+
+		if req.Format == proto.DataFormat_DATA_FORMAT_CSV {
+			csvWriter.Write([]string{key, rec.value})
+		} else {
+			line, err := json.Marshal(jsonRow{Key: &key, Value: &rec.value})
+			if err != nil {
+				return status.Errorf(codes.Internal, "failed to encode key '%s': %v", key, err)
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+
+		if buf.Len() >= exportFlushSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	log.Printf("Exported %d keys with prefix '%s' as %v", len(keys), req.Prefix, req.Format)
+	return nil
+}
+
+// rowError describes a malformed row in an import document
+type rowError struct {
+	line int64
+	msg  string
+}
+
+func (e *rowError) Error() string { return e.msg }
+
+// rowReader yields key-value rows from an import document
+type rowReader interface {
+	// next returns the next row and the line it starts on. Malformed rows
+	// are reported as a *rowError; any other error ends the import.
+	next() (line int64, key, value string, err error)
+}
+
+// jsonlReader reads rows from a JSON Lines document
+type jsonlReader struct {
+	r       *bufio.Reader
+	line    int64
+	maxLine int // longest line accepted, in bytes
+}
+
+func newJSONLReader(r io.Reader) *jsonlReader {
+	return &jsonlReader{r: bufio.NewReader(r), maxLine: maxImportLineBytes}
+}
+
+// readLine returns the next line. A line longer than maxLine is skipped
+// without being held in memory, and reported as too long.
+func (j *jsonlReader) readLine() (line []byte, tooLong bool, err error) {
+	for {
+		chunk, err := j.r.ReadSlice('\n')
+		if !tooLong {
+			if len(line)+len(chunk) > j.maxLine {
+				line, tooLong = nil, true
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && (len(line) > 0 || tooLong):
+			return line, tooLong, nil
+		case err != nil:
+			return nil, false, err
+		}
+		return line, tooLong, nil
+	}
+}
+
+func (j *jsonlReader) next() (int64, string, string, error) {
+	for {
+		raw, tooLong, err := j.readLine()
+		if err != nil {
+			return 0, "", "", err
+		}
+		j.line++
+		if tooLong {
+			return j.line, "", "", &rowError{line: j.line, msg: fmt.Sprintf("line exceeds %d bytes", j.maxLine)}
+		}
+
+		text := strings.TrimSpace(string(raw))
+		if text == "" {
+			continue
+		}
+
+		var row jsonRow
+		if err := json.Unmarshal([]byte(text), &row); err != nil {
+			return j.line, "", "", &rowError{line: j.line, msg: "invalid JSON: " + err.Error()}
+		}
+		if row.Key == nil || row.Value == nil {
+			return j.line, "", "", &rowError{line: j.line, msg: "row must have both 'key' and 'value'"}
+		}
+		return j.line, *row.Key, *row.Value, nil
+	}
+}
+
+// csvReader reads rows from a CSV document with an optional key,value header
+type csvReader struct {
+	r     *csv.Reader
+	first bool
+}
+
+func newCSVReader(r io.Reader) *csvReader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	return &csvReader{r: reader, first: true}
+}
+
+func (c *csvReader) next() (int64, string, string, error) {
+	for {
+		fields, err := c.r.Read()
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return int64(parseErr.StartLine), "", "", &rowError{line: int64(parseErr.StartLine), msg: parseErr.Err.Error()}
+			}
+			return 0, "", "", err
+		}
+		line, _ := c.r.FieldPos(0)
+
+		isHeader := c.first && len(fields) == 2 && fields[0] == csvHeader[0] && fields[1] == csvHeader[1]
+		c.first = false
+		if isHeader {
+			continue
+		}
+
+		if len(fields) != 2 {
+			return int64(line), "", "", &rowError{line: int64(line), msg: "row must have exactly 2 fields"}
+		}
+		return int64(line), fields[0], fields[1], nil
+	}
+}
+
+// importRow is a row waiting to be committed with the rest of its batch
+type importRow struct {
+	line       int64
+	key, value string
+}
+
+// Import bulk loads rows streamed by the client. Every row is subject to
+// the same rules as a Set, and each batch of rows is committed at once;
+// rows that are malformed or rejected are reported back instead of
+// aborting the import.
+func (k *kvStore) Import(stream grpc.BidiStreamingServer[proto.ImportRequest, proto.ImportProgress]) error {
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "import stream was empty")
+	}
+	if err != nil {
+		return err
+	}
+
+	// Feed the document through a pipe so the row readers see one continuous stream
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		data := first.Data
+		for {
+			if _, err := pw.Write(data); err != nil {
+				return
+			}
+			req, err := stream.Recv()
+			if err == io.EOF {
+				pw.Close()
+				return
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			data = req.Data
+		}
+	}()
+
+	var rows rowReader
+	switch first.Format {
+	case proto.DataFormat_DATA_FORMAT_JSONL:
+		rows = newJSONLReader(pr)
+	case proto.DataFormat_DATA_FORMAT_CSV:
+		rows = newCSVReader(pr)
+	default:
+		return status.Errorf(codes.InvalidArgument, "unknown import format %v", first.Format)
+	}
+
+	progress := &proto.ImportProgress{}
+	fail := func(line int64, msg string) {
+		progress.RowsFailed++
+		progress.Errors = append(progress.Errors, &proto.ImportError{Line: line, Error: msg})
+	}
+
+	for done := false; !done; {
+		var batch []importRow
+		for read := 0; read < importBatchSize; read++ {
+			line, key, value, err := rows.next()
+			if err == io.EOF {
+				done = true
+				break
+			}
+			var rowErr *rowError
+			if errors.As(err, &rowErr) {
+				progress.RowsRead++
+				fail(rowErr.line, rowErr.msg)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			progress.RowsRead++
+			k.access.record(key, true)
+			if err := k.limits.validate(key, value); err != nil {
+				fail(line, status.Convert(err).Message())
+				continue
+			}
+			batch = append(batch, importRow{line: line, key: key, value: value})
+		}
+
+		progress.RowsImported += k.importBatch(stream.Context(), batch, fail)
+		sort.SliceStable(progress.Errors, func(i, j int) bool { return progress.Errors[i].Line < progress.Errors[j].Line })
+		if !done {
+			if err := stream.Send(progress); err != nil {
+				return err
+			}
+			progress.Errors = nil
+		}
+	}
+
+	progress.Done = true
+	log.Printf("Import finished: %d rows read, %d imported, %d failed", progress.RowsRead, progress.RowsImported, progress.RowsFailed)
+	return stream.Send(progress)
+}
+
+// importBatch checks a batch of rows as Set would, each against the store
+// as the rows before it leave it, and commits the accepted ones together.
+// Rejected rows are passed to fail; it returns the number committed.
+func (k *kvStore) importBatch(ctx context.Context, batch []importRow, fail func(line int64, msg string)) int64 {
+	if len(batch) == 0 {
+		return 0
+	}
+	client := clientIdentity(ctx)
+
+	k.lockWrite()
+	defer k.unlockWrite()
+
+	// Quota usage is updated as if each accepted row were already written,
+	// then put back for the commit to apply them for real
+	saved := k.saveUsage()
+	written := make(map[string]record)
+	var accepted []importRow
+	var mutations []*proto.Mutation
+	for _, row := range batch {
+		old, exists := written[row.key]
+		if !exists {
+			old, exists = k.data[row.key]
+		}
+		err := k.checkSchema(row.key, row.value)
+		if err == nil && old.crdt != proto.CrdtType_CRDT_TYPE_NONE {
+			err = notCrdt(row.key, old.crdt)
+		}
+		if err == nil {
+			err = k.checkQuotasReplacing(row.key, row.value, client, old, exists)
+		}
+		if err != nil {
+			fail(row.line, status.Convert(err).Message())
+			continue
+		}
+
+		rec := record{value: row.value, owner: client}
+		if exists {
+			k.trackUsage(row.key, old, -1)
+		}
+		k.trackUsage(row.key, rec, 1)
+		written[row.key] = rec
+		accepted = append(accepted, row)
+		mutations = append(mutations, setMutation(row.key, row.value))
+	}
+	k.restoreUsage(saved)
+
+	if len(mutations) == 0 {
+		return 0
+	}
+	if err := k.commit(ctx, mutations...); err != nil {
+		msg := status.Convert(err).Message()
+		for _, row := range accepted {
+			fail(row.line, msg)
+		}
+		return 0
+	}
+	return int64(len(mutations))
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pwntato/Censys/proto"
+)
+
+// export runs an export and returns the whole document
+func export(t *testing.T, client proto.KeyValueStoreClient, format proto.DataFormat, prefix string) string {
+	t.Helper()
+
+	stream, err := client.Export(context.Background(), &proto.ExportRequest{Format: format, Prefix: prefix})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	var doc strings.Builder
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return doc.String()
+		}
+		if err != nil {
+			t.Fatalf("Export() stream error = %v", err)
+		}
+		doc.Write(chunk.Data)
+	}
+}
+
+// importDoc streams a document into the store in small pieces and returns
+// every progress message received
+func importDoc(t *testing.T, client proto.KeyValueStoreClient, format proto.DataFormat, doc string) []*proto.ImportProgress {
+	t.Helper()
+
+	stream, err := client.Import(context.Background())
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+
+	go func() {
+		// Split mid-row to make sure rows spanning messages are reassembled
+		data := []byte(doc)
+		stream.Send(&proto.ImportRequest{Format: format})
+		for len(data) > 0 {
+			n := min(7, len(data))
+			stream.Send(&proto.ImportRequest{Data: data[:n]})
+			data = data[n:]
+		}
+		stream.CloseSend()
+	}()
+
+	var progress []*proto.ImportProgress
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return progress
+		}
+		if err != nil {
+			t.Fatalf("Import() stream error = %v", err)
+		}
+		progress = append(progress, msg)
+	}
+}
+
+func TestExport_Formats(t *testing.T) {
+	ctx := context.Background()
+	store := NewKVStore()
+	store.Set(ctx, &proto.SetRequest{Key: "app/b", Value: "two, with comma"})
+	store.Set(ctx, &proto.SetRequest{Key: "app/a", Value: "one"})
+	store.Set(ctx, &proto.SetRequest{Key: "other", Value: "three"})
+	client := startTestServer(t, store)
+
+	jsonl := export(t, client, proto.DataFormat_DATA_FORMAT_JSONL, "app/")
+	expectedJSONL := `{"key":"app/a","value":"one"}` + "\n" + `{"key":"app/b","value":"two, with comma"}` + "\n"
+	if jsonl != expectedJSONL {
+		t.Errorf("JSONL export = %q, expected %q", jsonl, expectedJSONL)
+	}
+
+	csvDoc := export(t, client, proto.DataFormat_DATA_FORMAT_CSV, "")
+	expectedCSV := "key,value\napp/a,one\napp/b,\"two, with comma\"\nother,three\n"
+	if csvDoc != expectedCSV {
+		t.Errorf("CSV export = %q, expected %q", csvDoc, expectedCSV)
+	}
+}
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	source := NewKVStore()
+	for i := 0; i < 1200; i++ {
+		source.Set(ctx, &proto.SetRequest{Key: fmt.Sprintf("key-%04d", i), Value: fmt.Sprintf("line one\nline \"%d\"", i)})
+	}
+	sourceClient := startTestServer(t, source)
+
+	for _, format := range []proto.DataFormat{proto.DataFormat_DATA_FORMAT_JSONL, proto.DataFormat_DATA_FORMAT_CSV} {
+		t.Run(format.String(), func(t *testing.T) {
+			target := NewKVStore()
+			progress := importDoc(t, startTestServer(t, target), format, export(t, sourceClient, format, ""))
+
+			// One report per full batch plus the final one
+			if len(progress) != 3 {
+				t.Errorf("got %d progress messages, expected 3", len(progress))
+			}
+			final := progress[len(progress)-1]
+			if !final.Done || final.RowsImported != 1200 || final.RowsFailed != 0 {
+				t.Errorf("final progress = %v", final)
+			}
+			if len(target.data) != 1200 || target.data["key-0042"].value != source.data["key-0042"].value {
+				t.Errorf("imported store does not match source")
+			}
+		})
+	}
+}
+
+func TestImport_ReportsMalformedRows(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        proto.DataFormat
+		doc           string
+		expectedLines []int64
+	}{
+		{
+			name:   "JSONL",
+			format: proto.DataFormat_DATA_FORMAT_JSONL,
+			doc: `{"key":"a","value":"1"}` + "\n" +
+				`not json` + "\n" +
+				"\n" +
+				`{"value":"missing key"}` + "\n" +
+				`{"key":"b","value":"2"}`,
+			expectedLines: []int64{2, 4},
+		},
+		{
+			name:          "CSV",
+			format:        proto.DataFormat_DATA_FORMAT_CSV,
+			doc:           "key,value\na,1\nonly-one-field\nb,2\nc,\"unterminated\n",
+			expectedLines: []int64{3, 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewKVStore()
+			progress := importDoc(t, startTestServer(t, store), tt.format, tt.doc)
+
+			final := progress[len(progress)-1]
+			if !final.Done || final.RowsImported != 2 || final.RowsFailed != int64(len(tt.expectedLines)) {
+				t.Fatalf("final progress = %v", final)
+			}
+			for i, line := range tt.expectedLines {
+				if final.Errors[i].Line != line {
+					t.Errorf("error %d reported on line %d, expected %d", i, final.Errors[i].Line, line)
+				}
+			}
+			if store.data["a"].value != "1" || store.data["b"].value != "2" {
+				t.Errorf("valid rows were not imported: %v", store.data)
+			}
+		})
+	}
+}
+
+func TestImport_CommitsEachBatchAtOnce(t *testing.T) {
+	dir := t.TempDir()
+	store, err := OpenKVStore(dir, recoveryTarget{})
+	if err != nil {
+		t.Fatalf("OpenKVStore() error = %v", err)
+	}
+	defer store.Close()
+
+	var doc strings.Builder
+	for i := 0; i < 1200; i++ {
+		fmt.Fprintf(&doc, "{\"key\":\"key-%04d\",\"value\":\"%d\"}\n", i, i)
+	}
+	final := importDoc(t, startTestServer(t, store), proto.DataFormat_DATA_FORMAT_JSONL, doc.String())
+	if last := final[len(final)-1]; last.RowsImported != 1200 {
+		t.Fatalf("final progress = %v", last)
+	}
+
+	// Mutations committed together share their commit time
+	commits := make(map[int64]int)
+	if _, err := readMutationLog(filepath.Join(dir, mutationLogFile), func(m *proto.Mutation, end int64) error {
+		commits[m.Timestamp]++
+		return nil
+	}); err != nil {
+		t.Fatalf("readMutationLog() error = %v", err)
+	}
+	if len(commits) != 3 {
+		t.Errorf("import was committed in %d groups, expected 3", len(commits))
+	}
+}
+
+func TestImport_ChecksRowsAgainstEarlierRowsOfTheBatch(t *testing.T) {
+	store := NewKVStore()
+	store.quotas = []*quota{{Name: "q", Prefix: "q/", MaxKeys: 2}}
+
+	doc := "q/a,1\nq/a,2\nq/b,3\nq/c,4\n"
+	final := importDoc(t, startTestServer(t, store), proto.DataFormat_DATA_FORMAT_CSV, doc)
+	last := final[len(final)-1]
+	if last.RowsImported != 3 || last.RowsFailed != 1 || last.Errors[0].Line != 4 {
+		t.Fatalf("final progress = %v", last)
+	}
+	if store.data["q/a"].value != "2" || store.quotas[0].keys != 2 {
+		t.Errorf("store holds q/a = %q with %d keys counted", store.data["q/a"].value, store.quotas[0].keys)
+	}
+}
+
+func TestJSONLReader_LineTooLong(t *testing.T) {
+	doc := `{"key":"a","value":"1"}` + "\n" +
+		`{"key":"b","value":"` + strings.Repeat("x", 100) + `"}` + "\n" +
+		`{"key":"c","value":"3"}`
+	reader := newJSONLReader(strings.NewReader(doc))
+	reader.maxLine = 32
+
+	var keys []string
+	var failed []int64
+	for {
+		line, key, _, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *rowError
+		if errors.As(err, &rowErr) {
+			failed = append(failed, line)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("next() error = %v", err)
+		}
+		keys = append(keys, key)
+	}
+	if strings.Join(keys, ",") != "a,c" || len(failed) != 1 || failed[0] != 2 {
+		t.Errorf("read keys %v, failed lines %v", keys, failed)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pwntato/Censys/internal/certs"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// alwaysServed are the method prefixes answered while the server is not
+// serving, so that it can be probed and inspected during startup
+var alwaysServed = []string{
+	"/" + healthpb.Health_ServiceDesc.ServiceName + "/",
+	"/grpc.reflection.",
+}
+
+// serverHealth reports the health of a gRPC server through the standard
+// grpc.health.v1 service. The server and each of its services are
+// NOT_SERVING until the store has been rebuilt and again once it starts
+// shutting down; meanwhile other requests are refused as Unavailable.
+type serverHealth struct {
+	server  *grpc.Server
+	health  *health.Server
+	serving atomic.Bool
+}
+
+// newServerHealth creates the health service of server, not serving yet
+func newServerHealth() *serverHealth {
+	h := &serverHealth{health: health.NewServer()}
+	h.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	return h
+}
+
+// register adds the health service to server. It must be called once every
+// other service is registered, which are reported on by name.
+func (h *serverHealth) register(server *grpc.Server) {
+	h.server = server
+	for name := range server.GetServiceInfo() {
+		h.health.SetServingStatus(name, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	healthpb.RegisterHealthServer(server, h.health)
+}
+
+// setServing reports the server and every registered service as serving
+func (h *serverHealth) setServing() {
+	h.serving.Store(true)
+	h.health.Resume()
+}
+
+// reportService reports a single service as serving or not while the rest of
+// the server keeps serving, such as the change feed while changes cannot be
+// recorded. Until the server is serving, it is left as not serving.
+func (h *serverHealth) reportService(service string, err error) {
+	if !h.serving.Load() {
+		return
+	}
+	servingStatus := healthpb.HealthCheckResponse_SERVING
+	if err != nil {
+		servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	h.health.SetServingStatus(service, servingStatus)
+}
+
+// shutdown reports the server as not serving for good; requests are refused
+// from then on and health watchers are told so
+func (h *serverHealth) shutdown() {
+	h.serving.Store(false)
+	h.health.Shutdown()
+}
+
+// admit returns the error a request for method is refused with, if any
+func (h *serverHealth) admit(method string) error {
+	if h.serving.Load() {
+		return nil
+	}
+	for _, prefix := range alwaysServed {
+		if strings.HasPrefix(method, prefix) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unavailable, "server is not serving")
+}
+
+// unary is a unary interceptor refusing requests while not serving
+func (h *serverHealth) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := h.admit(info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// stream is a stream interceptor refusing requests while not serving
+func (h *serverHealth) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := h.admit(info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// checkHealth asks the server at addr whether it is serving, for container
+// health checks that cannot run a gRPC client of their own. With TLS, the
+// server is expected to present the certificate the probe presents.
+func checkHealth(addr string, certificates *certs.Reloader) error {
+	creds := insecure.NewCredentials()
+	if certificates != nil {
+		creds = credentials.NewTLS(certificates.ClientConfig(certificates.DNSName()))
+	}
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("server is %v", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+)
+
+// withHealth gates the server's calls on healthCheck and registers the
+// health service. It reports the services registered before it, so it
+// comes after the options that add services.
+func withHealth(healthCheck *serverHealth) testServerOption {
+	return func(c *testServerConfig) {
+		withServerOptions(
+			grpc.ChainUnaryInterceptor(healthCheck.unary),
+			grpc.ChainStreamInterceptor(healthCheck.stream),
+		)(c)
+		withService(healthCheck.register)(c)
+	}
+}
+
+func TestServerHealth(t *testing.T) {
+	ctx := context.Background()
+	store := NewKVStore()
+	store.Set(ctx, &proto.SetRequest{Key: "a", Value: "1"})
+
+	healthCheck := newServerHealth()
+	ts := startTestServer(t, store, withService(func(s *grpc.Server) {
+		proto.RegisterChangeFeedServer(s, &changeFeedServer{store: store})
+		reflection.Register(s)
+	}), withHealth(healthCheck))
+	client, conn := ts.KeyValueStoreClient, ts.conn
+	health := healthpb.NewHealthClient(conn)
+
+	expectStatus := func(t *testing.T, service string, expected healthpb.HealthCheckResponse_ServingStatus) {
+		t.Helper()
+		resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			t.Fatalf("Check(%q) error = %v", service, err)
+		}
+		if resp.Status != expected {
+			t.Errorf("Check(%q) = %v, expected %v", service, resp.Status, expected)
+		}
+	}
+
+	// While starting, only health checks and reflection are answered
+	expectStatus(t, "", healthpb.HealthCheckResponse_NOT_SERVING)
+	expectStatus(t, "kvstore.KeyValueStore", healthpb.HealthCheckResponse_NOT_SERVING)
+	if _, err := client.Get(ctx, &proto.GetRequest{Key: "a"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Get() while starting error = %v, expected Unavailable", err)
+	}
+	changes, err := proto.NewChangeFeedClient(conn).Subscribe(ctx, &proto.ChangeSubscribeRequest{Consumer: "c"})
+	if err == nil {
+		_, err = changes.Recv()
+	}
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Subscribe() while starting error = %v, expected Unavailable", err)
+	}
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		t.Fatalf("ServerReflectionInfo() error = %v", err)
+	}
+	stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("ListServices error = %v", err)
+	}
+	var services []string
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services = append(services, service.Name)
+	}
+	for _, name := range []string{"kvstore.KeyValueStore", "kvstore.ChangeFeed", "grpc.health.v1.Health"} {
+		if !slices.Contains(services, name) {
+			t.Errorf("reflection lists %v, missing %s", services, name)
+		}
+	}
+	stream.CloseSend()
+
+	// Once ready, every service is serving
+	healthCheck.setServing()
+	expectStatus(t, "", healthpb.HealthCheckResponse_SERVING)
+	expectStatus(t, "kvstore.ChangeFeed", healthpb.HealthCheckResponse_SERVING)
+	if get, err := client.Get(ctx, &proto.GetRequest{Key: "a"}); err != nil || get.Value != "1" {
+		t.Errorf("Get() when serving = %v, %v", get, err)
+	}
+	if _, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: "kvstore.Unknown"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Check() of an unknown service error = %v, expected NotFound", err)
+	}
+
+	// The change feed is reported on its own while changes are held back
+	healthCheck.reportService("kvstore.ChangeFeed", errors.New("disk full"))
+	expectStatus(t, "kvstore.ChangeFeed", healthpb.HealthCheckResponse_NOT_SERVING)
+	expectStatus(t, "kvstore.KeyValueStore", healthpb.HealthCheckResponse_SERVING)
+	healthCheck.reportService("kvstore.ChangeFeed", nil)
+	expectStatus(t, "kvstore.ChangeFeed", healthpb.HealthCheckResponse_SERVING)
+	// Shutting down stops serving again
+	healthCheck.shutdown()
+	expectStatus(t, "", healthpb.HealthCheckResponse_NOT_SERVING)
+	if _, err := client.Get(ctx, &proto.GetRequest{Key: "a"}); status.Code(err) != codes.Unavailable {
+		t.Errorf("Get() when shutting down error = %v, expected Unavailable", err)
+	}
+}
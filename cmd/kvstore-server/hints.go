@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Defaults for hinted handoff
+const (
+	// defaultMaxHints bounds the hints held for all replicas together
+	defaultMaxHints = 10000
+
+	// defaultHintTTL is how long a hint is kept for a replica that stays
+	// unreachable; after that the replica is left to read repair
+	defaultHintTTL = 3 * time.Hour
+
+	// defaultHintReplayInterval is how often delivery of held hints is
+	// attempted
+	defaultHintReplayInterval = 10 * time.Second
+)
+
+// hint is a write or delete held for a replica that could not be reached
+type hint struct {
+	key      string
+	value    string
+	version  []*proto.VersionEntry
+	delete   bool
+	storedAt time.Time
+}
+
+// hintStore holds writes for unreachable replicas, by replica address, and
+// delivers them in the order they were received once the replicas can be
+// reached again. Hints are only kept in memory and are lost on restart.
+type hintStore struct {
+	maxHints int
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	hints   map[string][]*hint
+	pending int
+	conns   map[string]*grpc.ClientConn
+
+	// Statistics since the server started
+	stored    int64
+	delivered int64
+	expired   int64
+	dropped   int64
+}
+
+// newHintStore creates a hint store holding at most maxHints hints for at
+// most ttl each
+func newHintStore(maxHints int, ttl time.Duration) *hintStore {
+	return &hintStore{
+		maxHints: maxHints,
+		ttl:      ttl,
+		hints:    make(map[string][]*hint),
+		conns:    make(map[string]*grpc.ClientConn),
+	}
+}
+
+// add queues a hint for the replica at target, refusing it when the store
+// is full
+func (h *hintStore) add(target string, hn *hint) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.pending >= h.maxHints {
+		h.dropped++
+		return status.Errorf(codes.ResourceExhausted, "hint storage is full (%d hints)", h.maxHints)
+	}
+	h.hints[target] = append(h.hints[target], hn)
+	h.pending++
+	h.stored++
+	return nil
+}
+
+// expire drops the hints stored before now minus the TTL and returns how
+// many were dropped
+func (h *hintStore) expire(now time.Time) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	cutoff := now.Add(-h.ttl)
+	expired := 0
+	for target, queue := range h.hints {
+		// Hints are queued in the order they were stored
+		n := sort.Search(len(queue), func(i int) bool { return queue[i].storedAt.After(cutoff) })
+		if n == len(queue) {
+			delete(h.hints, target)
+		} else {
+			h.hints[target] = queue[n:]
+		}
+		expired += n
+	}
+	h.pending -= expired
+	h.expired += int64(expired)
+	return expired
+}
+
+// client returns a client for the replica at target, connecting on first
+// use
+func (h *hintStore) client(target string) (proto.KeyValueStoreClient, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	conn, ok := h.conns[target]
+	if !ok {
+		var err error
+		conn, err = grpc.NewClient(target, grpc.WithTransportCredentials(peerCredentials))
+		if err != nil {
+			return nil, err
+		}
+		h.conns[target] = conn
+	}
+	return proto.NewKeyValueStoreClient(conn), nil
+}
+
+// deliver sends the hints held for target, oldest first, until one cannot
+// be delivered because the replica is still unreachable. A hint the
+// replica refuses, such as a write concurrent with its own version, is
+// dropped. It returns the number of hints delivered.
+func (h *hintStore) deliver(ctx context.Context, target string) (int, error) {
+	client, err := h.client(target)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for {
+		h.mu.Lock()
+		queue := h.hints[target]
+		h.mu.Unlock()
+		if len(queue) == 0 {
+			return delivered, nil
+		}
+		next := queue[0]
+
+		sendCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		if next.delete {
+			_, err = client.Delete(sendCtx, &proto.DeleteRequest{Key: next.key, Version: next.version})
+		} else {
+			_, err = client.Set(sendCtx, &proto.SetRequest{Key: next.key, Value: next.value, Version: next.version})
+		}
+		cancel()
+		if code := status.Code(err); code == codes.Unavailable || code == codes.DeadlineExceeded || code == codes.Canceled {
+			return delivered, err
+		}
+		if err != nil {
+			log.Printf("Dropping hint for key '%s' refused by %s: %v", next.key, target, err)
+		}
+
+		h.mu.Lock()
+		if queue := h.hints[target]; len(queue) > 0 && queue[0] == next {
+			if len(queue) == 1 {
+				delete(h.hints, target)
+			} else {
+				h.hints[target] = queue[1:]
+			}
+			h.pending--
+			if err == nil {
+				h.delivered++
+			}
+		}
+		h.mu.Unlock()
+		if err == nil {
+			delivered++
+		}
+	}
+}
+
+// replay expires old hints and delivers the others to every replica that
+// can be reached
+func (h *hintStore) replay(ctx context.Context) {
+	if expired := h.expire(time.Now()); expired > 0 {
+		log.Printf("Expired %d hints older than %v", expired, h.ttl)
+	}
+
+	h.mu.Lock()
+	targets := make([]string, 0, len(h.hints))
+	for target := range h.hints {
+		targets = append(targets, target)
+	}
+	h.mu.Unlock()
+
+	for _, target := range targets {
+		delivered, err := h.deliver(ctx, target)
+		if delivered > 0 {
+			log.Printf("Delivered %d hints to %s", delivered, target)
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Hints for %s remain pending: %v", target, err)
+		}
+	}
+}
+
+// run replays hints every interval until ctx is cancelled
+func (h *hintStore) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for _, conn := range h.conns {
+			conn.Close()
+		}
+	}()
+
+	for {
+		select {
+		case <-ticker.C:
+			h.replay(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// report adds the hint statistics to a replication status
+func (h *hintStore) report(resp *proto.ReplicationStatusResponse) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	resp.HintsPending = int64(h.pending)
+	resp.HintsStored = h.stored
+	resp.HintsDelivered = h.delivered
+	resp.HintsExpired = h.expired
+	resp.HintsDropped = h.dropped
+}
+
+// StoreHint holds a write for the replica at req.Target until it can be
+// delivered
+func (r *replicationServer) StoreHint(ctx context.Context, req *proto.StoreHintRequest) (*proto.StoreHintResponse, error) {
+	if req.Target == "" {
+		return nil, status.Error(codes.InvalidArgument, "target is required")
+	}
+	if req.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "key is required")
+	}
+
+	err := r.store.hints.add(req.Target, &hint{key: req.Key, value: req.Value, version: req.Version, delete: req.Delete, storedAt: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	return &proto.StoreHintResponse{
+		Success: true,
+		Message: fmt.Sprintf("Write of key '%s' held for %s", req.Key, req.Target),
+	}, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHintedHandoff(t *testing.T) {
+	ctx := context.Background()
+	holder, target := NewKVStore(), NewKVStore()
+	srv := &replicationServer{store: holder}
+
+	// Reserve an address for a replica that is down
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	addr := lis.Addr().String()
+	lis.Close()
+
+	for i, value := range []string{"v1", "v2"} {
+		_, err := srv.StoreHint(ctx, &proto.StoreHintRequest{
+			Target:  addr,
+			Key:     "k",
+			Value:   value,
+			Version: []*proto.VersionEntry{{Node: "a", Counter: uint64(i + 1)}},
+		})
+		if err != nil {
+			t.Fatalf("StoreHint() error = %v", err)
+		}
+	}
+	deleted := []*proto.VersionEntry{{Node: "a", Counter: 3}}
+	if _, err := srv.StoreHint(ctx, &proto.StoreHintRequest{Target: addr, Key: "k", Version: deleted, Delete: true}); err != nil {
+		t.Fatalf("StoreHint() of a delete error = %v", err)
+	}
+
+	// Hints wait while the replica is unreachable
+	holder.hints.replay(ctx)
+	resp, _ := srv.ReplicationStatus(ctx, &proto.ReplicationStatusRequest{})
+	if resp.HintsPending != 3 || resp.HintsStored != 3 || resp.HintsDelivered != 0 {
+		t.Fatalf("ReplicationStatus() with the replica down = %v", resp)
+	}
+
+	lis, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterKeyValueStoreServer(server, target)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	// and are delivered in order once it is back
+	waitFor(t, "the hints to be delivered", func() bool {
+		holder.hints.replay(ctx)
+		resp, _ := srv.ReplicationStatus(ctx, &proto.ReplicationStatusRequest{})
+		return resp.HintsPending == 0
+	})
+	got, err := target.Get(ctx, &proto.GetRequest{Key: "k", IncludeDeleted: true})
+	if err != nil || !got.Deleted || versionFromProto(got.Version)["a"] != 3 {
+		t.Errorf("replica Get(k) = %v, %v, expected the key deleted at {a:3}", got, err)
+	}
+	resp, _ = srv.ReplicationStatus(ctx, &proto.ReplicationStatusRequest{})
+	if resp.HintsDelivered != 3 {
+		t.Errorf("HintsDelivered = %d, expected 3", resp.HintsDelivered)
+	}
+}
+
+func TestHintStore_Limits(t *testing.T) {
+	ctx := context.Background()
+	store := NewKVStore()
+	store.hints = newHintStore(1, time.Hour)
+	srv := &replicationServer{store: store}
+
+	if _, err := srv.StoreHint(ctx, &proto.StoreHintRequest{Target: "kv2:50051", Key: "a"}); err != nil {
+		t.Fatalf("StoreHint() error = %v", err)
+	}
+	_, err := srv.StoreHint(ctx, &proto.StoreHintRequest{Target: "kv3:50051", Key: "b"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("StoreHint() beyond the limit error = %v, expected ResourceExhausted", err)
+	}
+
+	if expired := store.hints.expire(time.Now()); expired != 0 {
+		t.Errorf("expire() dropped %d fresh hints", expired)
+	}
+	if expired := store.hints.expire(time.Now().Add(2 * time.Hour)); expired != 1 {
+		t.Errorf("expire() dropped %d hints, expected 1", expired)
+	}
+
+	resp, _ := srv.ReplicationStatus(ctx, &proto.ReplicationStatusRequest{})
+	if resp.HintsPending != 0 || resp.HintsStored != 1 || resp.HintsExpired != 1 || resp.HintsDropped != 1 {
+		t.Errorf("ReplicationStatus() = %v", resp)
+	}
+}
+
+func TestStoreHint_InvalidRequest(t *testing.T) {
+	srv := &replicationServer{store: NewKVStore()}
+	tests := []struct {
+		name string
+		req  *proto.StoreHintRequest
+	}{
+		{"Missing target", &proto.StoreHintRequest{Key: "k"}},
+		{"Missing key", &proto.StoreHintRequest{Target: "kv2:50051"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := srv.StoreHint(context.Background(), tt.req)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("StoreHint() error = %v, expected InvalidArgument", err)
+			}
+		})
+	}
+}
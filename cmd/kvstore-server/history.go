@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// historyEnabled reports whether per-key history is being kept
+func (k *kvStore) historyEnabled() bool {
+	return k.historyDepth > 0 || k.historyMaxAge > 0
+}
+
+// recordHistory appends a committed mutation to the history of its key,
+// or of every key for a clear, and trims it to the configured bounds. The
+// caller must hold k.mu for writing.
+func (k *kvStore) recordHistory(m *proto.Mutation) {
+	if !k.historyEnabled() {
+		return
+	}
+
+	if m.Op == proto.MutationOp_MUTATION_OP_CLEAR {
+		for key := range k.data {
+			k.appendHistory(key, m)
+		}
+		return
+	}
+	k.appendHistory(m.Key, m)
+}
+
+// appendHistory adds a mutation to the history of key
+func (k *kvStore) appendHistory(key string, m *proto.Mutation) {
+	entries := append(k.history[key], &proto.HistoryEntry{
+		Revision:  m.Revision,
+		Timestamp: m.Timestamp,
+		Op:        m.Op,
+		Value:     m.Value,
+		Client:    m.Client,
+	})
+
+	if k.historyDepth > 0 && len(entries) > k.historyDepth {
+		entries = entries[len(entries)-k.historyDepth:]
+	}
+	if k.historyMaxAge > 0 {
+		cutoff := time.Now().Add(-k.historyMaxAge).UnixNano()
+		expired := 0
+		for expired < len(entries) && entries[expired].Timestamp < cutoff {
+			expired++
+		}
+		entries = entries[expired:]
+	}
+	k.history[key] = entries
+}
+
+// pruneHistory drops the changes older than the maximum age, and the
+// history of keys left with none, such as keys deleted long ago. It
+// returns the number of keys whose history was dropped.
+func (k *kvStore) pruneHistory(now time.Time) int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	cutoff := now.Add(-k.historyMaxAge).UnixNano()
+	dropped := 0
+	for key, entries := range k.history {
+		expired := 0
+		for expired < len(entries) && entries[expired].Timestamp < cutoff {
+			expired++
+		}
+		switch {
+		case expired == len(entries):
+			delete(k.history, key)
+			dropped++
+		case expired > 0:
+			k.history[key] = entries[expired:]
+		}
+	}
+	return dropped
+}
+
+// runHistoryGC prunes expired history periodically until stop is closed
+func (k *kvStore) runHistoryGC(stop <-chan struct{}) {
+	interval := min(max(k.historyMaxAge/10, time.Second), time.Minute)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if dropped := k.pruneHistory(now); dropped > 0 {
+				log.Printf("Dropped the expired history of %d keys", dropped)
+			}
+		}
+	}
+}
+
+// History retrieves the recorded changes to a key, newest first
+func (k *kvStore) History(ctx context.Context, req *proto.HistoryRequest) (*proto.HistoryResponse, error) {
+	if !k.historyEnabled() {
+		return nil, failedPrecondition(reasonFeatureDisabled, "history", "history is not enabled", nil)
+	}
+	if req.Limit < 0 {
+		return nil, status.Error(codes.InvalidArgument, "limit must not be negative")
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var cutoff int64
+	if k.historyMaxAge > 0 {
+		cutoff = time.Now().Add(-k.historyMaxAge).UnixNano()
+	}
+
+	entries := []*proto.HistoryEntry{}
+	stored := k.history[req.Key]
+	for i := len(stored) - 1; i >= 0; i-- {
+		if stored[i].Timestamp < cutoff || (req.Limit > 0 && len(entries) == int(req.Limit)) {
+			break
+		}
+		entries = append(entries, stored[i])
+	}
+
+	if len(entries) == 0 {
+		return nil, notFound(reasonHistoryNotFound, "history", req.Key, fmt.Sprintf("No history for key '%s'", req.Key))
+	}
+
+	return &proto.HistoryResponse{
+		Success: true,
+		Entries: entries,
+		Message: fmt.Sprintf("Found %d changes to key '%s'", len(entries), req.Key),
+	}, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// asClient returns a context carrying a client identity as incoming metadata
+func asClient(id string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(clientIDMetadataKey, id))
+}
+
+func TestHistory_RecordsChanges(t *testing.T) {
+	store := NewKVStore()
+	store.historyDepth = 10
+
+	store.Set(asClient("alice"), &proto.SetRequest{Key: "key", Value: "v1"})
+	store.Set(asClient("bob"), &proto.SetRequest{Key: "key", Value: "v2"})
+	store.Delete(asClient("carol"), &proto.DeleteRequest{Key: "key"})
+	store.Set(asClient("alice"), &proto.SetRequest{Key: "other", Value: "x"})
+
+	resp, err := store.History(context.Background(), &proto.HistoryRequest{Key: "key"})
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+
+	expected := []struct {
+		revision uint64
+		op       proto.MutationOp
+		value    string
+		client   string
+	}{
+		{3, proto.MutationOp_MUTATION_OP_DELETE, "", "carol"},
+		{2, proto.MutationOp_MUTATION_OP_SET, "v2", "bob"},
+		{1, proto.MutationOp_MUTATION_OP_SET, "v1", "alice"},
+	}
+	if len(resp.Entries) != len(expected) {
+		t.Fatalf("History() returned %d entries, expected %d", len(resp.Entries), len(expected))
+	}
+	for i, e := range expected {
+		got := resp.Entries[i]
+		if got.Revision != e.revision || got.Op != e.op || got.Value != e.value || got.Client != e.client {
+			t.Errorf("entry %d = %v, expected %+v", i, got, e)
+		}
+		if got.Timestamp == 0 {
+			t.Errorf("entry %d has no timestamp", i)
+		}
+	}
+}
+
+func TestHistory_Bounds(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Depth", func(t *testing.T) {
+		store := NewKVStore()
+		store.historyDepth = 3
+		for _, v := range []string{"v1", "v2", "v3", "v4", "v5"} {
+			store.Set(ctx, &proto.SetRequest{Key: "key", Value: v})
+		}
+
+		resp, _ := store.History(ctx, &proto.HistoryRequest{Key: "key"})
+		if len(resp.Entries) != 3 || resp.Entries[0].Value != "v5" || resp.Entries[2].Value != "v3" {
+			t.Errorf("History() = %v, expected v5..v3", resp.Entries)
+		}
+
+		resp, _ = store.History(ctx, &proto.HistoryRequest{Key: "key", Limit: 1})
+		if len(resp.Entries) != 1 || resp.Entries[0].Value != "v5" {
+			t.Errorf("History() with limit = %v, expected only v5", resp.Entries)
+		}
+	})
+
+	t.Run("Age", func(t *testing.T) {
+		store := NewKVStore()
+		store.historyMaxAge = 50 * time.Millisecond
+		store.Set(ctx, &proto.SetRequest{Key: "key", Value: "old"})
+		time.Sleep(60 * time.Millisecond)
+		store.Set(ctx, &proto.SetRequest{Key: "key", Value: "new"})
+
+		resp, _ := store.History(ctx, &proto.HistoryRequest{Key: "key"})
+		if len(resp.Entries) != 1 || resp.Entries[0].Value != "new" {
+			t.Errorf("History() = %v, expected only the recent change", resp.Entries)
+		}
+	})
+}
+
+func TestHistory_Pruned(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("With the tombstone", func(t *testing.T) {
+		store := newSoftDeleteStore(time.Minute)
+		store.historyDepth = 10
+		store.Set(ctx, &proto.SetRequest{Key: "deleted", Value: "v1"})
+		store.Delete(ctx, &proto.DeleteRequest{Key: "deleted"})
+		store.Set(ctx, &proto.SetRequest{Key: "kept", Value: "v1"})
+
+		store.purgeTombstones(time.Now().Add(time.Minute))
+		if _, exists := store.history["deleted"]; exists {
+			t.Error("history of a purged key was kept")
+		}
+		if len(store.history["kept"]) != 1 {
+			t.Errorf("history of a live key = %v", store.history["kept"])
+		}
+	})
+
+	t.Run("Past the maximum age", func(t *testing.T) {
+		store := NewKVStore()
+		store.historyMaxAge = time.Minute
+		store.Set(ctx, &proto.SetRequest{Key: "deleted", Value: "v1"})
+		store.Delete(ctx, &proto.DeleteRequest{Key: "deleted"})
+
+		if dropped := store.pruneHistory(time.Now()); dropped != 0 {
+			t.Errorf("dropped the history of %d keys inside the maximum age", dropped)
+		}
+		if dropped := store.pruneHistory(time.Now().Add(2 * time.Minute)); dropped != 1 || len(store.history) != 0 {
+			t.Errorf("dropped the history of %d keys past the maximum age, %d left", dropped, len(store.history))
+		}
+	})
+}
+
+func TestHistory_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	store := NewKVStore()
+	store.historyDepth = 10
+	if err := store.Recover(dir, recoveryTarget{}); err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	store.Set(asClient("alice"), &proto.SetRequest{Key: "key", Value: "v1"})
+	store.Set(asClient("bob"), &proto.SetRequest{Key: "key", Value: "v2"})
+	store.Close()
+
+	reopened := NewKVStore()
+	reopened.historyDepth = 10
+	if err := reopened.Recover(dir, recoveryTarget{}); err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	defer reopened.Close()
+
+	resp, _ := reopened.History(context.Background(), &proto.HistoryRequest{Key: "key"})
+	if len(resp.Entries) != 2 || resp.Entries[0].Client != "bob" || resp.Entries[1].Client != "alice" {
+		t.Errorf("History() after restart = %v", resp.Entries)
+	}
+}
+
+func TestHistory_Disabled(t *testing.T) {
+	store := NewKVStore()
+	store.Set(context.Background(), &proto.SetRequest{Key: "key", Value: "value"})
+
+	_, err := store.History(context.Background(), &proto.HistoryRequest{Key: "key"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("History() error = %v, expected FailedPrecondition", err)
+	}
+	if len(store.history) != 0 {
+		t.Errorf("history kept while disabled")
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Dimensions of the count-min sketches. With 4 rows of 2048 counters an
+// estimate overshoots by more than 0.13% of all sampled accesses with a
+// probability below 2%.
+const (
+	sketchDepth = 4
+	sketchWidth = 2048
+)
+
+// countMinSketch estimates how often each key was seen in bounded memory.
+// Estimates never undercount, and overcount only through hash collisions.
+type countMinSketch struct {
+	rows [sketchDepth][sketchWidth]uint32
+}
+
+// cells returns the counter of key in each row, using double hashing to
+// derive the row hashes from one 64-bit hash
+func (s *countMinSketch) cells(key string) [sketchDepth]*uint32 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	h1, h2 := uint32(sum), uint32(sum>>32)|1
+
+	var cells [sketchDepth]*uint32
+	for i := range cells {
+		cells[i] = &s.rows[i][(h1+uint32(i)*h2)%sketchWidth]
+	}
+	return cells
+}
+
+// add counts one occurrence of key
+func (s *countMinSketch) add(key string) {
+	for _, c := range s.cells(key) {
+		if *c < ^uint32(0) {
+			*c++
+		}
+	}
+}
+
+// estimate returns the estimated number of occurrences of key
+func (s *countMinSketch) estimate(key string) uint32 {
+	min := ^uint32(0)
+	for _, c := range s.cells(key) {
+		if *c < min {
+			min = *c
+		}
+	}
+	return min
+}
+
+// halve ages every counter so that old accesses weigh less than new ones
+func (s *countMinSketch) halve() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] /= 2
+		}
+	}
+}
+
+// hotKey is a candidate in the top-K heap
+type hotKey struct {
+	key   string
+	count uint32
+	index int
+}
+
+// hotKeyHeap is a min-heap of the busiest keys seen, so the least busy of
+// them is the one evicted when a busier key comes along
+type hotKeyHeap []*hotKey
+
+func (h hotKeyHeap) Len() int           { return len(h) }
+func (h hotKeyHeap) Less(i, j int) bool { return h[i].count < h[j].count }
+func (h hotKeyHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+func (h *hotKeyHeap) Push(x any) {
+	item := x.(*hotKey)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+func (h *hotKeyHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// accessTracker samples key accesses into count-min sketches and keeps the
+// top K keys by estimated accesses. It has its own lock so that sampling a
+// read does not contend with writers for the store lock.
+type accessTracker struct {
+	sampleRate float64
+	capacity   int
+
+	mu     sync.Mutex
+	reads  countMinSketch
+	writes countMinSketch
+	top    hotKeyHeap
+	byKey  map[string]*hotKey
+}
+
+// newAccessTracker creates a tracker sampling the given fraction of
+// accesses and keeping the busiest capacity keys
+func newAccessTracker(sampleRate float64, capacity int) *accessTracker {
+	return &accessTracker{
+		sampleRate: sampleRate,
+		capacity:   capacity,
+		byKey:      make(map[string]*hotKey),
+	}
+}
+
+// record samples an access to key. It is a no-op on a nil tracker.
+func (t *accessTracker) record(key string, write bool) {
+	if t == nil || (t.sampleRate < 1 && rand.Float64() >= t.sampleRate) {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if write {
+		t.writes.add(key)
+	} else {
+		t.reads.add(key)
+	}
+	count := t.reads.estimate(key) + t.writes.estimate(key)
+
+	switch item, tracked := t.byKey[key]; {
+	case tracked:
+		item.count = count
+		heap.Fix(&t.top, item.index)
+	case len(t.top) < t.capacity:
+		item = &hotKey{key: key, count: count}
+		heap.Push(&t.top, item)
+		t.byKey[key] = item
+	case count > t.top[0].count:
+		evicted := t.top[0]
+		delete(t.byKey, evicted.key)
+		evicted.key, evicted.count = key, count
+		heap.Fix(&t.top, 0)
+		t.byKey[key] = evicted
+	}
+}
+
+// decay halves every count so the statistics follow the current workload
+func (t *accessTracker) decay() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.reads.halve()
+	t.writes.halve()
+	for _, item := range t.top {
+		item.count /= 2
+	}
+}
+
+// runDecay halves the counts once per halfLife until stop is closed
+func (t *accessTracker) runDecay(halfLife time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(halfLife)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.decay()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// hottest returns up to limit tracked keys, busiest first, with their
+// counts scaled up to estimate all accesses rather than the sampled ones
+func (t *accessTracker) hottest(limit int) []*proto.HotKey {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	scale := 1 / t.sampleRate
+	keys := make([]*proto.HotKey, 0, len(t.top))
+	for _, item := range t.top {
+		keys = append(keys, &proto.HotKey{
+			Key:    item.key,
+			Reads:  int64(float64(t.reads.estimate(item.key)) * scale),
+			Writes: int64(float64(t.writes.estimate(item.key)) * scale),
+		})
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		ti, tj := keys[i].Reads+keys[i].Writes, keys[j].Reads+keys[j].Writes
+		if ti != tj {
+			return ti > tj
+		}
+		return keys[i].Key < keys[j].Key
+	})
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys
+}
+
+// TopKeys reports the most frequently accessed keys
+func (k *kvStore) TopKeys(ctx context.Context, req *proto.TopKeysRequest) (*proto.TopKeysResponse, error) {
+	if k.access == nil {
+		return nil, failedPrecondition(reasonFeatureDisabled, "access-statistics", "access statistics are not enabled", nil)
+	}
+	if req.Limit < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must not be negative, got %d", req.Limit)
+	}
+
+	keys := k.access.hottest(int(req.Limit))
+	return &proto.TopKeysResponse{
+		Success:    true,
+		Keys:       keys,
+		SampleRate: k.access.sampleRate,
+		Message:    fmt.Sprintf("Found %d hot keys", len(keys)),
+	}, nil
+}
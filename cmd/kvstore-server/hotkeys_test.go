@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCountMinSketch_NeverUndercounts(t *testing.T) {
+	var sketch countMinSketch
+	for i := 0; i < 5000; i++ {
+		sketch.add(fmt.Sprintf("key-%d", i%500))
+	}
+	for i := 0; i < 500; i++ {
+		if got := sketch.estimate(fmt.Sprintf("key-%d", i)); got < 10 {
+			t.Fatalf("estimate(key-%d) = %d, expected at least 10", i, got)
+		}
+	}
+
+	sketch.halve()
+	if got := sketch.estimate("key-0"); got < 5 {
+		t.Errorf("estimate after halve = %d, expected at least 5", got)
+	}
+}
+
+func TestTopKeys(t *testing.T) {
+	store := NewKVStore()
+	store.access = newAccessTracker(1, 3)
+	ctx := context.Background()
+
+	// A few hot keys among many cold ones
+	for i := 0; i < 200; i++ {
+		store.Set(ctx, &proto.SetRequest{Key: fmt.Sprintf("cold-%d", i), Value: "v"})
+	}
+	for i := 0; i < 50; i++ {
+		store.Get(ctx, &proto.GetRequest{Key: "hot-a"})
+		store.Get(ctx, &proto.GetRequest{Key: "hot-a"})
+		store.Set(ctx, &proto.SetRequest{Key: "hot-b", Value: "v"})
+		store.Get(ctx, &proto.GetRequest{Key: "hot-c"})
+	}
+
+	resp, err := store.TopKeys(ctx, &proto.TopKeysRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("TopKeys() error = %v", err)
+	}
+	if len(resp.Keys) != 2 || resp.Keys[0].Key != "hot-a" {
+		t.Fatalf("TopKeys() = %v, expected hot-a first", resp.Keys)
+	}
+	if resp.Keys[0].Reads < 100 || resp.Keys[0].Writes != 0 {
+		t.Errorf("hot-a counts = %d reads, %d writes", resp.Keys[0].Reads, resp.Keys[0].Writes)
+	}
+	if second := resp.Keys[1].Key; second != "hot-b" && second != "hot-c" {
+		t.Errorf("second hottest key = %s", second)
+	}
+
+	store.access.decay()
+	resp, _ = store.TopKeys(ctx, &proto.TopKeysRequest{})
+	if len(resp.Keys) != 3 || resp.Keys[0].Reads < 50 || resp.Keys[0].Reads > 60 {
+		t.Errorf("TopKeys() after decay = %v", resp.Keys)
+	}
+}
+
+func TestTopKeys_ScalesSampledCounts(t *testing.T) {
+	tracker := newAccessTracker(0.5, 10)
+	for i := 0; i < 10000; i++ {
+		tracker.record("key", false)
+	}
+
+	keys := tracker.hottest(0)
+	if len(keys) != 1 || keys[0].Reads < 9000 || keys[0].Reads > 11000 {
+		t.Errorf("hottest() = %v, expected about 10000 reads", keys)
+	}
+}
+
+func TestTopKeys_Disabled(t *testing.T) {
+	store := NewKVStore()
+
+	_, err := store.TopKeys(context.Background(), &proto.TopKeysRequest{})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("TopKeys() error = %v, expected FailedPrecondition", err)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net"
+
+	"github.com/pwntato/Censys/internal/certs"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// clientIDMetadataKey is the gRPC metadata key carrying the acting client's identity
+const clientIDMetadataKey = "x-client-id"
+
+// proxyIdentities are the certificate identities trusted to name the client
+// they act for in x-client-id metadata, as the API server does. No client
+// with a certificate is trusted unless listed.
+var proxyIdentities map[string]bool
+
+// clientIdentity returns the identity of the client behind a request. Over
+// mutual TLS it is the identity of the client's certificate, unless the
+// client is trusted to act for another named in the x-client-id metadata.
+// Otherwise it is the metadata when present, or else the peer's host, which
+// stays the same across the client's connections; the metadata is
+// self-declared then, so it identifies rather than authenticates.
+func clientIdentity(ctx context.Context) string {
+	certified := certIdentity(ctx)
+	if certified == "" || proxyIdentities[certified] {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if ids := md.Get(clientIDMetadataKey); len(ids) > 0 && ids[0] != "" {
+				return ids[0]
+			}
+		}
+	}
+	if certified != "" {
+		return certified
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr := p.Addr.String()
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			return host
+		}
+		return addr
+	}
+	return "unknown"
+}
+
+// certIdentity returns the identity of the certificate the client behind a
+// request presented, "" if it presented none
+func certIdentity(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return ""
+	}
+	return certs.Identity(info.State.PeerCertificates[0])
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSchemaViolations caps how many violations are reported for one value
+const maxSchemaViolations = 20
+
+// annotationKeywords are accepted in a schema but have no effect on validation
+var annotationKeywords = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "title": true,
+	"description": true, "default": true, "examples": true,
+	"readOnly": true, "writeOnly": true, "deprecated": true,
+}
+
+// jsonSchema is a compiled JSON Schema. Only the validation keywords that
+// can be checked on a single document are supported; schemas using anything
+// else, such as $ref or the applicators allOf/anyOf/oneOf, are rejected when
+// compiled rather than silently not enforced.
+type jsonSchema struct {
+	// always is set for the boolean schemas true and false
+	always *bool
+
+	types []string
+	enum  []any
+	konst any
+	// hasConst distinguishes "const": null from no const
+	hasConst bool
+
+	minimum, maximum                   *float64
+	exclusiveMinimum, exclusiveMaximum *float64
+	multipleOf                         *float64
+
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+
+	items              *jsonSchema
+	minItems, maxItems *int
+	uniqueItems        bool
+
+	properties           map[string]*jsonSchema
+	required             []string
+	additionalProperties *jsonSchema
+	minProperties        *int
+	maxProperties        *int
+}
+
+// schemaViolation is a place where a document does not conform to a schema
+type schemaViolation struct {
+	// path is a JSON Pointer to the offending part of the document
+	path    string
+	message string
+}
+
+// compileSchema parses a JSON Schema document
+func compileSchema(source string) (*jsonSchema, error) {
+	raw, err := decodeJSON(source)
+	if err != nil {
+		return nil, fmt.Errorf("schema is not valid JSON: %v", err)
+	}
+	return compileSchemaValue(raw, "")
+}
+
+// decodeJSON decodes a single JSON document, keeping numbers exact
+func decodeJSON(source string) (any, error) {
+	dec := json.NewDecoder(strings.NewReader(source))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err == nil {
+		return nil, fmt.Errorf("unexpected data after the top-level value")
+	}
+	return v, nil
+}
+
+// compileSchemaValue compiles the schema found at path within a schema document
+func compileSchemaValue(raw any, path string) (*jsonSchema, error) {
+	if b, ok := raw.(bool); ok {
+		return &jsonSchema{always: &b}, nil
+	}
+	obj, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: schema must be an object or a boolean", pointer(path))
+	}
+
+	s := &jsonSchema{}
+	for _, keyword := range sortedKeys(obj) {
+		v := obj[keyword]
+		at := path + "/" + escapePointer(keyword)
+
+		var err error
+		switch keyword {
+		case "type":
+			s.types, err = schemaTypes(v, at)
+		case "enum":
+			list, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be an array", at)
+			}
+			for _, item := range list {
+				s.enum = append(s.enum, normalizeJSON(item))
+			}
+		case "const":
+			s.konst, s.hasConst = normalizeJSON(v), true
+		case "minimum":
+			s.minimum, err = schemaNumber(v, at)
+		case "maximum":
+			s.maximum, err = schemaNumber(v, at)
+		case "exclusiveMinimum":
+			s.exclusiveMinimum, err = schemaNumber(v, at)
+		case "exclusiveMaximum":
+			s.exclusiveMaximum, err = schemaNumber(v, at)
+		case "multipleOf":
+			if s.multipleOf, err = schemaNumber(v, at); err == nil && *s.multipleOf <= 0 {
+				err = fmt.Errorf("%s: must be greater than 0", at)
+			}
+		case "minLength":
+			s.minLength, err = schemaCount(v, at)
+		case "maxLength":
+			s.maxLength, err = schemaCount(v, at)
+		case "pattern":
+			p, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be a string", at)
+			}
+			if s.pattern, err = regexp.Compile(p); err != nil {
+				err = fmt.Errorf("%s: invalid pattern: %v", at, err)
+			}
+		case "items":
+			s.items, err = compileSchemaValue(v, at)
+		case "minItems":
+			s.minItems, err = schemaCount(v, at)
+		case "maxItems":
+			s.maxItems, err = schemaCount(v, at)
+		case "uniqueItems":
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be a boolean", at)
+			}
+			s.uniqueItems = b
+		case "properties":
+			props, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be an object", at)
+			}
+			s.properties = make(map[string]*jsonSchema, len(props))
+			for name, prop := range props {
+				if s.properties[name], err = compileSchemaValue(prop, at+"/"+escapePointer(name)); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			list, ok := v.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be an array of strings", at)
+			}
+			for _, item := range list {
+				name, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s: must be an array of strings", at)
+				}
+				s.required = append(s.required, name)
+			}
+		case "additionalProperties":
+			s.additionalProperties, err = compileSchemaValue(v, at)
+		case "minProperties":
+			s.minProperties, err = schemaCount(v, at)
+		case "maxProperties":
+			s.maxProperties, err = schemaCount(v, at)
+		default:
+			if !annotationKeywords[keyword] {
+				return nil, fmt.Errorf("%s: unsupported keyword %q", pointer(path), keyword)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// schemaTypes reads the "type" keyword, a type name or a list of them
+func schemaTypes(v any, at string) ([]string, error) {
+	names, ok := v.([]any)
+	if !ok {
+		names = []any{v}
+	}
+
+	var types []string
+	for _, n := range names {
+		name, _ := n.(string)
+		switch name {
+		case "null", "boolean", "object", "array", "number", "integer", "string":
+			types = append(types, name)
+		default:
+			return nil, fmt.Errorf("%s: unknown type %v", at, n)
+		}
+	}
+	return types, nil
+}
+
+// schemaNumber reads a numeric keyword
+func schemaNumber(v any, at string) (*float64, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be a number", at)
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("%s: must be a number", at)
+	}
+	return &f, nil
+}
+
+// schemaCount reads a keyword that must be a non-negative integer
+func schemaCount(v any, at string) (*int, error) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be a non-negative integer", at)
+	}
+	i, err := strconv.Atoi(n.String())
+	if err != nil || i < 0 {
+		return nil, fmt.Errorf("%s: must be a non-negative integer", at)
+	}
+	return &i, nil
+}
+
+// validate checks a JSON document against the schema, returning the
+// violations in path order. A document that is not JSON is reported as a
+// single violation at the root.
+func (s *jsonSchema) validate(document string) []schemaViolation {
+	v, err := decodeJSON(document)
+	if err != nil {
+		return []schemaViolation{{path: "", message: fmt.Sprintf("value is not valid JSON: %v", err)}}
+	}
+
+	var violations []schemaViolation
+	s.check(normalizeJSON(v), "", &violations)
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].path < violations[j].path })
+	return violations
+}
+
+// check appends the violations of v, found at path, to violations
+func (s *jsonSchema) check(v any, path string, violations *[]schemaViolation) {
+	fail := func(path, format string, a ...any) {
+		if len(*violations) < maxSchemaViolations {
+			*violations = append(*violations, schemaViolation{path: path, message: fmt.Sprintf(format, a...)})
+		}
+	}
+
+	if s.always != nil {
+		if !*s.always {
+			fail(path, "no value is allowed here")
+		}
+		return
+	}
+
+	if len(s.types) > 0 && !matchesAnyType(v, s.types) {
+		fail(path, "expected %s, got %s", strings.Join(s.types, " or "), jsonTypeOf(v))
+		return
+	}
+	if s.enum != nil && !containsJSON(s.enum, v) {
+		fail(path, "value is not one of the allowed values")
+	}
+	if s.hasConst && !reflect.DeepEqual(s.konst, v) {
+		fail(path, "value does not match the required constant")
+	}
+
+	switch v := v.(type) {
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			fail(path, "%v is less than the minimum of %v", v, *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			fail(path, "%v is greater than the maximum of %v", v, *s.maximum)
+		}
+		if s.exclusiveMinimum != nil && v <= *s.exclusiveMinimum {
+			fail(path, "%v must be greater than %v", v, *s.exclusiveMinimum)
+		}
+		if s.exclusiveMaximum != nil && v >= *s.exclusiveMaximum {
+			fail(path, "%v must be less than %v", v, *s.exclusiveMaximum)
+		}
+		if s.multipleOf != nil {
+			if q := v / *s.multipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+				fail(path, "%v is not a multiple of %v", v, *s.multipleOf)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.minLength != nil && length < *s.minLength {
+			fail(path, "string is shorter than %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			fail(path, "string is longer than %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail(path, "string does not match pattern %q", s.pattern.String())
+		}
+	case []any:
+		if s.minItems != nil && len(v) < *s.minItems {
+			fail(path, "array has fewer than %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			fail(path, "array has more than %d items", *s.maxItems)
+		}
+		if s.uniqueItems {
+			for i := range v {
+				if containsJSON(v[:i], v[i]) {
+					fail(path+"/"+strconv.Itoa(i), "duplicate array item")
+				}
+			}
+		}
+		if s.items != nil {
+			for i, item := range v {
+				s.items.check(item, path+"/"+strconv.Itoa(i), violations)
+			}
+		}
+	case map[string]any:
+		if s.minProperties != nil && len(v) < *s.minProperties {
+			fail(path, "object has fewer than %d properties", *s.minProperties)
+		}
+		if s.maxProperties != nil && len(v) > *s.maxProperties {
+			fail(path, "object has more than %d properties", *s.maxProperties)
+		}
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				fail(path+"/"+escapePointer(name), "required property is missing")
+			}
+		}
+		for _, name := range sortedKeys(v) {
+			at := path + "/" + escapePointer(name)
+			if prop, ok := s.properties[name]; ok {
+				prop.check(v[name], at, violations)
+			} else if s.additionalProperties != nil {
+				s.additionalProperties.check(v[name], at, violations)
+			}
+		}
+	}
+}
+
+// normalizeJSON converts the json.Numbers in a decoded document to float64
+// so that documents can be compared with reflect.DeepEqual
+func normalizeJSON(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = normalizeJSON(item)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for name, item := range v {
+			out[name] = normalizeJSON(item)
+		}
+		return out
+	}
+	return v
+}
+
+// jsonTypeOf names the JSON type of a normalized value
+func jsonTypeOf(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	}
+	return "object"
+}
+
+// matchesAnyType reports whether v has one of the given types
+func matchesAnyType(v any, types []string) bool {
+	actual := jsonTypeOf(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// containsJSON reports whether list contains a value equal to v
+func containsJSON(list []any, v any) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the keys of an object in order, so that violations are
+// reported deterministically
+func sortedKeys(obj map[string]any) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// escapePointer escapes a property name for use in a JSON Pointer
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
+
+// pointer formats a JSON Pointer for messages, naming the root explicitly
+func pointer(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// compactJSON strips insignificant whitespace from a JSON document
+func compactJSON(source string) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(source)); err != nil {
+		return source
+	}
+	return buf.String()
+}
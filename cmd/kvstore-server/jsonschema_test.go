@@ -0,0 +1,77 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testSchema = `{
+	"type": "object",
+	"required": ["host", "port"],
+	"properties": {
+		"host": {"type": "string", "minLength": 1},
+		"port": {"type": "integer", "minimum": 1, "maximum": 65535},
+		"mode": {"enum": ["primary", "replica"]},
+		"tags": {"type": "array", "items": {"type": "string", "pattern": "^[a-z]+$"}, "uniqueItems": true}
+	},
+	"additionalProperties": false
+}`
+
+func TestJSONSchema_Validate(t *testing.T) {
+	schema, err := compileSchema(testSchema)
+	if err != nil {
+		t.Fatalf("compileSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		document string
+		expected []string
+	}{
+		{"Valid", `{"host":"db","port":5432,"mode":"replica","tags":["a","b"]}`, nil},
+		{"Integer written as float", `{"host":"db","port":5432.0}`, nil},
+		{"Missing required", `{"host":"db"}`, []string{"/port"}},
+		{"Wrong type", `{"host":"db","port":"5432"}`, []string{"/port"}},
+		{"Out of range", `{"host":"db","port":70000}`, []string{"/port"}},
+		{"Not in enum", `{"host":"db","port":1,"mode":"leader"}`, []string{"/mode"}},
+		{"Nested array items", `{"host":"db","port":1,"tags":["ok","Bad","ok"]}`, []string{"/tags/1", "/tags/2"}},
+		{"Additional property", `{"host":"db","port":1,"user/name":"x"}`, []string{"/user~1name"}},
+		{"Several violations", `{"host":"","port":0}`, []string{"/host", "/port"}},
+		{"Not an object", `[1,2]`, []string{""}},
+		{"Not JSON", `host=db`, []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			for _, v := range schema.validate(tt.document) {
+				paths = append(paths, v.path)
+			}
+			if !reflect.DeepEqual(paths, tt.expected) {
+				t.Errorf("violation paths = %v, expected %v", paths, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCompileSchema_Rejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{"Not JSON", `{"type":`},
+		{"Not an object", `"string"`},
+		{"Unknown type", `{"type":"text"}`},
+		{"Unsupported keyword", `{"properties":{"a":{"$ref":"#/definitions/a"}}}`},
+		{"Invalid pattern", `{"pattern":"("}`},
+		{"Negative count", `{"maxLength":-1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := compileSchema(tt.schema); err == nil {
+				t.Errorf("compileSchema(%s) succeeded, expected an error", tt.schema)
+			}
+		})
+	}
+}
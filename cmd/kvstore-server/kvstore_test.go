@@ -5,6 +5,9 @@ import (
 	"testing"
 
 	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestKVStore_Set(t *testing.T) {
@@ -16,6 +19,7 @@ func TestKVStore_Set(t *testing.T) {
 		key      string
 		value    string
 		expected bool
+		code     codes.Code
 	}{
 		{
 			name:     "Set valid key-value pair",
@@ -27,7 +31,8 @@ func TestKVStore_Set(t *testing.T) {
 			name:     "Set empty key",
 			key:      "",
 			value:    "test-value",
-			expected: true,
+			expected: false,
+			code:     codes.InvalidArgument,
 		},
 		{
 			name:     "Set empty value",
@@ -44,8 +49,11 @@ func TestKVStore_Set(t *testing.T) {
 				Value: tt.value,
 			}
 			resp, err := store.Set(ctx, req)
+			if status.Code(err) != tt.code {
+				t.Fatalf("Set() error = %v, expected code %v", err, tt.code)
+			}
 			if err != nil {
-				t.Fatalf("Set() error = %v", err)
+				return
 			}
 			if resp.Success != tt.expected {
 				t.Errorf("Set() success = %v, expected %v", resp.Success, tt.expected)
@@ -62,28 +70,26 @@ func TestKVStore_Get(t *testing.T) {
 	store.Set(ctx, &proto.SetRequest{Key: "existing-key", Value: "existing-value"})
 
 	tests := []struct {
-		name            string
-		key             string
-		expectedValue   string
-		expectedSuccess bool
+		name          string
+		key           string
+		expectedValue string
+		expectedCode  codes.Code
 	}{
 		{
-			name:            "Get existing key",
-			key:             "existing-key",
-			expectedValue:   "existing-value",
-			expectedSuccess: true,
+			name:          "Get existing key",
+			key:           "existing-key",
+			expectedValue: "existing-value",
+			expectedCode:  codes.OK,
 		},
 		{
-			name:            "Get non-existing key",
-			key:             "non-existing-key",
-			expectedValue:   "",
-			expectedSuccess: false,
+			name:         "Get non-existing key",
+			key:          "non-existing-key",
+			expectedCode: codes.NotFound,
 		},
 		{
-			name:            "Get empty key",
-			key:             "",
-			expectedValue:   "",
-			expectedSuccess: false,
+			name:         "Get empty key",
+			key:          "",
+			expectedCode: codes.NotFound,
 		},
 	}
 
@@ -91,11 +97,14 @@ func TestKVStore_Get(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			req := &proto.GetRequest{Key: tt.key}
 			resp, err := store.Get(ctx, req)
+			if status.Code(err) != tt.expectedCode {
+				t.Fatalf("Get() error = %v, expected %v", err, tt.expectedCode)
+			}
 			if err != nil {
-				t.Fatalf("Get() error = %v", err)
+				return
 			}
-			if resp.Success != tt.expectedSuccess {
-				t.Errorf("Get() success = %v, expected %v", resp.Success, tt.expectedSuccess)
+			if !resp.Success {
+				t.Errorf("Get() success = false")
 			}
 			if resp.Value != tt.expectedValue {
 				t.Errorf("Get() value = %v, expected %v", resp.Value, tt.expectedValue)
@@ -112,24 +121,24 @@ func TestKVStore_Delete(t *testing.T) {
 	store.Set(ctx, &proto.SetRequest{Key: "existing-key", Value: "existing-value"})
 
 	tests := []struct {
-		name            string
-		key             string
-		expectedSuccess bool
+		name         string
+		key          string
+		expectedCode codes.Code
 	}{
 		{
-			name:            "Delete existing key",
-			key:             "existing-key",
-			expectedSuccess: true,
+			name:         "Delete existing key",
+			key:          "existing-key",
+			expectedCode: codes.OK,
 		},
 		{
-			name:            "Delete non-existing key",
-			key:             "non-existing-key",
-			expectedSuccess: false,
+			name:         "Delete non-existing key",
+			key:          "non-existing-key",
+			expectedCode: codes.NotFound,
 		},
 		{
-			name:            "Delete empty key",
-			key:             "",
-			expectedSuccess: false,
+			name:         "Delete empty key",
+			key:          "",
+			expectedCode: codes.NotFound,
 		},
 	}
 
@@ -137,11 +146,11 @@ func TestKVStore_Delete(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			req := &proto.DeleteRequest{Key: tt.key}
 			resp, err := store.Delete(ctx, req)
-			if err != nil {
-				t.Fatalf("Delete() error = %v", err)
+			if status.Code(err) != tt.expectedCode {
+				t.Fatalf("Delete() error = %v, expected %v", err, tt.expectedCode)
 			}
-			if resp.Success != tt.expectedSuccess {
-				t.Errorf("Delete() success = %v, expected %v", resp.Success, tt.expectedSuccess)
+			if err == nil && !resp.Success {
+				t.Errorf("Delete() success = false")
 			}
 		})
 	}
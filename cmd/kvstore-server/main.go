@@ -6,77 +6,354 @@ import (
 	"log"
 	"net"
 	"os"
+	"os/signal"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
 
+	"github.com/pwntato/Censys/internal/certs"
 	"github.com/pwntato/Censys/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 )
 
+// record is a stored value together with the checksum computed when it was written
+type record struct {
+	value    string
+	checksum uint32
+	owner    string // client that last wrote the value
+	version  versionVector
+	crdt     proto.CrdtType // type of a CRDT, whose value is its encoded state
+}
+
 // In-memory key-value store implementation
 type kvStore struct {
 	proto.UnimplementedKeyValueStoreServer
-	mu   sync.RWMutex
-	data map[string]string
+	mu         sync.RWMutex
+	data       map[string]record
+	tombstones map[string]tombstone // soft-deleted keys, only used with a retention window
+	history    map[string][]*proto.HistoryEntry
+	revision   uint64       // revision of the last committed mutation
+	log        *mutationLog // nil when running without persistence
+
+	// deleted holds the version of each key removed by a versioned delete
+	// until it is written again, so that an older write arriving late
+	// cannot bring the key back
+	deleted map[string]versionVector
+
+	// writes serializes the writes that commit mutations. A write in a raft
+	// cluster releases mu while its entry is replicated; holding writes
+	// keeps the checks it made against the store valid until it is applied.
+	writes sync.Mutex
+
+	// tombstoneRetention enables soft delete when non-zero
+	tombstoneRetention time.Duration
+
+	// historyDepth and historyMaxAge bound the per-key history; it is not
+	// kept when both are zero
+	historyDepth  int
+	historyMaxAge time.Duration
+
+	// quotas limits writes per key prefix or client; their consumption is
+	// kept up to date under mu
+	quotas []*quota
+
+	// limits are the size and format rules for keys and values
+	limits limits
+
+	// schemas are the JSON Schemas enforced on values, by key prefix
+	schemas map[string]*keySchema
+
+	// access samples reads and writes per key; nil when disabled
+	access *accessTracker
+
+	// raft replicates mutations across a cluster; nil for a standalone
+	// server. appliedIndex is the last raft log entry applied to the store.
+	// raftGroup collects the mutations of a group of entries, from term
+	// raftGroupTerm, until its last entry is applied with them.
+	raft          *raftNode
+	appliedIndex  uint64
+	raftGroup     []*proto.Mutation
+	raftGroupTerm uint64
+
+	// feed publishes applied mutations to replicas streaming from this
+	// server; replica is set while this server replicates from a primary
+	feed    *replicationFeed
+	replica *replicaState
+
+	// antiEntropyInterval is how often a replica compares its records with
+	// its primary's and repairs any that differ; zero disables it
+	antiEntropyInterval time.Duration
+
+	// hints holds writes for other servers that could not be reached
+	hints *hintStore
+
+	// changes records every applied mutation for change data capture
+	changes *changeLog
+
+	// crdtNode identifies this server in the state of CRDT keys; CRDT
+	// operations are refused when it is empty
+	crdtNode string
+
+	// closing is closed when the server starts shutting down, ending the
+	// streams that follow new changes and the store's background work
+	closing chan struct{}
 }
 
 // NewKVStore creates a new key-value store instance
 func NewKVStore() *kvStore {
 	return &kvStore{
-		data: make(map[string]string),
+		data:       make(map[string]record),
+		tombstones: make(map[string]tombstone),
+		deleted:    make(map[string]versionVector),
+		history:    make(map[string][]*proto.HistoryEntry),
+		schemas:    make(map[string]*keySchema),
+		feed:       newReplicationFeed(defaultReplicationBacklog),
+		hints:      newHintStore(defaultMaxHints, defaultHintTTL),
+		changes:    newChangeLog(defaultChangeBacklog),
+		closing:    make(chan struct{}),
+	}
+}
+
+// lockWrite locks the store for a write that commits mutations
+func (k *kvStore) lockWrite() {
+	k.writes.Lock()
+	k.mu.Lock()
+}
+
+// unlockWrite releases the locks taken by lockWrite
+func (k *kvStore) unlockWrite() {
+	k.mu.Unlock()
+	k.writes.Unlock()
+}
+
+// commit assigns the next revisions to a group of mutations made by the
+// client behind ctx, persists them and applies them to the in-memory map.
+// The caller must hold the store locked with lockWrite.
+func (k *kvStore) commit(ctx context.Context, mutations ...*proto.Mutation) error {
+	if k.replica.readOnly() {
+		return failedPrecondition(reasonReadOnlyReplica, k.replica.primary, "read-only replica of "+k.replica.primary,
+			map[string]string{"primary": k.replica.primary})
+	}
+
+	now := time.Now().UnixNano()
+	client := clientIdentity(ctx)
+	for _, m := range mutations {
+		m.Timestamp = now
+		m.Client = client
+	}
+
+	if k.raft != nil {
+		return k.commitReplicated(ctx, mutations)
+	}
+
+	for i, m := range mutations {
+		m.Revision = k.revision + uint64(i) + 1
+	}
+
+	if k.log != nil {
+		if err := k.log.append(mutations...); err != nil {
+			log.Printf("Failed to persist mutations: %v", err)
+			return status.Errorf(codes.Internal, "failed to persist mutation: %v", err)
+		}
+	}
+
+	for _, m := range mutations {
+		k.applyMutation(m)
+	}
+	return nil
+}
+
+// applyMutation applies a committed mutation to the in-memory map
+func (k *kvStore) applyMutation(m *proto.Mutation) {
+	if m.Op == proto.MutationOp_MUTATION_OP_SET_SCHEMA || m.Op == proto.MutationOp_MUTATION_OP_DELETE_SCHEMA {
+		k.applySchemaMutation(m)
+		k.revision = m.Revision
+		k.feed.publish(m)
+		k.changes.observe(m)
+		return
+	}
+
+	k.recordHistory(m)
+
+	old, exists := k.data[m.Key]
+	switch m.Op {
+	case proto.MutationOp_MUTATION_OP_SET:
+		if exists {
+			k.trackUsage(m.Key, old, -1)
+		}
+		rec := record{value: m.Value, checksum: m.Checksum, owner: m.Client, version: versionFromProto(m.Version), crdt: m.Crdt}
+		k.data[m.Key] = rec
+		k.trackUsage(m.Key, rec, 1)
+		delete(k.tombstones, m.Key)
+		delete(k.deleted, m.Key)
+	case proto.MutationOp_MUTATION_OP_DELETE:
+		if exists {
+			k.trackUsage(m.Key, old, -1)
+			if k.tombstoneRetention > 0 {
+				k.tombstones[m.Key] = tombstone{record: old, deletedAt: time.Unix(0, m.Timestamp)}
+			}
+		}
+		delete(k.data, m.Key)
+		if version := versionFromProto(m.Version); version != nil {
+			k.deleted[m.Key] = version
+		}
+	case proto.MutationOp_MUTATION_OP_CLEAR:
+		k.data = make(map[string]record)
+		k.tombstones = make(map[string]tombstone)
+		k.deleted = make(map[string]versionVector)
+		k.resetUsage()
+	}
+	k.revision = m.Revision
+	k.feed.publish(m)
+	k.changes.observe(m)
+}
+
+// setMutation builds the mutation that stores value at key
+func setMutation(key, value string) *proto.Mutation {
+	return &proto.Mutation{
+		Op:       proto.MutationOp_MUTATION_OP_SET,
+		Key:      key,
+		Value:    value,
+		Checksum: checksumOf(key, value),
 	}
 }
 
 // Set stores a value at the given key
 func (k *kvStore) Set(ctx context.Context, req *proto.SetRequest) (*proto.SetResponse, error) {
-	k.mu.Lock()
-	defer k.mu.Unlock()
+	k.access.record(req.Key, true)
+
+	if err := k.limits.validate(req.Key, req.Value); err != nil {
+		return nil, err
+	}
 
-	k.data[req.Key] = req.Value
+	k.lockWrite()
+	defer k.unlockWrite()
+
+	if t := k.data[req.Key].crdt; t != proto.CrdtType_CRDT_TYPE_NONE {
+		return nil, notCrdt(req.Key, t)
+	}
+	if err := k.checkSchema(req.Key, req.Value); err != nil {
+		return nil, err
+	}
+	if err := k.checkQuotas(req.Key, req.Value, clientIdentity(ctx)); err != nil {
+		return nil, err
+	}
+
+	version, apply, err := k.writeVersion(req.Key, req.Version, req.Coordinator)
+	if err != nil {
+		return nil, err
+	}
+	if !apply {
+		return &proto.SetResponse{
+			Success: true,
+			Message: fmt.Sprintf("Key '%s' already has a newer version", req.Key),
+			Version: version.proto(),
+		}, nil
+	}
+
+	m := setMutation(req.Key, req.Value)
+	m.Version = version.proto()
+	if err := k.commit(ctx, m); err != nil {
+		return nil, err
+	}
 	return &proto.SetResponse{
 		Success: true,
 		Message: fmt.Sprintf("Key '%s' set successfully", req.Key),
+		Version: m.Version,
 	}, nil
 }
 
 // Get retrieves the value for the given key
 func (k *kvStore) Get(ctx context.Context, req *proto.GetRequest) (*proto.GetResponse, error) {
+	k.access.record(req.Key, false)
+
 	k.mu.RLock()
 	defer k.mu.RUnlock()
 
-	value, exists := k.data[req.Key]
+	rec, exists := k.data[req.Key]
 	if !exists {
-		return &proto.GetResponse{
-			Success: false,
-			Value:   "",
-			Message: fmt.Sprintf("Key '%s' not found", req.Key),
-		}, nil
+		if version, ok := k.deleted[req.Key]; ok && req.IncludeDeleted {
+			return &proto.GetResponse{
+				Success: false,
+				Message: fmt.Sprintf("Key '%s' was deleted", req.Key),
+				Version: version.proto(),
+				Deleted: true,
+			}, nil
+		}
+		return nil, notFound(reasonKeyNotFound, "key", req.Key, fmt.Sprintf("Key '%s' not found", req.Key))
+	}
+
+	// Never serve a value that no longer matches its checksum
+	if !rec.verify(req.Key) {
+		log.Printf("Checksum mismatch for key '%s'", req.Key)
+		return nil, status.Errorf(codes.DataLoss, "key '%s' failed checksum verification", req.Key)
+	}
+
+	// A CRDT key reads as the value its state represents
+	value := rec.value
+	if rec.crdt != proto.CrdtType_CRDT_TYPE_NONE {
+		var err error
+		if value, err = crdtValue(rec); err != nil {
+			return nil, status.Errorf(codes.DataLoss, "key '%s' has an unreadable CRDT state: %v", req.Key, err)
+		}
 	}
 
 	return &proto.GetResponse{
 		Success: true,
 		Value:   value,
 		Message: fmt.Sprintf("Key '%s' retrieved successfully", req.Key),
+		Version: rec.version.proto(),
 	}, nil
 }
 
 // Delete removes the given key
 func (k *kvStore) Delete(ctx context.Context, req *proto.DeleteRequest) (*proto.DeleteResponse, error) {
-	k.mu.Lock()
-	defer k.mu.Unlock()
+	k.access.record(req.Key, true)
 
-	_, exists := k.data[req.Key]
-	if !exists {
+	k.lockWrite()
+	defer k.unlockWrite()
+
+	// Other servers would bring a deleted CRDT back on the next sync
+	rec, exists := k.data[req.Key]
+	if rec.crdt != proto.CrdtType_CRDT_TYPE_NONE {
+		return nil, notCrdt(req.Key, rec.crdt)
+	}
+
+	version, apply, err := k.writeVersion(req.Key, req.Version, req.Coordinator)
+	if err != nil {
+		return nil, err
+	}
+	// A versioned delete is recorded even without a value to remove, so
+	// that the value cannot arrive later from a write it supersedes
+	if !exists && version == nil {
+		return nil, notFound(reasonKeyNotFound, "key", req.Key, fmt.Sprintf("Key '%s' not found", req.Key))
+	}
+	if !apply {
 		return &proto.DeleteResponse{
-			Success: false,
-			Message: fmt.Sprintf("Key '%s' not found", req.Key),
+			Success: exists,
+			Message: fmt.Sprintf("Key '%s' already has a newer version", req.Key),
+			Version: version.proto(),
 		}, nil
 	}
 
-	delete(k.data, req.Key)
+	m := &proto.Mutation{Op: proto.MutationOp_MUTATION_OP_DELETE, Key: req.Key, Version: version.proto()}
+	if err := k.commit(ctx, m); err != nil {
+		return nil, err
+	}
+	message := fmt.Sprintf("Key '%s' deleted successfully", req.Key)
+	if !exists {
+		message = fmt.Sprintf("Key '%s' not found; its deletion was recorded", req.Key)
+	}
 	return &proto.DeleteResponse{
-		Success: true,
-		Message: fmt.Sprintf("Key '%s' deleted successfully", req.Key),
+		Success: exists,
+		Message: message,
+		Version: m.Version,
 	}, nil
 }
 
@@ -87,23 +364,268 @@ func main() {
 		port = "50051"
 	}
 
-	// Create the key-value store instance
+	// With TLS configured, the server also dials other servers over TLS
+	certificates, err := tlsFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if certificates != nil {
+		useTLS(certificates)
+	}
+
+	// "kvstore-server healthcheck" probes the server running on this port
+	if len(os.Args) > 1 && os.Args[1] == "healthcheck" {
+		if err := checkHealth("localhost:"+port, certificates); err != nil {
+			log.Fatalf("Health check failed: %v", err)
+		}
+		return
+	}
+
+	// Create the key-value store instance, rebuilding it from the mutation
+	// log when a data directory is configured
 	store := NewKVStore()
 
-	// Create gRPC server
-	grpcServer := grpc.NewServer()
+	retention, err := envDuration("KVSTORE_SOFT_DELETE_RETENTION", 0)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	store.tombstoneRetention = retention
+
+	if store.historyDepth, err = envInt("KVSTORE_HISTORY_DEPTH", 0); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if store.historyMaxAge, err = envDuration("KVSTORE_HISTORY_MAX_AGE", 0); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	if store.limits, err = limitsFromEnv(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	if path := os.Getenv("KVSTORE_QUOTA_CONFIG"); path != "" {
+		if store.quotas, err = loadQuotas(path); err != nil {
+			log.Fatalf("Invalid configuration: %v", err)
+		}
+		log.Printf("Loaded %d quotas from %s", len(store.quotas), path)
+	}
+
+	dataDir := os.Getenv("KVSTORE_DATA_DIR")
+	target, err := recoveryTargetFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid recovery target: %v", err)
+	}
+	node, err := raftNodeFromEnv(dataDir)
+	if err != nil {
+		log.Fatalf("Invalid cluster configuration: %v", err)
+	}
+
+	backlog, err := envInt("KVSTORE_REPLICATION_BACKLOG", defaultReplicationBacklog)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	store.feed = newReplicationFeed(backlog)
+
+	changeBacklog, err := envInt("KVSTORE_CDC_BACKLOG", defaultChangeBacklog)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	store.changes = newChangeLog(changeBacklog)
+	if dataDir != "" {
+		// Opened before the store is rebuilt, so that replayed mutations
+		// are recognised as already recorded
+		if store.changes, err = openChangeLog(dataDir, changeBacklog); err != nil {
+			log.Fatalf("Failed to open change log: %v", err)
+		}
+	}
+	primary := os.Getenv("KVSTORE_REPLICA_OF")
+	if primary != "" && node != nil {
+		log.Fatalf("Invalid cluster configuration: a raft cluster member cannot also be a replica")
+	}
+
+	// background is cancelled once the server has stopped serving
+	background, stopBackground := context.WithCancel(context.Background())
+	shutdownTimeout, err := envDuration("KVSTORE_SHUTDOWN_TIMEOUT", defaultShutdownTimeout)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Create gRPC server; cluster members also serve raft and send writes
+	// on to the leader. It starts listening before the store is rebuilt, so
+	// that health checks can tell it is still starting.
+	store.raft = node
+	healthCheck := newServerHealth()
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(healthCheck.unary),
+		grpc.ChainStreamInterceptor(healthCheck.stream),
+	}
+	legacy, err := envBool("KVSTORE_LEGACY_STATUS", false)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if legacy {
+		// Outside raft forwarding, so that errors from the leader are
+		// converted too
+		opts = append(opts, grpc.ChainUnaryInterceptor(legacyStatus))
+		log.Printf("Reporting missing keys as unsuccessful responses for legacy clients")
+	}
+	if certificates != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(certificates.ServerConfig())))
+		go certificates.Watch(background, certs.ReloadInterval)
+		if len(proxyIdentities) > 0 {
+			log.Printf("Serving over TLS; only %d certificate identities may act for other clients", len(proxyIdentities))
+		} else {
+			log.Printf("Serving over TLS; no certified client may act for another without KVSTORE_TLS_PROXY_IDENTITIES")
+		}
+	}
+	if store.raft != nil {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(store.raft.forwardWrites),
+			grpc.ChainStreamInterceptor(store.raft.rejectFollowerStreams),
+		)
+	}
+	grpcServer := grpc.NewServer(opts...)
 	proto.RegisterKeyValueStoreServer(grpcServer, store)
+	if store.raft != nil {
+		proto.RegisterRaftServer(grpcServer, store.raft)
+	}
+	proto.RegisterReplicationServer(grpcServer, &replicationServer{store: store})
+	proto.RegisterChangeFeedServer(grpcServer, &changeFeedServer{store: store})
+	healthCheck.register(grpcServer)
+	reflection.Register(grpcServer)
 
 	// Start listening on the specified port
 	lis, err := net.Listen("tcp", ":"+port)
 	if err != nil {
 		log.Fatalf("Failed to listen: %v", err)
 	}
-
+	served := make(chan error, 1)
+	go func() {
+		served <- grpcServer.Serve(lis)
+	}()
 	log.Printf("Key-Value Store gRPC server starting on :%s", port)
 
-	// Start the server
-	if err := grpcServer.Serve(lis); err != nil {
+	switch {
+	case node != nil:
+		// The raft log in the data directory takes the place of the
+		// mutation log, and the store is rebuilt as entries are committed
+		if target.isSet() {
+			log.Fatalf("Point-in-time recovery is not supported in a raft cluster")
+		}
+		go store.runApplier(store.closing)
+		node.start()
+		log.Printf("Raft node %s started with %d peers", node.id, len(node.peers))
+	case dataDir != "":
+		if err := store.Recover(dataDir, target); err != nil {
+			log.Fatalf("Failed to open data directory: %v", err)
+		}
+	default:
+		log.Printf("KVSTORE_DATA_DIR not set, running without persistence")
+	}
+
+	// A replica starts streaming from where its own log left off
+	if primary != "" {
+		id := os.Getenv("KVSTORE_REPLICA_ID")
+		if id == "" {
+			id, _ = os.Hostname()
+		}
+		if store.antiEntropyInterval, err = envDuration("KVSTORE_ANTI_ENTROPY_INTERVAL", time.Minute); err != nil {
+			log.Fatalf("Invalid configuration: %v", err)
+		}
+		if err := store.startReplica(primary, id); err != nil {
+			log.Fatalf("Invalid replication configuration: %v", err)
+		}
+		log.Printf("Running as a read-only replica of %s", primary)
+	}
+
+	maxHints, err := envInt("KVSTORE_MAX_HINTS", defaultMaxHints)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	hintTTL, err := envDuration("KVSTORE_HINT_TTL", defaultHintTTL)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	hintReplay, err := envDuration("KVSTORE_HINT_REPLAY_INTERVAL", defaultHintReplayInterval)
+	if err != nil || hintReplay == 0 {
+		log.Fatalf("Invalid configuration: KVSTORE_HINT_REPLAY_INTERVAL must be a positive duration")
+	}
+	store.hints = newHintStore(maxHints, hintTTL)
+	go store.hints.run(background, hintReplay)
+
+	// In multi-master mode, CRDT keys are exchanged with the other sites
+	if store.crdtNode = os.Getenv("KVSTORE_CRDT_NODE_ID"); store.crdtNode != "" {
+		if node != nil || primary != "" {
+			log.Fatalf("Invalid cluster configuration: CRDT mode cannot be combined with raft or replication")
+		}
+		interval, err := envDuration("KVSTORE_CRDT_SYNC_INTERVAL", defaultCrdtSyncInterval)
+		if err != nil || interval == 0 {
+			log.Fatalf("Invalid configuration: KVSTORE_CRDT_SYNC_INTERVAL must be a positive duration")
+		}
+		var peers []string
+		for _, peer := range strings.Split(os.Getenv("KVSTORE_CRDT_PEERS"), ",") {
+			if peer = strings.TrimSpace(peer); peer != "" {
+				peers = append(peers, peer)
+			}
+		}
+		if err := store.startCrdtSync(background, peers, interval); err != nil {
+			log.Fatalf("Invalid configuration: %v", err)
+		}
+		log.Printf("CRDT mode enabled as node %s, syncing with %d peers", store.crdtNode, len(peers))
+	}
+
+	if store.tombstoneRetention > 0 {
+		log.Printf("Soft delete enabled with a retention of %v", store.tombstoneRetention)
+		go store.runTombstoneGC(store.closing)
+	}
+	if store.historyMaxAge > 0 {
+		go store.runHistoryGC(store.closing)
+	}
+
+	sampleRate, err := envFloat("KVSTORE_HOTKEY_SAMPLE_RATE", 0.01)
+	if err != nil || sampleRate > 1 {
+		log.Fatalf("Invalid configuration: KVSTORE_HOTKEY_SAMPLE_RATE must be between 0 and 1")
+	}
+	if sampleRate > 0 {
+		topK, err := envInt("KVSTORE_HOTKEY_TOP_K", 100)
+		if err != nil || topK == 0 {
+			log.Fatalf("Invalid configuration: KVSTORE_HOTKEY_TOP_K must be a positive integer")
+		}
+		halfLife, err := envDuration("KVSTORE_HOTKEY_HALF_LIFE", 10*time.Minute)
+		if err != nil {
+			log.Fatalf("Invalid configuration: %v", err)
+		}
+
+		store.access = newAccessTracker(sampleRate, topK)
+		if halfLife > 0 {
+			go store.access.runDecay(halfLife, store.closing)
+		}
+		log.Printf("Tracking the top %d keys from %g of accesses", topK, sampleRate)
+	}
+
+	// Report the server as serving now that the store is ready
+	healthCheck.setServing()
+	go store.changes.runRetry(changeRetryInterval, func(err error) {
+		healthCheck.reportService(proto.ChangeFeed_ServiceDesc.ServiceName, err)
+	}, store.closing)
+	log.Printf("Key-Value Store gRPC server serving on :%s", port)
+
+	// On SIGINT or SIGTERM, requests in flight are drained before the
+	// store is flushed and closed
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-served:
 		log.Fatalf("Failed to serve: %v", err)
+	case sig := <-signals:
+		log.Printf("Received %v, draining requests for up to %v", sig, shutdownTimeout)
+	}
+
+	if !drain(grpcServer, healthCheck, store, shutdownTimeout) {
+		log.Printf("Requests still in flight after %v were cut off", shutdownTimeout)
+	}
+	stopBackground()
+	if err := store.shutdown(); err != nil {
+		log.Fatalf("Failed to close the store: %v", err)
 	}
+	log.Printf("Key-Value Store gRPC server stopped")
 }
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pwntato/Censys/proto"
+
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+const (
+	// logFrameHeaderSize is the size of the length and CRC32C preceding every log entry
+	logFrameHeaderSize = 8
+
+	// maxLogEntrySize bounds the length read from a frame header so that a
+	// corrupt header cannot trigger a huge allocation
+	maxLogEntrySize = 64 << 20
+)
+
+// errLogCorrupt is returned when a log entry in the middle of the log fails verification
+var errLogCorrupt = errors.New("mutation log is corrupt")
+
+// mutationLog is an append-only file of committed mutations. Each entry is
+// framed as a little-endian uint32 length, a CRC32C of the payload and the
+// protobuf-encoded Mutation.
+type mutationLog struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// openMutationLog opens the log at path for appending, creating it if needed
+func openMutationLog(path string) (*mutationLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &mutationLog{path: path, file: file}, nil
+}
+
+// append durably writes mutations to the log. The entries are written with
+// a single write and fsync so that a group commit either fully reaches the
+// disk or is cut off at the tail.
+func (l *mutationLog) append(mutations ...*proto.Mutation) error {
+	var buf []byte
+	for _, m := range mutations {
+		var err error
+		if buf, err = appendFrame(buf, m); err != nil {
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.file.Write(buf); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// appendFrame appends the framed encoding of a message to buf
+func appendFrame(buf []byte, m protobuf.Message) ([]byte, error) {
+	payload, err := protobuf.Marshal(m)
+	if err != nil {
+		return buf, err
+	}
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(payload)))
+	buf = binary.LittleEndian.AppendUint32(buf, crc32.Checksum(payload, crc32cTable))
+	return append(buf, payload...), nil
+}
+
+// close closes the underlying file
+func (l *mutationLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// readMutationLog calls fn for every entry in the log at path, along with
+// the offset just past it. It returns the size of the valid prefix of the
+// log: an entry cut off at the tail by a crash ends the log there, whereas
+// a damaged entry with more data after it is reported as errLogCorrupt.
+// A missing log is treated as empty.
+func readMutationLog(path string, fn func(m *proto.Mutation, end int64) error) (int64, error) {
+	return readFrames(path, func(payload []byte, offset, end int64) error {
+		m := &proto.Mutation{}
+		if err := protobuf.Unmarshal(payload, m); err != nil {
+			return fmt.Errorf("%w: undecodable entry at offset %d: %v", errLogCorrupt, offset, err)
+		}
+		return fn(m, end)
+	})
+}
+
+// readFrames calls fn for the payload of every frame in the file at path,
+// with the offsets of the frame and of the end of it, and returns the size
+// of its valid prefix as described for readMutationLog
+func readFrames(path string, fn func(payload []byte, offset, end int64) error) (int64, error) {
+	return readFramesFrom(path, 0, fn)
+}
+
+// readFramesFrom is readFrames starting at the frame at offset start
+func readFramesFrom(path string, start int64, fn func(payload []byte, offset, end int64) error) (int64, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	reader := bufio.NewReader(file)
+	offset := start
+	header := make([]byte, logFrameHeaderSize)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return offset, nil
+			}
+			return offset, err
+		}
+
+		length := binary.LittleEndian.Uint32(header)
+		end := offset + logFrameHeaderSize + int64(length)
+		if length > maxLogEntrySize {
+			return offset, fmt.Errorf("%w: invalid entry length at offset %d", errLogCorrupt, offset)
+		}
+		if end > size {
+			// The last entry was only partially written
+			return offset, nil
+		}
+
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			return offset, err
+		}
+
+		if crc32.Checksum(payload, crc32cTable) != binary.LittleEndian.Uint32(header[4:]) {
+			if end == size {
+				return offset, nil
+			}
+			return offset, fmt.Errorf("%w: checksum mismatch at offset %d", errLogCorrupt, offset)
+		}
+
+		if err := fn(payload, offset, end); err != nil {
+			return offset, err
+		}
+		offset = end
+	}
+}
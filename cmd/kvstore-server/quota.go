@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// quota limits the keys under a prefix, the keys owned by a client, or
+// both at once. A limit of 0 means unlimited.
+type quota struct {
+	Name          string `json:"name"`
+	Prefix        string `json:"prefix,omitempty"`
+	Client        string `json:"client,omitempty"`
+	MaxKeys       int64  `json:"max_keys,omitempty"`
+	MaxBytes      int64  `json:"max_bytes,omitempty"`
+	MaxValueBytes int64  `json:"max_value_bytes,omitempty"`
+
+	// Current consumption, maintained as mutations are applied
+	keys  int64
+	bytes int64
+}
+
+// quotaConfig is the layout of the file named by KVSTORE_QUOTA_CONFIG
+type quotaConfig struct {
+	Quotas []*quota `json:"quotas"`
+}
+
+// loadQuotas reads and validates a quota configuration file
+func loadQuotas(path string) ([]*quota, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg quotaConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid quota config %s: %v", path, err)
+	}
+
+	names := make(map[string]bool)
+	for i, q := range cfg.Quotas {
+		if q.Name == "" {
+			return nil, fmt.Errorf("quota %d has no name", i)
+		}
+		if names[q.Name] {
+			return nil, fmt.Errorf("duplicate quota name '%s'", q.Name)
+		}
+		names[q.Name] = true
+		if q.MaxKeys < 0 || q.MaxBytes < 0 || q.MaxValueBytes < 0 {
+			return nil, fmt.Errorf("quota '%s' has a negative limit", q.Name)
+		}
+	}
+	return cfg.Quotas, nil
+}
+
+// applies reports whether the quota covers a key owned by the given client
+func (q *quota) applies(key, owner string) bool {
+	return strings.HasPrefix(key, q.Prefix) && (q.Client == "" || q.Client == owner)
+}
+
+// recordSize is the number of bytes a key-value pair counts for against a quota
+func recordSize(key, value string) int64 {
+	return int64(len(key) + len(value))
+}
+
+// trackUsage adds (sign 1) or removes (sign -1) a record from the
+// consumption of every quota that covers it
+func (k *kvStore) trackUsage(key string, rec record, sign int64) {
+	for _, q := range k.quotas {
+		if q.applies(key, rec.owner) {
+			q.keys += sign
+			q.bytes += sign * recordSize(key, rec.value)
+		}
+	}
+}
+
+// resetUsage zeroes the consumption of every quota
+func (k *kvStore) resetUsage() {
+	for _, q := range k.quotas {
+		q.keys, q.bytes = 0, 0
+	}
+}
+
+// quotaUsage is the consumption of one quota at some point
+type quotaUsage struct {
+	keys  int64
+	bytes int64
+}
+
+// saveUsage returns the consumption of every quota, for restoreUsage
+func (k *kvStore) saveUsage() []quotaUsage {
+	saved := make([]quotaUsage, len(k.quotas))
+	for i, q := range k.quotas {
+		saved[i] = quotaUsage{keys: q.keys, bytes: q.bytes}
+	}
+	return saved
+}
+
+// restoreUsage puts back the consumption returned by saveUsage
+func (k *kvStore) restoreUsage(saved []quotaUsage) {
+	for i, q := range k.quotas {
+		q.keys, q.bytes = saved[i].keys, saved[i].bytes
+	}
+}
+
+// checkQuotas returns a ResourceExhausted error if writing value at key on
+// behalf of client would exceed any quota. Writes that do not increase
+// consumption are always allowed, so keys can be shrunk back under a limit
+// that was lowered. The caller must hold k.mu.
+func (k *kvStore) checkQuotas(key, value, client string) error {
+	old, exists := k.data[key]
+	return k.checkQuotasReplacing(key, value, client, old, exists)
+}
+
+// checkQuotasReplacing is checkQuotas for a write replacing old, or a new
+// key when exists is false. The caller must hold k.mu.
+func (k *kvStore) checkQuotasReplacing(key, value, client string, old record, exists bool) error {
+	var violations []*errdetails.QuotaFailure_Violation
+	violate := func(q *quota, format string, a ...any) {
+		violations = append(violations, &errdetails.QuotaFailure_Violation{
+			Subject:     "quota:" + q.Name,
+			Description: fmt.Sprintf(format, a...),
+		})
+	}
+
+	for _, q := range k.quotas {
+		if !q.applies(key, client) {
+			continue
+		}
+
+		if q.MaxValueBytes > 0 && int64(len(value)) > q.MaxValueBytes {
+			violate(q, "value of %d bytes exceeds max_value_bytes %d", len(value), q.MaxValueBytes)
+		}
+
+		keys, bytes := q.keys+1, q.bytes+recordSize(key, value)
+		if exists && q.applies(key, old.owner) {
+			keys--
+			bytes -= recordSize(key, old.value)
+		}
+		if q.MaxKeys > 0 && keys > q.MaxKeys && keys > q.keys {
+			violate(q, "max_keys %d reached", q.MaxKeys)
+		}
+		if q.MaxBytes > 0 && bytes > q.MaxBytes && bytes > q.bytes {
+			violate(q, "write would use %d of max_bytes %d", bytes, q.MaxBytes)
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	st, err := status.New(codes.ResourceExhausted, fmt.Sprintf("quota exceeded for key '%s': %s", key, violations[0].Description)).
+		WithDetails(&errdetails.QuotaFailure{Violations: violations})
+	if err != nil {
+		return status.Errorf(codes.ResourceExhausted, "quota exceeded for key '%s'", key)
+	}
+	return st.Err()
+}
+
+// QuotaUsage reports consumption against every configured quota
+func (k *kvStore) QuotaUsage(ctx context.Context, req *proto.QuotaUsageRequest) (*proto.QuotaUsageResponse, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	quotas := make([]*proto.QuotaStatus, 0, len(k.quotas))
+	for _, q := range k.quotas {
+		quotas = append(quotas, &proto.QuotaStatus{
+			Name:          q.Name,
+			Prefix:        q.Prefix,
+			Client:        q.Client,
+			MaxKeys:       q.MaxKeys,
+			MaxBytes:      q.MaxBytes,
+			MaxValueBytes: q.MaxValueBytes,
+			Keys:          q.keys,
+			Bytes:         q.bytes,
+		})
+	}
+
+	return &proto.QuotaUsageResponse{
+		Success: true,
+		Quotas:  quotas,
+		Message: fmt.Sprintf("%d quotas configured", len(quotas)),
+	}, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newQuotaStore(quotas ...*quota) *kvStore {
+	store := NewKVStore()
+	store.quotas = quotas
+	return store
+}
+
+func TestQuota_MaxKeysPerPrefix(t *testing.T) {
+	store := newQuotaStore(&quota{Name: "tenant-a", Prefix: "a/", MaxKeys: 2})
+	ctx := context.Background()
+
+	for _, key := range []string{"a/1", "a/2", "b/1", "b/2", "b/3"} {
+		if _, err := store.Set(ctx, &proto.SetRequest{Key: key, Value: "v"}); err != nil {
+			t.Fatalf("Set(%s) error = %v", key, err)
+		}
+	}
+
+	_, err := store.Set(ctx, &proto.SetRequest{Key: "a/3", Value: "v"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Set() over quota error = %v, expected ResourceExhausted", err)
+	}
+
+	var failure *errdetails.QuotaFailure
+	for _, detail := range status.Convert(err).Details() {
+		if qf, ok := detail.(*errdetails.QuotaFailure); ok {
+			failure = qf
+		}
+	}
+	if failure == nil || len(failure.Violations) != 1 || failure.Violations[0].Subject != "quota:tenant-a" {
+		t.Errorf("QuotaFailure details = %v", failure)
+	}
+
+	// Overwriting an existing key does not add a key
+	if _, err := store.Set(ctx, &proto.SetRequest{Key: "a/1", Value: "updated"}); err != nil {
+		t.Errorf("Set() overwrite error = %v", err)
+	}
+
+	// Deleting frees up room
+	store.Delete(ctx, &proto.DeleteRequest{Key: "a/2"})
+	if _, err := store.Set(ctx, &proto.SetRequest{Key: "a/3", Value: "v"}); err != nil {
+		t.Errorf("Set() after delete error = %v", err)
+	}
+}
+
+func TestQuota_PerClientBytes(t *testing.T) {
+	store := newQuotaStore(&quota{Name: "alice", Client: "alice", MaxBytes: 10, MaxValueBytes: 6})
+
+	if _, err := store.Set(asClient("alice"), &proto.SetRequest{Key: "k1", Value: "123456"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		client   string
+		key      string
+		value    string
+		expected codes.Code
+	}{
+		{"Value too large", "alice", "k2", "1234567", codes.ResourceExhausted},
+		{"Bytes exhausted", "alice", "k2", "12", codes.ResourceExhausted},
+		{"Shrinking an existing key", "alice", "k1", "1", codes.OK},
+		{"Other client unaffected", "bob", "k3", "12345678", codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.Set(asClient(tt.client), &proto.SetRequest{Key: tt.key, Value: tt.value})
+			if status.Code(err) != tt.expected {
+				t.Errorf("Set() error = %v, expected %v", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestQuota_Undelete(t *testing.T) {
+	store := newSoftDeleteStore(time.Hour)
+	store.quotas = []*quota{{Name: "a", Prefix: "a/", MaxKeys: 1}}
+	ctx := context.Background()
+
+	store.Set(ctx, &proto.SetRequest{Key: "a/1", Value: "v"})
+	store.Delete(ctx, &proto.DeleteRequest{Key: "a/1"})
+	store.Set(ctx, &proto.SetRequest{Key: "a/2", Value: "v"})
+
+	if _, err := store.Undelete(ctx, &proto.UndeleteRequest{Key: "a/1"}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Undelete() over quota error = %v, expected ResourceExhausted", err)
+	}
+}
+
+func TestQuotaUsage(t *testing.T) {
+	store := newQuotaStore(
+		&quota{Name: "a", Prefix: "a/", MaxKeys: 10},
+		&quota{Name: "bob", Client: "bob"},
+	)
+
+	store.Set(asClient("bob"), &proto.SetRequest{Key: "a/1", Value: "xyz"})
+	store.Set(asClient("alice"), &proto.SetRequest{Key: "a/2", Value: "xyz"})
+	store.Set(asClient("bob"), &proto.SetRequest{Key: "b/1", Value: "xyz"})
+
+	// An overwrite by another client moves the key out of bob's quota
+	store.Set(asClient("alice"), &proto.SetRequest{Key: "b/1", Value: "xyz"})
+
+	resp, err := store.QuotaUsage(context.Background(), &proto.QuotaUsageRequest{})
+	if err != nil {
+		t.Fatalf("QuotaUsage() error = %v", err)
+	}
+
+	expected := map[string][2]int64{"a": {2, 12}, "bob": {1, 6}}
+	for _, q := range resp.Quotas {
+		if got := [2]int64{q.Keys, q.Bytes}; got != expected[q.Name] {
+			t.Errorf("quota %s usage = %v, expected %v", q.Name, got, expected[q.Name])
+		}
+	}
+
+	// Usage is rebuilt when the store is cleared by a replace restore
+	store.applyMutation(&proto.Mutation{Op: proto.MutationOp_MUTATION_OP_CLEAR})
+	resp, _ = store.QuotaUsage(context.Background(), &proto.QuotaUsageRequest{})
+	for _, q := range resp.Quotas {
+		if q.Keys != 0 || q.Bytes != 0 {
+			t.Errorf("quota %s usage after clear = %d keys, %d bytes", q.Name, q.Keys, q.Bytes)
+		}
+	}
+}
+
+func TestLoadQuotas(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{"Valid", `{"quotas":[{"name":"a","prefix":"a/","max_keys":5},{"name":"bob","client":"bob","max_bytes":1024}]}`, false},
+		{"Missing name", `{"quotas":[{"prefix":"a/"}]}`, true},
+		{"Duplicate name", `{"quotas":[{"name":"a"},{"name":"a"}]}`, true},
+		{"Negative limit", `{"quotas":[{"name":"a","max_keys":-1}]}`, true},
+		{"Malformed", `{"quotas":`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "quotas.json")
+			if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := loadQuotas(path); (err != nil) != tt.wantErr {
+				t.Errorf("loadQuotas() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
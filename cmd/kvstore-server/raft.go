@@ -0,0 +1,964 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// raftMaxBatch bounds the number of entries sent in one AppendEntries call
+const raftMaxBatch = 256
+
+// raftMaxMessageBytes bounds the mutations in an entry and the entries in an
+// AppendEntries call or a snapshot chunk, keeping every message well inside
+// gRPC's default 4MB limit. A single mutation is never split, so it may go
+// over on its own.
+const raftMaxMessageBytes = 1 << 20
+
+// raftRole is the part a node currently plays in the cluster
+type raftRole int
+
+const (
+	raftFollower raftRole = iota
+	raftCandidate
+	raftLeader
+)
+
+func (r raftRole) String() string {
+	switch r {
+	case raftCandidate:
+		return "candidate"
+	case raftLeader:
+		return "leader"
+	}
+	return "follower"
+}
+
+// raftConfig holds the timing of a node. A follower that hears nothing from
+// a leader for between one and two election timeouts starts an election.
+// The log is compacted into a snapshot once snapshotEntries entries have
+// been applied since the last one; zero never compacts it.
+type raftConfig struct {
+	electionTimeout   time.Duration
+	heartbeatInterval time.Duration
+	snapshotEntries   uint64
+}
+
+// raftPeer is another member of the cluster
+type raftPeer struct {
+	id   string
+	addr string
+	conn *grpc.ClientConn
+	raft proto.RaftClient
+
+	// trigger wakes the peer's replicator when there are new entries
+	trigger chan struct{}
+}
+
+// raftNode replicates the mutation log across the cluster with the Raft
+// consensus algorithm. The store applies entries once they are committed;
+// see kvStore.applyCommitted.
+type raftNode struct {
+	proto.UnimplementedRaftServer
+
+	id      string
+	peers   []*raftPeer
+	config  raftConfig
+	storage *raftStorage
+
+	// applyCh is signalled whenever the commit index advances
+	applyCh chan struct{}
+
+	mu       sync.Mutex
+	role     raftRole
+	term     uint64
+	votedFor string
+	leaderID string
+
+	// log holds the entries after the snapshot, preceded by a sentinel with
+	// the index and term of the snapshot's last entry; see entry
+	log         []*proto.RaftEntry
+	commitIndex uint64
+
+	// snapshot is the latest snapshot, or nil before the first one.
+	// snapshotMu serializes replacing it, which happens partly outside mu
+	// so that writing a large snapshot does not hold up the node.
+	snapshot   *proto.RaftSnapshot
+	snapshotMu sync.Mutex
+
+	// nextIndex and matchIndex are tracked per peer while leading
+	nextIndex  map[string]uint64
+	matchIndex map[string]uint64
+
+	electionDeadline time.Time
+
+	// changed is closed and replaced whenever the commit index or the
+	// term changes, waking proposers waiting for their entries
+	changed chan struct{}
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newRaftNode creates a cluster member. peers maps the id of every member,
+// including this one, to its gRPC address. The node's term, vote and log
+// are persisted in dataDir, or kept in memory only if dataDir is empty.
+func newRaftNode(id string, peers map[string]string, dataDir string, config raftConfig) (*raftNode, error) {
+	if _, ok := peers[id]; !ok {
+		return nil, fmt.Errorf("raft id %q is not one of the peers", id)
+	}
+
+	rn := &raftNode{
+		id:         id,
+		config:     config,
+		applyCh:    make(chan struct{}, 1),
+		log:        []*proto.RaftEntry{{}},
+		nextIndex:  make(map[string]uint64),
+		matchIndex: make(map[string]uint64),
+		changed:    make(chan struct{}),
+		stop:       make(chan struct{}),
+	}
+
+	if dataDir != "" {
+		storage, state, snap, entries, err := openRaftStorage(dataDir)
+		if err != nil {
+			return nil, err
+		}
+		rn.storage = storage
+		rn.term, rn.votedFor = state.Term, state.VotedFor
+		if snap != nil {
+			// Everything in the snapshot was committed; the store loads it
+			// as soon as its applier starts
+			rn.snapshot = snap
+			rn.log[0] = &proto.RaftEntry{Index: snap.LastIndex, Term: snap.LastTerm}
+			rn.setCommitIndex(snap.LastIndex)
+		}
+		rn.log = append(rn.log, entries...)
+	}
+
+	ids := make([]string, 0, len(peers))
+	for peerID := range peers {
+		if peerID != id {
+			ids = append(ids, peerID)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, peerID := range ids {
+		conn, err := grpc.NewClient(peers[peerID], grpc.WithTransportCredentials(peerCredentials))
+		if err != nil {
+			rn.closePeers()
+			return nil, fmt.Errorf("invalid address for raft peer %s: %v", peerID, err)
+		}
+		rn.peers = append(rn.peers, &raftPeer{
+			id:      peerID,
+			addr:    peers[peerID],
+			conn:    conn,
+			raft:    proto.NewRaftClient(conn),
+			trigger: make(chan struct{}, 1),
+		})
+	}
+	return rn, nil
+}
+
+// parseRaftPeers parses a list of members such as "a=host1:50051,b=host2:50051"
+func parseRaftPeers(s string) (map[string]string, error) {
+	peers := make(map[string]string)
+	for _, member := range strings.Split(s, ",") {
+		id, addr, ok := strings.Cut(strings.TrimSpace(member), "=")
+		if !ok || id == "" || addr == "" {
+			return nil, fmt.Errorf("invalid raft peer %q, expected id=host:port", member)
+		}
+		if _, dup := peers[id]; dup {
+			return nil, fmt.Errorf("duplicate raft peer id %q", id)
+		}
+		peers[id] = addr
+	}
+	return peers, nil
+}
+
+// start begins taking part in elections and replication
+func (rn *raftNode) start() {
+	rn.mu.Lock()
+	rn.resetElectionTimer()
+	rn.mu.Unlock()
+
+	rn.wg.Add(1)
+	go rn.runElectionTimer()
+	for _, p := range rn.peers {
+		rn.wg.Add(1)
+		go rn.replicate(p)
+	}
+}
+
+// shutdown stops the node and releases its connections and files
+func (rn *raftNode) shutdown() {
+	close(rn.stop)
+	rn.wg.Wait()
+	rn.closePeers()
+
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if rn.storage != nil {
+		rn.storage.close()
+	}
+}
+
+// closePeers closes the connections to the other members
+func (rn *raftNode) closePeers() {
+	for _, p := range rn.peers {
+		p.conn.Close()
+	}
+}
+
+// quorum is the number of members, including this one, that form a majority
+func (rn *raftNode) quorum() int {
+	return (len(rn.peers)+1)/2 + 1
+}
+
+// lastIndexAndTerm returns the position of the last log entry. The caller
+// must hold rn.mu.
+func (rn *raftNode) lastIndexAndTerm() (uint64, uint64) {
+	last := rn.log[len(rn.log)-1]
+	return last.Index, last.Term
+}
+
+// snapshotIndex returns the index of the last entry covered by the
+// snapshot, or zero. The caller must hold rn.mu.
+func (rn *raftNode) snapshotIndex() uint64 {
+	return rn.log[0].Index
+}
+
+// entry returns the entry at index, which must not be older than the
+// snapshot's last entry. The caller must hold rn.mu.
+func (rn *raftNode) entry(index uint64) *proto.RaftEntry {
+	return rn.log[index-rn.snapshotIndex()]
+}
+
+// resetElectionTimer schedules the next election a random time between one
+// and two election timeouts from now. The caller must hold rn.mu.
+func (rn *raftNode) resetElectionTimer() {
+	timeout := rn.config.electionTimeout + rand.N(rn.config.electionTimeout)
+	rn.electionDeadline = time.Now().Add(timeout)
+}
+
+// notifyChanged wakes everything waiting on a commit or term change. The
+// caller must hold rn.mu.
+func (rn *raftNode) notifyChanged() {
+	close(rn.changed)
+	rn.changed = make(chan struct{})
+}
+
+// persistState saves the term and vote before they are acted on. The
+// caller must hold rn.mu.
+func (rn *raftNode) persistState() {
+	if rn.storage == nil {
+		return
+	}
+	if err := rn.storage.saveState(raftHardState{Term: rn.term, VotedFor: rn.votedFor}); err != nil {
+		// Voting twice in a term could elect two leaders, so a node that
+		// cannot record its vote must not carry on
+		log.Fatalf("Failed to persist raft state: %v", err)
+	}
+}
+
+// appendLog adds entries to the end of the log and persists them. The
+// caller must hold rn.mu.
+func (rn *raftNode) appendLog(entries ...*proto.RaftEntry) error {
+	if rn.storage != nil {
+		if err := rn.storage.append(entries); err != nil {
+			return err
+		}
+	}
+	rn.log = append(rn.log, entries...)
+	return nil
+}
+
+// stepDown reverts to follower, adopting term if it is newer. The caller
+// must hold rn.mu.
+func (rn *raftNode) stepDown(term uint64) {
+	if term > rn.term {
+		rn.term = term
+		rn.votedFor = ""
+		rn.leaderID = ""
+		rn.persistState()
+	}
+	if rn.role != raftFollower {
+		log.Printf("Raft node %s stepping down to follower in term %d", rn.id, rn.term)
+		rn.role = raftFollower
+		rn.resetElectionTimer()
+	}
+	rn.notifyChanged()
+}
+
+// runElectionTimer starts an election whenever the election deadline
+// passes without word from a leader
+func (rn *raftNode) runElectionTimer() {
+	defer rn.wg.Done()
+
+	ticker := time.NewTicker(rn.config.heartbeatInterval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			rn.mu.Lock()
+			due := rn.role != raftLeader && time.Now().After(rn.electionDeadline)
+			rn.mu.Unlock()
+			if due {
+				rn.startElection()
+			}
+		case <-rn.stop:
+			return
+		}
+	}
+}
+
+// startElection becomes a candidate for the next term and asks every peer
+// for its vote
+func (rn *raftNode) startElection() {
+	rn.mu.Lock()
+	rn.role = raftCandidate
+	rn.term++
+	rn.votedFor = rn.id
+	rn.leaderID = ""
+	rn.persistState()
+	rn.resetElectionTimer()
+	rn.notifyChanged()
+
+	term := rn.term
+	lastIndex, lastTerm := rn.lastIndexAndTerm()
+	votes := 1
+	if votes >= rn.quorum() {
+		rn.becomeLeader()
+	}
+	rn.mu.Unlock()
+
+	req := &proto.VoteRequest{
+		Term:         term,
+		CandidateId:  rn.id,
+		LastLogIndex: lastIndex,
+		LastLogTerm:  lastTerm,
+	}
+	for _, p := range rn.peers {
+		go func(p *raftPeer) {
+			ctx, cancel := context.WithTimeout(context.Background(), rn.config.electionTimeout)
+			defer cancel()
+
+			resp, err := p.raft.RequestVote(ctx, req)
+			if err != nil {
+				return
+			}
+
+			rn.mu.Lock()
+			defer rn.mu.Unlock()
+			if resp.Term > rn.term {
+				rn.stepDown(resp.Term)
+				return
+			}
+			if rn.role != raftCandidate || rn.term != term || !resp.VoteGranted {
+				return
+			}
+			votes++
+			if votes == rn.quorum() {
+				rn.becomeLeader()
+			}
+		}(p)
+	}
+}
+
+// becomeLeader takes over leadership after winning an election. It appends
+// an empty entry, whose commit also commits everything left over from
+// earlier terms. The caller must hold rn.mu.
+func (rn *raftNode) becomeLeader() {
+	log.Printf("Raft node %s elected leader for term %d", rn.id, rn.term)
+	rn.role = raftLeader
+	rn.leaderID = rn.id
+
+	lastIndex, _ := rn.lastIndexAndTerm()
+	for _, p := range rn.peers {
+		rn.nextIndex[p.id] = lastIndex + 1
+		rn.matchIndex[p.id] = 0
+	}
+
+	if err := rn.appendLog(&proto.RaftEntry{Term: rn.term, Index: lastIndex + 1}); err != nil {
+		log.Printf("Failed to persist raft log: %v", err)
+		rn.stepDown(rn.term)
+		return
+	}
+	rn.advanceCommit()
+	rn.triggerReplication()
+}
+
+// triggerReplication wakes every peer's replicator. The caller must hold rn.mu.
+func (rn *raftNode) triggerReplication() {
+	for _, p := range rn.peers {
+		select {
+		case p.trigger <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// replicate sends new entries, or a heartbeat when there are none, to a
+// peer for as long as this node leads
+func (rn *raftNode) replicate(p *raftPeer) {
+	defer rn.wg.Done()
+
+	ticker := time.NewTicker(rn.config.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.trigger:
+		case <-rn.stop:
+			return
+		}
+
+		req, snap := rn.appendRequestFor(p)
+		if snap != nil {
+			rn.sendSnapshot(p, snap)
+			continue
+		}
+		if req == nil {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), rn.config.electionTimeout)
+		resp, err := p.raft.AppendEntries(ctx, req)
+		cancel()
+		if err != nil {
+			continue
+		}
+		rn.handleAppendResponse(p, req, resp)
+	}
+}
+
+// appendRequestFor builds the next AppendEntries request for a peer, or
+// returns the snapshot to send instead when the entries the peer needs have
+// been compacted away. It returns neither if this node is not the leader.
+func (rn *raftNode) appendRequestFor(p *raftPeer) (*proto.AppendEntriesRequest, *proto.RaftSnapshot) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	if rn.role != raftLeader {
+		return nil, nil
+	}
+
+	next := rn.nextIndex[p.id]
+	if next <= rn.snapshotIndex() {
+		return nil, rn.snapshot
+	}
+
+	req := &proto.AppendEntriesRequest{
+		Term:         rn.term,
+		LeaderId:     rn.id,
+		PrevLogIndex: next - 1,
+		PrevLogTerm:  rn.entry(next - 1).Term,
+		LeaderCommit: rn.commitIndex,
+	}
+	lastIndex, _ := rn.lastIndexAndTerm()
+	size := 0
+	for index := next; index <= lastIndex && len(req.Entries) < raftMaxBatch; index++ {
+		e := rn.entry(index)
+		size += protobuf.Size(e)
+		if size > raftMaxMessageBytes && len(req.Entries) > 0 {
+			break
+		}
+		req.Entries = append(req.Entries, e)
+	}
+	return req, nil
+}
+
+// sendSnapshot streams the snapshot to a peer whose next entries have been
+// compacted away, and records its progress once the peer has installed it
+func (rn *raftNode) sendSnapshot(p *raftPeer, snap *proto.RaftSnapshot) {
+	rn.mu.Lock()
+	term, leading := rn.term, rn.role == raftLeader
+	rn.mu.Unlock()
+	if !leading {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-rn.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	stream, err := p.raft.InstallSnapshot(ctx)
+	if err != nil {
+		return
+	}
+	chunk := func() *proto.InstallSnapshotRequest {
+		return &proto.InstallSnapshotRequest{
+			Term:      term,
+			LeaderId:  rn.id,
+			LastIndex: snap.LastIndex,
+			LastTerm:  snap.LastTerm,
+			Revision:  snap.Revision,
+			Timestamp: snap.Timestamp,
+		}
+	}
+
+	req, size := chunk(), 0
+	for _, m := range snap.Mutations {
+		size += protobuf.Size(m)
+		if size > raftMaxMessageBytes && len(req.Mutations) > 0 {
+			if err := stream.Send(req); err != nil {
+				return
+			}
+			req, size = chunk(), protobuf.Size(m)
+		}
+		req.Mutations = append(req.Mutations, m)
+	}
+	if err := stream.Send(req); err != nil {
+		return
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		log.Printf("Failed to send raft snapshot to %s: %v", p.id, err)
+		return
+	}
+
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if resp.Term > rn.term {
+		rn.stepDown(resp.Term)
+		return
+	}
+	if rn.role != raftLeader || rn.term != term {
+		return
+	}
+	log.Printf("Raft node %s sent %s a snapshot up to index %d", rn.id, p.id, snap.LastIndex)
+	rn.matchIndex[p.id] = max(rn.matchIndex[p.id], snap.LastIndex)
+	rn.nextIndex[p.id] = rn.matchIndex[p.id] + 1
+	rn.advanceCommit()
+	select {
+	case p.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// handleAppendResponse records a peer's progress and advances the commit
+// index once a majority holds an entry of the current term
+func (rn *raftNode) handleAppendResponse(p *raftPeer, req *proto.AppendEntriesRequest, resp *proto.AppendEntriesResponse) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	if resp.Term > rn.term {
+		rn.stepDown(resp.Term)
+		return
+	}
+	if rn.role != raftLeader || rn.term != req.Term {
+		return
+	}
+
+	lastIndex, _ := rn.lastIndexAndTerm()
+	if resp.Success {
+		rn.matchIndex[p.id] = max(rn.matchIndex[p.id], resp.MatchIndex)
+		rn.nextIndex[p.id] = rn.matchIndex[p.id] + 1
+		rn.advanceCommit()
+	} else {
+		rn.nextIndex[p.id] = min(max(resp.MatchIndex, 1), lastIndex+1)
+	}
+
+	// Keep going while the peer is behind rather than waiting for the
+	// next heartbeat
+	if rn.nextIndex[p.id] <= lastIndex {
+		select {
+		case p.trigger <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// advanceCommit moves the commit index to the newest entry of the current
+// term held by a majority. The caller must hold rn.mu.
+func (rn *raftNode) advanceCommit() {
+	lastIndex, _ := rn.lastIndexAndTerm()
+	for n := lastIndex; n > rn.commitIndex; n-- {
+		if rn.entry(n).Term != rn.term {
+			break
+		}
+		count := 1
+		for _, p := range rn.peers {
+			if rn.matchIndex[p.id] >= n {
+				count++
+			}
+		}
+		if count >= rn.quorum() {
+			rn.setCommitIndex(n)
+			return
+		}
+	}
+}
+
+// setCommitIndex records newly committed entries and wakes the applier.
+// The caller must hold rn.mu.
+func (rn *raftNode) setCommitIndex(index uint64) {
+	rn.commitIndex = index
+	rn.notifyChanged()
+	select {
+	case rn.applyCh <- struct{}{}:
+	default:
+	}
+}
+
+// RequestVote grants a vote to a candidate whose log is at least as up to
+// date as this node's, at most once per term
+func (rn *raftNode) RequestVote(ctx context.Context, req *proto.VoteRequest) (*proto.VoteResponse, error) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	if req.Term > rn.term {
+		rn.stepDown(req.Term)
+	}
+
+	lastIndex, lastTerm := rn.lastIndexAndTerm()
+	upToDate := req.LastLogTerm > lastTerm || (req.LastLogTerm == lastTerm && req.LastLogIndex >= lastIndex)
+	granted := req.Term == rn.term && (rn.votedFor == "" || rn.votedFor == req.CandidateId) && upToDate
+	if granted {
+		rn.votedFor = req.CandidateId
+		rn.persistState()
+		rn.resetElectionTimer()
+	}
+
+	return &proto.VoteResponse{Term: rn.term, VoteGranted: granted}, nil
+}
+
+// AppendEntries accepts entries from the leader, replacing any conflicting
+// suffix of the log, and learns the leader's commit index
+func (rn *raftNode) AppendEntries(ctx context.Context, req *proto.AppendEntriesRequest) (*proto.AppendEntriesResponse, error) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	if req.Term < rn.term {
+		return &proto.AppendEntriesResponse{Term: rn.term}, nil
+	}
+	if req.Term > rn.term || rn.role != raftFollower {
+		rn.stepDown(req.Term)
+	}
+	rn.leaderID = req.LeaderId
+	rn.resetElectionTimer()
+
+	// Entries up to the snapshot are committed, so they match the leader's
+	prevIndex, prevTerm, entries := req.PrevLogIndex, req.PrevLogTerm, req.Entries
+	if first := rn.snapshotIndex(); prevIndex < first {
+		skip := min(first-prevIndex, uint64(len(entries)))
+		prevIndex, prevTerm, entries = first, rn.log[0].Term, entries[skip:]
+	}
+
+	// The entry before the new ones must match, otherwise tell the leader
+	// where to retry from: just past our log, or the start of the
+	// conflicting term so that it can be skipped in one round trip
+	lastIndex, _ := rn.lastIndexAndTerm()
+	if prevIndex > lastIndex {
+		return &proto.AppendEntriesResponse{Term: rn.term, MatchIndex: lastIndex + 1}, nil
+	}
+	if conflict := rn.entry(prevIndex).Term; conflict != prevTerm {
+		retry := prevIndex
+		for retry > rn.snapshotIndex()+1 && rn.entry(retry-1).Term == conflict {
+			retry--
+		}
+		return &proto.AppendEntriesResponse{Term: rn.term, MatchIndex: retry}, nil
+	}
+
+	for i, e := range entries {
+		if e.Index <= lastIndex {
+			if rn.entry(e.Index).Term == e.Term {
+				continue
+			}
+			if rn.storage != nil {
+				if err := rn.storage.truncate(e.Index - 1); err != nil {
+					return nil, status.Errorf(codes.Internal, "failed to truncate raft log: %v", err)
+				}
+			}
+			rn.log = rn.log[:e.Index-rn.snapshotIndex()]
+		}
+		if err := rn.appendLog(entries[i:]...); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to persist raft log: %v", err)
+		}
+		break
+	}
+
+	match := req.PrevLogIndex + uint64(len(req.Entries))
+	if commit := min(req.LeaderCommit, match); commit > rn.commitIndex {
+		rn.setCommitIndex(commit)
+	}
+	return &proto.AppendEntriesResponse{Term: rn.term, Success: true, MatchIndex: match}, nil
+}
+
+// Status reports this node's view of the cluster
+func (rn *raftNode) Status(ctx context.Context, req *proto.RaftStatusRequest) (*proto.RaftStatusResponse, error) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	lastIndex, _ := rn.lastIndexAndTerm()
+	peers := make([]string, 0, len(rn.peers))
+	for _, p := range rn.peers {
+		peers = append(peers, p.id+"="+p.addr)
+	}
+
+	return &proto.RaftStatusResponse{
+		Id:            rn.id,
+		Role:          rn.role.String(),
+		Term:          rn.term,
+		LeaderId:      rn.leaderID,
+		CommitIndex:   rn.commitIndex,
+		LastLogIndex:  lastIndex,
+		Peers:         peers,
+		SnapshotIndex: rn.snapshotIndex(),
+	}, nil
+}
+
+// propose appends a group of mutations to the log as the leader and starts
+// replicating it. A group too large for one message is split across
+// consecutive partial entries, appended together so that nothing comes
+// between them. It returns the position of the group's last entry.
+func (rn *raftNode) propose(mutations []*proto.Mutation) (index, term uint64, err error) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	if rn.role != raftLeader {
+		return 0, 0, rn.notLeaderError()
+	}
+
+	lastIndex, _ := rn.lastIndexAndTerm()
+	entry := &proto.RaftEntry{Term: rn.term, Index: lastIndex + 1}
+	entries := []*proto.RaftEntry{entry}
+	size := 0
+	for _, m := range mutations {
+		size += protobuf.Size(m)
+		if size > raftMaxMessageBytes && len(entry.Mutations) > 0 {
+			entry.Partial = true
+			entry = &proto.RaftEntry{Term: rn.term, Index: entry.Index + 1}
+			entries = append(entries, entry)
+			size = protobuf.Size(m)
+		}
+		entry.Mutations = append(entry.Mutations, m)
+	}
+	if err := rn.appendLog(entries...); err != nil {
+		log.Printf("Failed to persist raft log: %v", err)
+		return 0, 0, status.Error(codes.Internal, "failed to persist mutation")
+	}
+
+	rn.advanceCommit()
+	rn.triggerReplication()
+	return entry.Index, entry.Term, nil
+}
+
+// waitCommitted blocks until the entry proposed at index in term is
+// committed. It fails if the entry was superseded by a new leader.
+func (rn *raftNode) waitCommitted(ctx context.Context, index, term uint64) error {
+	for {
+		rn.mu.Lock()
+		lastIndex, _ := rn.lastIndexAndTerm()
+		superseded := index > lastIndex || (index > rn.snapshotIndex() && rn.entry(index).Term != term)
+		if superseded {
+			rn.mu.Unlock()
+			return status.Error(codes.Unavailable, "leadership changed before the write was committed")
+		}
+		// An entry compacted into a snapshot is only known to be this one
+		// if no other leader has been elected since
+		if rn.commitIndex >= index && (index > rn.snapshotIndex() || rn.term == term) {
+			rn.mu.Unlock()
+			return nil
+		}
+		if rn.term != term {
+			// The entry may still be committed by the new leader, but this
+			// node can no longer tell when
+			rn.mu.Unlock()
+			return status.Error(codes.Unavailable, "leadership changed before the write was committed; it may still be applied")
+		}
+		changed := rn.changed
+		rn.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// committedAfter returns the committed entries following index. If some of
+// them have been compacted away it returns the snapshot as well, and the
+// entries that follow it.
+func (rn *raftNode) committedAfter(index uint64) (*proto.RaftSnapshot, []*proto.RaftEntry) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	var snap *proto.RaftSnapshot
+	if index < rn.snapshotIndex() {
+		snap, index = rn.snapshot, rn.snapshotIndex()
+	}
+	if index >= rn.commitIndex {
+		return snap, nil
+	}
+	return snap, append([]*proto.RaftEntry(nil), rn.log[index+1-rn.snapshotIndex():rn.commitIndex+1-rn.snapshotIndex()]...)
+}
+
+// snapshotDue reports whether enough entries have been applied since the
+// last snapshot to take another
+func (rn *raftNode) snapshotDue(applied uint64) bool {
+	if rn.config.snapshotEntries == 0 {
+		return false
+	}
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	return applied >= rn.snapshotIndex()+rn.config.snapshotEntries
+}
+
+// compact replaces the log up to snap.LastIndex, which must have been
+// applied, with snap. The snapshot is written outside rn.mu; only cutting
+// the log, which holds little more than the entries not yet applied, holds
+// up the node.
+func (rn *raftNode) compact(snap *proto.RaftSnapshot) error {
+	rn.snapshotMu.Lock()
+	defer rn.snapshotMu.Unlock()
+
+	rn.mu.Lock()
+	if snap.LastIndex <= rn.snapshotIndex() {
+		rn.mu.Unlock()
+		return nil
+	}
+	snap.LastTerm = rn.entry(snap.LastIndex).Term
+	rn.mu.Unlock()
+
+	return rn.installSnapshot(snap)
+}
+
+// installSnapshot persists snap and drops the log entries it covers. The
+// entries after it are kept if the log holds its last entry; otherwise the
+// log is replaced by the snapshot. The caller must hold rn.snapshotMu.
+func (rn *raftNode) installSnapshot(snap *proto.RaftSnapshot) error {
+	if rn.storage != nil {
+		if err := rn.storage.saveSnapshot(snap); err != nil {
+			return err
+		}
+	}
+
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	lastIndex, _ := rn.lastIndexAndTerm()
+	var rest []*proto.RaftEntry
+	if snap.LastIndex <= lastIndex && rn.entry(snap.LastIndex).Term == snap.LastTerm {
+		rest = rn.log[snap.LastIndex+1-rn.snapshotIndex():]
+	}
+	if rn.storage != nil {
+		if err := rn.storage.compact(snap.LastIndex, rest); err != nil {
+			// The saved snapshot covers the entries left in the file,
+			// which are skipped when the log is next opened
+			return err
+		}
+	}
+	rn.log = append([]*proto.RaftEntry{{Index: snap.LastIndex, Term: snap.LastTerm}}, rest...)
+	rn.snapshot = snap
+	if snap.LastIndex > rn.commitIndex {
+		rn.setCommitIndex(snap.LastIndex)
+	}
+	return nil
+}
+
+// InstallSnapshot receives a snapshot from the leader in chunks and
+// replaces the part of the log it covers, or the whole log if it does not
+// match. The store loads it in place of the entries it replaces.
+func (rn *raftNode) InstallSnapshot(stream grpc.ClientStreamingServer[proto.InstallSnapshotRequest, proto.InstallSnapshotResponse]) error {
+	var snap *proto.RaftSnapshot
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		// Hearing from the leader between chunks holds off an election
+		rn.mu.Lock()
+		if req.Term < rn.term {
+			term := rn.term
+			rn.mu.Unlock()
+			return stream.SendAndClose(&proto.InstallSnapshotResponse{Term: term})
+		}
+		if req.Term > rn.term || rn.role != raftFollower {
+			rn.stepDown(req.Term)
+		}
+		rn.leaderID = req.LeaderId
+		rn.resetElectionTimer()
+		rn.mu.Unlock()
+
+		if snap == nil {
+			snap = &proto.RaftSnapshot{LastIndex: req.LastIndex, LastTerm: req.LastTerm, Revision: req.Revision, Timestamp: req.Timestamp}
+		}
+		snap.Mutations = append(snap.Mutations, req.Mutations...)
+	}
+	if snap == nil {
+		return status.Error(codes.InvalidArgument, "snapshot stream was empty")
+	}
+
+	rn.snapshotMu.Lock()
+	defer rn.snapshotMu.Unlock()
+
+	rn.mu.Lock()
+	stale := snap.LastIndex <= rn.snapshotIndex()
+	rn.mu.Unlock()
+	if !stale {
+		if err := rn.installSnapshot(snap); err != nil {
+			log.Printf("Failed to install raft snapshot: %v", err)
+			return status.Errorf(codes.Internal, "failed to install snapshot: %v", err)
+		}
+		log.Printf("Raft node %s installed a snapshot up to index %d", rn.id, snap.LastIndex)
+	}
+
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	return stream.SendAndClose(&proto.InstallSnapshotResponse{Term: rn.term})
+}
+
+// leader returns the current leader, or nil if it is this node or unknown
+func (rn *raftNode) leader() (peer *raftPeer, isSelf bool) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+
+	if rn.role == raftLeader {
+		return nil, true
+	}
+	for _, p := range rn.peers {
+		if p.id == rn.leaderID {
+			return p, false
+		}
+	}
+	return nil, false
+}
+
+// notLeaderError tells the client where writes must go instead. The caller
+// must hold rn.mu.
+func (rn *raftNode) notLeaderError() error {
+	for _, p := range rn.peers {
+		if p.id == rn.leaderID {
+			return status.Errorf(codes.Unavailable, "not the raft leader; send writes to %s at %s", p.id, p.addr)
+		}
+	}
+	return status.Error(codes.Unavailable, "not the raft leader and no leader is known yet")
+}
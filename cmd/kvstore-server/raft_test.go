@@ -0,0 +1,577 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testRaftConfig keeps elections fast enough for tests
+var testRaftConfig = raftConfig{electionTimeout: 150 * time.Millisecond, heartbeatInterval: 30 * time.Millisecond}
+
+// testMember is a cluster member served over loopback
+type testMember struct {
+	id     string
+	addr   string
+	dir    string
+	config raftConfig
+	store  *kvStore
+	server *grpc.Server
+	stop   chan struct{}
+}
+
+// startTestCluster starts n members on loopback listeners
+func startTestCluster(t *testing.T, n int) []*testMember {
+	t.Helper()
+	return startTestClusterWith(t, n, testRaftConfig)
+}
+
+// startTestClusterWith starts n members with config on loopback listeners
+func startTestClusterWith(t *testing.T, n int, config raftConfig) []*testMember {
+	t.Helper()
+
+	peers := make(map[string]string)
+	listeners := make([]net.Listener, n)
+	for i := range listeners {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Listen() error = %v", err)
+		}
+		listeners[i] = lis
+		peers[fmt.Sprintf("node%d", i+1)] = lis.Addr().String()
+	}
+
+	members := make([]*testMember, n)
+	for i, lis := range listeners {
+		m := &testMember{id: fmt.Sprintf("node%d", i+1), addr: lis.Addr().String(), dir: t.TempDir(), config: config}
+		m.start(t, peers, lis)
+		members[i] = m
+	}
+	t.Cleanup(func() {
+		for _, m := range members {
+			m.shutdown()
+		}
+	})
+	return members
+}
+
+// start runs the member's store, raft node and gRPC server on lis
+func (m *testMember) start(t *testing.T, peers map[string]string, lis net.Listener) {
+	t.Helper()
+
+	node, err := newRaftNode(m.id, peers, m.dir, m.config)
+	if err != nil {
+		t.Fatalf("newRaftNode() error = %v", err)
+	}
+
+	m.store = NewKVStore()
+	m.store.raft = node
+	m.stop = make(chan struct{})
+	m.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(node.forwardWrites),
+		grpc.ChainStreamInterceptor(node.rejectFollowerStreams),
+	)
+	proto.RegisterKeyValueStoreServer(m.server, m.store)
+	proto.RegisterRaftServer(m.server, node)
+
+	go m.server.Serve(lis)
+	go m.store.runApplier(m.stop)
+	node.start()
+}
+
+// shutdown stops the member, as if it crashed; it is safe to call twice
+func (m *testMember) shutdown() {
+	if m.server == nil {
+		return
+	}
+	m.server.Stop()
+	m.store.raft.shutdown()
+	close(m.stop)
+	m.server = nil
+}
+
+// client returns a KeyValueStore client connected to the member
+func (m *testMember) client(t *testing.T) proto.KeyValueStoreClient {
+	t.Helper()
+
+	conn, err := grpc.NewClient(m.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return proto.NewKeyValueStoreClient(conn)
+}
+
+// value returns the value the member's store holds for key
+func (m *testMember) value(key string) (record, bool) {
+	m.store.mu.RLock()
+	defer m.store.mu.RUnlock()
+	rec, ok := m.store.data[key]
+	return rec, ok
+}
+
+// waitFor polls cond until it holds, failing the test after a few seconds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitForLeader returns the single running member that leads the cluster
+func waitForLeader(t *testing.T, members []*testMember) *testMember {
+	t.Helper()
+
+	var leader *testMember
+	waitFor(t, "a leader", func() bool {
+		leader = nil
+		for _, m := range members {
+			if m.server == nil {
+				continue
+			}
+			if _, isSelf := m.store.raft.leader(); isSelf {
+				if leader != nil {
+					return false
+				}
+				leader = m
+			}
+		}
+		return leader != nil
+	})
+	return leader
+}
+
+func TestRaft_ReplicatesWritesThroughFollower(t *testing.T) {
+	members := startTestCluster(t, 3)
+	leader := waitForLeader(t, members)
+
+	var follower *testMember
+	for _, m := range members {
+		if m != leader {
+			follower = m
+			break
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, clientIDMetadataKey, "alice")
+
+	client := follower.client(t)
+	if _, err := client.Set(ctx, &proto.SetRequest{Key: "key", Value: "v1"}); err != nil {
+		t.Fatalf("Set() through follower error = %v", err)
+	}
+	if _, err := client.Delete(ctx, &proto.DeleteRequest{Key: "missing"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("Delete() of missing key error = %v, expected NotFound", err)
+	}
+
+	for _, m := range members {
+		waitFor(t, m.id+" to apply the write", func() bool {
+			rec, ok := m.value("key")
+			return ok && rec.value == "v1" && rec.owner == "alice"
+		})
+	}
+
+	// Every member numbers the mutation the same way
+	for _, m := range members {
+		m.store.mu.RLock()
+		revision := m.store.revision
+		m.store.mu.RUnlock()
+		if revision != 1 {
+			t.Errorf("%s revision = %d, expected 1", m.id, revision)
+		}
+	}
+}
+
+func TestRaft_SurvivesMinorityFailure(t *testing.T) {
+	members := startTestCluster(t, 3)
+	leader := waitForLeader(t, members)
+	ctx := context.Background()
+
+	if _, err := leader.store.Set(ctx, &proto.SetRequest{Key: "before", Value: "1"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	leader.shutdown()
+	newLeader := waitForLeader(t, members)
+	if newLeader == leader {
+		t.Fatal("stopped node is still the leader")
+	}
+
+	if _, err := newLeader.store.Set(ctx, &proto.SetRequest{Key: "after", Value: "2"}); err != nil {
+		t.Fatalf("Set() after failover error = %v", err)
+	}
+	if _, ok := newLeader.value("before"); !ok {
+		t.Error("write committed before failover was lost")
+	}
+
+	// The old leader rejoins from its persisted log and catches up
+	peers := make(map[string]string)
+	for _, m := range members {
+		peers[m.id] = m.addr
+	}
+	lis, err := net.Listen("tcp", leader.addr)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	leader.start(t, peers, lis)
+
+	waitFor(t, "the restarted node to catch up", func() bool {
+		_, before := leader.value("before")
+		_, after := leader.value("after")
+		return before && after
+	})
+}
+
+func TestRaft_ReadsDoNotWaitForReplication(t *testing.T) {
+	members := startTestCluster(t, 3)
+	leader := waitForLeader(t, members)
+	ctx := context.Background()
+
+	if _, err := leader.store.Set(ctx, &proto.SetRequest{Key: "key", Value: "v1"}); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	// Without its followers the leader cannot commit the next write
+	for _, m := range members {
+		if m != leader {
+			m.shutdown()
+		}
+	}
+	writeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := leader.store.Set(writeCtx, &proto.SetRequest{Key: "key", Value: "v2"})
+		done <- err
+	}()
+	waitFor(t, "the write to be proposed", func() bool {
+		leader.store.raft.mu.Lock()
+		defer leader.store.raft.mu.Unlock()
+		last, _ := leader.store.raft.lastIndexAndTerm()
+		return last > leader.store.raft.commitIndex
+	})
+
+	read := make(chan *proto.GetResponse, 1)
+	go func() {
+		resp, _ := leader.store.Get(ctx, &proto.GetRequest{Key: "key"})
+		read <- resp
+	}()
+	select {
+	case resp := <-read:
+		if resp == nil || resp.Value != "v1" {
+			t.Errorf("Get() = %v, expected the committed value v1", resp)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Get() waited for a write that cannot commit")
+	}
+
+	cancel()
+	if err := <-done; status.Code(err) != codes.Canceled {
+		t.Errorf("Set() error = %v, expected Canceled", err)
+	}
+}
+
+func TestRaft_AppendEntriesReplacesConflictingSuffix(t *testing.T) {
+	dir := t.TempDir()
+	node, err := newRaftNode("a", map[string]string{"a": "127.0.0.1:1", "b": "127.0.0.1:2"}, dir, testRaftConfig)
+	if err != nil {
+		t.Fatalf("newRaftNode() error = %v", err)
+	}
+	ctx := context.Background()
+
+	entry := func(term, index uint64, key string) *proto.RaftEntry {
+		return &proto.RaftEntry{Term: term, Index: index, Mutations: []*proto.Mutation{setMutation(key, "v")}}
+	}
+
+	resp, _ := node.AppendEntries(ctx, &proto.AppendEntriesRequest{
+		Term: 1, LeaderId: "b",
+		Entries: []*proto.RaftEntry{entry(1, 1, "x"), entry(1, 2, "y"), entry(1, 3, "z")},
+	})
+	if !resp.Success || resp.MatchIndex != 3 {
+		t.Fatalf("AppendEntries() = %v", resp)
+	}
+
+	// A new leader that never saw entries 2 and 3 overwrites them
+	resp, _ = node.AppendEntries(ctx, &proto.AppendEntriesRequest{
+		Term: 2, LeaderId: "b", PrevLogIndex: 1, PrevLogTerm: 1,
+		Entries: []*proto.RaftEntry{entry(2, 2, "w")}, LeaderCommit: 2,
+	})
+	if !resp.Success || resp.MatchIndex != 2 {
+		t.Fatalf("AppendEntries() = %v", resp)
+	}
+
+	// A mismatched previous entry is refused with a retry point
+	resp, _ = node.AppendEntries(ctx, &proto.AppendEntriesRequest{Term: 2, LeaderId: "b", PrevLogIndex: 5, PrevLogTerm: 2})
+	if resp.Success || resp.MatchIndex != 3 {
+		t.Errorf("AppendEntries() past the log = %v, expected a retry from 3", resp)
+	}
+	node.shutdown()
+
+	_, state, _, entries, err := openRaftStorage(dir)
+	if err != nil {
+		t.Fatalf("openRaftStorage() error = %v", err)
+	}
+	if state.Term != 2 || len(entries) != 2 || entries[1].Term != 2 || entries[1].Mutations[0].Key != "w" {
+		t.Errorf("persisted term %d, entries %v", state.Term, entries)
+	}
+}
+
+func TestRaft_SnapshotCatchesUpFollower(t *testing.T) {
+	config := testRaftConfig
+	config.snapshotEntries = 5
+	members := startTestClusterWith(t, 3, config)
+	leader := waitForLeader(t, members)
+	ctx := context.Background()
+
+	var lagging *testMember
+	for _, m := range members {
+		if m != leader {
+			lagging = m
+			break
+		}
+	}
+	lagging.shutdown()
+
+	for i := 0; i < 20; i++ {
+		if _, err := leader.store.Set(ctx, &proto.SetRequest{Key: fmt.Sprintf("key-%d", i), Value: "v"}); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+	waitFor(t, "the leader to compact its log", func() bool {
+		resp, _ := leader.store.raft.Status(ctx, &proto.RaftStatusRequest{})
+		return resp.SnapshotIndex >= 5
+	})
+
+	// The entries the stopped member missed are gone, so it is sent the
+	// snapshot followed by what came after it
+	peers := make(map[string]string)
+	for _, m := range members {
+		peers[m.id] = m.addr
+	}
+	lis, err := net.Listen("tcp", lagging.addr)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	lagging.start(t, peers, lis)
+
+	waitFor(t, "the restarted member to catch up", func() bool {
+		for i := 0; i < 20; i++ {
+			if _, ok := lagging.value(fmt.Sprintf("key-%d", i)); !ok {
+				return false
+			}
+		}
+		return true
+	})
+	if got, want := lagging.store.currentRevision(), leader.store.currentRevision(); got != want {
+		t.Errorf("restarted member revision = %d, expected %d", got, want)
+	}
+}
+
+func TestRaft_RestartsFromSnapshot(t *testing.T) {
+	config := testRaftConfig
+	config.snapshotEntries = 5
+	members := startTestClusterWith(t, 1, config)
+	m := waitForLeader(t, members)
+	ctx := context.Background()
+
+	for i := 0; i < 12; i++ {
+		if _, err := m.store.Set(ctx, &proto.SetRequest{Key: fmt.Sprintf("key-%d", i), Value: "v"}); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+	waitFor(t, "the log to be compacted", func() bool {
+		resp, _ := m.store.raft.Status(ctx, &proto.RaftStatusRequest{})
+		return resp.SnapshotIndex >= 5
+	})
+	revision := m.store.currentRevision()
+	m.shutdown()
+
+	lis, err := net.Listen("tcp", m.addr)
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	m.start(t, map[string]string{m.id: m.addr}, lis)
+
+	waitFor(t, "the store to be rebuilt", func() bool {
+		return m.store.currentRevision() >= revision
+	})
+	for i := 0; i < 12; i++ {
+		if _, ok := m.value(fmt.Sprintf("key-%d", i)); !ok {
+			t.Errorf("key-%d was lost on restart", i)
+		}
+	}
+}
+
+func TestRaft_SplitsLargeGroups(t *testing.T) {
+	members := startTestCluster(t, 3)
+	leader := waitForLeader(t, members)
+
+	value := strings.Repeat("x", 600<<10)
+	mutations := []*proto.Mutation{setMutation("a", value), setMutation("b", value), setMutation("c", value)}
+	leader.store.lockWrite()
+	err := leader.store.commit(context.Background(), mutations...)
+	leader.store.unlockWrite()
+	if err != nil {
+		t.Fatalf("commit() error = %v", err)
+	}
+
+	leader.store.raft.mu.Lock()
+	partial := 0
+	for _, e := range leader.store.raft.log {
+		if e.Partial {
+			partial++
+		}
+	}
+	leader.store.raft.mu.Unlock()
+	if partial != 2 {
+		t.Errorf("group was split into %d partial entries, expected 2", partial)
+	}
+
+	for _, m := range members {
+		waitFor(t, m.id+" to apply the group", func() bool {
+			_, ok := m.value("c")
+			return ok
+		})
+		if _, ok := m.value("a"); !ok {
+			t.Errorf("%s is missing the start of the group", m.id)
+		}
+	}
+}
+
+func TestRaft_DropsGroupCutShort(t *testing.T) {
+	node, err := newRaftNode("a", map[string]string{"a": "127.0.0.1:1"}, "", testRaftConfig)
+	if err != nil {
+		t.Fatalf("newRaftNode() error = %v", err)
+	}
+	entry := func(term, index uint64, partial bool, keys ...string) *proto.RaftEntry {
+		e := &proto.RaftEntry{Term: term, Index: index, Partial: partial}
+		for _, key := range keys {
+			e.Mutations = append(e.Mutations, setMutation(key, "v"))
+		}
+		return e
+	}
+
+	// The leader of term 1 failed after replicating half of a group
+	resp, _ := node.AppendEntries(context.Background(), &proto.AppendEntriesRequest{
+		Term: 2, LeaderId: "b", LeaderCommit: 5,
+		Entries: []*proto.RaftEntry{
+			entry(1, 1, true, "lost"),
+			entry(2, 2, false),
+			entry(2, 3, false, "single"),
+			entry(2, 4, true, "first"),
+			entry(2, 5, false, "second"),
+		},
+	})
+	if !resp.Success {
+		t.Fatalf("AppendEntries() = %v", resp)
+	}
+
+	store := NewKVStore()
+	store.raft = node
+	store.mu.Lock()
+	store.applyCommitted()
+	store.mu.Unlock()
+
+	for key, expected := range map[string]bool{"lost": false, "single": true, "first": true, "second": true} {
+		if _, ok := store.data[key]; ok != expected {
+			t.Errorf("key %q stored = %v, expected %v", key, ok, expected)
+		}
+	}
+	if store.revision != 3 || store.appliedIndex != 5 {
+		t.Errorf("revision %d, applied index %d, expected 3 and 5", store.revision, store.appliedIndex)
+	}
+}
+
+func TestRaftStorage_Compact(t *testing.T) {
+	dir := t.TempDir()
+	s, _, _, _, err := openRaftStorage(dir)
+	if err != nil {
+		t.Fatalf("openRaftStorage() error = %v", err)
+	}
+	var entries []*proto.RaftEntry
+	for i := uint64(1); i <= 6; i++ {
+		entries = append(entries, &proto.RaftEntry{Term: 1, Index: i, Mutations: []*proto.Mutation{setMutation(fmt.Sprintf("key-%d", i), "v")}})
+	}
+	if err := s.append(entries); err != nil {
+		t.Fatalf("append() error = %v", err)
+	}
+
+	// A node that stops after saving a snapshot but before compacting
+	// skips the entries the snapshot covers when it starts again
+	if err := s.saveSnapshot(&proto.RaftSnapshot{LastIndex: 2, LastTerm: 1, Revision: 2}); err != nil {
+		t.Fatalf("saveSnapshot() error = %v", err)
+	}
+	s.close()
+	s, _, snap, loaded, err := openRaftStorage(dir)
+	if err != nil {
+		t.Fatalf("openRaftStorage() error = %v", err)
+	}
+	if snap.GetLastIndex() != 2 || len(loaded) != 4 || loaded[0].Index != 3 {
+		t.Fatalf("reopened with snapshot %v and %d entries", snap, len(loaded))
+	}
+
+	// Compacting further and cutting back the log keep the file in step
+	if err := s.saveSnapshot(&proto.RaftSnapshot{LastIndex: 4, LastTerm: 1, Revision: 4}); err != nil {
+		t.Fatalf("saveSnapshot() error = %v", err)
+	}
+	if err := s.compact(4, entries[4:]); err != nil {
+		t.Fatalf("compact() error = %v", err)
+	}
+	if err := s.truncate(5); err != nil {
+		t.Fatalf("truncate() error = %v", err)
+	}
+	if err := s.append([]*proto.RaftEntry{{Term: 2, Index: 6}}); err != nil {
+		t.Fatalf("append() error = %v", err)
+	}
+	s.close()
+
+	_, _, snap, loaded, err = openRaftStorage(dir)
+	if err != nil {
+		t.Fatalf("openRaftStorage() error = %v", err)
+	}
+	if snap.GetLastIndex() != 4 || len(loaded) != 2 || loaded[0].Index != 5 || loaded[1].Term != 2 {
+		t.Errorf("reopened with snapshot %v and entries %v", snap, loaded)
+	}
+}
+
+func TestParseRaftPeers(t *testing.T) {
+	tests := []struct {
+		name    string
+		peers   string
+		wantErr bool
+	}{
+		{"Valid", "a=kv1:50051, b=kv2:50051,c=kv3:50051", false},
+		{"Missing address", "a=kv1:50051,b", true},
+		{"Duplicate id", "a=kv1:50051,a=kv2:50051", true},
+		{"Empty", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peers, err := parseRaftPeers(tt.peers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRaftPeers() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(peers) != 3 {
+				t.Errorf("parseRaftPeers() = %v", peers)
+			}
+		})
+	}
+}
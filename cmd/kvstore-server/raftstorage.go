@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/pwntato/Censys/proto"
+
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+// Names of the Raft files inside the data directory
+const (
+	raftLogFile      = "raft.log"
+	raftStateFile    = "raft.state"
+	raftSnapshotFile = "raft.snapshot"
+)
+
+// raftHardState is the part of a node's state that must survive a restart
+// for its votes to stay safe
+type raftHardState struct {
+	Term     uint64 `json:"term"`
+	VotedFor string `json:"voted_for"`
+}
+
+// raftStorage persists a node's hard state, snapshot and log. The log uses
+// the same framing as the mutation log, one RaftEntry per frame, and holds
+// the entries that follow the snapshot.
+type raftStorage struct {
+	dir  string
+	file *os.File
+
+	// first is the index of the last entry covered by the snapshot, so the
+	// file starts with entry first+1
+	first uint64
+
+	// ends holds the file offset just past each entry in the file, so the
+	// log can be cut back when a leader overwrites a conflicting suffix
+	ends []int64
+}
+
+// openRaftStorage loads the hard state, snapshot and log persisted in dir.
+// The snapshot is nil if none was taken yet.
+func openRaftStorage(dir string) (*raftStorage, raftHardState, *proto.RaftSnapshot, []*proto.RaftEntry, error) {
+	var state raftHardState
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, state, nil, nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, raftStateFile))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, state, nil, nil, err
+	default:
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, state, nil, nil, fmt.Errorf("invalid %s: %v", raftStateFile, err)
+		}
+	}
+
+	snap, err := readRaftSnapshot(filepath.Join(dir, raftSnapshotFile))
+	if err != nil {
+		return nil, state, nil, nil, err
+	}
+
+	s := &raftStorage{dir: dir}
+	if snap != nil {
+		s.first = snap.LastIndex
+	}
+	path := filepath.Join(dir, raftLogFile)
+
+	// Entries the snapshot already covers are left behind if the node
+	// stopped between saving a snapshot and compacting its log
+	var entries []*proto.RaftEntry
+	stale := false
+	validSize, err := readFrames(path, func(payload []byte, offset, end int64) error {
+		e := &proto.RaftEntry{}
+		if err := protobuf.Unmarshal(payload, e); err != nil {
+			return fmt.Errorf("%w: undecodable entry at offset %d: %v", errLogCorrupt, offset, err)
+		}
+		if e.Index <= s.first && len(entries) == 0 {
+			stale = true
+			return nil
+		}
+		if e.Index != s.first+uint64(len(entries))+1 {
+			return fmt.Errorf("%w: expected index %d at offset %d, found %d", errLogCorrupt, s.first+uint64(len(entries))+1, offset, e.Index)
+		}
+		entries = append(entries, e)
+		s.ends = append(s.ends, end)
+		return nil
+	})
+	if err != nil {
+		return nil, state, nil, nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	if s.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+		return nil, state, nil, nil, err
+	}
+	if stale {
+		err = s.compact(s.first, entries)
+	} else if info, statErr := s.file.Stat(); statErr == nil && info.Size() > validSize {
+		log.Printf("Discarding %d bytes of partially written entries at the end of %s", info.Size()-validSize, path)
+		err = s.file.Truncate(validSize)
+	}
+	if err != nil {
+		s.file.Close()
+		return nil, state, nil, nil, err
+	}
+	return s, state, snap, entries, nil
+}
+
+// readRaftSnapshot loads the snapshot at path, or returns nil if there is none
+func readRaftSnapshot(path string) (*proto.RaftSnapshot, error) {
+	var snap *proto.RaftSnapshot
+	validSize, err := readFrames(path, func(payload []byte, offset, end int64) error {
+		snap = &proto.RaftSnapshot{}
+		if err := protobuf.Unmarshal(payload, snap); err != nil {
+			return fmt.Errorf("%w: undecodable snapshot: %v", errLogCorrupt, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	// The snapshot is written in one piece and renamed into place, so
+	// anything short of a whole frame is damage rather than a torn write
+	if info, err := os.Stat(path); err == nil && info.Size() != validSize {
+		return nil, fmt.Errorf("reading %s: %w: %d bytes are not part of the snapshot", path, errLogCorrupt, info.Size()-validSize)
+	}
+	return snap, nil
+}
+
+// replaceFile durably replaces the file name in dir with data
+func (s *raftStorage) replaceFile(name string, data []byte) error {
+	tmp := filepath.Join(s.dir, name+".tmp")
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, name))
+}
+
+// saveState durably replaces the persisted hard state
+func (s *raftStorage) saveState(state raftHardState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return s.replaceFile(raftStateFile, data)
+}
+
+// saveSnapshot durably replaces the persisted snapshot. The log still holds
+// the entries it covers until compact is called.
+func (s *raftStorage) saveSnapshot(snap *proto.RaftSnapshot) error {
+	data, err := appendFrame(nil, snap)
+	if err != nil {
+		return err
+	}
+	return s.replaceFile(raftSnapshotFile, data)
+}
+
+// compact rewrites the log to hold only entries, which follow the entry at
+// index that the saved snapshot ends with
+func (s *raftStorage) compact(index uint64, entries []*proto.RaftEntry) error {
+	var buf []byte
+	ends := make([]int64, 0, len(entries))
+	for _, e := range entries {
+		var err error
+		if buf, err = appendFrame(buf, e); err != nil {
+			return err
+		}
+		ends = append(ends, int64(len(buf)))
+	}
+	if err := s.replaceFile(raftLogFile, buf); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(s.dir, raftLogFile), os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.file.Close()
+	s.file, s.first, s.ends = file, index, ends
+	return nil
+}
+
+// append durably writes entries that directly follow the persisted log
+func (s *raftStorage) append(entries []*proto.RaftEntry) error {
+	var buf []byte
+	offset := s.size()
+	ends := make([]int64, 0, len(entries))
+	for _, e := range entries {
+		var err error
+		if buf, err = appendFrame(buf, e); err != nil {
+			return err
+		}
+		ends = append(ends, offset+int64(len(buf)))
+	}
+
+	if _, err := s.file.Write(buf); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	s.ends = append(s.ends, ends...)
+	return nil
+}
+
+// truncate removes every entry after index from the persisted log. Entries
+// covered by the snapshot are committed and never removed.
+func (s *raftStorage) truncate(index uint64) error {
+	kept := index - s.first
+	if kept >= uint64(len(s.ends)) {
+		return nil
+	}
+
+	var size int64
+	if kept > 0 {
+		size = s.ends[kept-1]
+	}
+	if err := s.file.Truncate(size); err != nil {
+		return err
+	}
+	s.ends = s.ends[:kept]
+	return s.file.Sync()
+}
+
+// size is the length of the persisted log in bytes
+func (s *raftStorage) size() int64 {
+	if len(s.ends) == 0 {
+		return 0
+	}
+	return s.ends[len(s.ends)-1]
+}
+
+// close closes the log file
+func (s *raftStorage) close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+)
+
+// mutationLogFile is the name of the mutation log inside the data directory
+const mutationLogFile = "mutations.log"
+
+// recoveryMarkerFile records the last recovery target applied to the
+// mutation log, next to it in the data directory
+const recoveryMarkerFile = mutationLogFile + ".recovered"
+
+// recoveryTarget is the point a store is rebuilt to when replaying its log.
+// The zero value replays the whole log.
+type recoveryTarget struct {
+	revision uint64    // last revision to replay, 0 for no limit
+	time     time.Time // last commit time to replay, zero for no limit
+}
+
+// isSet reports whether the target stops replay before the end of the log
+func (t recoveryTarget) isSet() bool {
+	return t.revision != 0 || !t.time.IsZero()
+}
+
+// includes reports whether a mutation is at or before the target
+func (t recoveryTarget) includes(m *proto.Mutation) bool {
+	if t.revision != 0 && m.Revision > t.revision {
+		return false
+	}
+	if !t.time.IsZero() && time.Unix(0, m.Timestamp).After(t.time) {
+		return false
+	}
+	return true
+}
+
+// equal reports whether two targets stop replay at the same point
+func (t recoveryTarget) equal(other recoveryTarget) bool {
+	return t.revision == other.revision && t.time.Equal(other.time)
+}
+
+// String describes the target for logs and errors
+func (t recoveryTarget) String() string {
+	switch {
+	case t.revision != 0 && !t.time.IsZero():
+		return fmt.Sprintf("revision %d and %s", t.revision, t.time.Format(time.RFC3339Nano))
+	case t.revision != 0:
+		return fmt.Sprintf("revision %d", t.revision)
+	}
+	return t.time.Format(time.RFC3339Nano)
+}
+
+// recoveryMarker is the content of the recovery marker file
+type recoveryMarker struct {
+	Revision  uint64    `json:"revision,omitempty"`
+	Time      time.Time `json:"time,omitzero"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// readRecoveryMarker returns the last recovery target applied in dataDir
+// and when, or a zero target if none was
+func readRecoveryMarker(dataDir string) (recoveryTarget, time.Time, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, recoveryMarkerFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return recoveryTarget{}, time.Time{}, nil
+	}
+	if err != nil {
+		return recoveryTarget{}, time.Time{}, err
+	}
+	var marker recoveryMarker
+	if err := json.Unmarshal(data, &marker); err != nil {
+		return recoveryTarget{}, time.Time{}, fmt.Errorf("invalid %s: %v", recoveryMarkerFile, err)
+	}
+	return recoveryTarget{revision: marker.Revision, time: marker.Time}, marker.AppliedAt, nil
+}
+
+// saveRecoveryMarker durably records that target was applied in dataDir
+func saveRecoveryMarker(dataDir string, target recoveryTarget) error {
+	data, err := json.Marshal(recoveryMarker{Revision: target.revision, Time: target.time, AppliedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dataDir, recoveryMarkerFile)
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// recoveryTargetFromEnv reads KVSTORE_RECOVER_TO_REVISION and KVSTORE_RECOVER_TO_TIME
+func recoveryTargetFromEnv() (recoveryTarget, error) {
+	var target recoveryTarget
+
+	if v := os.Getenv("KVSTORE_RECOVER_TO_REVISION"); v != "" {
+		revision, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || revision == 0 {
+			return target, fmt.Errorf("invalid KVSTORE_RECOVER_TO_REVISION %q", v)
+		}
+		target.revision = revision
+	}
+
+	if v := os.Getenv("KVSTORE_RECOVER_TO_TIME"); v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return target, fmt.Errorf("invalid KVSTORE_RECOVER_TO_TIME %q: %v", v, err)
+		}
+		target.time = t
+	}
+
+	return target, nil
+}
+
+// OpenKVStore creates a store with default settings and recovers it from
+// the mutation log in dataDir, recording changes in its change log
+func OpenKVStore(dataDir string, target recoveryTarget) (*kvStore, error) {
+	store := NewKVStore()
+	changes, err := openChangeLog(dataDir, defaultChangeBacklog)
+	if err != nil {
+		return nil, err
+	}
+	store.changes = changes
+	if err := store.Recover(dataDir, target); err != nil {
+		changes.close()
+		return nil, err
+	}
+	return store, nil
+}
+
+// Recover rebuilds the store from the mutation log in dataDir and keeps
+// persisting to it. When a recovery target is set, replay stops at the
+// target; the original log is archived next to it and replaced by the
+// replayed prefix, so later writes continue from the recovered state.
+// The target is recorded once applied, and refused if it is the last one
+// applied, so that a target left set cannot discard the writes made since.
+func (k *kvStore) Recover(dataDir string, target recoveryTarget) error {
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return err
+	}
+	path := filepath.Join(dataDir, mutationLogFile)
+
+	if target.isSet() {
+		applied, appliedAt, err := readRecoveryMarker(dataDir)
+		if err != nil {
+			return err
+		}
+		if applied.equal(target) {
+			return fmt.Errorf("recovery to %s was already applied at %s; unset KVSTORE_RECOVER_TO_REVISION and KVSTORE_RECOVER_TO_TIME to keep the writes made since",
+				target, appliedAt.Format(time.RFC3339))
+		}
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var keptSize int64
+	var skipped int
+	validSize, err := readMutationLog(path, func(m *proto.Mutation, end int64) error {
+		if skipped > 0 || !target.includes(m) {
+			skipped++
+			return nil
+		}
+		if m.Op == proto.MutationOp_MUTATION_OP_SET && !(record{value: m.Value, checksum: m.Checksum}).verify(m.Key) {
+			return fmt.Errorf("%w: checksum mismatch for key '%s' at revision %d", errLogCorrupt, m.Key, m.Revision)
+		}
+		k.applyMutation(m)
+		keptSize = end
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replay %s: %w", path, err)
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Size() > validSize {
+		log.Printf("Discarding %d bytes of partially written mutations at the end of %s", info.Size()-validSize, path)
+		if err := os.Truncate(path, validSize); err != nil {
+			return err
+		}
+	}
+
+	if skipped > 0 {
+		archive, err := truncateForRecovery(path, keptSize)
+		if err != nil {
+			return err
+		}
+		log.Printf("Recovered to revision %d, skipping %d later mutations; original log archived as %s",
+			k.revision, skipped, archive)
+		if err := k.changes.rewind(k.revision, k.data); err != nil {
+			return err
+		}
+	}
+	if target.isSet() {
+		if err := saveRecoveryMarker(dataDir, target); err != nil {
+			return err
+		}
+	}
+
+	k.log, err = openMutationLog(path)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Replayed %d keys at revision %d from %s", len(k.data), k.revision, path)
+	return nil
+}
+
+// truncateForRecovery archives the log at path and replaces it with its
+// first size bytes, returning the archive's path
+func truncateForRecovery(path string, size int64) (string, error) {
+	archive := fmt.Sprintf("%s.pre-recovery-%d", path, time.Now().Unix())
+	if err := os.Rename(path, archive); err != nil {
+		return "", err
+	}
+
+	src, err := os.Open(archive)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.CopyN(dst, src, size); err != nil {
+		dst.Close()
+		return "", err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return "", err
+	}
+	return archive, dst.Close()
+}
+
+// Close releases the store's mutation log and change log, if any
+func (k *kvStore) Close() error {
+	if err := k.changes.close(); err != nil {
+		return err
+	}
+	if k.log == nil {
+		return nil
+	}
+	return k.log.close()
+}
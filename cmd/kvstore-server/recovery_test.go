@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+)
+
+// openTestStore opens a store in dir and closes it when the test ends
+func openTestStore(t *testing.T, dir string, target recoveryTarget) *kvStore {
+	t.Helper()
+
+	store, err := OpenKVStore(dir, target)
+	if err != nil {
+		t.Fatalf("OpenKVStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestOpenKVStore_ReplaysLog(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store := openTestStore(t, dir, recoveryTarget{})
+	store.Set(ctx, &proto.SetRequest{Key: "a", Value: "1"})
+	store.Set(ctx, &proto.SetRequest{Key: "b", Value: "2"})
+	store.Set(ctx, &proto.SetRequest{Key: "a", Value: "3"})
+	store.Delete(ctx, &proto.DeleteRequest{Key: "b"})
+	store.Close()
+
+	reopened := openTestStore(t, dir, recoveryTarget{})
+	if reopened.revision != 4 {
+		t.Errorf("revision after replay = %d, expected 4", reopened.revision)
+	}
+	if len(reopened.data) != 1 || reopened.data["a"].value != "3" {
+		t.Errorf("data after replay = %v, expected only a=3", reopened.data)
+	}
+
+	// New writes continue from the replayed revision
+	reopened.Set(ctx, &proto.SetRequest{Key: "c", Value: "4"})
+	if reopened.revision != 5 {
+		t.Errorf("revision after write = %d, expected 5", reopened.revision)
+	}
+}
+
+func TestOpenKVStore_ReplaysRestore(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store := openTestStore(t, dir, recoveryTarget{})
+	store.Set(ctx, &proto.SetRequest{Key: "dropped", Value: "1"})
+	client := startTestServer(t, store)
+
+	stream, err := client.Restore(ctx)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	stream.Send(&proto.RestoreRequest{Entries: []*proto.BackupEntry{
+		{Key: "restored", Value: "2", Checksum: checksumOf("restored", "2")},
+	}})
+	if _, err := stream.CloseAndRecv(); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	store.Close()
+
+	reopened := openTestStore(t, dir, recoveryTarget{})
+	if len(reopened.data) != 1 || reopened.data["restored"].value != "2" {
+		t.Errorf("data after replay = %v, expected only restored=2", reopened.data)
+	}
+}
+
+func TestOpenKVStore_TornTail(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store := openTestStore(t, dir, recoveryTarget{})
+	store.Set(ctx, &proto.SetRequest{Key: "a", Value: "1"})
+	store.Set(ctx, &proto.SetRequest{Key: "b", Value: "2"})
+	store.Close()
+
+	// Cut the last entry in half as a crash mid-write would
+	path := filepath.Join(dir, mutationLogFile)
+	info, _ := os.Stat(path)
+	os.Truncate(path, info.Size()-5)
+
+	reopened := openTestStore(t, dir, recoveryTarget{})
+	if len(reopened.data) != 1 || reopened.revision != 1 {
+		t.Errorf("after torn tail data = %v at revision %d, expected only a at revision 1", reopened.data, reopened.revision)
+	}
+
+	// The torn bytes are discarded so new entries are readable
+	reopened.Set(ctx, &proto.SetRequest{Key: "c", Value: "3"})
+	reopened.Close()
+	again := openTestStore(t, dir, recoveryTarget{})
+	if again.data["c"].value != "3" {
+		t.Errorf("write after torn tail was not replayed: %v", again.data)
+	}
+}
+
+func TestOpenKVStore_CorruptLog(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store := openTestStore(t, dir, recoveryTarget{})
+	store.Set(ctx, &proto.SetRequest{Key: "a", Value: "1"})
+	store.Set(ctx, &proto.SetRequest{Key: "b", Value: "2"})
+	store.Close()
+
+	// Flip a bit inside the first entry's payload
+	path := filepath.Join(dir, mutationLogFile)
+	data, _ := os.ReadFile(path)
+	data[logFrameHeaderSize+3] ^= 0x01
+	os.WriteFile(path, data, 0o644)
+
+	_, err := OpenKVStore(dir, recoveryTarget{})
+	if !errors.Is(err, errLogCorrupt) {
+		t.Fatalf("OpenKVStore() error = %v, expected errLogCorrupt", err)
+	}
+}
+
+func TestOpenKVStore_PointInTimeRecovery(t *testing.T) {
+	ctx := context.Background()
+
+	// populate writes three keys, then deletes all of them as an incident would
+	populate := func(t *testing.T, dir string) time.Time {
+		store := openTestStore(t, dir, recoveryTarget{})
+		for _, key := range []string{"a", "b", "c"} {
+			store.Set(ctx, &proto.SetRequest{Key: key, Value: "value-" + key})
+		}
+		time.Sleep(10 * time.Millisecond)
+		beforeIncident := time.Now()
+		time.Sleep(10 * time.Millisecond)
+		for _, key := range []string{"a", "b", "c"} {
+			store.Delete(ctx, &proto.DeleteRequest{Key: key})
+		}
+		store.Close()
+		return beforeIncident
+	}
+
+	tests := []struct {
+		name   string
+		target func(beforeIncident time.Time) recoveryTarget
+	}{
+		{
+			name:   "By revision",
+			target: func(time.Time) recoveryTarget { return recoveryTarget{revision: 3} },
+		},
+		{
+			name:   "By time",
+			target: func(beforeIncident time.Time) recoveryTarget { return recoveryTarget{time: beforeIncident} },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			beforeIncident := populate(t, dir)
+
+			store := openTestStore(t, dir, tt.target(beforeIncident))
+			if len(store.data) != 3 || store.revision != 3 {
+				t.Fatalf("recovered data = %v at revision %d, expected 3 keys at revision 3", store.data, store.revision)
+			}
+
+			archives, _ := filepath.Glob(filepath.Join(dir, mutationLogFile+".pre-recovery-*"))
+			if len(archives) != 1 {
+				t.Errorf("expected the original log to be archived, found %v", archives)
+			}
+
+			// The recovered state is what later restarts replay
+			store.Set(ctx, &proto.SetRequest{Key: "d", Value: "value-d"})
+			store.Close()
+			reopened := openTestStore(t, dir, recoveryTarget{})
+			if len(reopened.data) != 4 || reopened.revision != 4 {
+				t.Errorf("data after restart = %v at revision %d, expected 4 keys at revision 4", reopened.data, reopened.revision)
+			}
+			reopened.Close()
+
+			// A target left set is not applied a second time
+			if _, err := OpenKVStore(dir, tt.target(beforeIncident)); err == nil {
+				t.Fatal("OpenKVStore() with the target already applied succeeded")
+			}
+			again := openTestStore(t, dir, recoveryTarget{})
+			if len(again.data) != 4 {
+				t.Errorf("data after refused recovery = %v, expected 4 keys", again.data)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+)
+
+// replicaRetryInterval is how long a replica waits before reconnecting to
+// its primary
+const replicaRetryInterval = time.Second
+
+// replicaState tracks a server replicating from a primary
+type replicaState struct {
+	primary string
+	id      string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu              sync.Mutex
+	promoted        bool
+	connected       bool
+	primaryRevision uint64
+	behindSince     time.Time // when the replica last fell behind; zero while caught up
+	lastContact     time.Time
+	lastError       string
+
+	// Anti-entropy statistics
+	antiEntropyRounds int64
+	keysRepaired      int64
+	lastAntiEntropy   time.Time
+	antiEntropyError  string
+}
+
+// readOnly reports whether writes must be refused because the server is
+// still a replica. It is false on a nil state.
+func (rs *replicaState) readOnly() bool {
+	if rs == nil {
+		return false
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return !rs.promoted
+}
+
+// observe records an event from the primary and the revision the replica
+// has applied since
+func (rs *replicaState) observe(primaryRevision, applied uint64) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	now := time.Now()
+	rs.connected = true
+	rs.lastContact = now
+	rs.lastError = ""
+	rs.primaryRevision = max(rs.primaryRevision, primaryRevision)
+
+	switch {
+	case applied >= rs.primaryRevision:
+		rs.behindSince = time.Time{}
+	case rs.behindSince.IsZero():
+		rs.behindSince = now
+	}
+}
+
+// disconnected records why the stream from the primary ended
+func (rs *replicaState) disconnected(err error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.connected = false
+	rs.lastError = err.Error()
+}
+
+// report fills in the replica's side of a status response
+func (rs *replicaState) report(resp *proto.ReplicationStatusResponse) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.promoted {
+		return
+	}
+	resp.Role = "replica"
+	resp.Primary = rs.primary
+	resp.Connected = rs.connected
+	resp.PrimaryRevision = max(rs.primaryRevision, resp.AppliedRevision)
+	resp.LagRevisions = resp.PrimaryRevision - resp.AppliedRevision
+	resp.LastError = rs.lastError
+	if !rs.lastContact.IsZero() {
+		resp.LastContact = rs.lastContact.UnixNano()
+	}
+	if !rs.behindSince.IsZero() && resp.LagRevisions > 0 {
+		resp.LagSeconds = time.Since(rs.behindSince).Seconds()
+	}
+	resp.AntiEntropyRounds = rs.antiEntropyRounds
+	resp.KeysRepaired = rs.keysRepaired
+	resp.AntiEntropyError = rs.antiEntropyError
+	if !rs.lastAntiEntropy.IsZero() {
+		resp.LastAntiEntropy = rs.lastAntiEntropy.UnixNano()
+	}
+}
+
+// antiEntropyDone records the outcome of an anti-entropy round
+func (rs *replicaState) antiEntropyDone(repaired int, err error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.antiEntropyRounds++
+	rs.keysRepaired += int64(repaired)
+	rs.lastAntiEntropy = time.Now()
+	rs.antiEntropyError = ""
+	if err != nil {
+		rs.antiEntropyError = err.Error()
+	}
+}
+
+// promote stops replication and lets the server accept writes. It returns
+// false if the server was already promoted.
+func (rs *replicaState) promote() bool {
+	rs.mu.Lock()
+	if rs.promoted {
+		rs.mu.Unlock()
+		return false
+	}
+	rs.mu.Unlock()
+
+	// Writes stay refused until the stream has stopped, so that no local
+	// write can take a revision the primary also sends
+	rs.cancel()
+	<-rs.done
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.promoted {
+		return false
+	}
+	rs.promoted = true
+	rs.connected = false
+	return true
+}
+
+// startReplica makes the store a read-only replica of the primary at addr
+// and starts streaming its mutations in the background, along with
+// anti-entropy rounds when an interval is configured
+func (k *kvStore) startReplica(addr, id string) error {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(peerCredentials))
+	if err != nil {
+		return fmt.Errorf("invalid primary address %q: %v", addr, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rs := &replicaState{primary: addr, id: id, cancel: cancel, done: make(chan struct{})}
+	k.replica = rs
+
+	go func() {
+		defer close(rs.done)
+		defer conn.Close()
+
+		client := proto.NewReplicationClient(conn)
+		var wg sync.WaitGroup
+		if k.antiEntropyInterval > 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				k.runAntiEntropy(ctx, client, k.antiEntropyInterval)
+			}()
+		}
+		k.runReplica(ctx, client)
+		wg.Wait()
+	}()
+	return nil
+}
+
+// runReplica follows the primary until ctx is cancelled, reconnecting
+// after every failure
+func (k *kvStore) runReplica(ctx context.Context, client proto.ReplicationClient) {
+	rs := k.replica
+	for {
+		err := k.followPrimary(ctx, client)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Replication from %s interrupted: %v", rs.primary, err)
+		rs.disconnected(err)
+
+		select {
+		case <-time.After(replicaRetryInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// followPrimary streams the primary's mutation feed from the revision after
+// the last one applied, until the stream fails
+func (k *kvStore) followPrimary(ctx context.Context, client proto.ReplicationClient) error {
+	rs := k.replica
+	from := k.currentRevision() + 1
+
+	stream, err := client.Subscribe(ctx, &proto.SubscribeRequest{FromRevision: from, ReplicaId: rs.id})
+	if err != nil {
+		return err
+	}
+	log.Printf("Replicating from %s at revision %d", rs.primary, from)
+
+	var snapshot []*proto.Mutation
+	var snapshotRevision uint64
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return fmt.Errorf("primary closed the stream")
+		}
+		if err != nil {
+			return err
+		}
+
+		switch event.Type {
+		case proto.ReplicationEventType_REPLICATION_EVENT_SNAPSHOT_BEGIN:
+			snapshotRevision = event.Revision
+			snapshot = event.Mutations
+		case proto.ReplicationEventType_REPLICATION_EVENT_SNAPSHOT_ENTRIES:
+			for _, entry := range event.Entries {
+				if entry.Checksum != checksumOf(entry.Key, entry.Value) {
+					return fmt.Errorf("snapshot entry for key '%s' failed checksum verification", entry.Key)
+				}
+				snapshot = append(snapshot, &proto.Mutation{
+					Op:       proto.MutationOp_MUTATION_OP_SET,
+					Key:      entry.Key,
+					Value:    entry.Value,
+					Checksum: entry.Checksum,
+					Version:  entry.Version,
+					Crdt:     entry.Crdt,
+				})
+			}
+		case proto.ReplicationEventType_REPLICATION_EVENT_SNAPSHOT_END:
+			if err := k.loadSnapshot(snapshotRevision, event.Timestamp, snapshot); err != nil {
+				return err
+			}
+			log.Printf("Loaded snapshot of %s at revision %d", rs.primary, snapshotRevision)
+			snapshot = nil
+		case proto.ReplicationEventType_REPLICATION_EVENT_MUTATIONS:
+			if err := k.applyReplicated(event.Mutations); err != nil {
+				return err
+			}
+		}
+
+		rs.observe(event.Revision, k.currentRevision())
+	}
+}
+
+// applyReplicated persists and applies mutations streamed from the
+// primary, keeping the revisions the primary assigned. Mutations already
+// applied are skipped; a gap means the stream lost its place.
+func (k *kvStore) applyReplicated(mutations []*proto.Mutation) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var fresh []*proto.Mutation
+	next := k.revision + 1
+	for _, m := range mutations {
+		if m.Revision < next {
+			continue
+		}
+		if m.Revision != next {
+			return fmt.Errorf("expected revision %d from the primary, got %d", next, m.Revision)
+		}
+		if m.Op == proto.MutationOp_MUTATION_OP_SET && m.Checksum != checksumOf(m.Key, m.Value) {
+			return fmt.Errorf("mutation for key '%s' at revision %d failed checksum verification", m.Key, m.Revision)
+		}
+		fresh = append(fresh, m)
+		next++
+	}
+	return k.applyLocally(fresh)
+}
+
+// loadSnapshot replaces the store's contents with a snapshot of the primary
+// taken at revision
+func (k *kvStore) loadSnapshot(revision uint64, timestamp int64, snapshot []*proto.Mutation) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.replaceContents(revision, timestamp, snapshot)
+}
+
+// replaceContents replaces the store's contents, schemas included, with a
+// snapshot taken at revision. The replacement is persisted as a clear
+// followed by the snapshot's writes, all at that revision. The caller must
+// hold k.mu for writing.
+func (k *kvStore) replaceContents(revision uint64, timestamp int64, snapshot []*proto.Mutation) error {
+	mutations := []*proto.Mutation{{Op: proto.MutationOp_MUTATION_OP_CLEAR}}
+	prefixes := make([]string, 0, len(k.schemas))
+	for prefix := range k.schemas {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	for _, prefix := range prefixes {
+		mutations = append(mutations, &proto.Mutation{Op: proto.MutationOp_MUTATION_OP_DELETE_SCHEMA, Key: prefix})
+	}
+	mutations = append(mutations, snapshot...)
+	for _, m := range mutations {
+		m.Revision = revision
+		m.Timestamp = timestamp
+	}
+
+	if err := k.applyLocally(mutations); err != nil {
+		return err
+	}
+
+	// Replicas of this server cannot follow the jump; they start over from
+	// the snapshot too
+	k.feed.reset()
+	return nil
+}
+
+// applyLocally persists and applies mutations whose revisions are already
+// assigned. The caller must hold k.mu for writing.
+func (k *kvStore) applyLocally(mutations []*proto.Mutation) error {
+	if len(mutations) == 0 {
+		return nil
+	}
+	if k.log != nil {
+		if err := k.log.append(mutations...); err != nil {
+			return fmt.Errorf("failed to persist replicated mutations: %v", err)
+		}
+	}
+	for _, m := range mutations {
+		k.applyMutation(m)
+	}
+	return nil
+}
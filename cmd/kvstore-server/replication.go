@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Defaults for the replication feed
+const (
+	// defaultReplicationBacklog is how many recent mutations are kept for
+	// replicas to catch up from without a snapshot
+	defaultReplicationBacklog = 10000
+
+	// replicationHeartbeat is how often an idle feed reports the primary's
+	// revision to its replicas
+	replicationHeartbeat = time.Second
+
+	// subscriberBuffer is how many mutations a replica may fall behind the
+	// live feed before it is switched back to catching up from the backlog
+	subscriberBuffer = 1024
+)
+
+// feedSubscriber receives the mutations published after it subscribed. Its
+// channel is closed if it cannot keep up.
+type feedSubscriber struct {
+	ch chan *proto.Mutation
+}
+
+// replicationFeed publishes every applied mutation to the replicas
+// streaming from this server and keeps a backlog of the most recent ones
+type replicationFeed struct {
+	mu      sync.Mutex
+	size    int
+	backlog []*proto.Mutation
+	subs    map[*feedSubscriber]struct{}
+}
+
+// newReplicationFeed creates a feed keeping size mutations in its backlog
+func newReplicationFeed(size int) *replicationFeed {
+	return &replicationFeed{
+		size: size,
+		subs: make(map[*feedSubscriber]struct{}),
+	}
+}
+
+// publish adds an applied mutation to the backlog and sends it to every
+// subscriber. A subscriber whose buffer is full is dropped rather than
+// allowed to hold up writes. The caller must hold k.mu for writing, which
+// keeps mutations in revision order.
+func (f *replicationFeed) publish(m *proto.Mutation) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.size > 0 {
+		f.backlog = append(f.backlog, m)
+		if len(f.backlog) > f.size {
+			// Trim by copying, so the backing array does not grow forever
+			f.backlog = append(f.backlog[:0:0], f.backlog[len(f.backlog)-f.size:]...)
+		}
+	}
+
+	for sub := range f.subs {
+		select {
+		case sub.ch <- m:
+		default:
+			close(sub.ch)
+			delete(f.subs, sub)
+		}
+	}
+}
+
+// subscribe registers a subscriber for mutations published from now on
+func (f *replicationFeed) subscribe() *feedSubscriber {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	sub := &feedSubscriber{ch: make(chan *proto.Mutation, subscriberBuffer)}
+	f.subs[sub] = struct{}{}
+	return sub
+}
+
+// unsubscribe removes a subscriber that has not already been dropped
+func (f *replicationFeed) unsubscribe(sub *feedSubscriber) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.subs[sub]; ok {
+		close(sub.ch)
+		delete(f.subs, sub)
+	}
+}
+
+// since returns the backlogged mutations from revision on, or false if the
+// backlog no longer reaches back that far
+func (f *replicationFeed) since(revision uint64) ([]*proto.Mutation, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.backlog) == 0 || f.backlog[0].Revision > revision {
+		return nil, false
+	}
+	i := sort.Search(len(f.backlog), func(i int) bool { return f.backlog[i].Revision >= revision })
+	return append([]*proto.Mutation(nil), f.backlog[i:]...), true
+}
+
+// reset empties the backlog and drops every subscriber, after the store
+// was replaced by a snapshot that the backlog cannot describe
+func (f *replicationFeed) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.backlog = nil
+	for sub := range f.subs {
+		close(sub.ch)
+		delete(f.subs, sub)
+	}
+}
+
+// subscribers returns the number of replicas streaming from the feed
+func (f *replicationFeed) subscribers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs)
+}
+
+// feedSnapshot is a consistent copy of the store for a replica that is too
+// far behind to catch up from the backlog
+type feedSnapshot struct {
+	revision uint64
+	records  map[string]record
+
+	// mutations set the schemas and record the versioned deletions; they
+	// are sent ahead of the records
+	mutations []*proto.Mutation
+}
+
+// replicationServer serves the Replication service for a store
+type replicationServer struct {
+	proto.UnimplementedReplicationServer
+	store *kvStore
+
+	// tree is the Merkle tree of the latest anti-entropy round, so that
+	// the replica reads every level of it at the same revision
+	treeMu sync.Mutex
+	tree   *merkleTree
+}
+
+// startFeed subscribes to the live feed and returns what a replica needs
+// before it: the backlogged mutations from next on, or a snapshot when the
+// backlog does not reach back that far or the replica is ahead of this
+// server. Both are taken under the store lock so that nothing is missed or
+// sent twice.
+func (k *kvStore) startFeed(next uint64) (*feedSubscriber, []*proto.Mutation, *feedSnapshot) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	sub := k.feed.subscribe()
+	if next == k.revision+1 {
+		return sub, nil, nil
+	}
+	if next <= k.revision {
+		if catchUp, ok := k.feed.since(next); ok {
+			return sub, catchUp, nil
+		}
+	}
+
+	snap := &feedSnapshot{revision: k.revision, records: make(map[string]record, len(k.data))}
+	for key, rec := range k.data {
+		snap.records[key] = rec
+	}
+	for prefix, s := range k.schemas {
+		snap.mutations = append(snap.mutations, &proto.Mutation{
+			Op:    proto.MutationOp_MUTATION_OP_SET_SCHEMA,
+			Key:   prefix,
+			Value: s.source,
+		})
+	}
+	snap.mutations = append(snap.mutations, k.deletions()...)
+	return sub, nil, snap
+}
+
+// Subscribe streams the mutation feed to a replica. When the replica falls
+// too far behind the live feed it is caught up again from the backlog, or
+// from a fresh snapshot, without ending the stream.
+func (r *replicationServer) Subscribe(req *proto.SubscribeRequest, stream grpc.ServerStreamingServer[proto.ReplicationEvent]) error {
+	k := r.store
+	next := req.FromRevision
+	log.Printf("Replica %s subscribed from revision %d", req.ReplicaId, next)
+
+	for {
+		sub, catchUp, snap := k.startFeed(next)
+		err := r.stream(stream, sub, catchUp, snap, &next)
+		k.feed.unsubscribe(sub)
+		if err != nil || stream.Context().Err() != nil {
+			log.Printf("Replica %s disconnected at revision %d", req.ReplicaId, next-1)
+			return err
+		}
+		log.Printf("Replica %s fell behind the live feed at revision %d, catching up", req.ReplicaId, next-1)
+	}
+}
+
+// stream sends a snapshot or backlog followed by the live feed, advancing
+// next past every mutation sent. It returns nil when the subscriber was
+// dropped for falling behind or the replica went away.
+func (r *replicationServer) stream(stream grpc.ServerStreamingServer[proto.ReplicationEvent], sub *feedSubscriber, catchUp []*proto.Mutation, snap *feedSnapshot, next *uint64) error {
+	if snap != nil {
+		if err := sendSnapshot(stream, snap); err != nil {
+			return err
+		}
+		*next = snap.revision + 1
+	}
+
+	for len(catchUp) > 0 {
+		batch := catchUp[:min(len(catchUp), backupBatchSize)]
+		catchUp = catchUp[len(batch):]
+		if err := sendMutations(stream, batch); err != nil {
+			return err
+		}
+		*next = batch[len(batch)-1].Revision + 1
+	}
+
+	heartbeat := time.NewTicker(replicationHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case m, ok := <-sub.ch:
+			if !ok {
+				return nil
+			}
+			// Send whatever else is already waiting along with it
+			batch := []*proto.Mutation{m}
+			for len(batch) < backupBatchSize && len(sub.ch) > 0 {
+				if m, ok = <-sub.ch; !ok {
+					break
+				}
+				batch = append(batch, m)
+			}
+			if err := sendMutations(stream, batch); err != nil {
+				return err
+			}
+			*next = batch[len(batch)-1].Revision + 1
+			if !ok {
+				return nil
+			}
+		case <-heartbeat.C:
+			if err := stream.Send(&proto.ReplicationEvent{
+				Type:      proto.ReplicationEventType_REPLICATION_EVENT_HEARTBEAT,
+				Revision:  r.store.currentRevision(),
+				Timestamp: time.Now().UnixNano(),
+			}); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-r.store.closing:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		}
+	}
+}
+
+// sendMutations sends a batch of mutations from the feed
+func sendMutations(stream grpc.ServerStreamingServer[proto.ReplicationEvent], batch []*proto.Mutation) error {
+	return stream.Send(&proto.ReplicationEvent{
+		Type:      proto.ReplicationEventType_REPLICATION_EVENT_MUTATIONS,
+		Mutations: batch,
+		Revision:  batch[len(batch)-1].Revision,
+		Timestamp: time.Now().UnixNano(),
+	})
+}
+
+// sendSnapshot streams a snapshot in key order
+func sendSnapshot(stream grpc.ServerStreamingServer[proto.ReplicationEvent], snap *feedSnapshot) error {
+	event := func(t proto.ReplicationEventType) *proto.ReplicationEvent {
+		return &proto.ReplicationEvent{Type: t, Revision: snap.revision, Timestamp: time.Now().UnixNano()}
+	}
+
+	begin := event(proto.ReplicationEventType_REPLICATION_EVENT_SNAPSHOT_BEGIN)
+	begin.Mutations = snap.mutations
+	if err := stream.Send(begin); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(snap.records))
+	for key := range snap.records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for len(keys) > 0 {
+		batch := keys[:min(len(keys), backupBatchSize)]
+		keys = keys[len(batch):]
+
+		chunk := event(proto.ReplicationEventType_REPLICATION_EVENT_SNAPSHOT_ENTRIES)
+		for _, key := range batch {
+			rec := snap.records[key]
+			chunk.Entries = append(chunk.Entries, &proto.BackupEntry{Key: key, Value: rec.value, Checksum: rec.checksum, Version: rec.version.proto(), Crdt: rec.crdt})
+		}
+		if err := stream.Send(chunk); err != nil {
+			return err
+		}
+	}
+
+	return stream.Send(event(proto.ReplicationEventType_REPLICATION_EVENT_SNAPSHOT_END))
+}
+
+// currentRevision returns the revision of the last applied mutation
+func (k *kvStore) currentRevision() uint64 {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.revision
+}
+
+// ReplicationStatus reports this server's replication role and lag
+func (r *replicationServer) ReplicationStatus(ctx context.Context, req *proto.ReplicationStatusRequest) (*proto.ReplicationStatusResponse, error) {
+	k := r.store
+	resp := &proto.ReplicationStatusResponse{
+		Role:            "primary",
+		AppliedRevision: k.currentRevision(),
+		Replicas:        int32(k.feed.subscribers()),
+	}
+	resp.PrimaryRevision = resp.AppliedRevision
+
+	if k.replica != nil {
+		k.replica.report(resp)
+	}
+	k.hints.report(resp)
+	return resp, nil
+}
+
+// Promote stops replicating and makes this server a primary
+func (r *replicationServer) Promote(ctx context.Context, req *proto.PromoteRequest) (*proto.PromoteResponse, error) {
+	k := r.store
+	if k.replica == nil || !k.replica.promote() {
+		return nil, failedPrecondition(reasonAlreadyPrimary, "replication", "This server is already a primary",
+			map[string]string{"revision": strconv.FormatUint(k.currentRevision(), 10)})
+	}
+
+	revision := k.currentRevision()
+	log.Printf("Promoted to primary at revision %d", revision)
+	return &proto.PromoteResponse{
+		Success:  true,
+		Revision: revision,
+		Message:  "Promoted to primary at revision " + strconv.FormatUint(revision, 10),
+	}, nil
+}
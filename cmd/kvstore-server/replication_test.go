@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startTestPrimary serves the replication feed of store over loopback and
+// returns its address
+func startTestPrimary(t *testing.T, store *kvStore) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	server := grpc.NewServer()
+	proto.RegisterReplicationServer(server, &replicationServer{store: store})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String()
+}
+
+// startTestReplica makes store a replica of the primary at addr
+func startTestReplica(t *testing.T, store *kvStore, addr string) {
+	t.Helper()
+
+	if err := store.startReplica(addr, "replica"); err != nil {
+		t.Fatalf("startReplica() error = %v", err)
+	}
+	t.Cleanup(func() {
+		store.replica.cancel()
+		<-store.replica.done
+	})
+}
+
+// caughtUp reports whether replica has applied every revision of primary
+func caughtUp(primary, replica *kvStore) bool {
+	return replica.currentRevision() == primary.currentRevision()
+}
+
+func TestReplication_StreamsWrites(t *testing.T) {
+	ctx := context.Background()
+	primary, replica := NewKVStore(), NewKVStore()
+	startTestReplica(t, replica, startTestPrimary(t, primary))
+
+	primary.Set(ctx, &proto.SetRequest{Key: "a", Value: "1"})
+	primary.Set(ctx, &proto.SetRequest{Key: "b", Value: "2"})
+	primary.Delete(ctx, &proto.DeleteRequest{Key: "a"})
+	waitFor(t, "the replica to catch up", func() bool { return caughtUp(primary, replica) })
+
+	if resp, _ := replica.Get(ctx, &proto.GetRequest{Key: "b"}); !resp.Success || resp.Value != "2" {
+		t.Errorf("replica Get(b) = %v", resp)
+	}
+	if _, err := replica.Get(ctx, &proto.GetRequest{Key: "a"}); status.Code(err) != codes.NotFound {
+		t.Errorf("replica still has deleted key a")
+	}
+
+	_, err := replica.Set(ctx, &proto.SetRequest{Key: "c", Value: "3"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Set() on replica error = %v, expected FailedPrecondition", err)
+	}
+
+	srv := &replicationServer{store: replica}
+	resp, err := srv.ReplicationStatus(ctx, &proto.ReplicationStatusRequest{})
+	if err != nil {
+		t.Fatalf("ReplicationStatus() error = %v", err)
+	}
+	if resp.Role != "replica" || !resp.Connected || resp.AppliedRevision != 3 || resp.LagRevisions != 0 {
+		t.Errorf("ReplicationStatus() = %v", resp)
+	}
+
+	waitFor(t, "the primary to count its replica", func() bool { return primary.feed.subscribers() == 1 })
+	resp, _ = (&replicationServer{store: primary}).ReplicationStatus(ctx, &proto.ReplicationStatusRequest{})
+	if resp.Role != "primary" || resp.Replicas != 1 {
+		t.Errorf("primary ReplicationStatus() = %v", resp)
+	}
+}
+
+func TestReplication_CatchesUpFromSnapshot(t *testing.T) {
+	ctx := context.Background()
+	primary := NewKVStore()
+	primary.feed = newReplicationFeed(2)
+	if _, err := primary.RegisterSchema(ctx, &proto.RegisterSchemaRequest{Prefix: "user:", Schema: `{"type": "object"}`}); err != nil {
+		t.Fatalf("RegisterSchema() error = %v", err)
+	}
+	for _, key := range []string{"k1", "k2", "k3", "k4"} {
+		primary.Set(ctx, &proto.SetRequest{Key: key, Value: key})
+	}
+	primary.Set(ctx, &proto.SetRequest{Key: "user:1", Value: `{}`})
+
+	// The replica holds data the primary never had, and is further behind
+	// than the backlog reaches
+	replica := NewKVStore()
+	replica.Set(ctx, &proto.SetRequest{Key: "stale", Value: "x"})
+	replica.RegisterSchema(ctx, &proto.RegisterSchemaRequest{Prefix: "old:", Schema: `true`})
+
+	startTestReplica(t, replica, startTestPrimary(t, primary))
+	waitFor(t, "the replica to load a snapshot", func() bool { return caughtUp(primary, replica) })
+
+	replica.mu.RLock()
+	keys, schema, oldSchema := len(replica.data), replica.schemas["user:"], replica.schemas["old:"]
+	_, stale := replica.data["stale"]
+	replica.mu.RUnlock()
+	if keys != 5 || stale {
+		t.Errorf("replica has %d keys (stale: %v), expected the primary's 5", keys, stale)
+	}
+	if schema == nil || oldSchema != nil {
+		t.Errorf("replica schemas not replaced by the primary's")
+	}
+
+	// The live feed continues from the snapshot
+	primary.Set(ctx, &proto.SetRequest{Key: "k5", Value: "k5"})
+	waitFor(t, "the replica to stream after the snapshot", func() bool { return caughtUp(primary, replica) })
+}
+
+func TestReplication_Promote(t *testing.T) {
+	ctx := context.Background()
+	primary, replica := NewKVStore(), NewKVStore()
+	startTestReplica(t, replica, startTestPrimary(t, primary))
+
+	primary.Set(ctx, &proto.SetRequest{Key: "a", Value: "1"})
+	waitFor(t, "the replica to catch up", func() bool { return caughtUp(primary, replica) })
+
+	srv := &replicationServer{store: replica}
+	resp, err := srv.Promote(ctx, &proto.PromoteRequest{})
+	if err != nil || !resp.Success || resp.Revision != 1 {
+		t.Fatalf("Promote() = %v, %v", resp, err)
+	}
+	if _, err := srv.Promote(ctx, &proto.PromoteRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("second Promote() error = %v, expected FailedPrecondition", err)
+	}
+	if _, err := (&replicationServer{store: primary}).Promote(ctx, &proto.PromoteRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Promote() of a primary error = %v, expected FailedPrecondition", err)
+	}
+
+	if _, err := replica.Set(ctx, &proto.SetRequest{Key: "b", Value: "2"}); err != nil {
+		t.Fatalf("Set() after promotion error = %v", err)
+	}
+	if status, _ := srv.ReplicationStatus(ctx, &proto.ReplicationStatusRequest{}); status.Role != "primary" || status.AppliedRevision != 2 {
+		t.Errorf("ReplicationStatus() after promotion = %v", status)
+	}
+}
+
+func TestReplicationFeed(t *testing.T) {
+	feed := newReplicationFeed(3)
+	sub := feed.subscribe()
+	for i := uint64(1); i <= subscriberBuffer+1; i++ {
+		feed.publish(&proto.Mutation{Revision: i})
+	}
+
+	// A subscriber that falls too far behind is dropped
+	for range sub.ch {
+	}
+	if feed.subscribers() != 0 {
+		t.Errorf("subscribers() = %d, expected the slow subscriber dropped", feed.subscribers())
+	}
+
+	tests := []struct {
+		name     string
+		revision uint64
+		want     int
+		wantOK   bool
+	}{
+		{"Oldest kept", subscriberBuffer - 1, 3, true},
+		{"Newest", subscriberBuffer + 1, 1, true},
+		{"Trimmed", subscriberBuffer - 2, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := feed.since(tt.revision)
+			if ok != tt.wantOK || len(got) != tt.want {
+				t.Errorf("since(%d) = %d mutations, %v", tt.revision, len(got), ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// reasonSchemaViolation is the ErrorInfo reason of a write rejected by a schema
+const reasonSchemaViolation = "SCHEMA_VIOLATION"
+
+// keySchema is a JSON Schema registered for a key prefix
+type keySchema struct {
+	source   string
+	compiled *jsonSchema
+}
+
+// schemaFor returns the schema governing key among schemas, and the prefix
+// it is registered under. When several prefixes match, the longest one wins.
+func schemaFor(schemas map[string]*keySchema, key string) (string, *keySchema) {
+	var prefix string
+	var found *keySchema
+	for p, s := range schemas {
+		if strings.HasPrefix(key, p) && (found == nil || len(p) > len(prefix)) {
+			prefix, found = p, s
+		}
+	}
+	return prefix, found
+}
+
+// checkSchema returns an InvalidArgument error listing every place where
+// value does not conform to the schema governing key. The caller must hold
+// k.mu.
+func (k *kvStore) checkSchema(key, value string) error {
+	return checkSchemaIn(k.schemas, key, value)
+}
+
+// checkSchemaIn is checkSchema against a given set of schemas
+func checkSchemaIn(schemas map[string]*keySchema, key, value string) error {
+	prefix, schema := schemaFor(schemas, key)
+	if schema == nil {
+		return nil
+	}
+
+	violations := schema.compiled.validate(value)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	fields := make([]*errdetails.BadRequest_FieldViolation, 0, len(violations))
+	for _, v := range violations {
+		fields = append(fields, &errdetails.BadRequest_FieldViolation{
+			Field:       "value" + v.path,
+			Description: v.message,
+		})
+	}
+
+	message := fmt.Sprintf("value for key '%s' does not match the schema for prefix '%s': %s: %s",
+		key, prefix, pointer(violations[0].path), violations[0].message)
+	st, err := status.New(codes.InvalidArgument, message).WithDetails(
+		&errdetails.ErrorInfo{Reason: reasonSchemaViolation, Domain: errorDomain, Metadata: map[string]string{"prefix": prefix}},
+		&errdetails.BadRequest{FieldViolations: fields},
+	)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, message)
+	}
+	return st.Err()
+}
+
+// applySchemaMutation registers or removes a schema. The schema was
+// compiled when it was committed, so failing here means the log was
+// written by an incompatible version.
+func (k *kvStore) applySchemaMutation(m *proto.Mutation) {
+	if m.Op == proto.MutationOp_MUTATION_OP_DELETE_SCHEMA {
+		delete(k.schemas, m.Key)
+		return
+	}
+
+	compiled, err := compileSchema(m.Value)
+	if err != nil {
+		log.Printf("Ignoring invalid schema for prefix '%s' at revision %d: %v", m.Key, m.Revision, err)
+		return
+	}
+	k.schemas[m.Key] = &keySchema{source: m.Value, compiled: compiled}
+}
+
+// RegisterSchema registers or replaces the JSON Schema enforced on values
+// under a key prefix. Values already stored are not re-validated.
+func (k *kvStore) RegisterSchema(ctx context.Context, req *proto.RegisterSchemaRequest) (*proto.RegisterSchemaResponse, error) {
+	if _, err := compileSchema(req.Schema); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid schema: %v", err)
+	}
+
+	k.lockWrite()
+	defer k.unlockWrite()
+
+	m := &proto.Mutation{
+		Op:    proto.MutationOp_MUTATION_OP_SET_SCHEMA,
+		Key:   req.Prefix,
+		Value: compactJSON(req.Schema),
+	}
+	if err := k.commit(ctx, m); err != nil {
+		return nil, err
+	}
+
+	return &proto.RegisterSchemaResponse{
+		Success: true,
+		Message: fmt.Sprintf("Schema registered for prefix '%s'", req.Prefix),
+	}, nil
+}
+
+// schemaList returns the registered schemas in prefix order. The caller
+// must hold k.mu.
+func (k *kvStore) schemaList() []*proto.KeySchema {
+	schemas := make([]*proto.KeySchema, 0, len(k.schemas))
+	for prefix, s := range k.schemas {
+		schemas = append(schemas, &proto.KeySchema{Prefix: prefix, Schema: s.source})
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].Prefix < schemas[j].Prefix })
+	return schemas
+}
+
+// ListSchemas lists the registered JSON Schemas in prefix order
+func (k *kvStore) ListSchemas(ctx context.Context, req *proto.ListSchemasRequest) (*proto.ListSchemasResponse, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	schemas := k.schemaList()
+
+	return &proto.ListSchemasResponse{
+		Success: true,
+		Schemas: schemas,
+		Message: fmt.Sprintf("Found %d schemas", len(schemas)),
+	}, nil
+}
+
+// DeleteSchema removes the JSON Schema for a key prefix
+func (k *kvStore) DeleteSchema(ctx context.Context, req *proto.DeleteSchemaRequest) (*proto.DeleteSchemaResponse, error) {
+	k.lockWrite()
+	defer k.unlockWrite()
+
+	if _, exists := k.schemas[req.Prefix]; !exists {
+		return nil, notFound(reasonSchemaNotFound, "schema", req.Prefix, fmt.Sprintf("No schema registered for prefix '%s'", req.Prefix))
+	}
+
+	if err := k.commit(ctx, &proto.Mutation{Op: proto.MutationOp_MUTATION_OP_DELETE_SCHEMA, Key: req.Prefix}); err != nil {
+		return nil, err
+	}
+
+	return &proto.DeleteSchemaResponse{
+		Success: true,
+		Message: fmt.Sprintf("Schema for prefix '%s' deleted successfully", req.Prefix),
+	}, nil
+}
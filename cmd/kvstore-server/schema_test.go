@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSchema_EnforcedOnSet(t *testing.T) {
+	store := NewKVStore()
+	ctx := context.Background()
+
+	if _, err := store.RegisterSchema(ctx, &proto.RegisterSchemaRequest{Prefix: "config/", Schema: testSchema}); err != nil {
+		t.Fatalf("RegisterSchema() error = %v", err)
+	}
+	store.RegisterSchema(ctx, &proto.RegisterSchemaRequest{Prefix: "config/raw/", Schema: `true`})
+
+	tests := []struct {
+		name     string
+		key      string
+		value    string
+		expected codes.Code
+	}{
+		{"Conforming value", "config/db", `{"host":"db","port":5432}`, codes.OK},
+		{"Nonconforming value", "config/db", `{"host":"db"}`, codes.InvalidArgument},
+		{"Longest prefix wins", "config/raw/blob", `"any JSON value"`, codes.OK},
+		{"Not JSON", "config/raw/blob", `not json`, codes.InvalidArgument},
+		{"Other prefix unaffected", "cache/db", `{"host":"db"}`, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.Set(ctx, &proto.SetRequest{Key: tt.key, Value: tt.value})
+			if status.Code(err) != tt.expected {
+				t.Errorf("Set() error = %v, expected %v", err, tt.expected)
+			}
+		})
+	}
+
+	_, err := store.Set(ctx, &proto.SetRequest{Key: "config/db", Value: `{"host":"db","port":"x","extra":1}`})
+	var fields []string
+	for _, detail := range status.Convert(err).Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range br.FieldViolations {
+				fields = append(fields, v.Field)
+			}
+		}
+	}
+	if len(fields) != 2 || fields[0] != "value/extra" || fields[1] != "value/port" {
+		t.Errorf("field violations = %v, expected [value/extra value/port]", fields)
+	}
+}
+
+func TestSchema_RegisterListDelete(t *testing.T) {
+	store := NewKVStore()
+	ctx := context.Background()
+
+	if _, err := store.RegisterSchema(ctx, &proto.RegisterSchemaRequest{Prefix: "a/", Schema: `{"oneOf":[]}`}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("RegisterSchema() with invalid schema error = %v, expected InvalidArgument", err)
+	}
+
+	store.RegisterSchema(ctx, &proto.RegisterSchemaRequest{Prefix: "b/", Schema: `{"type": "string"}`})
+	store.RegisterSchema(ctx, &proto.RegisterSchemaRequest{Prefix: "a/", Schema: `{"type": "number"}`})
+
+	resp, _ := store.ListSchemas(ctx, &proto.ListSchemasRequest{})
+	if len(resp.Schemas) != 2 || resp.Schemas[0].Prefix != "a/" || resp.Schemas[1].Schema != `{"type":"string"}` {
+		t.Errorf("ListSchemas() = %v", resp.Schemas)
+	}
+
+	if resp, _ := store.DeleteSchema(ctx, &proto.DeleteSchemaRequest{Prefix: "a/"}); !resp.Success {
+		t.Errorf("DeleteSchema() failed: %s", resp.Message)
+	}
+	if _, err := store.DeleteSchema(ctx, &proto.DeleteSchemaRequest{Prefix: "a/"}); status.Code(err) != codes.NotFound {
+		t.Errorf("DeleteSchema() of a missing schema error = %v, expected NotFound", err)
+	}
+	if _, err := store.Set(ctx, &proto.SetRequest{Key: "a/1", Value: "anything"}); err != nil {
+		t.Errorf("Set() after DeleteSchema() error = %v", err)
+	}
+}
+
+func TestSchema_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store := openTestStore(t, dir, recoveryTarget{})
+	store.RegisterSchema(ctx, &proto.RegisterSchemaRequest{Prefix: "n/", Schema: `{"type": "integer"}`})
+	store.Set(ctx, &proto.SetRequest{Key: "n/1", Value: "1"})
+	store.Close()
+
+	store = openTestStore(t, dir, recoveryTarget{})
+	if _, err := store.Set(ctx, &proto.SetRequest{Key: "n/2", Value: "two"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Set() after restart error = %v, expected InvalidArgument", err)
+	}
+	if resp, _ := store.History(ctx, &proto.HistoryRequest{Key: "n/"}); resp != nil && len(resp.Entries) > 0 {
+		t.Error("schema change recorded in key history")
+	}
+}
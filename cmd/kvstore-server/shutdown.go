@@ -0,0 +1,68 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// defaultShutdownTimeout is how long requests in flight at shutdown are
+// given to finish before they are cut off
+const defaultShutdownTimeout = 25 * time.Second
+
+// drain stops server gracefully. Health checks report it as not serving and
+// new requests are refused at once, the streams following new changes are
+// ended so that their clients reconnect elsewhere, and the requests in
+// flight are given until timeout to finish before the server stops outright.
+// Handlers still running then are cancelled and given another timeout to
+// return; one that ignores its cancellation is left behind rather than
+// holding up the shutdown. It reports whether they all finished in time.
+func drain(server *grpc.Server, health *serverHealth, store *kvStore, timeout time.Duration) bool {
+	health.shutdown()
+	close(store.closing)
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-stopped:
+		return true
+	case <-timer.C:
+	}
+
+	go server.Stop()
+	timer.Reset(timeout)
+	select {
+	case <-stopped:
+	case <-timer.C:
+		log.Printf("Requests still running %v after being cancelled; shutting down without them", timeout)
+	}
+	return false
+}
+
+// shutdown stops replicating and the raft node, then flushes and closes the
+// store's files. It must be called once no more requests are served; a
+// write still being made by background work finishes first.
+func (k *kvStore) shutdown() error {
+	if k.replica != nil {
+		k.replica.cancel()
+		<-k.replica.done
+	}
+	if k.raft != nil {
+		k.raft.shutdown()
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err := k.Close(); err != nil {
+		return err
+	}
+	log.Printf("Store closed at revision %d", k.revision)
+	return nil
+}
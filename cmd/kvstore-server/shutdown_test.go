@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// drainable is a server started by startDrainable
+type drainable struct {
+	*testServer
+	health  *serverHealth
+	started chan struct{} // receives a value as each Set arrives
+	release chan struct{} // lets the held Sets through once closed
+}
+
+// startDrainable serves store with the health gate, holding every Set until
+// release is closed
+func startDrainable(t *testing.T, store *kvStore) *drainable {
+	t.Helper()
+
+	d := &drainable{health: newServerHealth(), started: make(chan struct{}, 1), release: make(chan struct{})}
+	hold := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if _, ok := req.(*proto.SetRequest); ok {
+			d.started <- struct{}{}
+			<-d.release
+		}
+		return handler(ctx, req)
+	}
+
+	d.testServer = startTestServer(t, store,
+		withService(func(s *grpc.Server) {
+			proto.RegisterChangeFeedServer(s, &changeFeedServer{store: store})
+		}),
+		withHealth(d.health),
+		withServerOptions(grpc.ChainUnaryInterceptor(hold)),
+	)
+	d.health.setServing()
+	return d
+}
+
+func TestDrain_InFlightRequestsFinish(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := OpenKVStore(dir, recoveryTarget{})
+	if err != nil {
+		t.Fatalf("OpenKVStore() error = %v", err)
+	}
+	d := startDrainable(t, store)
+
+	// A client following the change feed is let go rather than waited for
+	changes, err := proto.NewChangeFeedClient(d.conn).Subscribe(ctx, &proto.ChangeSubscribeRequest{FromCursor: 1})
+	if err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	followed := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := changes.Recv(); err != nil {
+				followed <- err
+				return
+			}
+		}
+	}()
+
+	set := make(chan error, 1)
+	go func() {
+		_, err := d.Set(ctx, &proto.SetRequest{Key: "a", Value: "1"})
+		set <- err
+	}()
+	<-d.started
+
+	drained := make(chan bool, 1)
+	go func() {
+		drained <- drain(d.server, d.health, store, 10*time.Second)
+	}()
+
+	// New requests are refused while the one in flight is drained
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, err := d.Get(ctx, &proto.GetRequest{Key: "a"})
+		if code := status.Code(err); code == codes.Unavailable {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("Get() while draining error = %v, expected Unavailable", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case err := <-followed:
+		if status.Code(err) != codes.Unavailable {
+			t.Errorf("change feed ended with %v, expected Unavailable", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("change feed still open while draining")
+	}
+	select {
+	case <-drained:
+		t.Fatal("drain() returned before the request in flight finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(d.release)
+	if err := <-set; err != nil {
+		t.Errorf("Set() in flight error = %v", err)
+	}
+	if !<-drained {
+		t.Error("drain() = false, expected every request to finish in time")
+	}
+
+	// The write is in the files once the store is shut down
+	if err := store.shutdown(); err != nil {
+		t.Fatalf("shutdown() error = %v", err)
+	}
+	reopened, err := OpenKVStore(dir, recoveryTarget{})
+	if err != nil {
+		t.Fatalf("OpenKVStore() error = %v", err)
+	}
+	defer reopened.Close()
+	if resp, err := reopened.Get(ctx, &proto.GetRequest{Key: "a"}); err != nil || resp.Value != "1" {
+		t.Errorf("Get() after restart = %v, %v", resp, err)
+	}
+	if latest := reopened.changes.latest(); latest != 1 {
+		t.Errorf("change log after restart ends at %d, expected 1", latest)
+	}
+}
+
+func TestDrain_Timeout(t *testing.T) {
+	ctx := context.Background()
+	store := NewKVStore()
+	d := startDrainable(t, store)
+
+	set := make(chan error, 1)
+	go func() {
+		_, err := d.Set(ctx, &proto.SetRequest{Key: "a", Value: "1"})
+		set <- err
+	}()
+	<-d.started
+
+	if drain(d.server, d.health, store, 50*time.Millisecond) {
+		t.Error("drain() = true with a request still in flight")
+	}
+	// The held handler ignores its cancellation until released
+	close(d.release)
+	if err := <-set; status.Code(err) != codes.Unavailable {
+		t.Errorf("Set() cut off error = %v, expected Unavailable", err)
+	}
+}
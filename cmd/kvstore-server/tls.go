@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pwntato/Censys/internal/certs"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// peerCredentials are the transport credentials this server dials other
+// servers with: its raft peers, its primary, its CRDT peers and the servers
+// it holds hints for
+var peerCredentials = insecure.NewCredentials()
+
+// tlsFromEnv loads the certificates named by KVSTORE_TLS_CERT, KVSTORE_TLS_KEY
+// and KVSTORE_TLS_CA. It returns nil when TLS is not configured.
+func tlsFromEnv() (*certs.Reloader, error) {
+	files := certs.Files{
+		Cert: os.Getenv("KVSTORE_TLS_CERT"),
+		Key:  os.Getenv("KVSTORE_TLS_KEY"),
+		CA:   os.Getenv("KVSTORE_TLS_CA"),
+	}
+	if files.Cert == "" && files.Key == "" {
+		if files.CA != "" {
+			return nil, fmt.Errorf("KVSTORE_TLS_CA needs KVSTORE_TLS_CERT and KVSTORE_TLS_KEY")
+		}
+		return nil, nil
+	}
+	r, err := certs.Load(files)
+	if err != nil {
+		return nil, fmt.Errorf("TLS: %v", err)
+	}
+	return r, nil
+}
+
+// useTLS makes the server dial other servers over TLS, presenting its own
+// certificate, and reads the identities trusted to act for other clients
+// from KVSTORE_TLS_PROXY_IDENTITIES
+func useTLS(r *certs.Reloader) {
+	peerCredentials = credentials.NewTLS(r.ClientConfig(""))
+	if v := os.Getenv("KVSTORE_TLS_PROXY_IDENTITIES"); v != "" {
+		proxyIdentities = make(map[string]bool)
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				proxyIdentities[id] = true
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/pwntato/Censys/internal/certs"
+	"github.com/pwntato/Censys/internal/certs/certstest"
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestClientIdentity_MutualTLS(t *testing.T) {
+	files := certstest.Write(t, t.TempDir(), "kvstore-server", "api-server", "kvstore-mirror")
+	load := func(name string) *certs.Reloader {
+		r, err := certs.Load(files[name])
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		return r
+	}
+
+	identities := make(chan string, 1)
+	record := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		identities <- clientIdentity(ctx)
+		return handler(ctx, req)
+	}
+	ts := startTestServer(t, NewKVStore(),
+		withServerOptions(
+			grpc.Creds(credentials.NewTLS(load("kvstore-server").ServerConfig())),
+			grpc.ChainUnaryInterceptor(record),
+		),
+		withClientCreds(credentials.NewTLS(load("api-server").ClientConfig("kvstore-server"))),
+	)
+	api := ts.KeyValueStoreClient
+	mirror := proto.NewKeyValueStoreClient(ts.dial(t, credentials.NewTLS(load("kvstore-mirror").ClientConfig("kvstore-server"))))
+
+	tests := []struct {
+		name     string
+		proxies  map[string]bool
+		client   proto.KeyValueStoreClient
+		clientID string
+		expected string
+	}{
+		{"Certificate alone", nil, api, "", "api-server"},
+		{"No certified client may act for another by default", nil, api, "alice", "api-server"},
+		{"Trusted proxy", map[string]bool{"api-server": true}, api, "alice", "alice"},
+		{"Untrusted client is known by its certificate", map[string]bool{"api-server": true}, mirror, "alice", "kvstore-mirror"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxyIdentities = tt.proxies
+			t.Cleanup(func() { proxyIdentities = nil })
+
+			ctx := context.Background()
+			if tt.clientID != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, clientIDMetadataKey, tt.clientID)
+			}
+			if _, err := tt.client.Set(ctx, &proto.SetRequest{Key: "a", Value: "1"}); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+			if got := <-identities; got != tt.expected {
+				t.Errorf("clientIdentity() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestClientIdentity_WithoutCertificate(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     net.Addr
+		clientID string
+		expected string
+	}{
+		{"Declared client", &net.TCPAddr{IP: net.IPv4(10, 0, 0, 7), Port: 51234}, "alice", "alice"},
+		{"Host without the port", &net.TCPAddr{IP: net.IPv4(10, 0, 0, 7), Port: 51234}, "", "10.0.0.7"},
+		{"IPv6 host", &net.TCPAddr{IP: net.IPv6loopback, Port: 40000}, "", "::1"},
+		{"Address without a port", bufconnAddr{}, "", "bufconn"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tt.addr})
+			if tt.clientID != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(clientIDMetadataKey, tt.clientID))
+			}
+			if got := clientIdentity(ctx); got != tt.expected {
+				t.Errorf("clientIdentity() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// bufconnAddr is the address of an in-memory connection, which has no port
+type bufconnAddr struct{}
+
+func (bufconnAddr) Network() string { return "bufconn" }
+func (bufconnAddr) String() string  { return "bufconn" }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// tombstone keeps a soft-deleted record until its retention window ends
+type tombstone struct {
+	record
+	deletedAt time.Time
+}
+
+// expired reports whether a tombstone's retention window ended before now.
+// An expired tombstone can no longer be listed or restored, even before
+// it is purged.
+func (k *kvStore) expired(tomb tombstone, now time.Time) bool {
+	return now.Sub(tomb.deletedAt) >= k.tombstoneRetention
+}
+
+// Undelete restores a soft-deleted key from its tombstone
+func (k *kvStore) Undelete(ctx context.Context, req *proto.UndeleteRequest) (*proto.UndeleteResponse, error) {
+	if k.tombstoneRetention == 0 {
+		return nil, failedPrecondition(reasonFeatureDisabled, "soft-delete", "soft delete is not enabled", nil)
+	}
+
+	k.lockWrite()
+	defer k.unlockWrite()
+
+	tomb, exists := k.tombstones[req.Key]
+	if !exists || k.expired(tomb, time.Now()) {
+		return nil, notFound(reasonDeletedKeyNotFound, "deleted key", req.Key, fmt.Sprintf("No deleted key '%s' found", req.Key))
+	}
+
+	if !tomb.verify(req.Key) {
+		log.Printf("Checksum mismatch for tombstone of key '%s'", req.Key)
+		return nil, status.Errorf(codes.DataLoss, "deleted key '%s' failed checksum verification", req.Key)
+	}
+
+	// Restoring is an ordinary write of the old value, so it is subject to
+	// schemas and quotas and logged and replayed like any other
+	if err := k.checkSchema(req.Key, tomb.value); err != nil {
+		return nil, err
+	}
+	if err := k.checkQuotas(req.Key, tomb.value, clientIdentity(ctx)); err != nil {
+		return nil, err
+	}
+	if err := k.commit(ctx, setMutation(req.Key, tomb.value)); err != nil {
+		return nil, err
+	}
+
+	return &proto.UndeleteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Key '%s' restored successfully", req.Key),
+	}, nil
+}
+
+// ListDeleted lists soft-deleted keys that can still be restored
+func (k *kvStore) ListDeleted(ctx context.Context, req *proto.ListDeletedRequest) (*proto.ListDeletedResponse, error) {
+	if k.tombstoneRetention == 0 {
+		return nil, failedPrecondition(reasonFeatureDisabled, "soft-delete", "soft delete is not enabled", nil)
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	now := time.Now()
+	keys := []*proto.DeletedKey{}
+	for key, tomb := range k.tombstones {
+		if strings.HasPrefix(key, req.Prefix) && !k.expired(tomb, now) {
+			keys = append(keys, &proto.DeletedKey{
+				Key:       key,
+				DeletedAt: tomb.deletedAt.UnixNano(),
+				ExpiresAt: tomb.deletedAt.Add(k.tombstoneRetention).UnixNano(),
+			})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Key < keys[j].Key })
+
+	return &proto.ListDeletedResponse{
+		Success: true,
+		Keys:    keys,
+		Message: fmt.Sprintf("Found %d deleted keys", len(keys)),
+	}, nil
+}
+
+// purgeTombstones drops every tombstone whose retention window ended before
+// now, along with the history of its key, which can no longer come back
+func (k *kvStore) purgeTombstones(now time.Time) int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	purged := 0
+	for key, tomb := range k.tombstones {
+		if k.expired(tomb, now) {
+			delete(k.tombstones, key)
+			delete(k.history, key)
+			purged++
+		}
+	}
+	return purged
+}
+
+// runTombstoneGC purges expired tombstones periodically until stop is closed
+func (k *kvStore) runTombstoneGC(stop <-chan struct{}) {
+	interval := min(max(k.tombstoneRetention/10, time.Second), time.Minute)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if purged := k.purgeTombstones(now); purged > 0 {
+				log.Printf("Purged %d expired tombstones", purged)
+			}
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newSoftDeleteStore creates a store with soft delete enabled
+func newSoftDeleteStore(retention time.Duration) *kvStore {
+	store := NewKVStore()
+	store.tombstoneRetention = retention
+	return store
+}
+
+func TestSoftDelete_Undelete(t *testing.T) {
+	store := newSoftDeleteStore(time.Hour)
+	ctx := context.Background()
+
+	store.Set(ctx, &proto.SetRequest{Key: "key", Value: "value"})
+	store.Delete(ctx, &proto.DeleteRequest{Key: "key"})
+
+	if _, err := store.Get(ctx, &proto.GetRequest{Key: "key"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("Get() of soft-deleted key error = %v, expected NotFound", err)
+	}
+
+	resp, err := store.Undelete(ctx, &proto.UndeleteRequest{Key: "key"})
+	if err != nil || !resp.Success {
+		t.Fatalf("Undelete() = %v, %v", resp, err)
+	}
+
+	getResp, _ := store.Get(ctx, &proto.GetRequest{Key: "key"})
+	if !getResp.Success || getResp.Value != "value" {
+		t.Errorf("Get() after undelete = %v, expected value", getResp)
+	}
+
+	// The tombstone is consumed by the undelete
+	if _, err = store.Undelete(ctx, &proto.UndeleteRequest{Key: "key"}); status.Code(err) != codes.NotFound {
+		t.Errorf("second Undelete() error = %v, expected NotFound", err)
+	}
+}
+
+func TestSoftDelete_SetClearsTombstone(t *testing.T) {
+	store := newSoftDeleteStore(time.Hour)
+	ctx := context.Background()
+
+	store.Set(ctx, &proto.SetRequest{Key: "key", Value: "old"})
+	store.Delete(ctx, &proto.DeleteRequest{Key: "key"})
+	store.Set(ctx, &proto.SetRequest{Key: "key", Value: "new"})
+
+	if _, err := store.Undelete(ctx, &proto.UndeleteRequest{Key: "key"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Undelete() over a newer write error = %v, expected NotFound", err)
+	}
+	if store.data["key"].value != "new" {
+		t.Errorf("value = %q, expected new", store.data["key"].value)
+	}
+}
+
+func TestSoftDelete_ListDeleted(t *testing.T) {
+	store := newSoftDeleteStore(time.Hour)
+	ctx := context.Background()
+
+	for _, key := range []string{"app/b", "app/a", "other"} {
+		store.Set(ctx, &proto.SetRequest{Key: key, Value: "value"})
+		store.Delete(ctx, &proto.DeleteRequest{Key: key})
+	}
+
+	resp, err := store.ListDeleted(ctx, &proto.ListDeletedRequest{Prefix: "app/"})
+	if err != nil {
+		t.Fatalf("ListDeleted() error = %v", err)
+	}
+	if len(resp.Keys) != 2 || resp.Keys[0].Key != "app/a" || resp.Keys[1].Key != "app/b" {
+		t.Fatalf("ListDeleted() keys = %v, expected app/a and app/b", resp.Keys)
+	}
+	if window := time.Duration(resp.Keys[0].ExpiresAt - resp.Keys[0].DeletedAt); window != time.Hour {
+		t.Errorf("retention window = %v, expected 1h", window)
+	}
+}
+
+func TestSoftDelete_PurgeTombstones(t *testing.T) {
+	store := newSoftDeleteStore(time.Minute)
+	ctx := context.Background()
+
+	store.Set(ctx, &proto.SetRequest{Key: "key", Value: "value"})
+	store.Delete(ctx, &proto.DeleteRequest{Key: "key"})
+
+	if purged := store.purgeTombstones(time.Now()); purged != 0 {
+		t.Errorf("purged %d tombstones inside the retention window", purged)
+	}
+	if purged := store.purgeTombstones(time.Now().Add(time.Minute)); purged != 1 {
+		t.Errorf("purged %d tombstones after the retention window, expected 1", purged)
+	}
+
+	if _, err := store.Undelete(ctx, &proto.UndeleteRequest{Key: "key"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Undelete() after the tombstone was purged error = %v, expected NotFound", err)
+	}
+}
+
+func TestSoftDelete_ExpiredBeforePurge(t *testing.T) {
+	store := newSoftDeleteStore(time.Minute)
+	ctx := context.Background()
+
+	store.Set(ctx, &proto.SetRequest{Key: "key", Value: "value"})
+	store.Delete(ctx, &proto.DeleteRequest{Key: "key"})
+
+	// As a tombstone replayed after a restart would be, before the next sweep
+	tomb := store.tombstones["key"]
+	tomb.deletedAt = time.Now().Add(-time.Hour)
+	store.tombstones["key"] = tomb
+
+	resp, err := store.ListDeleted(ctx, &proto.ListDeletedRequest{})
+	if err != nil || len(resp.Keys) != 0 {
+		t.Errorf("ListDeleted() = %v, %v, expected no keys", resp, err)
+	}
+	if _, err := store.Undelete(ctx, &proto.UndeleteRequest{Key: "key"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Undelete() of an expired tombstone error = %v, expected NotFound", err)
+	}
+}
+
+func TestSoftDelete_Disabled(t *testing.T) {
+	store := NewKVStore()
+	ctx := context.Background()
+
+	store.Set(ctx, &proto.SetRequest{Key: "key", Value: "value"})
+	store.Delete(ctx, &proto.DeleteRequest{Key: "key"})
+
+	if len(store.tombstones) != 0 {
+		t.Errorf("tombstone kept with soft delete disabled")
+	}
+	_, err := store.Undelete(ctx, &proto.UndeleteRequest{Key: "key"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Undelete() error = %v, expected FailedPrecondition", err)
+	}
+}
+
+func TestSoftDelete_SurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store := newSoftDeleteStore(time.Hour)
+	if err := store.Recover(dir, recoveryTarget{}); err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	store.Set(ctx, &proto.SetRequest{Key: "key", Value: "value"})
+	store.Delete(ctx, &proto.DeleteRequest{Key: "key"})
+	store.Close()
+
+	reopened := newSoftDeleteStore(time.Hour)
+	if err := reopened.Recover(dir, recoveryTarget{}); err != nil {
+		t.Fatalf("Recover() error = %v", err)
+	}
+	defer reopened.Close()
+
+	resp, err := reopened.Undelete(ctx, &proto.UndeleteRequest{Key: "key"})
+	if err != nil || !resp.Success {
+		t.Errorf("Undelete() after restart = %v, %v", resp, err)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain identifies this service in ErrorInfo details
+const errorDomain = "kvstore.censys"
+
+// Reasons reported in the ErrorInfo of a rejected write
+const (
+	reasonEmptyKey         = "EMPTY_KEY"
+	reasonKeyTooLarge      = "KEY_TOO_LARGE"
+	reasonValueTooLarge    = "VALUE_TOO_LARGE"
+	reasonInvalidKeyFormat = "INVALID_KEY_FORMAT"
+)
+
+// limits are the rules every key and value written to the store must meet.
+// A size limit of 0 means unlimited.
+type limits struct {
+	maxKeyBytes   int
+	maxValueBytes int
+	allowEmptyKey bool
+
+	// keyCharset is the character class from KVSTORE_KEY_CHARSET and
+	// keyPattern the expression that matches keys made only of it
+	keyCharset string
+	keyPattern *regexp.Regexp
+}
+
+// limitsFromEnv reads the validation limits from the environment
+func limitsFromEnv() (limits, error) {
+	var l limits
+	var err error
+
+	if l.maxKeyBytes, err = envInt("KVSTORE_MAX_KEY_BYTES", 0); err != nil {
+		return limits{}, err
+	}
+	if l.maxValueBytes, err = envInt("KVSTORE_MAX_VALUE_BYTES", 0); err != nil {
+		return limits{}, err
+	}
+	if l.allowEmptyKey, err = envBool("KVSTORE_ALLOW_EMPTY_KEY", false); err != nil {
+		return limits{}, err
+	}
+	if charset := os.Getenv("KVSTORE_KEY_CHARSET"); charset != "" {
+		if l, err = l.withKeyCharset(charset); err != nil {
+			return limits{}, err
+		}
+	}
+	return l, nil
+}
+
+// withKeyCharset restricts keys to the characters of a regular expression
+// character class such as "A-Za-z0-9/_.-"
+func (l limits) withKeyCharset(charset string) (limits, error) {
+	pattern, err := regexp.Compile("^[" + charset + "]*$")
+	if err != nil {
+		return limits{}, fmt.Errorf("invalid KVSTORE_KEY_CHARSET %q: %v", charset, err)
+	}
+	l.keyCharset, l.keyPattern = charset, pattern
+	return l, nil
+}
+
+// validate returns an InvalidArgument error describing why key and value
+// may not be written, or nil if they may
+func (l limits) validate(key, value string) error {
+	switch {
+	case key == "" && !l.allowEmptyKey:
+		return invalidArgument("key", reasonEmptyKey, "key must not be empty")
+	case l.maxKeyBytes > 0 && len(key) > l.maxKeyBytes:
+		return invalidArgument("key", reasonKeyTooLarge,
+			fmt.Sprintf("key is %d bytes, limit is %d", len(key), l.maxKeyBytes))
+	case l.maxValueBytes > 0 && len(value) > l.maxValueBytes:
+		return invalidArgument("value", reasonValueTooLarge,
+			fmt.Sprintf("value is %d bytes, limit is %d", len(value), l.maxValueBytes))
+	case l.keyPattern != nil && !l.keyPattern.MatchString(key):
+		return invalidArgument("key", reasonInvalidKeyFormat,
+			fmt.Sprintf("key contains %s, allowed characters are [%s]", firstInvalidRune(l.keyPattern, key), l.keyCharset))
+	}
+	return nil
+}
+
+// firstInvalidRune describes the first character of key outside the charset
+func firstInvalidRune(pattern *regexp.Regexp, key string) string {
+	for i, r := range key {
+		if !pattern.MatchString(string(r)) {
+			return fmt.Sprintf("%q at byte %d", r, i)
+		}
+	}
+	return "a disallowed character"
+}
+
+// invalidArgument builds an InvalidArgument error carrying the offending
+// field and a machine-readable reason
+func invalidArgument(field, reason, description string) error {
+	st, err := status.New(codes.InvalidArgument, description).WithDetails(
+		&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain},
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: field, Description: description},
+		}},
+	)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, description)
+	}
+	return st.Err()
+}
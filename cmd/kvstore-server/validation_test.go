@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLimits_Validate(t *testing.T) {
+	l, err := limits{maxKeyBytes: 8, maxValueBytes: 4}.withKeyCharset("a-z0-9/_-")
+	if err != nil {
+		t.Fatalf("withKeyCharset() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		limits limits
+		key    string
+		value  string
+		reason string
+	}{
+		{"Valid", l, "app/a_1", "1234", ""},
+		{"Empty key", l, "", "v", reasonEmptyKey},
+		{"Empty key allowed", limits{allowEmptyKey: true}, "", "v", ""},
+		{"Key too large", l, "app/abcde", "v", reasonKeyTooLarge},
+		{"Value too large", l, "app/a", "12345", reasonValueTooLarge},
+		{"Disallowed character", l, "app/A", "v", reasonInvalidKeyFormat},
+		{"No limits", limits{}, strings.Repeat("K", 4096), strings.Repeat("v", 1<<20), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.validate(tt.key, tt.value)
+			if tt.reason == "" {
+				if err != nil {
+					t.Errorf("validate() error = %v", err)
+				}
+				return
+			}
+
+			st := status.Convert(err)
+			if st.Code() != codes.InvalidArgument {
+				t.Fatalf("validate() error = %v, expected InvalidArgument", err)
+			}
+			var reason string
+			for _, detail := range st.Details() {
+				if info, ok := detail.(*errdetails.ErrorInfo); ok {
+					reason = info.Reason
+				}
+			}
+			if reason != tt.reason {
+				t.Errorf("validate() reason = %q, expected %q", reason, tt.reason)
+			}
+		})
+	}
+}
+
+func TestLimits_InvalidCharset(t *testing.T) {
+	if _, err := (limits{}).withKeyCharset("z-a"); err == nil {
+		t.Error("withKeyCharset() accepted an invalid character class")
+	}
+}
+
+func TestSet_RejectsInvalidWrites(t *testing.T) {
+	store := NewKVStore()
+	store.limits = limits{maxValueBytes: 3}
+	ctx := context.Background()
+
+	_, err := store.Set(ctx, &proto.SetRequest{Key: "key", Value: "toolong"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("Set() error = %v, expected InvalidArgument", err)
+	}
+
+	if _, err := store.Get(ctx, &proto.GetRequest{Key: "key"}); status.Code(err) != codes.NotFound {
+		t.Error("rejected value was stored")
+	}
+	if store.revision != 0 {
+		t.Errorf("revision = %d after a rejected write, expected 0", store.revision)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// versionVector counts the quorum writes each coordinating node has made
+// to a key. One vector descends from another when it is at least as large
+// for every node; when neither descends from the other, the writes that
+// produced them were concurrent.
+type versionVector map[string]uint64
+
+// versionFromProto converts a version vector received over gRPC. An empty
+// vector is nil.
+func versionFromProto(entries []*proto.VersionEntry) versionVector {
+	if len(entries) == 0 {
+		return nil
+	}
+	v := make(versionVector, len(entries))
+	for _, e := range entries {
+		v[e.Node] = max(v[e.Node], e.Counter)
+	}
+	return v
+}
+
+// proto converts the vector for gRPC, in node order
+func (v versionVector) proto() []*proto.VersionEntry {
+	nodes := make([]string, 0, len(v))
+	for node := range v {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	entries := make([]*proto.VersionEntry, 0, len(nodes))
+	for _, node := range nodes {
+		entries = append(entries, &proto.VersionEntry{Node: node, Counter: v[node]})
+	}
+	return entries
+}
+
+// descends reports whether v has seen every write other has
+func (v versionVector) descends(other versionVector) bool {
+	for node, counter := range other {
+		if v[node] < counter {
+			return false
+		}
+	}
+	return true
+}
+
+// increment returns a copy of v with node's counter advanced by one
+func (v versionVector) increment(node string) versionVector {
+	next := make(versionVector, len(v)+1)
+	for n, counter := range v {
+		next[n] = counter
+	}
+	next[node]++
+	return next
+}
+
+// String formats the vector as {node:counter, ...}
+func (v versionVector) String() string {
+	parts := make([]string, 0, len(v))
+	for _, e := range v.proto() {
+		parts = append(parts, fmt.Sprintf("%s:%d", e.Node, e.Counter))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// storedVersion returns the version of the value stored at key or, for a
+// key removed by a versioned delete, the version of its deletion. The
+// caller must hold k.mu.
+func (k *kvStore) storedVersion(key string) versionVector {
+	if rec, ok := k.data[key]; ok {
+		return rec.version
+	}
+	return k.deleted[key]
+}
+
+// writeVersion decides the version a write or delete of key is stored
+// under, and whether it is applied at all. A coordinated write advances the
+// coordinator's counter past the client's version, or past the stored one
+// for a blind write; it conflicts when the client has not seen the stored
+// version. A write that a coordinator already versioned is applied if it
+// descends from the stored version and skipped if the stored version
+// descends from it. Plain writes carry no version. The caller must hold
+// k.mu.
+func (k *kvStore) writeVersion(key string, version []*proto.VersionEntry, coordinator string) (versionVector, bool, error) {
+	stored := k.storedVersion(key)
+	v := versionFromProto(version)
+
+	switch {
+	case coordinator != "":
+		base := stored
+		if v != nil {
+			if !v.descends(stored) {
+				return nil, false, status.Errorf(codes.Aborted, "key '%s' was written concurrently: version %v has not seen the stored version %v", key, v, stored)
+			}
+			base = v
+		}
+		return base.increment(coordinator), true, nil
+	case v != nil:
+		if stored.descends(v) {
+			return stored, false, nil
+		}
+		if !v.descends(stored) {
+			return nil, false, status.Errorf(codes.Aborted, "key '%s' was written concurrently: version %v conflicts with the stored version %v", key, v, stored)
+		}
+		return v, true, nil
+	default:
+		return nil, true, nil
+	}
+}
+
+// deletions returns the mutations recording the version of every key
+// removed by a versioned delete, in key order. The caller must hold k.mu.
+func (k *kvStore) deletions() []*proto.Mutation {
+	keys := make([]string, 0, len(k.deleted))
+	for key := range k.deleted {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	mutations := make([]*proto.Mutation, 0, len(keys))
+	for _, key := range keys {
+		mutations = append(mutations, &proto.Mutation{Op: proto.MutationOp_MUTATION_OP_DELETE, Key: key, Version: k.deleted[key].proto()})
+	}
+	return mutations
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pwntato/Censys/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestVersionVector(t *testing.T) {
+	a := versionVector{"n1": 2, "n2": 1}
+	tests := []struct {
+		name  string
+		other versionVector
+		want  bool
+	}{
+		{"Empty", nil, true},
+		{"Equal", versionVector{"n1": 2, "n2": 1}, true},
+		{"Older", versionVector{"n1": 1}, true},
+		{"Newer", versionVector{"n1": 3, "n2": 1}, false},
+		{"Concurrent", versionVector{"n3": 1}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.descends(tt.other); got != tt.want {
+				t.Errorf("%v.descends(%v) = %v, expected %v", a, tt.other, got, tt.want)
+			}
+		})
+	}
+
+	if next := a.increment("n2"); next["n2"] != 2 || a["n2"] != 1 {
+		t.Errorf("increment() = %v and changed the original to %v", next, a)
+	}
+}
+
+func TestSet_Versions(t *testing.T) {
+	ctx := context.Background()
+	v := func(entries ...*proto.VersionEntry) []*proto.VersionEntry { return entries }
+	e := func(node string, counter uint64) *proto.VersionEntry {
+		return &proto.VersionEntry{Node: node, Counter: counter}
+	}
+
+	tests := []struct {
+		name      string
+		req       *proto.SetRequest
+		wantValue string
+		want      versionVector
+		wantCode  codes.Code
+	}{
+		// The store starts each case holding "stored" at {a:2, b:1}
+		{"Coordinated blind write", &proto.SetRequest{Value: "new", Coordinator: "a"}, "new", versionVector{"a": 3, "b": 1}, codes.OK},
+		{"Coordinated write from the stored version", &proto.SetRequest{Value: "new", Coordinator: "b", Version: v(e("a", 2), e("b", 1))}, "new", versionVector{"a": 2, "b": 2}, codes.OK},
+		{"Coordinated write from a stale version", &proto.SetRequest{Value: "new", Coordinator: "a", Version: v(e("a", 1))}, "stored", versionVector{"a": 2, "b": 1}, codes.Aborted},
+		{"Replicated newer version", &proto.SetRequest{Value: "new", Version: v(e("a", 3), e("b", 1))}, "new", versionVector{"a": 3, "b": 1}, codes.OK},
+		{"Replicated older version", &proto.SetRequest{Value: "new", Version: v(e("a", 1))}, "stored", versionVector{"a": 2, "b": 1}, codes.OK},
+		{"Replicated concurrent version", &proto.SetRequest{Value: "new", Version: v(e("c", 1))}, "stored", versionVector{"a": 2, "b": 1}, codes.Aborted},
+		{"Plain write", &proto.SetRequest{Value: "new"}, "new", nil, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewKVStore()
+			store.Set(ctx, &proto.SetRequest{Key: "k", Value: "stored", Version: v(e("a", 2), e("b", 1))})
+
+			tt.req.Key = "k"
+			_, err := store.Set(ctx, tt.req)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("Set() error = %v, expected %v", err, tt.wantCode)
+			}
+
+			resp, _ := store.Get(ctx, &proto.GetRequest{Key: "k"})
+			got := versionFromProto(resp.Version)
+			if resp.Value != tt.wantValue || !got.descends(tt.want) || !tt.want.descends(got) {
+				t.Errorf("stored %q at %v, expected %q at %v", resp.Value, got, tt.wantValue, tt.want)
+			}
+		})
+	}
+}
+
+func TestDelete_Versions(t *testing.T) {
+	ctx := context.Background()
+	v := func(entries ...*proto.VersionEntry) []*proto.VersionEntry { return entries }
+	e := func(node string, counter uint64) *proto.VersionEntry {
+		return &proto.VersionEntry{Node: node, Counter: counter}
+	}
+
+	tests := []struct {
+		name        string
+		req         *proto.DeleteRequest
+		wantDeleted bool
+		want        versionVector
+		wantCode    codes.Code
+	}{
+		// The store starts each case holding "stored" at {a:2, b:1}
+		{"Coordinated blind delete", &proto.DeleteRequest{Coordinator: "a"}, true, versionVector{"a": 3, "b": 1}, codes.OK},
+		{"Coordinated delete from a stale version", &proto.DeleteRequest{Coordinator: "a", Version: v(e("a", 1))}, false, versionVector{"a": 2, "b": 1}, codes.Aborted},
+		{"Replicated newer version", &proto.DeleteRequest{Version: v(e("a", 3), e("b", 1))}, true, versionVector{"a": 3, "b": 1}, codes.OK},
+		{"Replicated older version", &proto.DeleteRequest{Version: v(e("a", 1))}, false, versionVector{"a": 2, "b": 1}, codes.OK},
+		{"Replicated concurrent version", &proto.DeleteRequest{Version: v(e("c", 1))}, false, versionVector{"a": 2, "b": 1}, codes.Aborted},
+		{"Plain delete", &proto.DeleteRequest{}, true, nil, codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewKVStore()
+			store.Set(ctx, &proto.SetRequest{Key: "k", Value: "stored", Version: v(e("a", 2), e("b", 1))})
+
+			tt.req.Key = "k"
+			_, err := store.Delete(ctx, tt.req)
+			if status.Code(err) != tt.wantCode {
+				t.Fatalf("Delete() error = %v, expected %v", err, tt.wantCode)
+			}
+
+			_, held := store.data["k"]
+			got := store.storedVersion("k")
+			if held == tt.wantDeleted || !got.descends(tt.want) || !tt.want.descends(got) {
+				t.Errorf("held = %v at %v, expected deleted = %v at %v", held, got, tt.wantDeleted, tt.want)
+			}
+		})
+	}
+}
+
+func TestDelete_RecordedVersionOutlivesOlderWrites(t *testing.T) {
+	ctx := context.Background()
+	store := NewKVStore()
+	version := []*proto.VersionEntry{{Node: "a", Counter: 2}}
+
+	// A replica that never held the key still records its deletion
+	resp, err := store.Delete(ctx, &proto.DeleteRequest{Key: "k", Version: version})
+	if err != nil || resp.Success {
+		t.Fatalf("Delete() = %v, %v, expected the deletion recorded without a value", resp, err)
+	}
+
+	// The write the delete superseded arrives late and is skipped
+	store.Set(ctx, &proto.SetRequest{Key: "k", Value: "old", Version: []*proto.VersionEntry{{Node: "a", Counter: 1}}})
+	if _, err := store.Get(ctx, &proto.GetRequest{Key: "k"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Get() error = %v after a superseded write, expected NotFound", err)
+	}
+	got, err := store.Get(ctx, &proto.GetRequest{Key: "k", IncludeDeleted: true})
+	if err != nil || got.Success || !got.Deleted || versionFromProto(got.Version)["a"] != 2 {
+		t.Errorf("Get(IncludeDeleted) = %v, %v, expected the deletion at {a:2}", got, err)
+	}
+
+	// A later write starts from the deletion's version
+	set, err := store.Set(ctx, &proto.SetRequest{Key: "k", Value: "new", Coordinator: "b"})
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got := versionFromProto(set.Version); got["a"] != 2 || got["b"] != 1 {
+		t.Errorf("Set() version = %v, expected {a:2, b:1}", got)
+	}
+	if _, ok := store.deleted["k"]; ok {
+		t.Errorf("deletion of 'k' kept after it was written again")
+	}
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/pwntato/Censys/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 )
 
 // Integration test helper functions
@@ -75,12 +77,8 @@ func TestIntegration_SetAndGet(t *testing.T) {
 	}
 
 	// Verify deletion
-	getResp2, err := client.Get(ctx, getReq)
-	if err != nil {
-		t.Fatalf("Get after delete failed: %v", err)
-	}
-	if getResp2.Success {
-		t.Fatalf("Expected key to be deleted, but it still exists")
+	if _, err := client.Get(ctx, getReq); status.Code(err) != codes.NotFound {
+		t.Fatalf("Get after delete error = %v, expected NotFound", err)
 	}
 }
 
@@ -0,0 +1,248 @@
+// Package certs loads the TLS certificates shared by the kvstore servers,
+// the API server and the mirror, and reloads them when their files change
+// on disk so that certificates can be rotated without a restart.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ReloadInterval is how often the certificate files are checked for changes
+const ReloadInterval = 10 * time.Second
+
+// Files names the PEM files of a component's TLS configuration. Cert and Key
+// are its own certificate and private key; CA is the bundle of certificate
+// authorities its peers' certificates are verified against, the system's
+// when empty. Any of them may be left empty.
+type Files struct {
+	Cert string
+	Key  string
+	CA   string
+}
+
+// fileStamp identifies a version of a file's contents
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Reloader holds the certificate and certificate authorities loaded from a
+// set of files, and reloads them when the files change. The TLS
+// configurations it returns always use the latest ones, including for
+// connections already configured.
+type Reloader struct {
+	files Files
+
+	mu     sync.RWMutex
+	cert   *tls.Certificate // nil without a certificate
+	pool   *x509.CertPool   // nil to use the system's authorities
+	stamps map[string]fileStamp
+}
+
+// Load reads the files into a new Reloader
+func Load(files Files) (*Reloader, error) {
+	if (files.Cert == "") != (files.Key == "") {
+		return nil, errors.New("a certificate and its key must be given together")
+	}
+	r := &Reloader{files: files}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// stamp returns the current stamps of the files
+func (r *Reloader) stamp() (map[string]fileStamp, error) {
+	stamps := make(map[string]fileStamp)
+	for _, path := range []string{r.files.Cert, r.files.Key, r.files.CA} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		stamps[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps, nil
+}
+
+// reload reads the files again. On error the previous certificates are kept.
+func (r *Reloader) reload() error {
+	stamps, err := r.stamp()
+	if err != nil {
+		return err
+	}
+
+	var cert *tls.Certificate
+	if r.files.Cert != "" {
+		loaded, err := tls.LoadX509KeyPair(r.files.Cert, r.files.Key)
+		if err != nil {
+			return fmt.Errorf("certificate %s: %v", r.files.Cert, err)
+		}
+		cert = &loaded
+	}
+	var pool *x509.CertPool
+	if r.files.CA != "" {
+		pem, err := os.ReadFile(r.files.CA)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("certificate authorities %s: no certificates found", r.files.CA)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert, r.pool, r.stamps = cert, pool, stamps
+	return nil
+}
+
+// changed reports whether any of the files changed since they were loaded
+func (r *Reloader) changed() bool {
+	stamps, err := r.stamp()
+	if err != nil {
+		// A file being replaced may briefly be missing
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for path, stamp := range stamps {
+		if loaded := r.stamps[path]; !stamp.modTime.Equal(loaded.modTime) || stamp.size != loaded.size {
+			return true
+		}
+	}
+	return false
+}
+
+// Watch reloads the files every interval in which they changed, until ctx
+// is cancelled. Files that fail to load are reported and the previous
+// certificates kept in use.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.reload(); err != nil {
+				log.Printf("Failed to reload TLS certificates, keeping the previous ones: %v", err)
+				continue
+			}
+			log.Printf("Reloaded TLS certificates")
+		}
+	}
+}
+
+// current returns the loaded certificate and certificate authorities
+func (r *Reloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, r.pool
+}
+
+// DNSName returns the first DNS name of the loaded certificate, "" if none
+func (r *Reloader) DNSName() string {
+	cert, _ := r.current()
+	if cert == nil || cert.Leaf == nil || len(cert.Leaf.DNSNames) == 0 {
+		return ""
+	}
+	return cert.Leaf.DNSNames[0]
+}
+
+// verify checks that a peer's certificate chain was issued by the loaded
+// certificate authorities for usage and, when name is set, for name
+func (r *Reloader) verify(certs []*x509.Certificate, name string, usage x509.ExtKeyUsage) error {
+	if len(certs) == 0 {
+		return errors.New("no certificate presented")
+	}
+	_, pool := r.current()
+	opts := x509.VerifyOptions{
+		Roots:         pool,
+		DNSName:       name,
+		Intermediates: x509.NewCertPool(),
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(opts)
+	return err
+}
+
+// ServerConfig returns the TLS configuration of a server presenting the
+// loaded certificate. When certificate authorities are loaded, clients must
+// present a certificate they issued.
+func (r *Reloader) ServerConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			if cert == nil {
+				return nil, errors.New("no server certificate loaded")
+			}
+			return cert, nil
+		},
+	}
+	if r.files.CA != "" {
+		// Client certificates are verified here rather than by crypto/tls,
+		// so that reloaded authorities apply to new connections
+		config.ClientAuth = tls.RequireAnyClientCert
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			return r.verify(state.PeerCertificates, "", x509.ExtKeyUsageClientAuth)
+		}
+	}
+	return config
+}
+
+// ClientConfig returns the TLS configuration of a client verifying servers
+// against the loaded certificate authorities and presenting the loaded
+// certificate, if any, when asked for one. Servers must be certified for
+// serverName, or for the host they are dialed at when it is empty.
+func (r *Reloader) ClientConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		// The server's certificate is verified here rather than by
+		// crypto/tls, so that reloaded authorities apply to new connections
+		InsecureSkipVerify: true,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			return r.verify(state.PeerCertificates, state.ServerName, x509.ExtKeyUsageServerAuth)
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := r.current()
+			if cert == nil {
+				return &tls.Certificate{}, nil
+			}
+			return cert, nil
+		},
+	}
+}
+
+// Identity returns the name a certificate identifies its holder by: its
+// common name, or else its first URI or DNS name
+func Identity(cert *x509.Certificate) string {
+	switch {
+	case cert.Subject.CommonName != "":
+		return cert.Subject.CommonName
+	case len(cert.URIs) > 0:
+		return cert.URIs[0].String()
+	case len(cert.DNSNames) > 0:
+		return cert.DNSNames[0]
+	}
+	return ""
+}
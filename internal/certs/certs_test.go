@@ -0,0 +1,289 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// authority is a certificate authority issuing test certificates
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newAuthority(t *testing.T, name string) *authority {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &authority{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM certificate and key of a certificate for template
+func (a *authority) issue(t *testing.T, template *x509.Certificate) ([]byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFiles writes a certificate, its key and the authority that issued it
+// under dir, dated at modTime, and returns their paths
+func writeFiles(t *testing.T, dir string, a *authority, cert, key []byte, modTime time.Time) Files {
+	t.Helper()
+	files := Files{Cert: filepath.Join(dir, "cert.pem"), Key: filepath.Join(dir, "key.pem"), CA: filepath.Join(dir, "ca.pem")}
+	for path, data := range map[string][]byte{files.Cert: cert, files.Key: key, files.CA: a.pem} {
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return files
+}
+
+// handshake connects a client and a server over a loopback connection and
+// returns the certificate each saw from the other, or the first error
+func handshake(serverConfig, clientConfig *tls.Config) (*x509.Certificate, *x509.Certificate, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer lis.Close()
+
+	servers := make(chan *tls.Conn, 1)
+	errs := make(chan error, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			errs <- err
+			return
+		}
+		server := tls.Server(conn, serverConfig)
+		errs <- server.Handshake()
+		servers <- server
+	}()
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		return nil, nil, err
+	}
+	client := tls.Client(conn, clientConfig)
+	defer client.Close()
+
+	// With TLS 1.3 the client is done before the server has checked its
+	// certificate, so the server's verdict is the one awaited
+	clientErr := client.Handshake()
+	if clientErr != nil {
+		conn.Close()
+	}
+	if err := <-errs; err != nil {
+		return nil, nil, err
+	}
+	if clientErr != nil {
+		return nil, nil, clientErr
+	}
+	server := <-servers
+	defer server.Close()
+
+	var seenByClient, seenByServer *x509.Certificate
+	if certs := client.ConnectionState().PeerCertificates; len(certs) > 0 {
+		seenByClient = certs[0]
+	}
+	if certs := server.ConnectionState().PeerCertificates; len(certs) > 0 {
+		seenByServer = certs[0]
+	}
+	return seenByClient, seenByServer, nil
+}
+
+func TestLoad(t *testing.T) {
+	if _, err := Load(Files{Cert: "cert.pem"}); err == nil {
+		t.Error("Load() of a certificate without a key succeeded")
+	}
+	if _, err := Load(Files{CA: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("Load() of a missing file succeeded")
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca, other := newAuthority(t, "kvstore-ca"), newAuthority(t, "other-ca")
+	now := time.Now()
+
+	serverCert, serverKey := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "kvstore-server"}, DNSNames: []string{"kvstore-server"}})
+	server, err := Load(writeFiles(t, t.TempDir(), ca, serverCert, serverKey, now))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	clientCert, clientKey := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "api-server"}})
+	client, err := Load(writeFiles(t, t.TempDir(), ca, clientCert, clientKey, now))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	strangerCert, strangerKey := other.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "stranger"}, DNSNames: []string{"kvstore-server"}})
+	stranger, err := Load(writeFiles(t, t.TempDir(), ca, strangerCert, strangerKey, now))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	anonymous, err := Load(Files{CA: client.files.CA})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		server     *Reloader
+		client     *Reloader
+		serverName string
+		wantErr    bool
+	}{
+		{"Both certified", server, client, "kvstore-server", false},
+		{"Wrong server name", server, client, "elsewhere", true},
+		{"Server from another authority", stranger, client, "kvstore-server", true},
+		{"Client from another authority", server, stranger, "kvstore-server", true},
+		{"Client without a certificate", server, anonymous, "kvstore-server", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seenByClient, seenByServer, err := handshake(tt.server.ServerConfig(), tt.client.ClientConfig(tt.serverName))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("handshake error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if Identity(seenByClient) != "kvstore-server" || Identity(seenByServer) != "api-server" {
+				t.Errorf("handshake saw %q and %q", Identity(seenByClient), Identity(seenByServer))
+			}
+		})
+	}
+
+	// Without authorities of its own, a server does not ask for certificates
+	plain, err := Load(Files{Cert: server.files.Cert, Key: server.files.Key})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if _, seenByServer, err := handshake(plain.ServerConfig(), anonymous.ClientConfig("kvstore-server")); err != nil || seenByServer != nil {
+		t.Errorf("handshake without client authentication = %v, %v", seenByServer, err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	ca := newAuthority(t, "kvstore-ca")
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+
+	cert, key := ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "first"}, DNSNames: []string{"kvstore-server"}})
+	r, err := Load(writeFiles(t, dir, ca, cert, key, start))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Watch(ctx, 10*time.Millisecond)
+
+	served := func() string {
+		seen, _, err := handshake(r.ServerConfig(), r.ClientConfig("kvstore-server"))
+		if err != nil {
+			return err.Error()
+		}
+		return Identity(seen)
+	}
+	waitFor := func(expected string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for served() != expected {
+			if time.Now().After(deadline) {
+				t.Fatalf("server presents %q, expected %q", served(), expected)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor("first")
+
+	// A rotated certificate is picked up
+	cert, key = ca.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "second"}, DNSNames: []string{"kvstore-server"}})
+	writeFiles(t, dir, ca, cert, key, start.Add(time.Second))
+	waitFor("second")
+
+	// A damaged one is not, and the previous one stays in use
+	if err := os.WriteFile(filepath.Join(dir, "cert.pem"), []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := served(); got != "second" {
+		t.Errorf("server presents %q after a damaged rotation, expected second", got)
+	}
+
+	// So is a new authority, with certificates it issued
+	next := newAuthority(t, "next-ca")
+	cert, key = next.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "third"}, DNSNames: []string{"kvstore-server"}})
+	writeFiles(t, dir, next, cert, key, start.Add(2*time.Second))
+	waitFor("third")
+}
+
+func TestIdentity(t *testing.T) {
+	uri, _ := url.Parse("spiffe://kvstore/api-server")
+	tests := []struct {
+		name     string
+		cert     *x509.Certificate
+		expected string
+	}{
+		{"Common name", &x509.Certificate{Subject: pkix.Name{CommonName: "api-server"}, DNSNames: []string{"api"}}, "api-server"},
+		{"URI", &x509.Certificate{URIs: []*url.URL{uri}, DNSNames: []string{"api"}}, "spiffe://kvstore/api-server"},
+		{"DNS name", &x509.Certificate{DNSNames: []string{"api"}}, "api"},
+		{"Nothing", &x509.Certificate{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Identity(tt.cert); got != tt.expected {
+				t.Errorf("Identity() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -21,11 +21,352 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// How a restored snapshot is combined with the existing contents
+type RestoreMode int32
+
+const (
+	RestoreMode_RESTORE_MODE_REPLACE RestoreMode = 0
+	RestoreMode_RESTORE_MODE_MERGE   RestoreMode = 1
+)
+
+// Enum value maps for RestoreMode.
+var (
+	RestoreMode_name = map[int32]string{
+		0: "RESTORE_MODE_REPLACE",
+		1: "RESTORE_MODE_MERGE",
+	}
+	RestoreMode_value = map[string]int32{
+		"RESTORE_MODE_REPLACE": 0,
+		"RESTORE_MODE_MERGE":   1,
+	}
+)
+
+func (x RestoreMode) Enum() *RestoreMode {
+	p := new(RestoreMode)
+	*p = x
+	return p
+}
+
+func (x RestoreMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RestoreMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_kvstore_proto_enumTypes[0].Descriptor()
+}
+
+func (RestoreMode) Type() protoreflect.EnumType {
+	return &file_proto_kvstore_proto_enumTypes[0]
+}
+
+func (x RestoreMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RestoreMode.Descriptor instead.
+func (RestoreMode) EnumDescriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{0}
+}
+
+// Human-readable formats supported by export and import
+type DataFormat int32
+
+const (
+	DataFormat_DATA_FORMAT_JSONL DataFormat = 0
+	DataFormat_DATA_FORMAT_CSV   DataFormat = 1
+)
+
+// Enum value maps for DataFormat.
+var (
+	DataFormat_name = map[int32]string{
+		0: "DATA_FORMAT_JSONL",
+		1: "DATA_FORMAT_CSV",
+	}
+	DataFormat_value = map[string]int32{
+		"DATA_FORMAT_JSONL": 0,
+		"DATA_FORMAT_CSV":   1,
+	}
+)
+
+func (x DataFormat) Enum() *DataFormat {
+	p := new(DataFormat)
+	*p = x
+	return p
+}
+
+func (x DataFormat) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DataFormat) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_kvstore_proto_enumTypes[1].Descriptor()
+}
+
+func (DataFormat) Type() protoreflect.EnumType {
+	return &file_proto_kvstore_proto_enumTypes[1]
+}
+
+func (x DataFormat) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DataFormat.Descriptor instead.
+func (DataFormat) EnumDescriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{1}
+}
+
+// Kind of change recorded in a mutation
+type MutationOp int32
+
+const (
+	MutationOp_MUTATION_OP_SET    MutationOp = 0
+	MutationOp_MUTATION_OP_DELETE MutationOp = 1
+	// Remove every key, as done by a restore in replace mode
+	MutationOp_MUTATION_OP_CLEAR MutationOp = 2
+	// Register or replace the JSON Schema in value for the key prefix in key
+	MutationOp_MUTATION_OP_SET_SCHEMA MutationOp = 3
+	// Remove the JSON Schema for the key prefix in key
+	MutationOp_MUTATION_OP_DELETE_SCHEMA MutationOp = 4
+)
+
+// Enum value maps for MutationOp.
+var (
+	MutationOp_name = map[int32]string{
+		0: "MUTATION_OP_SET",
+		1: "MUTATION_OP_DELETE",
+		2: "MUTATION_OP_CLEAR",
+		3: "MUTATION_OP_SET_SCHEMA",
+		4: "MUTATION_OP_DELETE_SCHEMA",
+	}
+	MutationOp_value = map[string]int32{
+		"MUTATION_OP_SET":           0,
+		"MUTATION_OP_DELETE":        1,
+		"MUTATION_OP_CLEAR":         2,
+		"MUTATION_OP_SET_SCHEMA":    3,
+		"MUTATION_OP_DELETE_SCHEMA": 4,
+	}
+)
+
+func (x MutationOp) Enum() *MutationOp {
+	p := new(MutationOp)
+	*p = x
+	return p
+}
+
+func (x MutationOp) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MutationOp) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_kvstore_proto_enumTypes[2].Descriptor()
+}
+
+func (MutationOp) Type() protoreflect.EnumType {
+	return &file_proto_kvstore_proto_enumTypes[2]
+}
+
+func (x MutationOp) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MutationOp.Descriptor instead.
+func (MutationOp) EnumDescriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{2}
+}
+
+// Kind of replication event
+type ReplicationEventType int32
+
+const (
+	// Mutations committed on the primary, in revision order
+	ReplicationEventType_REPLICATION_EVENT_MUTATIONS ReplicationEventType = 0
+	// Start of a snapshot at revision; mutations holds the schemas
+	ReplicationEventType_REPLICATION_EVENT_SNAPSHOT_BEGIN ReplicationEventType = 1
+	// Records of the snapshot
+	ReplicationEventType_REPLICATION_EVENT_SNAPSHOT_ENTRIES ReplicationEventType = 2
+	// End of the snapshot; the feed continues after revision
+	ReplicationEventType_REPLICATION_EVENT_SNAPSHOT_END ReplicationEventType = 3
+	// Sent while idle so replicas can tell how far behind they are
+	ReplicationEventType_REPLICATION_EVENT_HEARTBEAT ReplicationEventType = 4
+)
+
+// Enum value maps for ReplicationEventType.
+var (
+	ReplicationEventType_name = map[int32]string{
+		0: "REPLICATION_EVENT_MUTATIONS",
+		1: "REPLICATION_EVENT_SNAPSHOT_BEGIN",
+		2: "REPLICATION_EVENT_SNAPSHOT_ENTRIES",
+		3: "REPLICATION_EVENT_SNAPSHOT_END",
+		4: "REPLICATION_EVENT_HEARTBEAT",
+	}
+	ReplicationEventType_value = map[string]int32{
+		"REPLICATION_EVENT_MUTATIONS":        0,
+		"REPLICATION_EVENT_SNAPSHOT_BEGIN":   1,
+		"REPLICATION_EVENT_SNAPSHOT_ENTRIES": 2,
+		"REPLICATION_EVENT_SNAPSHOT_END":     3,
+		"REPLICATION_EVENT_HEARTBEAT":        4,
+	}
+)
+
+func (x ReplicationEventType) Enum() *ReplicationEventType {
+	p := new(ReplicationEventType)
+	*p = x
+	return p
+}
+
+func (x ReplicationEventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ReplicationEventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_kvstore_proto_enumTypes[3].Descriptor()
+}
+
+func (ReplicationEventType) Type() protoreflect.EnumType {
+	return &file_proto_kvstore_proto_enumTypes[3]
+}
+
+func (x ReplicationEventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ReplicationEventType.Descriptor instead.
+func (ReplicationEventType) EnumDescriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{3}
+}
+
+// Conflict-free replicated data type a key is declared as in multi-master
+// mode
+type CrdtType int32
+
+const (
+	// A plain value
+	CrdtType_CRDT_TYPE_NONE CrdtType = 0
+	// Register keeping the value written last
+	CrdtType_CRDT_TYPE_LWW_REGISTER CrdtType = 1
+	// Counter that only grows
+	CrdtType_CRDT_TYPE_G_COUNTER CrdtType = 2
+	// Counter that can be incremented and decremented
+	CrdtType_CRDT_TYPE_PN_COUNTER CrdtType = 3
+	// Set whose removals only affect the additions they have seen
+	CrdtType_CRDT_TYPE_OR_SET CrdtType = 4
+)
+
+// Enum value maps for CrdtType.
+var (
+	CrdtType_name = map[int32]string{
+		0: "CRDT_TYPE_NONE",
+		1: "CRDT_TYPE_LWW_REGISTER",
+		2: "CRDT_TYPE_G_COUNTER",
+		3: "CRDT_TYPE_PN_COUNTER",
+		4: "CRDT_TYPE_OR_SET",
+	}
+	CrdtType_value = map[string]int32{
+		"CRDT_TYPE_NONE":         0,
+		"CRDT_TYPE_LWW_REGISTER": 1,
+		"CRDT_TYPE_G_COUNTER":    2,
+		"CRDT_TYPE_PN_COUNTER":   3,
+		"CRDT_TYPE_OR_SET":       4,
+	}
+)
+
+func (x CrdtType) Enum() *CrdtType {
+	p := new(CrdtType)
+	*p = x
+	return p
+}
+
+func (x CrdtType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CrdtType) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_kvstore_proto_enumTypes[4].Descriptor()
+}
+
+func (CrdtType) Type() protoreflect.EnumType {
+	return &file_proto_kvstore_proto_enumTypes[4]
+}
+
+func (x CrdtType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CrdtType.Descriptor instead.
+func (CrdtType) EnumDescriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{4}
+}
+
+// Operation on a CRDT
+type CrdtOp int32
+
+const (
+	// Write a register
+	CrdtOp_CRDT_OP_ASSIGN    CrdtOp = 0
+	CrdtOp_CRDT_OP_INCREMENT CrdtOp = 1
+	CrdtOp_CRDT_OP_DECREMENT CrdtOp = 2
+	// Add an element to a set
+	CrdtOp_CRDT_OP_ADD CrdtOp = 3
+	// Remove an element from a set
+	CrdtOp_CRDT_OP_REMOVE CrdtOp = 4
+)
+
+// Enum value maps for CrdtOp.
+var (
+	CrdtOp_name = map[int32]string{
+		0: "CRDT_OP_ASSIGN",
+		1: "CRDT_OP_INCREMENT",
+		2: "CRDT_OP_DECREMENT",
+		3: "CRDT_OP_ADD",
+		4: "CRDT_OP_REMOVE",
+	}
+	CrdtOp_value = map[string]int32{
+		"CRDT_OP_ASSIGN":    0,
+		"CRDT_OP_INCREMENT": 1,
+		"CRDT_OP_DECREMENT": 2,
+		"CRDT_OP_ADD":       3,
+		"CRDT_OP_REMOVE":    4,
+	}
+)
+
+func (x CrdtOp) Enum() *CrdtOp {
+	p := new(CrdtOp)
+	*p = x
+	return p
+}
+
+func (x CrdtOp) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CrdtOp) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_kvstore_proto_enumTypes[5].Descriptor()
+}
+
+func (CrdtOp) Type() protoreflect.EnumType {
+	return &file_proto_kvstore_proto_enumTypes[5]
+}
+
+func (x CrdtOp) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CrdtOp.Descriptor instead.
+func (CrdtOp) EnumDescriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{5}
+}
+
 // Request to store a key-value pair
 type SetRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// Version vector of the write. With coordinator set it is the version
+	// the client last read, and empty for a blind write; otherwise it is the
+	// version a coordinator assigned.
+	Version []*VersionEntry `protobuf:"bytes,3,rep,name=version,proto3" json:"version,omitempty"`
+	// Node name under which this server coordinates a quorum write
+	Coordinator   string `protobuf:"bytes,4,opt,name=coordinator,proto3" json:"coordinator,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -74,18 +415,87 @@ func (x *SetRequest) GetValue() string {
 	return ""
 }
 
+func (x *SetRequest) GetVersion() []*VersionEntry {
+	if x != nil {
+		return x.Version
+	}
+	return nil
+}
+
+func (x *SetRequest) GetCoordinator() string {
+	if x != nil {
+		return x.Coordinator
+	}
+	return ""
+}
+
+// One node's counter in a version vector
+type VersionEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Node          string                 `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Counter       uint64                 `protobuf:"varint,2,opt,name=counter,proto3" json:"counter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VersionEntry) Reset() {
+	*x = VersionEntry{}
+	mi := &file_proto_kvstore_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VersionEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VersionEntry) ProtoMessage() {}
+
+func (x *VersionEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VersionEntry.ProtoReflect.Descriptor instead.
+func (*VersionEntry) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *VersionEntry) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+func (x *VersionEntry) GetCounter() uint64 {
+	if x != nil {
+		return x.Counter
+	}
+	return 0
+}
+
 // Response for storing a key-value pair
 type SetResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// Version vector the value is stored under
+	Version       []*VersionEntry `protobuf:"bytes,3,rep,name=version,proto3" json:"version,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *SetResponse) Reset() {
 	*x = SetResponse{}
-	mi := &file_proto_kvstore_proto_msgTypes[1]
+	mi := &file_proto_kvstore_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -97,7 +507,7 @@ func (x *SetResponse) String() string {
 func (*SetResponse) ProtoMessage() {}
 
 func (x *SetResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_kvstore_proto_msgTypes[1]
+	mi := &file_proto_kvstore_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -110,7 +520,7 @@ func (x *SetResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SetResponse.ProtoReflect.Descriptor instead.
 func (*SetResponse) Descriptor() ([]byte, []int) {
-	return file_proto_kvstore_proto_rawDescGZIP(), []int{1}
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *SetResponse) GetSuccess() bool {
@@ -127,17 +537,27 @@ func (x *SetResponse) GetMessage() string {
 	return ""
 }
 
+func (x *SetResponse) GetVersion() []*VersionEntry {
+	if x != nil {
+		return x.Version
+	}
+	return nil
+}
+
 // Request to retrieve a value by key
 type GetRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Answer for a key removed by a versioned delete with the deletion's
+	// version rather than NotFound
+	IncludeDeleted bool `protobuf:"varint,2,opt,name=include_deleted,json=includeDeleted,proto3" json:"include_deleted,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *GetRequest) Reset() {
 	*x = GetRequest{}
-	mi := &file_proto_kvstore_proto_msgTypes[2]
+	mi := &file_proto_kvstore_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -149,7 +569,7 @@ func (x *GetRequest) String() string {
 func (*GetRequest) ProtoMessage() {}
 
 func (x *GetRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_kvstore_proto_msgTypes[2]
+	mi := &file_proto_kvstore_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -162,7 +582,7 @@ func (x *GetRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
 func (*GetRequest) Descriptor() ([]byte, []int) {
-	return file_proto_kvstore_proto_rawDescGZIP(), []int{2}
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *GetRequest) GetKey() string {
@@ -172,19 +592,31 @@ func (x *GetRequest) GetKey() string {
 	return ""
 }
 
+func (x *GetRequest) GetIncludeDeleted() bool {
+	if x != nil {
+		return x.IncludeDeleted
+	}
+	return false
+}
+
 // Response for retrieving a value
 type GetResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
-	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Value   string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Message string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	// Version vector of the value, empty unless written by a quorum write
+	Version []*VersionEntry `protobuf:"bytes,4,rep,name=version,proto3" json:"version,omitempty"`
+	// Set, with success false, when include_deleted was requested and the key
+	// was removed by a versioned delete; version is then the deletion's
+	Deleted       bool `protobuf:"varint,5,opt,name=deleted,proto3" json:"deleted,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetResponse) Reset() {
 	*x = GetResponse{}
-	mi := &file_proto_kvstore_proto_msgTypes[3]
+	mi := &file_proto_kvstore_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -196,7 +628,7 @@ func (x *GetResponse) String() string {
 func (*GetResponse) ProtoMessage() {}
 
 func (x *GetResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_kvstore_proto_msgTypes[3]
+	mi := &file_proto_kvstore_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -209,7 +641,7 @@ func (x *GetResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetResponse.ProtoReflect.Descriptor instead.
 func (*GetResponse) Descriptor() ([]byte, []int) {
-	return file_proto_kvstore_proto_rawDescGZIP(), []int{3}
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *GetResponse) GetSuccess() bool {
@@ -233,17 +665,36 @@ func (x *GetResponse) GetMessage() string {
 	return ""
 }
 
+func (x *GetResponse) GetVersion() []*VersionEntry {
+	if x != nil {
+		return x.Version
+	}
+	return nil
+}
+
+func (x *GetResponse) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
 // Request to delete a key
 type DeleteRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Version vector of the delete, as for a write. A versioned delete is
+	// recorded even for a key the server does not hold.
+	Version []*VersionEntry `protobuf:"bytes,2,rep,name=version,proto3" json:"version,omitempty"`
+	// Node name under which this server coordinates a quorum delete
+	Coordinator   string `protobuf:"bytes,3,opt,name=coordinator,proto3" json:"coordinator,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *DeleteRequest) Reset() {
 	*x = DeleteRequest{}
-	mi := &file_proto_kvstore_proto_msgTypes[4]
+	mi := &file_proto_kvstore_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -255,7 +706,7 @@ func (x *DeleteRequest) String() string {
 func (*DeleteRequest) ProtoMessage() {}
 
 func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_kvstore_proto_msgTypes[4]
+	mi := &file_proto_kvstore_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -268,7 +719,7 @@ func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
 func (*DeleteRequest) Descriptor() ([]byte, []int) {
-	return file_proto_kvstore_proto_rawDescGZIP(), []int{4}
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *DeleteRequest) GetKey() string {
@@ -278,18 +729,35 @@ func (x *DeleteRequest) GetKey() string {
 	return ""
 }
 
+func (x *DeleteRequest) GetVersion() []*VersionEntry {
+	if x != nil {
+		return x.Version
+	}
+	return nil
+}
+
+func (x *DeleteRequest) GetCoordinator() string {
+	if x != nil {
+		return x.Coordinator
+	}
+	return ""
+}
+
 // Response for deleting a key
 type DeleteResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// False when a versioned delete found no value to remove
+	Success bool   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// Version vector the deletion is recorded under
+	Version       []*VersionEntry `protobuf:"bytes,3,rep,name=version,proto3" json:"version,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *DeleteResponse) Reset() {
 	*x = DeleteResponse{}
-	mi := &file_proto_kvstore_proto_msgTypes[5]
+	mi := &file_proto_kvstore_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -301,7 +769,7 @@ func (x *DeleteResponse) String() string {
 func (*DeleteResponse) ProtoMessage() {}
 
 func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_kvstore_proto_msgTypes[5]
+	mi := &file_proto_kvstore_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -314,7 +782,7 @@ func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
 func (*DeleteResponse) Descriptor() ([]byte, []int) {
-	return file_proto_kvstore_proto_rawDescGZIP(), []int{5}
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *DeleteResponse) GetSuccess() bool {
@@ -331,34 +799,4818 @@ func (x *DeleteResponse) GetMessage() string {
 	return ""
 }
 
-var File_proto_kvstore_proto protoreflect.FileDescriptor
+func (x *DeleteResponse) GetVersion() []*VersionEntry {
+	if x != nil {
+		return x.Version
+	}
+	return nil
+}
 
-const file_proto_kvstore_proto_rawDesc = "" +
+// Request to verify the checksum of every stored record
+type VerifyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyRequest) Reset() {
+	*x = VerifyRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyRequest) ProtoMessage() {}
+
+func (x *VerifyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyRequest.ProtoReflect.Descriptor instead.
+func (*VerifyRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{7}
+}
+
+// Response for verifying the store
+type VerifyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	KeysScanned   int64                  `protobuf:"varint,2,opt,name=keys_scanned,json=keysScanned,proto3" json:"keys_scanned,omitempty"`
+	CorruptKeys   []string               `protobuf:"bytes,3,rep,name=corrupt_keys,json=corruptKeys,proto3" json:"corrupt_keys,omitempty"`
+	Message       string                 `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerifyResponse) Reset() {
+	*x = VerifyResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerifyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerifyResponse) ProtoMessage() {}
+
+func (x *VerifyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerifyResponse.ProtoReflect.Descriptor instead.
+func (*VerifyResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *VerifyResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *VerifyResponse) GetKeysScanned() int64 {
+	if x != nil {
+		return x.KeysScanned
+	}
+	return 0
+}
+
+func (x *VerifyResponse) GetCorruptKeys() []string {
+	if x != nil {
+		return x.CorruptKeys
+	}
+	return nil
+}
+
+func (x *VerifyResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Request to back up the store
+type BackupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BackupRequest) Reset() {
+	*x = BackupRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BackupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackupRequest) ProtoMessage() {}
+
+func (x *BackupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackupRequest.ProtoReflect.Descriptor instead.
+func (*BackupRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{9}
+}
+
+// A single record in a backup
+type BackupEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value         string                 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	Checksum      uint32                 `protobuf:"varint,3,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	Version       []*VersionEntry        `protobuf:"bytes,4,rep,name=version,proto3" json:"version,omitempty"`
+	Crdt          CrdtType               `protobuf:"varint,5,opt,name=crdt,proto3,enum=kvstore.CrdtType" json:"crdt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BackupEntry) Reset() {
+	*x = BackupEntry{}
+	mi := &file_proto_kvstore_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BackupEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackupEntry) ProtoMessage() {}
+
+func (x *BackupEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackupEntry.ProtoReflect.Descriptor instead.
+func (*BackupEntry) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *BackupEntry) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *BackupEntry) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *BackupEntry) GetChecksum() uint32 {
+	if x != nil {
+		return x.Checksum
+	}
+	return 0
+}
+
+func (x *BackupEntry) GetVersion() []*VersionEntry {
+	if x != nil {
+		return x.Version
+	}
+	return nil
+}
+
+func (x *BackupEntry) GetCrdt() CrdtType {
+	if x != nil {
+		return x.Crdt
+	}
+	return CrdtType_CRDT_TYPE_NONE
+}
+
+// A batch of records streamed during a backup. The first chunk also
+// carries the registered JSON Schemas.
+type BackupChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*BackupEntry         `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	Schemas       []*KeySchema           `protobuf:"bytes,2,rep,name=schemas,proto3" json:"schemas,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BackupChunk) Reset() {
+	*x = BackupChunk{}
+	mi := &file_proto_kvstore_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BackupChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BackupChunk) ProtoMessage() {}
+
+func (x *BackupChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BackupChunk.ProtoReflect.Descriptor instead.
+func (*BackupChunk) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *BackupChunk) GetEntries() []*BackupEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *BackupChunk) GetSchemas() []*KeySchema {
+	if x != nil {
+		return x.Schemas
+	}
+	return nil
+}
+
+// A batch of records to restore; the mode is taken from the first message.
+// Schemas may be sent in any message and are restored along with the records.
+type RestoreRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Mode          RestoreMode            `protobuf:"varint,1,opt,name=mode,proto3,enum=kvstore.RestoreMode" json:"mode,omitempty"`
+	Entries       []*BackupEntry         `protobuf:"bytes,2,rep,name=entries,proto3" json:"entries,omitempty"`
+	Schemas       []*KeySchema           `protobuf:"bytes,3,rep,name=schemas,proto3" json:"schemas,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreRequest) Reset() {
+	*x = RestoreRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreRequest) ProtoMessage() {}
+
+func (x *RestoreRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreRequest.ProtoReflect.Descriptor instead.
+func (*RestoreRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *RestoreRequest) GetMode() RestoreMode {
+	if x != nil {
+		return x.Mode
+	}
+	return RestoreMode_RESTORE_MODE_REPLACE
+}
+
+func (x *RestoreRequest) GetEntries() []*BackupEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *RestoreRequest) GetSchemas() []*KeySchema {
+	if x != nil {
+		return x.Schemas
+	}
+	return nil
+}
+
+// Response for restoring a backup
+type RestoreResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	KeysRestored  int64                  `protobuf:"varint,2,opt,name=keys_restored,json=keysRestored,proto3" json:"keys_restored,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreResponse) Reset() {
+	*x = RestoreResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreResponse) ProtoMessage() {}
+
+func (x *RestoreResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreResponse.ProtoReflect.Descriptor instead.
+func (*RestoreResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *RestoreResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RestoreResponse) GetKeysRestored() int64 {
+	if x != nil {
+		return x.KeysRestored
+	}
+	return 0
+}
+
+func (x *RestoreResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Request to export the keyspace
+type ExportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Format        DataFormat             `protobuf:"varint,1,opt,name=format,proto3,enum=kvstore.DataFormat" json:"format,omitempty"`
+	Prefix        string                 `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportRequest) Reset() {
+	*x = ExportRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportRequest) ProtoMessage() {}
+
+func (x *ExportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportRequest.ProtoReflect.Descriptor instead.
+func (*ExportRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ExportRequest) GetFormat() DataFormat {
+	if x != nil {
+		return x.Format
+	}
+	return DataFormat_DATA_FORMAT_JSONL
+}
+
+func (x *ExportRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+// A piece of the exported document
+type ExportChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Data          []byte                 `protobuf:"bytes,1,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportChunk) Reset() {
+	*x = ExportChunk{}
+	mi := &file_proto_kvstore_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportChunk) ProtoMessage() {}
+
+func (x *ExportChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportChunk.ProtoReflect.Descriptor instead.
+func (*ExportChunk) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ExportChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// A piece of the document being imported; the format is taken from the first message
+type ImportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Format        DataFormat             `protobuf:"varint,1,opt,name=format,proto3,enum=kvstore.DataFormat" json:"format,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportRequest) Reset() {
+	*x = ImportRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportRequest) ProtoMessage() {}
+
+func (x *ImportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportRequest.ProtoReflect.Descriptor instead.
+func (*ImportRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ImportRequest) GetFormat() DataFormat {
+	if x != nil {
+		return x.Format
+	}
+	return DataFormat_DATA_FORMAT_JSONL
+}
+
+func (x *ImportRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// A row that could not be imported
+type ImportError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Line          int64                  `protobuf:"varint,1,opt,name=line,proto3" json:"line,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportError) Reset() {
+	*x = ImportError{}
+	mi := &file_proto_kvstore_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportError) ProtoMessage() {}
+
+func (x *ImportError) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportError.ProtoReflect.Descriptor instead.
+func (*ImportError) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ImportError) GetLine() int64 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *ImportError) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Progress of a running import
+type ImportProgress struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	RowsRead     int64                  `protobuf:"varint,1,opt,name=rows_read,json=rowsRead,proto3" json:"rows_read,omitempty"`
+	RowsImported int64                  `protobuf:"varint,2,opt,name=rows_imported,json=rowsImported,proto3" json:"rows_imported,omitempty"`
+	RowsFailed   int64                  `protobuf:"varint,3,opt,name=rows_failed,json=rowsFailed,proto3" json:"rows_failed,omitempty"`
+	// Errors for the rows failed since the previous progress message
+	Errors        []*ImportError `protobuf:"bytes,4,rep,name=errors,proto3" json:"errors,omitempty"`
+	Done          bool           `protobuf:"varint,5,opt,name=done,proto3" json:"done,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportProgress) Reset() {
+	*x = ImportProgress{}
+	mi := &file_proto_kvstore_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportProgress) ProtoMessage() {}
+
+func (x *ImportProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportProgress.ProtoReflect.Descriptor instead.
+func (*ImportProgress) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ImportProgress) GetRowsRead() int64 {
+	if x != nil {
+		return x.RowsRead
+	}
+	return 0
+}
+
+func (x *ImportProgress) GetRowsImported() int64 {
+	if x != nil {
+		return x.RowsImported
+	}
+	return 0
+}
+
+func (x *ImportProgress) GetRowsFailed() int64 {
+	if x != nil {
+		return x.RowsFailed
+	}
+	return 0
+}
+
+func (x *ImportProgress) GetErrors() []*ImportError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *ImportProgress) GetDone() bool {
+	if x != nil {
+		return x.Done
+	}
+	return false
+}
+
+// A single committed change to the store, as recorded in the mutation log
+type Mutation struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Revision uint64                 `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	// Commit time in nanoseconds since the Unix epoch
+	Timestamp int64      `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Op        MutationOp `protobuf:"varint,3,opt,name=op,proto3,enum=kvstore.MutationOp" json:"op,omitempty"`
+	Key       string     `protobuf:"bytes,4,opt,name=key,proto3" json:"key,omitempty"`
+	Value     string     `protobuf:"bytes,5,opt,name=value,proto3" json:"value,omitempty"`
+	Checksum  uint32     `protobuf:"varint,6,opt,name=checksum,proto3" json:"checksum,omitempty"`
+	// Identity of the client that made the change
+	Client string `protobuf:"bytes,7,opt,name=client,proto3" json:"client,omitempty"`
+	// Version vector of a quorum write or delete
+	Version []*VersionEntry `protobuf:"bytes,8,rep,name=version,proto3" json:"version,omitempty"`
+	// Type of a CRDT key, whose value is then its encoded state
+	Crdt          CrdtType `protobuf:"varint,9,opt,name=crdt,proto3,enum=kvstore.CrdtType" json:"crdt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Mutation) Reset() {
+	*x = Mutation{}
+	mi := &file_proto_kvstore_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Mutation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Mutation) ProtoMessage() {}
+
+func (x *Mutation) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Mutation.ProtoReflect.Descriptor instead.
+func (*Mutation) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Mutation) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *Mutation) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *Mutation) GetOp() MutationOp {
+	if x != nil {
+		return x.Op
+	}
+	return MutationOp_MUTATION_OP_SET
+}
+
+func (x *Mutation) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Mutation) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *Mutation) GetChecksum() uint32 {
+	if x != nil {
+		return x.Checksum
+	}
+	return 0
+}
+
+func (x *Mutation) GetClient() string {
+	if x != nil {
+		return x.Client
+	}
+	return ""
+}
+
+func (x *Mutation) GetVersion() []*VersionEntry {
+	if x != nil {
+		return x.Version
+	}
+	return nil
+}
+
+func (x *Mutation) GetCrdt() CrdtType {
+	if x != nil {
+		return x.Crdt
+	}
+	return CrdtType_CRDT_TYPE_NONE
+}
+
+// Request to restore a soft-deleted key
+type UndeleteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UndeleteRequest) Reset() {
+	*x = UndeleteRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UndeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UndeleteRequest) ProtoMessage() {}
+
+func (x *UndeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UndeleteRequest.ProtoReflect.Descriptor instead.
+func (*UndeleteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *UndeleteRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+// Response for restoring a soft-deleted key
+type UndeleteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UndeleteResponse) Reset() {
+	*x = UndeleteResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UndeleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UndeleteResponse) ProtoMessage() {}
+
+func (x *UndeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UndeleteResponse.ProtoReflect.Descriptor instead.
+func (*UndeleteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *UndeleteResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *UndeleteResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Request to list soft-deleted keys
+type ListDeletedRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDeletedRequest) Reset() {
+	*x = ListDeletedRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDeletedRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDeletedRequest) ProtoMessage() {}
+
+func (x *ListDeletedRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDeletedRequest.ProtoReflect.Descriptor instead.
+func (*ListDeletedRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ListDeletedRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+// A soft-deleted key and its retention window, in nanoseconds since the Unix epoch
+type DeletedKey struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	DeletedAt     int64                  `protobuf:"varint,2,opt,name=deleted_at,json=deletedAt,proto3" json:"deleted_at,omitempty"`
+	ExpiresAt     int64                  `protobuf:"varint,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeletedKey) Reset() {
+	*x = DeletedKey{}
+	mi := &file_proto_kvstore_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeletedKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeletedKey) ProtoMessage() {}
+
+func (x *DeletedKey) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeletedKey.ProtoReflect.Descriptor instead.
+func (*DeletedKey) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *DeletedKey) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *DeletedKey) GetDeletedAt() int64 {
+	if x != nil {
+		return x.DeletedAt
+	}
+	return 0
+}
+
+func (x *DeletedKey) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+// Response for listing soft-deleted keys
+type ListDeletedResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Keys          []*DeletedKey          `protobuf:"bytes,2,rep,name=keys,proto3" json:"keys,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDeletedResponse) Reset() {
+	*x = ListDeletedResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDeletedResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDeletedResponse) ProtoMessage() {}
+
+func (x *ListDeletedResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDeletedResponse.ProtoReflect.Descriptor instead.
+func (*ListDeletedResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ListDeletedResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ListDeletedResponse) GetKeys() []*DeletedKey {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+func (x *ListDeletedResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Request to retrieve the change history of a key
+type HistoryRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Maximum number of entries to return, 0 for all retained entries
+	Limit         int32 `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryRequest) Reset() {
+	*x = HistoryRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryRequest) ProtoMessage() {}
+
+func (x *HistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryRequest.ProtoReflect.Descriptor instead.
+func (*HistoryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *HistoryRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *HistoryRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// A recorded change to a key
+type HistoryEntry struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Revision uint64                 `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	// Commit time in nanoseconds since the Unix epoch
+	Timestamp     int64      `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Op            MutationOp `protobuf:"varint,3,opt,name=op,proto3,enum=kvstore.MutationOp" json:"op,omitempty"`
+	Value         string     `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	Client        string     `protobuf:"bytes,5,opt,name=client,proto3" json:"client,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryEntry) Reset() {
+	*x = HistoryEntry{}
+	mi := &file_proto_kvstore_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryEntry) ProtoMessage() {}
+
+func (x *HistoryEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryEntry.ProtoReflect.Descriptor instead.
+func (*HistoryEntry) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *HistoryEntry) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetOp() MutationOp {
+	if x != nil {
+		return x.Op
+	}
+	return MutationOp_MUTATION_OP_SET
+}
+
+func (x *HistoryEntry) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetClient() string {
+	if x != nil {
+		return x.Client
+	}
+	return ""
+}
+
+// Response for retrieving the change history of a key
+type HistoryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Entries       []*HistoryEntry        `protobuf:"bytes,2,rep,name=entries,proto3" json:"entries,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryResponse) Reset() {
+	*x = HistoryResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryResponse) ProtoMessage() {}
+
+func (x *HistoryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryResponse.ProtoReflect.Descriptor instead.
+func (*HistoryResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *HistoryResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *HistoryResponse) GetEntries() []*HistoryEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *HistoryResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Request to report quota consumption
+type QuotaUsageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuotaUsageRequest) Reset() {
+	*x = QuotaUsageRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuotaUsageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuotaUsageRequest) ProtoMessage() {}
+
+func (x *QuotaUsageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuotaUsageRequest.ProtoReflect.Descriptor instead.
+func (*QuotaUsageRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{28}
+}
+
+// Limits and current consumption of a quota; a limit of 0 means unlimited
+type QuotaStatus struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Prefix        string                 `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Client        string                 `protobuf:"bytes,3,opt,name=client,proto3" json:"client,omitempty"`
+	MaxKeys       int64                  `protobuf:"varint,4,opt,name=max_keys,json=maxKeys,proto3" json:"max_keys,omitempty"`
+	MaxBytes      int64                  `protobuf:"varint,5,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	MaxValueBytes int64                  `protobuf:"varint,6,opt,name=max_value_bytes,json=maxValueBytes,proto3" json:"max_value_bytes,omitempty"`
+	Keys          int64                  `protobuf:"varint,7,opt,name=keys,proto3" json:"keys,omitempty"`
+	Bytes         int64                  `protobuf:"varint,8,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuotaStatus) Reset() {
+	*x = QuotaStatus{}
+	mi := &file_proto_kvstore_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuotaStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuotaStatus) ProtoMessage() {}
+
+func (x *QuotaStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuotaStatus.ProtoReflect.Descriptor instead.
+func (*QuotaStatus) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *QuotaStatus) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *QuotaStatus) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *QuotaStatus) GetClient() string {
+	if x != nil {
+		return x.Client
+	}
+	return ""
+}
+
+func (x *QuotaStatus) GetMaxKeys() int64 {
+	if x != nil {
+		return x.MaxKeys
+	}
+	return 0
+}
+
+func (x *QuotaStatus) GetMaxBytes() int64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+func (x *QuotaStatus) GetMaxValueBytes() int64 {
+	if x != nil {
+		return x.MaxValueBytes
+	}
+	return 0
+}
+
+func (x *QuotaStatus) GetKeys() int64 {
+	if x != nil {
+		return x.Keys
+	}
+	return 0
+}
+
+func (x *QuotaStatus) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+// Response for reporting quota consumption
+type QuotaUsageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Quotas        []*QuotaStatus         `protobuf:"bytes,2,rep,name=quotas,proto3" json:"quotas,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuotaUsageResponse) Reset() {
+	*x = QuotaUsageResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuotaUsageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuotaUsageResponse) ProtoMessage() {}
+
+func (x *QuotaUsageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuotaUsageResponse.ProtoReflect.Descriptor instead.
+func (*QuotaUsageResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *QuotaUsageResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *QuotaUsageResponse) GetQuotas() []*QuotaStatus {
+	if x != nil {
+		return x.Quotas
+	}
+	return nil
+}
+
+func (x *QuotaUsageResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Request to register a JSON Schema for a key prefix
+type RegisterSchemaRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Prefix string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// JSON Schema document
+	Schema        string `protobuf:"bytes,2,opt,name=schema,proto3" json:"schema,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterSchemaRequest) Reset() {
+	*x = RegisterSchemaRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterSchemaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterSchemaRequest) ProtoMessage() {}
+
+func (x *RegisterSchemaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterSchemaRequest.ProtoReflect.Descriptor instead.
+func (*RegisterSchemaRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *RegisterSchemaRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *RegisterSchemaRequest) GetSchema() string {
+	if x != nil {
+		return x.Schema
+	}
+	return ""
+}
+
+// Response for registering a JSON Schema
+type RegisterSchemaResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterSchemaResponse) Reset() {
+	*x = RegisterSchemaResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterSchemaResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterSchemaResponse) ProtoMessage() {}
+
+func (x *RegisterSchemaResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterSchemaResponse.ProtoReflect.Descriptor instead.
+func (*RegisterSchemaResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *RegisterSchemaResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RegisterSchemaResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Request to list the registered JSON Schemas
+type ListSchemasRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSchemasRequest) Reset() {
+	*x = ListSchemasRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSchemasRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSchemasRequest) ProtoMessage() {}
+
+func (x *ListSchemasRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSchemasRequest.ProtoReflect.Descriptor instead.
+func (*ListSchemasRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{33}
+}
+
+// A JSON Schema and the key prefix it applies to
+type KeySchema struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	Schema        string                 `protobuf:"bytes,2,opt,name=schema,proto3" json:"schema,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeySchema) Reset() {
+	*x = KeySchema{}
+	mi := &file_proto_kvstore_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeySchema) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeySchema) ProtoMessage() {}
+
+func (x *KeySchema) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeySchema.ProtoReflect.Descriptor instead.
+func (*KeySchema) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *KeySchema) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *KeySchema) GetSchema() string {
+	if x != nil {
+		return x.Schema
+	}
+	return ""
+}
+
+// Response for listing the registered JSON Schemas
+type ListSchemasResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Schemas       []*KeySchema           `protobuf:"bytes,2,rep,name=schemas,proto3" json:"schemas,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSchemasResponse) Reset() {
+	*x = ListSchemasResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSchemasResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSchemasResponse) ProtoMessage() {}
+
+func (x *ListSchemasResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSchemasResponse.ProtoReflect.Descriptor instead.
+func (*ListSchemasResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *ListSchemasResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ListSchemasResponse) GetSchemas() []*KeySchema {
+	if x != nil {
+		return x.Schemas
+	}
+	return nil
+}
+
+func (x *ListSchemasResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Request to remove the JSON Schema for a key prefix
+type DeleteSchemaRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSchemaRequest) Reset() {
+	*x = DeleteSchemaRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSchemaRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSchemaRequest) ProtoMessage() {}
+
+func (x *DeleteSchemaRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSchemaRequest.ProtoReflect.Descriptor instead.
+func (*DeleteSchemaRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *DeleteSchemaRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+// Response for removing a JSON Schema
+type DeleteSchemaResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteSchemaResponse) Reset() {
+	*x = DeleteSchemaResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteSchemaResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSchemaResponse) ProtoMessage() {}
+
+func (x *DeleteSchemaResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSchemaResponse.ProtoReflect.Descriptor instead.
+func (*DeleteSchemaResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *DeleteSchemaResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *DeleteSchemaResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Request to report the most frequently accessed keys
+type TopKeysRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Maximum number of keys to return; 0 returns every tracked key
+	Limit         int32 `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopKeysRequest) Reset() {
+	*x = TopKeysRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopKeysRequest) ProtoMessage() {}
+
+func (x *TopKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopKeysRequest.ProtoReflect.Descriptor instead.
+func (*TopKeysRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *TopKeysRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// Estimated access counts for a key, scaled up from the sampled accesses
+type HotKey struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Reads         int64                  `protobuf:"varint,2,opt,name=reads,proto3" json:"reads,omitempty"`
+	Writes        int64                  `protobuf:"varint,3,opt,name=writes,proto3" json:"writes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HotKey) Reset() {
+	*x = HotKey{}
+	mi := &file_proto_kvstore_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HotKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HotKey) ProtoMessage() {}
+
+func (x *HotKey) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HotKey.ProtoReflect.Descriptor instead.
+func (*HotKey) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *HotKey) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *HotKey) GetReads() int64 {
+	if x != nil {
+		return x.Reads
+	}
+	return 0
+}
+
+func (x *HotKey) GetWrites() int64 {
+	if x != nil {
+		return x.Writes
+	}
+	return 0
+}
+
+// Response for reporting the most frequently accessed keys
+type TopKeysResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	// Keys ordered by estimated reads plus writes, busiest first
+	Keys []*HotKey `protobuf:"bytes,2,rep,name=keys,proto3" json:"keys,omitempty"`
+	// Fraction of accesses that were sampled
+	SampleRate    float64 `protobuf:"fixed64,3,opt,name=sample_rate,json=sampleRate,proto3" json:"sample_rate,omitempty"`
+	Message       string  `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopKeysResponse) Reset() {
+	*x = TopKeysResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopKeysResponse) ProtoMessage() {}
+
+func (x *TopKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopKeysResponse.ProtoReflect.Descriptor instead.
+func (*TopKeysResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *TopKeysResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *TopKeysResponse) GetKeys() []*HotKey {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+func (x *TopKeysResponse) GetSampleRate() float64 {
+	if x != nil {
+		return x.SampleRate
+	}
+	return 0
+}
+
+func (x *TopKeysResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// An entry of the replicated log: a group of mutations committed together.
+// Revisions are assigned as the entry is applied; an entry without
+// mutations is the no-op a new leader commits to start its term.
+type RaftEntry struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Term      uint64                 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Index     uint64                 `protobuf:"varint,2,opt,name=index,proto3" json:"index,omitempty"`
+	Mutations []*Mutation            `protobuf:"bytes,3,rep,name=mutations,proto3" json:"mutations,omitempty"`
+	// A group too large for one entry is split across consecutive entries of
+	// the same term; all but the last are partial. The group is applied once
+	// its last entry is committed, and dropped if a new term cuts it short.
+	Partial       bool `protobuf:"varint,4,opt,name=partial,proto3" json:"partial,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RaftEntry) Reset() {
+	*x = RaftEntry{}
+	mi := &file_proto_kvstore_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RaftEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RaftEntry) ProtoMessage() {}
+
+func (x *RaftEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RaftEntry.ProtoReflect.Descriptor instead.
+func (*RaftEntry) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *RaftEntry) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *RaftEntry) GetIndex() uint64 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *RaftEntry) GetMutations() []*Mutation {
+	if x != nil {
+		return x.Mutations
+	}
+	return nil
+}
+
+func (x *RaftEntry) GetPartial() bool {
+	if x != nil {
+		return x.Partial
+	}
+	return false
+}
+
+// A snapshot of the store that replaces the raft log up to and including
+// last_index. Loading it replaces the store's keys and schemas.
+type RaftSnapshot struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	LastIndex uint64                 `protobuf:"varint,1,opt,name=last_index,json=lastIndex,proto3" json:"last_index,omitempty"`
+	LastTerm  uint64                 `protobuf:"varint,2,opt,name=last_term,json=lastTerm,proto3" json:"last_term,omitempty"`
+	// Revision of the store once the snapshot is loaded
+	Revision      uint64      `protobuf:"varint,3,opt,name=revision,proto3" json:"revision,omitempty"`
+	Timestamp     int64       `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Mutations     []*Mutation `protobuf:"bytes,5,rep,name=mutations,proto3" json:"mutations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RaftSnapshot) Reset() {
+	*x = RaftSnapshot{}
+	mi := &file_proto_kvstore_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RaftSnapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RaftSnapshot) ProtoMessage() {}
+
+func (x *RaftSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RaftSnapshot.ProtoReflect.Descriptor instead.
+func (*RaftSnapshot) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *RaftSnapshot) GetLastIndex() uint64 {
+	if x != nil {
+		return x.LastIndex
+	}
+	return 0
+}
+
+func (x *RaftSnapshot) GetLastTerm() uint64 {
+	if x != nil {
+		return x.LastTerm
+	}
+	return 0
+}
+
+func (x *RaftSnapshot) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *RaftSnapshot) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *RaftSnapshot) GetMutations() []*Mutation {
+	if x != nil {
+		return x.Mutations
+	}
+	return nil
+}
+
+// A chunk of a snapshot sent by the leader. Every chunk repeats the header;
+// the snapshot is installed once the stream ends.
+type InstallSnapshotRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Term          uint64                 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	LeaderId      string                 `protobuf:"bytes,2,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"`
+	LastIndex     uint64                 `protobuf:"varint,3,opt,name=last_index,json=lastIndex,proto3" json:"last_index,omitempty"`
+	LastTerm      uint64                 `protobuf:"varint,4,opt,name=last_term,json=lastTerm,proto3" json:"last_term,omitempty"`
+	Revision      uint64                 `protobuf:"varint,5,opt,name=revision,proto3" json:"revision,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Mutations     []*Mutation            `protobuf:"bytes,7,rep,name=mutations,proto3" json:"mutations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InstallSnapshotRequest) Reset() {
+	*x = InstallSnapshotRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InstallSnapshotRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InstallSnapshotRequest) ProtoMessage() {}
+
+func (x *InstallSnapshotRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InstallSnapshotRequest.ProtoReflect.Descriptor instead.
+func (*InstallSnapshotRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *InstallSnapshotRequest) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *InstallSnapshotRequest) GetLeaderId() string {
+	if x != nil {
+		return x.LeaderId
+	}
+	return ""
+}
+
+func (x *InstallSnapshotRequest) GetLastIndex() uint64 {
+	if x != nil {
+		return x.LastIndex
+	}
+	return 0
+}
+
+func (x *InstallSnapshotRequest) GetLastTerm() uint64 {
+	if x != nil {
+		return x.LastTerm
+	}
+	return 0
+}
+
+func (x *InstallSnapshotRequest) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *InstallSnapshotRequest) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *InstallSnapshotRequest) GetMutations() []*Mutation {
+	if x != nil {
+		return x.Mutations
+	}
+	return nil
+}
+
+// Response to an installed snapshot
+type InstallSnapshotResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Term          uint64                 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InstallSnapshotResponse) Reset() {
+	*x = InstallSnapshotResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InstallSnapshotResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InstallSnapshotResponse) ProtoMessage() {}
+
+func (x *InstallSnapshotResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InstallSnapshotResponse.ProtoReflect.Descriptor instead.
+func (*InstallSnapshotResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *InstallSnapshotResponse) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+// Request for a vote from a candidate
+type VoteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Term          uint64                 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	CandidateId   string                 `protobuf:"bytes,2,opt,name=candidate_id,json=candidateId,proto3" json:"candidate_id,omitempty"`
+	LastLogIndex  uint64                 `protobuf:"varint,3,opt,name=last_log_index,json=lastLogIndex,proto3" json:"last_log_index,omitempty"`
+	LastLogTerm   uint64                 `protobuf:"varint,4,opt,name=last_log_term,json=lastLogTerm,proto3" json:"last_log_term,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VoteRequest) Reset() {
+	*x = VoteRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VoteRequest) ProtoMessage() {}
+
+func (x *VoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VoteRequest.ProtoReflect.Descriptor instead.
+func (*VoteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *VoteRequest) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *VoteRequest) GetCandidateId() string {
+	if x != nil {
+		return x.CandidateId
+	}
+	return ""
+}
+
+func (x *VoteRequest) GetLastLogIndex() uint64 {
+	if x != nil {
+		return x.LastLogIndex
+	}
+	return 0
+}
+
+func (x *VoteRequest) GetLastLogTerm() uint64 {
+	if x != nil {
+		return x.LastLogTerm
+	}
+	return 0
+}
+
+// Response to a vote request
+type VoteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Term          uint64                 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	VoteGranted   bool                   `protobuf:"varint,2,opt,name=vote_granted,json=voteGranted,proto3" json:"vote_granted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VoteResponse) Reset() {
+	*x = VoteResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VoteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VoteResponse) ProtoMessage() {}
+
+func (x *VoteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VoteResponse.ProtoReflect.Descriptor instead.
+func (*VoteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *VoteResponse) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *VoteResponse) GetVoteGranted() bool {
+	if x != nil {
+		return x.VoteGranted
+	}
+	return false
+}
+
+// Request from the leader to replicate entries after prev_log_index
+type AppendEntriesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Term          uint64                 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	LeaderId      string                 `protobuf:"bytes,2,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"`
+	PrevLogIndex  uint64                 `protobuf:"varint,3,opt,name=prev_log_index,json=prevLogIndex,proto3" json:"prev_log_index,omitempty"`
+	PrevLogTerm   uint64                 `protobuf:"varint,4,opt,name=prev_log_term,json=prevLogTerm,proto3" json:"prev_log_term,omitempty"`
+	Entries       []*RaftEntry           `protobuf:"bytes,5,rep,name=entries,proto3" json:"entries,omitempty"`
+	LeaderCommit  uint64                 `protobuf:"varint,6,opt,name=leader_commit,json=leaderCommit,proto3" json:"leader_commit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AppendEntriesRequest) Reset() {
+	*x = AppendEntriesRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppendEntriesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppendEntriesRequest) ProtoMessage() {}
+
+func (x *AppendEntriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppendEntriesRequest.ProtoReflect.Descriptor instead.
+func (*AppendEntriesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *AppendEntriesRequest) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *AppendEntriesRequest) GetLeaderId() string {
+	if x != nil {
+		return x.LeaderId
+	}
+	return ""
+}
+
+func (x *AppendEntriesRequest) GetPrevLogIndex() uint64 {
+	if x != nil {
+		return x.PrevLogIndex
+	}
+	return 0
+}
+
+func (x *AppendEntriesRequest) GetPrevLogTerm() uint64 {
+	if x != nil {
+		return x.PrevLogTerm
+	}
+	return 0
+}
+
+func (x *AppendEntriesRequest) GetEntries() []*RaftEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *AppendEntriesRequest) GetLeaderCommit() uint64 {
+	if x != nil {
+		return x.LeaderCommit
+	}
+	return 0
+}
+
+// Response to an append request
+type AppendEntriesResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Term    uint64                 `protobuf:"varint,1,opt,name=term,proto3" json:"term,omitempty"`
+	Success bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	// On success the last index known to match the leader; otherwise the
+	// index the leader should retry from
+	MatchIndex    uint64 `protobuf:"varint,3,opt,name=match_index,json=matchIndex,proto3" json:"match_index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AppendEntriesResponse) Reset() {
+	*x = AppendEntriesResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AppendEntriesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppendEntriesResponse) ProtoMessage() {}
+
+func (x *AppendEntriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppendEntriesResponse.ProtoReflect.Descriptor instead.
+func (*AppendEntriesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *AppendEntriesResponse) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *AppendEntriesResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *AppendEntriesResponse) GetMatchIndex() uint64 {
+	if x != nil {
+		return x.MatchIndex
+	}
+	return 0
+}
+
+// Request for a node's view of the cluster
+type RaftStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RaftStatusRequest) Reset() {
+	*x = RaftStatusRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RaftStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RaftStatusRequest) ProtoMessage() {}
+
+func (x *RaftStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RaftStatusRequest.ProtoReflect.Descriptor instead.
+func (*RaftStatusRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{49}
+}
+
+// A node's view of the cluster
+type RaftStatusResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// follower, candidate or leader
+	Role         string   `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"`
+	Term         uint64   `protobuf:"varint,3,opt,name=term,proto3" json:"term,omitempty"`
+	LeaderId     string   `protobuf:"bytes,4,opt,name=leader_id,json=leaderId,proto3" json:"leader_id,omitempty"`
+	CommitIndex  uint64   `protobuf:"varint,5,opt,name=commit_index,json=commitIndex,proto3" json:"commit_index,omitempty"`
+	LastLogIndex uint64   `protobuf:"varint,6,opt,name=last_log_index,json=lastLogIndex,proto3" json:"last_log_index,omitempty"`
+	AppliedIndex uint64   `protobuf:"varint,7,opt,name=applied_index,json=appliedIndex,proto3" json:"applied_index,omitempty"`
+	Peers        []string `protobuf:"bytes,8,rep,name=peers,proto3" json:"peers,omitempty"`
+	// Last log index covered by the snapshot; earlier entries are compacted
+	SnapshotIndex uint64 `protobuf:"varint,9,opt,name=snapshot_index,json=snapshotIndex,proto3" json:"snapshot_index,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RaftStatusResponse) Reset() {
+	*x = RaftStatusResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RaftStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RaftStatusResponse) ProtoMessage() {}
+
+func (x *RaftStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RaftStatusResponse.ProtoReflect.Descriptor instead.
+func (*RaftStatusResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *RaftStatusResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RaftStatusResponse) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *RaftStatusResponse) GetTerm() uint64 {
+	if x != nil {
+		return x.Term
+	}
+	return 0
+}
+
+func (x *RaftStatusResponse) GetLeaderId() string {
+	if x != nil {
+		return x.LeaderId
+	}
+	return ""
+}
+
+func (x *RaftStatusResponse) GetCommitIndex() uint64 {
+	if x != nil {
+		return x.CommitIndex
+	}
+	return 0
+}
+
+func (x *RaftStatusResponse) GetLastLogIndex() uint64 {
+	if x != nil {
+		return x.LastLogIndex
+	}
+	return 0
+}
+
+func (x *RaftStatusResponse) GetAppliedIndex() uint64 {
+	if x != nil {
+		return x.AppliedIndex
+	}
+	return 0
+}
+
+func (x *RaftStatusResponse) GetPeers() []string {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+func (x *RaftStatusResponse) GetSnapshotIndex() uint64 {
+	if x != nil {
+		return x.SnapshotIndex
+	}
+	return 0
+}
+
+// Request to follow the mutation feed
+type SubscribeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// First revision the replica needs
+	FromRevision uint64 `protobuf:"varint,1,opt,name=from_revision,json=fromRevision,proto3" json:"from_revision,omitempty"`
+	// Name of the replica, for the primary's logs
+	ReplicaId     string `protobuf:"bytes,2,opt,name=replica_id,json=replicaId,proto3" json:"replica_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *SubscribeRequest) GetFromRevision() uint64 {
+	if x != nil {
+		return x.FromRevision
+	}
+	return 0
+}
+
+func (x *SubscribeRequest) GetReplicaId() string {
+	if x != nil {
+		return x.ReplicaId
+	}
+	return ""
+}
+
+// A message of the replication stream
+type ReplicationEvent struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Type      ReplicationEventType   `protobuf:"varint,1,opt,name=type,proto3,enum=kvstore.ReplicationEventType" json:"type,omitempty"`
+	Mutations []*Mutation            `protobuf:"bytes,2,rep,name=mutations,proto3" json:"mutations,omitempty"`
+	Entries   []*BackupEntry         `protobuf:"bytes,3,rep,name=entries,proto3" json:"entries,omitempty"`
+	// Snapshot revision, or the primary's current revision for a heartbeat
+	Revision uint64 `protobuf:"varint,4,opt,name=revision,proto3" json:"revision,omitempty"`
+	// Primary's clock in nanoseconds since the Unix epoch when sent
+	Timestamp     int64 `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicationEvent) Reset() {
+	*x = ReplicationEvent{}
+	mi := &file_proto_kvstore_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicationEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicationEvent) ProtoMessage() {}
+
+func (x *ReplicationEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicationEvent.ProtoReflect.Descriptor instead.
+func (*ReplicationEvent) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *ReplicationEvent) GetType() ReplicationEventType {
+	if x != nil {
+		return x.Type
+	}
+	return ReplicationEventType_REPLICATION_EVENT_MUTATIONS
+}
+
+func (x *ReplicationEvent) GetMutations() []*Mutation {
+	if x != nil {
+		return x.Mutations
+	}
+	return nil
+}
+
+func (x *ReplicationEvent) GetEntries() []*BackupEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+func (x *ReplicationEvent) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *ReplicationEvent) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+// Request for the replication status
+type ReplicationStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicationStatusRequest) Reset() {
+	*x = ReplicationStatusRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicationStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicationStatusRequest) ProtoMessage() {}
+
+func (x *ReplicationStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicationStatusRequest.ProtoReflect.Descriptor instead.
+func (*ReplicationStatusRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{53}
+}
+
+// Replication role and lag of a server
+type ReplicationStatusResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// primary or replica
+	Role string `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"`
+	// Address of the primary when this server is a replica
+	Primary         string `protobuf:"bytes,2,opt,name=primary,proto3" json:"primary,omitempty"`
+	Connected       bool   `protobuf:"varint,3,opt,name=connected,proto3" json:"connected,omitempty"`
+	AppliedRevision uint64 `protobuf:"varint,4,opt,name=applied_revision,json=appliedRevision,proto3" json:"applied_revision,omitempty"`
+	// Latest revision the primary is known to have committed
+	PrimaryRevision uint64 `protobuf:"varint,5,opt,name=primary_revision,json=primaryRevision,proto3" json:"primary_revision,omitempty"`
+	LagRevisions    uint64 `protobuf:"varint,6,opt,name=lag_revisions,json=lagRevisions,proto3" json:"lag_revisions,omitempty"`
+	// How long the replica has been behind the primary
+	LagSeconds float64 `protobuf:"fixed64,7,opt,name=lag_seconds,json=lagSeconds,proto3" json:"lag_seconds,omitempty"`
+	// Last time the replica heard from the primary, in nanoseconds since the Unix epoch
+	LastContact int64  `protobuf:"varint,8,opt,name=last_contact,json=lastContact,proto3" json:"last_contact,omitempty"`
+	LastError   string `protobuf:"bytes,9,opt,name=last_error,json=lastError,proto3" json:"last_error,omitempty"`
+	// Number of replicas streaming from this server
+	Replicas int32 `protobuf:"varint,10,opt,name=replicas,proto3" json:"replicas,omitempty"`
+	// Anti-entropy rounds completed against the primary
+	AntiEntropyRounds int64 `protobuf:"varint,11,opt,name=anti_entropy_rounds,json=antiEntropyRounds,proto3" json:"anti_entropy_rounds,omitempty"`
+	// Keys repaired by anti-entropy since the server started
+	KeysRepaired int64 `protobuf:"varint,12,opt,name=keys_repaired,json=keysRepaired,proto3" json:"keys_repaired,omitempty"`
+	// Last time an anti-entropy round finished, in nanoseconds since the Unix epoch
+	LastAntiEntropy  int64  `protobuf:"varint,13,opt,name=last_anti_entropy,json=lastAntiEntropy,proto3" json:"last_anti_entropy,omitempty"`
+	AntiEntropyError string `protobuf:"bytes,14,opt,name=anti_entropy_error,json=antiEntropyError,proto3" json:"anti_entropy_error,omitempty"`
+	// Hinted writes held for unreachable replicas
+	HintsPending int64 `protobuf:"varint,15,opt,name=hints_pending,json=hintsPending,proto3" json:"hints_pending,omitempty"`
+	// Hints accepted, delivered, expired before delivery and refused for
+	// lack of room since the server started
+	HintsStored    int64 `protobuf:"varint,16,opt,name=hints_stored,json=hintsStored,proto3" json:"hints_stored,omitempty"`
+	HintsDelivered int64 `protobuf:"varint,17,opt,name=hints_delivered,json=hintsDelivered,proto3" json:"hints_delivered,omitempty"`
+	HintsExpired   int64 `protobuf:"varint,18,opt,name=hints_expired,json=hintsExpired,proto3" json:"hints_expired,omitempty"`
+	HintsDropped   int64 `protobuf:"varint,19,opt,name=hints_dropped,json=hintsDropped,proto3" json:"hints_dropped,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ReplicationStatusResponse) Reset() {
+	*x = ReplicationStatusResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicationStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicationStatusResponse) ProtoMessage() {}
+
+func (x *ReplicationStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicationStatusResponse.ProtoReflect.Descriptor instead.
+func (*ReplicationStatusResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *ReplicationStatusResponse) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *ReplicationStatusResponse) GetPrimary() string {
+	if x != nil {
+		return x.Primary
+	}
+	return ""
+}
+
+func (x *ReplicationStatusResponse) GetConnected() bool {
+	if x != nil {
+		return x.Connected
+	}
+	return false
+}
+
+func (x *ReplicationStatusResponse) GetAppliedRevision() uint64 {
+	if x != nil {
+		return x.AppliedRevision
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetPrimaryRevision() uint64 {
+	if x != nil {
+		return x.PrimaryRevision
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetLagRevisions() uint64 {
+	if x != nil {
+		return x.LagRevisions
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetLagSeconds() float64 {
+	if x != nil {
+		return x.LagSeconds
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetLastContact() int64 {
+	if x != nil {
+		return x.LastContact
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetLastError() string {
+	if x != nil {
+		return x.LastError
+	}
+	return ""
+}
+
+func (x *ReplicationStatusResponse) GetReplicas() int32 {
+	if x != nil {
+		return x.Replicas
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetAntiEntropyRounds() int64 {
+	if x != nil {
+		return x.AntiEntropyRounds
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetKeysRepaired() int64 {
+	if x != nil {
+		return x.KeysRepaired
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetLastAntiEntropy() int64 {
+	if x != nil {
+		return x.LastAntiEntropy
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetAntiEntropyError() string {
+	if x != nil {
+		return x.AntiEntropyError
+	}
+	return ""
+}
+
+func (x *ReplicationStatusResponse) GetHintsPending() int64 {
+	if x != nil {
+		return x.HintsPending
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetHintsStored() int64 {
+	if x != nil {
+		return x.HintsStored
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetHintsDelivered() int64 {
+	if x != nil {
+		return x.HintsDelivered
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetHintsExpired() int64 {
+	if x != nil {
+		return x.HintsExpired
+	}
+	return 0
+}
+
+func (x *ReplicationStatusResponse) GetHintsDropped() int64 {
+	if x != nil {
+		return x.HintsDropped
+	}
+	return 0
+}
+
+// Request to promote a replica to primary
+type PromoteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PromoteRequest) Reset() {
+	*x = PromoteRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PromoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PromoteRequest) ProtoMessage() {}
+
+func (x *PromoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PromoteRequest.ProtoReflect.Descriptor instead.
+func (*PromoteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{55}
+}
+
+// Response for promoting a replica
+type PromoteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Revision      uint64                 `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PromoteResponse) Reset() {
+	*x = PromoteResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[56]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PromoteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PromoteResponse) ProtoMessage() {}
+
+func (x *PromoteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[56]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PromoteResponse.ProtoReflect.Descriptor instead.
+func (*PromoteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{56}
+}
+
+func (x *PromoteResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *PromoteResponse) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *PromoteResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Request for the hashes of Merkle tree nodes
+type MerkleTreeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Depth of the nodes, the root being level 0
+	Level uint32 `protobuf:"varint,1,opt,name=level,proto3" json:"level,omitempty"`
+	// Indexes of the nodes within their level
+	Nodes []uint32 `protobuf:"varint,2,rep,packed,name=nodes,proto3" json:"nodes,omitempty"`
+	// Revision of the tree the round started with, so that every level is
+	// read from the same tree; zero starts a round with a fresh tree
+	Revision      uint64 `protobuf:"varint,3,opt,name=revision,proto3" json:"revision,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MerkleTreeRequest) Reset() {
+	*x = MerkleTreeRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[57]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MerkleTreeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MerkleTreeRequest) ProtoMessage() {}
+
+func (x *MerkleTreeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[57]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MerkleTreeRequest.ProtoReflect.Descriptor instead.
+func (*MerkleTreeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{57}
+}
+
+func (x *MerkleTreeRequest) GetLevel() uint32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *MerkleTreeRequest) GetNodes() []uint32 {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+func (x *MerkleTreeRequest) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+// Hashes of Merkle tree nodes, in the order requested
+type MerkleTreeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Revision the tree was hashed at
+	Revision      uint64   `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	Hashes        []uint64 `protobuf:"varint,2,rep,packed,name=hashes,proto3" json:"hashes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MerkleTreeResponse) Reset() {
+	*x = MerkleTreeResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[58]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MerkleTreeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MerkleTreeResponse) ProtoMessage() {}
+
+func (x *MerkleTreeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[58]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MerkleTreeResponse.ProtoReflect.Descriptor instead.
+func (*MerkleTreeResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{58}
+}
+
+func (x *MerkleTreeResponse) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *MerkleTreeResponse) GetHashes() []uint64 {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
+// Request for the records under Merkle tree leaves
+type MerkleLeavesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Leaves        []uint32               `protobuf:"varint,1,rep,packed,name=leaves,proto3" json:"leaves,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MerkleLeavesRequest) Reset() {
+	*x = MerkleLeavesRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[59]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MerkleLeavesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MerkleLeavesRequest) ProtoMessage() {}
+
+func (x *MerkleLeavesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[59]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MerkleLeavesRequest.ProtoReflect.Descriptor instead.
+func (*MerkleLeavesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{59}
+}
+
+func (x *MerkleLeavesRequest) GetLeaves() []uint32 {
+	if x != nil {
+		return x.Leaves
+	}
+	return nil
+}
+
+// Records under Merkle tree leaves
+type MerkleLeavesResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Revision the records were read at
+	Revision      uint64         `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	Entries       []*BackupEntry `protobuf:"bytes,2,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MerkleLeavesResponse) Reset() {
+	*x = MerkleLeavesResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[60]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MerkleLeavesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MerkleLeavesResponse) ProtoMessage() {}
+
+func (x *MerkleLeavesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[60]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MerkleLeavesResponse.ProtoReflect.Descriptor instead.
+func (*MerkleLeavesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{60}
+}
+
+func (x *MerkleLeavesResponse) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *MerkleLeavesResponse) GetEntries() []*BackupEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+// A write held on behalf of a replica that could not be reached
+type StoreHintRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Address of the replica the write is for
+	Target string `protobuf:"bytes,1,opt,name=target,proto3" json:"target,omitempty"`
+	Key    string `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	Value  string `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	// Version the write was given by its coordinator
+	Version []*VersionEntry `protobuf:"bytes,4,rep,name=version,proto3" json:"version,omitempty"`
+	// Set when the write is a delete of key, in which case value is empty
+	Delete        bool `protobuf:"varint,5,opt,name=delete,proto3" json:"delete,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StoreHintRequest) Reset() {
+	*x = StoreHintRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[61]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StoreHintRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StoreHintRequest) ProtoMessage() {}
+
+func (x *StoreHintRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[61]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StoreHintRequest.ProtoReflect.Descriptor instead.
+func (*StoreHintRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{61}
+}
+
+func (x *StoreHintRequest) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *StoreHintRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *StoreHintRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *StoreHintRequest) GetVersion() []*VersionEntry {
+	if x != nil {
+		return x.Version
+	}
+	return nil
+}
+
+func (x *StoreHintRequest) GetDelete() bool {
+	if x != nil {
+		return x.Delete
+	}
+	return false
+}
+
+// Response for storing a hint
+type StoreHintResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StoreHintResponse) Reset() {
+	*x = StoreHintResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[62]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StoreHintResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StoreHintResponse) ProtoMessage() {}
+
+func (x *StoreHintResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[62]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StoreHintResponse.ProtoReflect.Descriptor instead.
+func (*StoreHintResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{62}
+}
+
+func (x *StoreHintResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *StoreHintResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Request to update a CRDT key
+type CrdtUpdateRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Type the key is declared as; it must match once declared
+	Type CrdtType `protobuf:"varint,2,opt,name=type,proto3,enum=kvstore.CrdtType" json:"type,omitempty"`
+	Op   CrdtOp   `protobuf:"varint,3,opt,name=op,proto3,enum=kvstore.CrdtOp" json:"op,omitempty"`
+	// Value written to a register, or element added to or removed from a set
+	Value string `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	// Amount a counter changes by
+	Amount        uint64 `protobuf:"varint,5,opt,name=amount,proto3" json:"amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrdtUpdateRequest) Reset() {
+	*x = CrdtUpdateRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[63]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrdtUpdateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrdtUpdateRequest) ProtoMessage() {}
+
+func (x *CrdtUpdateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[63]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrdtUpdateRequest.ProtoReflect.Descriptor instead.
+func (*CrdtUpdateRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{63}
+}
+
+func (x *CrdtUpdateRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *CrdtUpdateRequest) GetType() CrdtType {
+	if x != nil {
+		return x.Type
+	}
+	return CrdtType_CRDT_TYPE_NONE
+}
+
+func (x *CrdtUpdateRequest) GetOp() CrdtOp {
+	if x != nil {
+		return x.Op
+	}
+	return CrdtOp_CRDT_OP_ASSIGN
+}
+
+func (x *CrdtUpdateRequest) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *CrdtUpdateRequest) GetAmount() uint64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+// Response for updating a CRDT key, with its value after the update
+type CrdtUpdateResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Type    CrdtType               `protobuf:"varint,3,opt,name=type,proto3,enum=kvstore.CrdtType" json:"type,omitempty"`
+	// Value of a register
+	Value string `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	// Value of a counter
+	Count int64 `protobuf:"varint,5,opt,name=count,proto3" json:"count,omitempty"`
+	// Elements of a set, in order
+	Elements      []string `protobuf:"bytes,6,rep,name=elements,proto3" json:"elements,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrdtUpdateResponse) Reset() {
+	*x = CrdtUpdateResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[64]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrdtUpdateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrdtUpdateResponse) ProtoMessage() {}
+
+func (x *CrdtUpdateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[64]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrdtUpdateResponse.ProtoReflect.Descriptor instead.
+func (*CrdtUpdateResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{64}
+}
+
+func (x *CrdtUpdateResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *CrdtUpdateResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *CrdtUpdateResponse) GetType() CrdtType {
+	if x != nil {
+		return x.Type
+	}
+	return CrdtType_CRDT_TYPE_NONE
+}
+
+func (x *CrdtUpdateResponse) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *CrdtUpdateResponse) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *CrdtUpdateResponse) GetElements() []string {
+	if x != nil {
+		return x.Elements
+	}
+	return nil
+}
+
+// Request to read a CRDT key
+type CrdtGetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrdtGetRequest) Reset() {
+	*x = CrdtGetRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[65]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrdtGetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrdtGetRequest) ProtoMessage() {}
+
+func (x *CrdtGetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[65]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrdtGetRequest.ProtoReflect.Descriptor instead.
+func (*CrdtGetRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{65}
+}
+
+func (x *CrdtGetRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+// Value of a CRDT key
+type CrdtGetResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Success bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Type    CrdtType               `protobuf:"varint,3,opt,name=type,proto3,enum=kvstore.CrdtType" json:"type,omitempty"`
+	// Value of a register
+	Value string `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	// Value of a counter
+	Count int64 `protobuf:"varint,5,opt,name=count,proto3" json:"count,omitempty"`
+	// Elements of a set, in order
+	Elements      []string `protobuf:"bytes,6,rep,name=elements,proto3" json:"elements,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrdtGetResponse) Reset() {
+	*x = CrdtGetResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[66]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrdtGetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrdtGetResponse) ProtoMessage() {}
+
+func (x *CrdtGetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[66]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrdtGetResponse.ProtoReflect.Descriptor instead.
+func (*CrdtGetResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{66}
+}
+
+func (x *CrdtGetResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *CrdtGetResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *CrdtGetResponse) GetType() CrdtType {
+	if x != nil {
+		return x.Type
+	}
+	return CrdtType_CRDT_TYPE_NONE
+}
+
+func (x *CrdtGetResponse) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *CrdtGetResponse) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *CrdtGetResponse) GetElements() []string {
+	if x != nil {
+		return x.Elements
+	}
+	return nil
+}
+
+// The state of a CRDT key, for merging into another server's
+type CrdtEntry struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Type  CrdtType               `protobuf:"varint,2,opt,name=type,proto3,enum=kvstore.CrdtType" json:"type,omitempty"`
+	// JSON-encoded state
+	State         string `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrdtEntry) Reset() {
+	*x = CrdtEntry{}
+	mi := &file_proto_kvstore_proto_msgTypes[67]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrdtEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrdtEntry) ProtoMessage() {}
+
+func (x *CrdtEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[67]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrdtEntry.ProtoReflect.Descriptor instead.
+func (*CrdtEntry) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{67}
+}
+
+func (x *CrdtEntry) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *CrdtEntry) GetType() CrdtType {
+	if x != nil {
+		return x.Type
+	}
+	return CrdtType_CRDT_TYPE_NONE
+}
+
+func (x *CrdtEntry) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+// CRDT states sent by another server
+type CrdtSyncRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Node id of the sending server
+	Node          string       `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Entries       []*CrdtEntry `protobuf:"bytes,2,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrdtSyncRequest) Reset() {
+	*x = CrdtSyncRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[68]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrdtSyncRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrdtSyncRequest) ProtoMessage() {}
+
+func (x *CrdtSyncRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[68]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrdtSyncRequest.ProtoReflect.Descriptor instead.
+func (*CrdtSyncRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{68}
+}
+
+func (x *CrdtSyncRequest) GetNode() string {
+	if x != nil {
+		return x.Node
+	}
+	return ""
+}
+
+func (x *CrdtSyncRequest) GetEntries() []*CrdtEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+// This server's CRDT states after merging the request's
+type CrdtSyncResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*CrdtEntry           `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CrdtSyncResponse) Reset() {
+	*x = CrdtSyncResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[69]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CrdtSyncResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CrdtSyncResponse) ProtoMessage() {}
+
+func (x *CrdtSyncResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[69]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CrdtSyncResponse.ProtoReflect.Descriptor instead.
+func (*CrdtSyncResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{69}
+}
+
+func (x *CrdtSyncResponse) GetEntries() []*CrdtEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+// Request to stream the change feed
+type ChangeSubscribeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Consumer whose acknowledged cursor to resume after
+	Consumer string `protobuf:"bytes,1,opt,name=consumer,proto3" json:"consumer,omitempty"`
+	// First cursor to stream; 0 resumes after the consumer's acknowledged cursor
+	FromCursor    uint64 `protobuf:"varint,2,opt,name=from_cursor,json=fromCursor,proto3" json:"from_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangeSubscribeRequest) Reset() {
+	*x = ChangeSubscribeRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[70]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeSubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeSubscribeRequest) ProtoMessage() {}
+
+func (x *ChangeSubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[70]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeSubscribeRequest.ProtoReflect.Descriptor instead.
+func (*ChangeSubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{70}
+}
+
+func (x *ChangeSubscribeRequest) GetConsumer() string {
+	if x != nil {
+		return x.Consumer
+	}
+	return ""
+}
+
+func (x *ChangeSubscribeRequest) GetFromCursor() uint64 {
+	if x != nil {
+		return x.FromCursor
+	}
+	return 0
+}
+
+// A mutation in the change feed
+type ChangeEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Position of the change in the feed, starting at 1 with no gaps
+	Cursor uint64 `protobuf:"varint,1,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// Revision of the store the mutation was applied at
+	Revision uint64 `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"`
+	// Commit time in nanoseconds since the Unix epoch
+	Timestamp int64      `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Op        MutationOp `protobuf:"varint,4,opt,name=op,proto3,enum=kvstore.MutationOp" json:"op,omitempty"`
+	Key       string     `protobuf:"bytes,5,opt,name=key,proto3" json:"key,omitempty"`
+	Value     string     `protobuf:"bytes,6,opt,name=value,proto3" json:"value,omitempty"`
+	// Identity of the client that made the change
+	Client string `protobuf:"bytes,7,opt,name=client,proto3" json:"client,omitempty"`
+	// Type of a CRDT key, whose value is its encoded state
+	Crdt          CrdtType `protobuf:"varint,8,opt,name=crdt,proto3,enum=kvstore.CrdtType" json:"crdt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangeEvent) Reset() {
+	*x = ChangeEvent{}
+	mi := &file_proto_kvstore_proto_msgTypes[71]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeEvent) ProtoMessage() {}
+
+func (x *ChangeEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[71]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeEvent.ProtoReflect.Descriptor instead.
+func (*ChangeEvent) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{71}
+}
+
+func (x *ChangeEvent) GetCursor() uint64 {
+	if x != nil {
+		return x.Cursor
+	}
+	return 0
+}
+
+func (x *ChangeEvent) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *ChangeEvent) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *ChangeEvent) GetOp() MutationOp {
+	if x != nil {
+		return x.Op
+	}
+	return MutationOp_MUTATION_OP_SET
+}
+
+func (x *ChangeEvent) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetClient() string {
+	if x != nil {
+		return x.Client
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetCrdt() CrdtType {
+	if x != nil {
+		return x.Crdt
+	}
+	return CrdtType_CRDT_TYPE_NONE
+}
+
+// Acknowledgement of the changes a consumer has processed
+type ChangeAckRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Consumer string                 `protobuf:"bytes,1,opt,name=consumer,proto3" json:"consumer,omitempty"`
+	// Cursor of the last change processed
+	Cursor        uint64 `protobuf:"varint,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangeAckRequest) Reset() {
+	*x = ChangeAckRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[72]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeAckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeAckRequest) ProtoMessage() {}
+
+func (x *ChangeAckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[72]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeAckRequest.ProtoReflect.Descriptor instead.
+func (*ChangeAckRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{72}
+}
+
+func (x *ChangeAckRequest) GetConsumer() string {
+	if x != nil {
+		return x.Consumer
+	}
+	return ""
+}
+
+func (x *ChangeAckRequest) GetCursor() uint64 {
+	if x != nil {
+		return x.Cursor
+	}
+	return 0
+}
+
+// Response for acknowledging changes
+type ChangeAckResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangeAckResponse) Reset() {
+	*x = ChangeAckResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[73]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeAckResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeAckResponse) ProtoMessage() {}
+
+func (x *ChangeAckResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[73]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeAckResponse.ProtoReflect.Descriptor instead.
+func (*ChangeAckResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{73}
+}
+
+func (x *ChangeAckResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ChangeAckResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Request for the consumers of the change feed
+type ListCursorsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCursorsRequest) Reset() {
+	*x = ListCursorsRequest{}
+	mi := &file_proto_kvstore_proto_msgTypes[74]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCursorsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCursorsRequest) ProtoMessage() {}
+
+func (x *ListCursorsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[74]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCursorsRequest.ProtoReflect.Descriptor instead.
+func (*ListCursorsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{74}
+}
+
+// A consumer's acknowledged position in the change feed
+type ConsumerCursor struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Consumer string                 `protobuf:"bytes,1,opt,name=consumer,proto3" json:"consumer,omitempty"`
+	Cursor   uint64                 `protobuf:"varint,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// Changes made after the cursor
+	Lag           uint64 `protobuf:"varint,3,opt,name=lag,proto3" json:"lag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConsumerCursor) Reset() {
+	*x = ConsumerCursor{}
+	mi := &file_proto_kvstore_proto_msgTypes[75]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConsumerCursor) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConsumerCursor) ProtoMessage() {}
+
+func (x *ConsumerCursor) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[75]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConsumerCursor.ProtoReflect.Descriptor instead.
+func (*ConsumerCursor) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{75}
+}
+
+func (x *ConsumerCursor) GetConsumer() string {
+	if x != nil {
+		return x.Consumer
+	}
+	return ""
+}
+
+func (x *ConsumerCursor) GetCursor() uint64 {
+	if x != nil {
+		return x.Cursor
+	}
+	return 0
+}
+
+func (x *ConsumerCursor) GetLag() uint64 {
+	if x != nil {
+		return x.Lag
+	}
+	return 0
+}
+
+// Consumers of the change feed, by name
+type ListCursorsResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Consumers []*ConsumerCursor      `protobuf:"bytes,1,rep,name=consumers,proto3" json:"consumers,omitempty"`
+	// Cursor of the latest change
+	Latest        uint64 `protobuf:"varint,2,opt,name=latest,proto3" json:"latest,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCursorsResponse) Reset() {
+	*x = ListCursorsResponse{}
+	mi := &file_proto_kvstore_proto_msgTypes[76]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCursorsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCursorsResponse) ProtoMessage() {}
+
+func (x *ListCursorsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_kvstore_proto_msgTypes[76]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCursorsResponse.ProtoReflect.Descriptor instead.
+func (*ListCursorsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_kvstore_proto_rawDescGZIP(), []int{76}
+}
+
+func (x *ListCursorsResponse) GetConsumers() []*ConsumerCursor {
+	if x != nil {
+		return x.Consumers
+	}
+	return nil
+}
+
+func (x *ListCursorsResponse) GetLatest() uint64 {
+	if x != nil {
+		return x.Latest
+	}
+	return 0
+}
+
+var File_proto_kvstore_proto protoreflect.FileDescriptor
+
+const file_proto_kvstore_proto_rawDesc = "" +
 	"\n" +
-	"\x13proto/kvstore.proto\x12\akvstore\"4\n" +
+	"\x13proto/kvstore.proto\x12\akvstore\"\x87\x01\n" +
 	"\n" +
 	"SetRequest\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value\"A\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\x12/\n" +
+	"\aversion\x18\x03 \x03(\v2\x15.kvstore.VersionEntryR\aversion\x12 \n" +
+	"\vcoordinator\x18\x04 \x01(\tR\vcoordinator\"<\n" +
+	"\fVersionEntry\x12\x12\n" +
+	"\x04node\x18\x01 \x01(\tR\x04node\x12\x18\n" +
+	"\acounter\x18\x02 \x01(\x04R\acounter\"r\n" +
 	"\vSetResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"\x1e\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12/\n" +
+	"\aversion\x18\x03 \x03(\v2\x15.kvstore.VersionEntryR\aversion\"G\n" +
 	"\n" +
 	"GetRequest\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\"W\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12'\n" +
+	"\x0finclude_deleted\x18\x02 \x01(\bR\x0eincludeDeleted\"\xa2\x01\n" +
 	"\vGetResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value\x12\x18\n" +
-	"\amessage\x18\x03 \x01(\tR\amessage\"!\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12/\n" +
+	"\aversion\x18\x04 \x03(\v2\x15.kvstore.VersionEntryR\aversion\x12\x18\n" +
+	"\adeleted\x18\x05 \x01(\bR\adeleted\"t\n" +
 	"\rDeleteRequest\x12\x10\n" +
-	"\x03key\x18\x01 \x01(\tR\x03key\"D\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12/\n" +
+	"\aversion\x18\x02 \x03(\v2\x15.kvstore.VersionEntryR\aversion\x12 \n" +
+	"\vcoordinator\x18\x03 \x01(\tR\vcoordinator\"u\n" +
 	"\x0eDeleteResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage2\xae\x01\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12/\n" +
+	"\aversion\x18\x03 \x03(\v2\x15.kvstore.VersionEntryR\aversion\"\x0f\n" +
+	"\rVerifyRequest\"\x8a\x01\n" +
+	"\x0eVerifyResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12!\n" +
+	"\fkeys_scanned\x18\x02 \x01(\x03R\vkeysScanned\x12!\n" +
+	"\fcorrupt_keys\x18\x03 \x03(\tR\vcorruptKeys\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\"\x0f\n" +
+	"\rBackupRequest\"\xa9\x01\n" +
+	"\vBackupEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value\x12\x1a\n" +
+	"\bchecksum\x18\x03 \x01(\rR\bchecksum\x12/\n" +
+	"\aversion\x18\x04 \x03(\v2\x15.kvstore.VersionEntryR\aversion\x12%\n" +
+	"\x04crdt\x18\x05 \x01(\x0e2\x11.kvstore.CrdtTypeR\x04crdt\"k\n" +
+	"\vBackupChunk\x12.\n" +
+	"\aentries\x18\x01 \x03(\v2\x14.kvstore.BackupEntryR\aentries\x12,\n" +
+	"\aschemas\x18\x02 \x03(\v2\x12.kvstore.KeySchemaR\aschemas\"\x98\x01\n" +
+	"\x0eRestoreRequest\x12(\n" +
+	"\x04mode\x18\x01 \x01(\x0e2\x14.kvstore.RestoreModeR\x04mode\x12.\n" +
+	"\aentries\x18\x02 \x03(\v2\x14.kvstore.BackupEntryR\aentries\x12,\n" +
+	"\aschemas\x18\x03 \x03(\v2\x12.kvstore.KeySchemaR\aschemas\"j\n" +
+	"\x0fRestoreResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12#\n" +
+	"\rkeys_restored\x18\x02 \x01(\x03R\fkeysRestored\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"T\n" +
+	"\rExportRequest\x12+\n" +
+	"\x06format\x18\x01 \x01(\x0e2\x13.kvstore.DataFormatR\x06format\x12\x16\n" +
+	"\x06prefix\x18\x02 \x01(\tR\x06prefix\"!\n" +
+	"\vExportChunk\x12\x12\n" +
+	"\x04data\x18\x01 \x01(\fR\x04data\"P\n" +
+	"\rImportRequest\x12+\n" +
+	"\x06format\x18\x01 \x01(\x0e2\x13.kvstore.DataFormatR\x06format\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"7\n" +
+	"\vImportError\x12\x12\n" +
+	"\x04line\x18\x01 \x01(\x03R\x04line\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"\xb5\x01\n" +
+	"\x0eImportProgress\x12\x1b\n" +
+	"\trows_read\x18\x01 \x01(\x03R\browsRead\x12#\n" +
+	"\rrows_imported\x18\x02 \x01(\x03R\frowsImported\x12\x1f\n" +
+	"\vrows_failed\x18\x03 \x01(\x03R\n" +
+	"rowsFailed\x12,\n" +
+	"\x06errors\x18\x04 \x03(\v2\x14.kvstore.ImportErrorR\x06errors\x12\x12\n" +
+	"\x04done\x18\x05 \x01(\bR\x04done\"\x9d\x02\n" +
+	"\bMutation\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x04R\brevision\x12\x1c\n" +
+	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\x12#\n" +
+	"\x02op\x18\x03 \x01(\x0e2\x13.kvstore.MutationOpR\x02op\x12\x10\n" +
+	"\x03key\x18\x04 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x05 \x01(\tR\x05value\x12\x1a\n" +
+	"\bchecksum\x18\x06 \x01(\rR\bchecksum\x12\x16\n" +
+	"\x06client\x18\a \x01(\tR\x06client\x12/\n" +
+	"\aversion\x18\b \x03(\v2\x15.kvstore.VersionEntryR\aversion\x12%\n" +
+	"\x04crdt\x18\t \x01(\x0e2\x11.kvstore.CrdtTypeR\x04crdt\"#\n" +
+	"\x0fUndeleteRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\"F\n" +
+	"\x10UndeleteResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\",\n" +
+	"\x12ListDeletedRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\"\\\n" +
+	"\n" +
+	"DeletedKey\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x1d\n" +
+	"\n" +
+	"deleted_at\x18\x02 \x01(\x03R\tdeletedAt\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x03 \x01(\x03R\texpiresAt\"r\n" +
+	"\x13ListDeletedResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12'\n" +
+	"\x04keys\x18\x02 \x03(\v2\x13.kvstore.DeletedKeyR\x04keys\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"8\n" +
+	"\x0eHistoryRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\"\x9b\x01\n" +
+	"\fHistoryEntry\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x04R\brevision\x12\x1c\n" +
+	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\x12#\n" +
+	"\x02op\x18\x03 \x01(\x0e2\x13.kvstore.MutationOpR\x02op\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\tR\x05value\x12\x16\n" +
+	"\x06client\x18\x05 \x01(\tR\x06client\"v\n" +
+	"\x0fHistoryResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12/\n" +
+	"\aentries\x18\x02 \x03(\v2\x15.kvstore.HistoryEntryR\aentries\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"\x13\n" +
+	"\x11QuotaUsageRequest\"\xdb\x01\n" +
+	"\vQuotaStatus\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06prefix\x18\x02 \x01(\tR\x06prefix\x12\x16\n" +
+	"\x06client\x18\x03 \x01(\tR\x06client\x12\x19\n" +
+	"\bmax_keys\x18\x04 \x01(\x03R\amaxKeys\x12\x1b\n" +
+	"\tmax_bytes\x18\x05 \x01(\x03R\bmaxBytes\x12&\n" +
+	"\x0fmax_value_bytes\x18\x06 \x01(\x03R\rmaxValueBytes\x12\x12\n" +
+	"\x04keys\x18\a \x01(\x03R\x04keys\x12\x14\n" +
+	"\x05bytes\x18\b \x01(\x03R\x05bytes\"v\n" +
+	"\x12QuotaUsageResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12,\n" +
+	"\x06quotas\x18\x02 \x03(\v2\x14.kvstore.QuotaStatusR\x06quotas\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"G\n" +
+	"\x15RegisterSchemaRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x16\n" +
+	"\x06schema\x18\x02 \x01(\tR\x06schema\"L\n" +
+	"\x16RegisterSchemaResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\x14\n" +
+	"\x12ListSchemasRequest\";\n" +
+	"\tKeySchema\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x16\n" +
+	"\x06schema\x18\x02 \x01(\tR\x06schema\"w\n" +
+	"\x13ListSchemasResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12,\n" +
+	"\aschemas\x18\x02 \x03(\v2\x12.kvstore.KeySchemaR\aschemas\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"-\n" +
+	"\x13DeleteSchemaRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\"J\n" +
+	"\x14DeleteSchemaResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"&\n" +
+	"\x0eTopKeysRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\"H\n" +
+	"\x06HotKey\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05reads\x18\x02 \x01(\x03R\x05reads\x12\x16\n" +
+	"\x06writes\x18\x03 \x01(\x03R\x06writes\"\x8b\x01\n" +
+	"\x0fTopKeysResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12#\n" +
+	"\x04keys\x18\x02 \x03(\v2\x0f.kvstore.HotKeyR\x04keys\x12\x1f\n" +
+	"\vsample_rate\x18\x03 \x01(\x01R\n" +
+	"sampleRate\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\"\x80\x01\n" +
+	"\tRaftEntry\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x04R\x04term\x12\x14\n" +
+	"\x05index\x18\x02 \x01(\x04R\x05index\x12/\n" +
+	"\tmutations\x18\x03 \x03(\v2\x11.kvstore.MutationR\tmutations\x12\x18\n" +
+	"\apartial\x18\x04 \x01(\bR\apartial\"\xb5\x01\n" +
+	"\fRaftSnapshot\x12\x1d\n" +
+	"\n" +
+	"last_index\x18\x01 \x01(\x04R\tlastIndex\x12\x1b\n" +
+	"\tlast_term\x18\x02 \x01(\x04R\blastTerm\x12\x1a\n" +
+	"\brevision\x18\x03 \x01(\x04R\brevision\x12\x1c\n" +
+	"\ttimestamp\x18\x04 \x01(\x03R\ttimestamp\x12/\n" +
+	"\tmutations\x18\x05 \x03(\v2\x11.kvstore.MutationR\tmutations\"\xf0\x01\n" +
+	"\x16InstallSnapshotRequest\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x04R\x04term\x12\x1b\n" +
+	"\tleader_id\x18\x02 \x01(\tR\bleaderId\x12\x1d\n" +
+	"\n" +
+	"last_index\x18\x03 \x01(\x04R\tlastIndex\x12\x1b\n" +
+	"\tlast_term\x18\x04 \x01(\x04R\blastTerm\x12\x1a\n" +
+	"\brevision\x18\x05 \x01(\x04R\brevision\x12\x1c\n" +
+	"\ttimestamp\x18\x06 \x01(\x03R\ttimestamp\x12/\n" +
+	"\tmutations\x18\a \x03(\v2\x11.kvstore.MutationR\tmutations\"-\n" +
+	"\x17InstallSnapshotResponse\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x04R\x04term\"\x8e\x01\n" +
+	"\vVoteRequest\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x04R\x04term\x12!\n" +
+	"\fcandidate_id\x18\x02 \x01(\tR\vcandidateId\x12$\n" +
+	"\x0elast_log_index\x18\x03 \x01(\x04R\flastLogIndex\x12\"\n" +
+	"\rlast_log_term\x18\x04 \x01(\x04R\vlastLogTerm\"E\n" +
+	"\fVoteResponse\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x04R\x04term\x12!\n" +
+	"\fvote_granted\x18\x02 \x01(\bR\vvoteGranted\"\xe4\x01\n" +
+	"\x14AppendEntriesRequest\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x04R\x04term\x12\x1b\n" +
+	"\tleader_id\x18\x02 \x01(\tR\bleaderId\x12$\n" +
+	"\x0eprev_log_index\x18\x03 \x01(\x04R\fprevLogIndex\x12\"\n" +
+	"\rprev_log_term\x18\x04 \x01(\x04R\vprevLogTerm\x12,\n" +
+	"\aentries\x18\x05 \x03(\v2\x12.kvstore.RaftEntryR\aentries\x12#\n" +
+	"\rleader_commit\x18\x06 \x01(\x04R\fleaderCommit\"f\n" +
+	"\x15AppendEntriesResponse\x12\x12\n" +
+	"\x04term\x18\x01 \x01(\x04R\x04term\x12\x18\n" +
+	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x1f\n" +
+	"\vmatch_index\x18\x03 \x01(\x04R\n" +
+	"matchIndex\"\x13\n" +
+	"\x11RaftStatusRequest\"\x94\x02\n" +
+	"\x12RaftStatusResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04role\x18\x02 \x01(\tR\x04role\x12\x12\n" +
+	"\x04term\x18\x03 \x01(\x04R\x04term\x12\x1b\n" +
+	"\tleader_id\x18\x04 \x01(\tR\bleaderId\x12!\n" +
+	"\fcommit_index\x18\x05 \x01(\x04R\vcommitIndex\x12$\n" +
+	"\x0elast_log_index\x18\x06 \x01(\x04R\flastLogIndex\x12#\n" +
+	"\rapplied_index\x18\a \x01(\x04R\fappliedIndex\x12\x14\n" +
+	"\x05peers\x18\b \x03(\tR\x05peers\x12%\n" +
+	"\x0esnapshot_index\x18\t \x01(\x04R\rsnapshotIndex\"V\n" +
+	"\x10SubscribeRequest\x12#\n" +
+	"\rfrom_revision\x18\x01 \x01(\x04R\ffromRevision\x12\x1d\n" +
+	"\n" +
+	"replica_id\x18\x02 \x01(\tR\treplicaId\"\xe0\x01\n" +
+	"\x10ReplicationEvent\x121\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1d.kvstore.ReplicationEventTypeR\x04type\x12/\n" +
+	"\tmutations\x18\x02 \x03(\v2\x11.kvstore.MutationR\tmutations\x12.\n" +
+	"\aentries\x18\x03 \x03(\v2\x14.kvstore.BackupEntryR\aentries\x12\x1a\n" +
+	"\brevision\x18\x04 \x01(\x04R\brevision\x12\x1c\n" +
+	"\ttimestamp\x18\x05 \x01(\x03R\ttimestamp\"\x1a\n" +
+	"\x18ReplicationStatusRequest\"\xcb\x05\n" +
+	"\x19ReplicationStatusResponse\x12\x12\n" +
+	"\x04role\x18\x01 \x01(\tR\x04role\x12\x18\n" +
+	"\aprimary\x18\x02 \x01(\tR\aprimary\x12\x1c\n" +
+	"\tconnected\x18\x03 \x01(\bR\tconnected\x12)\n" +
+	"\x10applied_revision\x18\x04 \x01(\x04R\x0fappliedRevision\x12)\n" +
+	"\x10primary_revision\x18\x05 \x01(\x04R\x0fprimaryRevision\x12#\n" +
+	"\rlag_revisions\x18\x06 \x01(\x04R\flagRevisions\x12\x1f\n" +
+	"\vlag_seconds\x18\a \x01(\x01R\n" +
+	"lagSeconds\x12!\n" +
+	"\flast_contact\x18\b \x01(\x03R\vlastContact\x12\x1d\n" +
+	"\n" +
+	"last_error\x18\t \x01(\tR\tlastError\x12\x1a\n" +
+	"\breplicas\x18\n" +
+	" \x01(\x05R\breplicas\x12.\n" +
+	"\x13anti_entropy_rounds\x18\v \x01(\x03R\x11antiEntropyRounds\x12#\n" +
+	"\rkeys_repaired\x18\f \x01(\x03R\fkeysRepaired\x12*\n" +
+	"\x11last_anti_entropy\x18\r \x01(\x03R\x0flastAntiEntropy\x12,\n" +
+	"\x12anti_entropy_error\x18\x0e \x01(\tR\x10antiEntropyError\x12#\n" +
+	"\rhints_pending\x18\x0f \x01(\x03R\fhintsPending\x12!\n" +
+	"\fhints_stored\x18\x10 \x01(\x03R\vhintsStored\x12'\n" +
+	"\x0fhints_delivered\x18\x11 \x01(\x03R\x0ehintsDelivered\x12#\n" +
+	"\rhints_expired\x18\x12 \x01(\x03R\fhintsExpired\x12#\n" +
+	"\rhints_dropped\x18\x13 \x01(\x03R\fhintsDropped\"\x10\n" +
+	"\x0ePromoteRequest\"a\n" +
+	"\x0fPromoteResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1a\n" +
+	"\brevision\x18\x02 \x01(\x04R\brevision\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"[\n" +
+	"\x11MerkleTreeRequest\x12\x14\n" +
+	"\x05level\x18\x01 \x01(\rR\x05level\x12\x14\n" +
+	"\x05nodes\x18\x02 \x03(\rR\x05nodes\x12\x1a\n" +
+	"\brevision\x18\x03 \x01(\x04R\brevision\"H\n" +
+	"\x12MerkleTreeResponse\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x04R\brevision\x12\x16\n" +
+	"\x06hashes\x18\x02 \x03(\x04R\x06hashes\"-\n" +
+	"\x13MerkleLeavesRequest\x12\x16\n" +
+	"\x06leaves\x18\x01 \x03(\rR\x06leaves\"b\n" +
+	"\x14MerkleLeavesResponse\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x04R\brevision\x12.\n" +
+	"\aentries\x18\x02 \x03(\v2\x14.kvstore.BackupEntryR\aentries\"\x9b\x01\n" +
+	"\x10StoreHintRequest\x12\x16\n" +
+	"\x06target\x18\x01 \x01(\tR\x06target\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\x12/\n" +
+	"\aversion\x18\x04 \x03(\v2\x15.kvstore.VersionEntryR\aversion\x12\x16\n" +
+	"\x06delete\x18\x05 \x01(\bR\x06delete\"G\n" +
+	"\x11StoreHintResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\x9b\x01\n" +
+	"\x11CrdtUpdateRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12%\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x11.kvstore.CrdtTypeR\x04type\x12\x1f\n" +
+	"\x02op\x18\x03 \x01(\x0e2\x0f.kvstore.CrdtOpR\x02op\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\tR\x05value\x12\x16\n" +
+	"\x06amount\x18\x05 \x01(\x04R\x06amount\"\xb7\x01\n" +
+	"\x12CrdtUpdateResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12%\n" +
+	"\x04type\x18\x03 \x01(\x0e2\x11.kvstore.CrdtTypeR\x04type\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\tR\x05value\x12\x14\n" +
+	"\x05count\x18\x05 \x01(\x03R\x05count\x12\x1a\n" +
+	"\belements\x18\x06 \x03(\tR\belements\"\"\n" +
+	"\x0eCrdtGetRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\"\xb4\x01\n" +
+	"\x0fCrdtGetResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12%\n" +
+	"\x04type\x18\x03 \x01(\x0e2\x11.kvstore.CrdtTypeR\x04type\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\tR\x05value\x12\x14\n" +
+	"\x05count\x18\x05 \x01(\x03R\x05count\x12\x1a\n" +
+	"\belements\x18\x06 \x03(\tR\belements\"Z\n" +
+	"\tCrdtEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12%\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x11.kvstore.CrdtTypeR\x04type\x12\x14\n" +
+	"\x05state\x18\x03 \x01(\tR\x05state\"S\n" +
+	"\x0fCrdtSyncRequest\x12\x12\n" +
+	"\x04node\x18\x01 \x01(\tR\x04node\x12,\n" +
+	"\aentries\x18\x02 \x03(\v2\x12.kvstore.CrdtEntryR\aentries\"@\n" +
+	"\x10CrdtSyncResponse\x12,\n" +
+	"\aentries\x18\x01 \x03(\v2\x12.kvstore.CrdtEntryR\aentries\"U\n" +
+	"\x16ChangeSubscribeRequest\x12\x1a\n" +
+	"\bconsumer\x18\x01 \x01(\tR\bconsumer\x12\x1f\n" +
+	"\vfrom_cursor\x18\x02 \x01(\x04R\n" +
+	"fromCursor\"\xeb\x01\n" +
+	"\vChangeEvent\x12\x16\n" +
+	"\x06cursor\x18\x01 \x01(\x04R\x06cursor\x12\x1a\n" +
+	"\brevision\x18\x02 \x01(\x04R\brevision\x12\x1c\n" +
+	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\x12#\n" +
+	"\x02op\x18\x04 \x01(\x0e2\x13.kvstore.MutationOpR\x02op\x12\x10\n" +
+	"\x03key\x18\x05 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x06 \x01(\tR\x05value\x12\x16\n" +
+	"\x06client\x18\a \x01(\tR\x06client\x12%\n" +
+	"\x04crdt\x18\b \x01(\x0e2\x11.kvstore.CrdtTypeR\x04crdt\"F\n" +
+	"\x10ChangeAckRequest\x12\x1a\n" +
+	"\bconsumer\x18\x01 \x01(\tR\bconsumer\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\x04R\x06cursor\"G\n" +
+	"\x11ChangeAckResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\x14\n" +
+	"\x12ListCursorsRequest\"V\n" +
+	"\x0eConsumerCursor\x12\x1a\n" +
+	"\bconsumer\x18\x01 \x01(\tR\bconsumer\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\x04R\x06cursor\x12\x10\n" +
+	"\x03lag\x18\x03 \x01(\x04R\x03lag\"d\n" +
+	"\x13ListCursorsResponse\x125\n" +
+	"\tconsumers\x18\x01 \x03(\v2\x17.kvstore.ConsumerCursorR\tconsumers\x12\x16\n" +
+	"\x06latest\x18\x02 \x01(\x04R\x06latest*?\n" +
+	"\vRestoreMode\x12\x18\n" +
+	"\x14RESTORE_MODE_REPLACE\x10\x00\x12\x16\n" +
+	"\x12RESTORE_MODE_MERGE\x10\x01*8\n" +
+	"\n" +
+	"DataFormat\x12\x15\n" +
+	"\x11DATA_FORMAT_JSONL\x10\x00\x12\x13\n" +
+	"\x0fDATA_FORMAT_CSV\x10\x01*\x8b\x01\n" +
+	"\n" +
+	"MutationOp\x12\x13\n" +
+	"\x0fMUTATION_OP_SET\x10\x00\x12\x16\n" +
+	"\x12MUTATION_OP_DELETE\x10\x01\x12\x15\n" +
+	"\x11MUTATION_OP_CLEAR\x10\x02\x12\x1a\n" +
+	"\x16MUTATION_OP_SET_SCHEMA\x10\x03\x12\x1d\n" +
+	"\x19MUTATION_OP_DELETE_SCHEMA\x10\x04*\xca\x01\n" +
+	"\x14ReplicationEventType\x12\x1f\n" +
+	"\x1bREPLICATION_EVENT_MUTATIONS\x10\x00\x12$\n" +
+	" REPLICATION_EVENT_SNAPSHOT_BEGIN\x10\x01\x12&\n" +
+	"\"REPLICATION_EVENT_SNAPSHOT_ENTRIES\x10\x02\x12\"\n" +
+	"\x1eREPLICATION_EVENT_SNAPSHOT_END\x10\x03\x12\x1f\n" +
+	"\x1bREPLICATION_EVENT_HEARTBEAT\x10\x04*\x83\x01\n" +
+	"\bCrdtType\x12\x12\n" +
+	"\x0eCRDT_TYPE_NONE\x10\x00\x12\x1a\n" +
+	"\x16CRDT_TYPE_LWW_REGISTER\x10\x01\x12\x17\n" +
+	"\x13CRDT_TYPE_G_COUNTER\x10\x02\x12\x18\n" +
+	"\x14CRDT_TYPE_PN_COUNTER\x10\x03\x12\x14\n" +
+	"\x10CRDT_TYPE_OR_SET\x10\x04*o\n" +
+	"\x06CrdtOp\x12\x12\n" +
+	"\x0eCRDT_OP_ASSIGN\x10\x00\x12\x15\n" +
+	"\x11CRDT_OP_INCREMENT\x10\x01\x12\x15\n" +
+	"\x11CRDT_OP_DECREMENT\x10\x02\x12\x0f\n" +
+	"\vCRDT_OP_ADD\x10\x03\x12\x12\n" +
+	"\x0eCRDT_OP_REMOVE\x10\x042\xda\t\n" +
 	"\rKeyValueStore\x120\n" +
 	"\x03Set\x12\x13.kvstore.SetRequest\x1a\x14.kvstore.SetResponse\x120\n" +
 	"\x03Get\x12\x13.kvstore.GetRequest\x1a\x14.kvstore.GetResponse\x129\n" +
-	"\x06Delete\x12\x16.kvstore.DeleteRequest\x1a\x17.kvstore.DeleteResponseB!Z\x1fgithub.com/pwntato/Censys/protob\x06proto3"
+	"\x06Delete\x12\x16.kvstore.DeleteRequest\x1a\x17.kvstore.DeleteResponse\x129\n" +
+	"\x06Verify\x12\x16.kvstore.VerifyRequest\x1a\x17.kvstore.VerifyResponse\x128\n" +
+	"\x06Backup\x12\x16.kvstore.BackupRequest\x1a\x14.kvstore.BackupChunk0\x01\x12>\n" +
+	"\aRestore\x12\x17.kvstore.RestoreRequest\x1a\x18.kvstore.RestoreResponse(\x01\x128\n" +
+	"\x06Export\x12\x16.kvstore.ExportRequest\x1a\x14.kvstore.ExportChunk0\x01\x12=\n" +
+	"\x06Import\x12\x16.kvstore.ImportRequest\x1a\x17.kvstore.ImportProgress(\x010\x01\x12?\n" +
+	"\bUndelete\x12\x18.kvstore.UndeleteRequest\x1a\x19.kvstore.UndeleteResponse\x12H\n" +
+	"\vListDeleted\x12\x1b.kvstore.ListDeletedRequest\x1a\x1c.kvstore.ListDeletedResponse\x12<\n" +
+	"\aHistory\x12\x17.kvstore.HistoryRequest\x1a\x18.kvstore.HistoryResponse\x12E\n" +
+	"\n" +
+	"QuotaUsage\x12\x1a.kvstore.QuotaUsageRequest\x1a\x1b.kvstore.QuotaUsageResponse\x12Q\n" +
+	"\x0eRegisterSchema\x12\x1e.kvstore.RegisterSchemaRequest\x1a\x1f.kvstore.RegisterSchemaResponse\x12H\n" +
+	"\vListSchemas\x12\x1b.kvstore.ListSchemasRequest\x1a\x1c.kvstore.ListSchemasResponse\x12K\n" +
+	"\fDeleteSchema\x12\x1c.kvstore.DeleteSchemaRequest\x1a\x1d.kvstore.DeleteSchemaResponse\x12<\n" +
+	"\aTopKeys\x12\x17.kvstore.TopKeysRequest\x1a\x18.kvstore.TopKeysResponse\x12E\n" +
+	"\n" +
+	"CrdtUpdate\x12\x1a.kvstore.CrdtUpdateRequest\x1a\x1b.kvstore.CrdtUpdateResponse\x12<\n" +
+	"\aCrdtGet\x12\x17.kvstore.CrdtGetRequest\x1a\x18.kvstore.CrdtGetResponse\x12?\n" +
+	"\bCrdtSync\x12\x18.kvstore.CrdtSyncRequest\x1a\x19.kvstore.CrdtSyncResponse2\xad\x02\n" +
+	"\x04Raft\x12:\n" +
+	"\vRequestVote\x12\x14.kvstore.VoteRequest\x1a\x15.kvstore.VoteResponse\x12N\n" +
+	"\rAppendEntries\x12\x1d.kvstore.AppendEntriesRequest\x1a\x1e.kvstore.AppendEntriesResponse\x12A\n" +
+	"\x06Status\x12\x1a.kvstore.RaftStatusRequest\x1a\x1b.kvstore.RaftStatusResponse\x12V\n" +
+	"\x0fInstallSnapshot\x12\x1f.kvstore.InstallSnapshotRequest\x1a .kvstore.InstallSnapshotResponse(\x012\xc4\x03\n" +
+	"\vReplication\x12C\n" +
+	"\tSubscribe\x12\x19.kvstore.SubscribeRequest\x1a\x19.kvstore.ReplicationEvent0\x01\x12Z\n" +
+	"\x11ReplicationStatus\x12!.kvstore.ReplicationStatusRequest\x1a\".kvstore.ReplicationStatusResponse\x12<\n" +
+	"\aPromote\x12\x17.kvstore.PromoteRequest\x1a\x18.kvstore.PromoteResponse\x12E\n" +
+	"\n" +
+	"MerkleTree\x12\x1a.kvstore.MerkleTreeRequest\x1a\x1b.kvstore.MerkleTreeResponse\x12K\n" +
+	"\fMerkleLeaves\x12\x1c.kvstore.MerkleLeavesRequest\x1a\x1d.kvstore.MerkleLeavesResponse\x12B\n" +
+	"\tStoreHint\x12\x19.kvstore.StoreHintRequest\x1a\x1a.kvstore.StoreHintResponse2\xda\x01\n" +
+	"\n" +
+	"ChangeFeed\x12D\n" +
+	"\tSubscribe\x12\x1f.kvstore.ChangeSubscribeRequest\x1a\x14.kvstore.ChangeEvent0\x01\x12<\n" +
+	"\x03Ack\x12\x19.kvstore.ChangeAckRequest\x1a\x1a.kvstore.ChangeAckResponse\x12H\n" +
+	"\vListCursors\x12\x1b.kvstore.ListCursorsRequest\x1a\x1c.kvstore.ListCursorsResponseB!Z\x1fgithub.com/pwntato/Censys/protob\x06proto3"
 
 var (
 	file_proto_kvstore_proto_rawDescOnce sync.Once
@@ -372,27 +5624,206 @@ func file_proto_kvstore_proto_rawDescGZIP() []byte {
 	return file_proto_kvstore_proto_rawDescData
 }
 
-var file_proto_kvstore_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_proto_kvstore_proto_enumTypes = make([]protoimpl.EnumInfo, 6)
+var file_proto_kvstore_proto_msgTypes = make([]protoimpl.MessageInfo, 77)
 var file_proto_kvstore_proto_goTypes = []any{
-	(*SetRequest)(nil),     // 0: kvstore.SetRequest
-	(*SetResponse)(nil),    // 1: kvstore.SetResponse
-	(*GetRequest)(nil),     // 2: kvstore.GetRequest
-	(*GetResponse)(nil),    // 3: kvstore.GetResponse
-	(*DeleteRequest)(nil),  // 4: kvstore.DeleteRequest
-	(*DeleteResponse)(nil), // 5: kvstore.DeleteResponse
+	(RestoreMode)(0),                  // 0: kvstore.RestoreMode
+	(DataFormat)(0),                   // 1: kvstore.DataFormat
+	(MutationOp)(0),                   // 2: kvstore.MutationOp
+	(ReplicationEventType)(0),         // 3: kvstore.ReplicationEventType
+	(CrdtType)(0),                     // 4: kvstore.CrdtType
+	(CrdtOp)(0),                       // 5: kvstore.CrdtOp
+	(*SetRequest)(nil),                // 6: kvstore.SetRequest
+	(*VersionEntry)(nil),              // 7: kvstore.VersionEntry
+	(*SetResponse)(nil),               // 8: kvstore.SetResponse
+	(*GetRequest)(nil),                // 9: kvstore.GetRequest
+	(*GetResponse)(nil),               // 10: kvstore.GetResponse
+	(*DeleteRequest)(nil),             // 11: kvstore.DeleteRequest
+	(*DeleteResponse)(nil),            // 12: kvstore.DeleteResponse
+	(*VerifyRequest)(nil),             // 13: kvstore.VerifyRequest
+	(*VerifyResponse)(nil),            // 14: kvstore.VerifyResponse
+	(*BackupRequest)(nil),             // 15: kvstore.BackupRequest
+	(*BackupEntry)(nil),               // 16: kvstore.BackupEntry
+	(*BackupChunk)(nil),               // 17: kvstore.BackupChunk
+	(*RestoreRequest)(nil),            // 18: kvstore.RestoreRequest
+	(*RestoreResponse)(nil),           // 19: kvstore.RestoreResponse
+	(*ExportRequest)(nil),             // 20: kvstore.ExportRequest
+	(*ExportChunk)(nil),               // 21: kvstore.ExportChunk
+	(*ImportRequest)(nil),             // 22: kvstore.ImportRequest
+	(*ImportError)(nil),               // 23: kvstore.ImportError
+	(*ImportProgress)(nil),            // 24: kvstore.ImportProgress
+	(*Mutation)(nil),                  // 25: kvstore.Mutation
+	(*UndeleteRequest)(nil),           // 26: kvstore.UndeleteRequest
+	(*UndeleteResponse)(nil),          // 27: kvstore.UndeleteResponse
+	(*ListDeletedRequest)(nil),        // 28: kvstore.ListDeletedRequest
+	(*DeletedKey)(nil),                // 29: kvstore.DeletedKey
+	(*ListDeletedResponse)(nil),       // 30: kvstore.ListDeletedResponse
+	(*HistoryRequest)(nil),            // 31: kvstore.HistoryRequest
+	(*HistoryEntry)(nil),              // 32: kvstore.HistoryEntry
+	(*HistoryResponse)(nil),           // 33: kvstore.HistoryResponse
+	(*QuotaUsageRequest)(nil),         // 34: kvstore.QuotaUsageRequest
+	(*QuotaStatus)(nil),               // 35: kvstore.QuotaStatus
+	(*QuotaUsageResponse)(nil),        // 36: kvstore.QuotaUsageResponse
+	(*RegisterSchemaRequest)(nil),     // 37: kvstore.RegisterSchemaRequest
+	(*RegisterSchemaResponse)(nil),    // 38: kvstore.RegisterSchemaResponse
+	(*ListSchemasRequest)(nil),        // 39: kvstore.ListSchemasRequest
+	(*KeySchema)(nil),                 // 40: kvstore.KeySchema
+	(*ListSchemasResponse)(nil),       // 41: kvstore.ListSchemasResponse
+	(*DeleteSchemaRequest)(nil),       // 42: kvstore.DeleteSchemaRequest
+	(*DeleteSchemaResponse)(nil),      // 43: kvstore.DeleteSchemaResponse
+	(*TopKeysRequest)(nil),            // 44: kvstore.TopKeysRequest
+	(*HotKey)(nil),                    // 45: kvstore.HotKey
+	(*TopKeysResponse)(nil),           // 46: kvstore.TopKeysResponse
+	(*RaftEntry)(nil),                 // 47: kvstore.RaftEntry
+	(*RaftSnapshot)(nil),              // 48: kvstore.RaftSnapshot
+	(*InstallSnapshotRequest)(nil),    // 49: kvstore.InstallSnapshotRequest
+	(*InstallSnapshotResponse)(nil),   // 50: kvstore.InstallSnapshotResponse
+	(*VoteRequest)(nil),               // 51: kvstore.VoteRequest
+	(*VoteResponse)(nil),              // 52: kvstore.VoteResponse
+	(*AppendEntriesRequest)(nil),      // 53: kvstore.AppendEntriesRequest
+	(*AppendEntriesResponse)(nil),     // 54: kvstore.AppendEntriesResponse
+	(*RaftStatusRequest)(nil),         // 55: kvstore.RaftStatusRequest
+	(*RaftStatusResponse)(nil),        // 56: kvstore.RaftStatusResponse
+	(*SubscribeRequest)(nil),          // 57: kvstore.SubscribeRequest
+	(*ReplicationEvent)(nil),          // 58: kvstore.ReplicationEvent
+	(*ReplicationStatusRequest)(nil),  // 59: kvstore.ReplicationStatusRequest
+	(*ReplicationStatusResponse)(nil), // 60: kvstore.ReplicationStatusResponse
+	(*PromoteRequest)(nil),            // 61: kvstore.PromoteRequest
+	(*PromoteResponse)(nil),           // 62: kvstore.PromoteResponse
+	(*MerkleTreeRequest)(nil),         // 63: kvstore.MerkleTreeRequest
+	(*MerkleTreeResponse)(nil),        // 64: kvstore.MerkleTreeResponse
+	(*MerkleLeavesRequest)(nil),       // 65: kvstore.MerkleLeavesRequest
+	(*MerkleLeavesResponse)(nil),      // 66: kvstore.MerkleLeavesResponse
+	(*StoreHintRequest)(nil),          // 67: kvstore.StoreHintRequest
+	(*StoreHintResponse)(nil),         // 68: kvstore.StoreHintResponse
+	(*CrdtUpdateRequest)(nil),         // 69: kvstore.CrdtUpdateRequest
+	(*CrdtUpdateResponse)(nil),        // 70: kvstore.CrdtUpdateResponse
+	(*CrdtGetRequest)(nil),            // 71: kvstore.CrdtGetRequest
+	(*CrdtGetResponse)(nil),           // 72: kvstore.CrdtGetResponse
+	(*CrdtEntry)(nil),                 // 73: kvstore.CrdtEntry
+	(*CrdtSyncRequest)(nil),           // 74: kvstore.CrdtSyncRequest
+	(*CrdtSyncResponse)(nil),          // 75: kvstore.CrdtSyncResponse
+	(*ChangeSubscribeRequest)(nil),    // 76: kvstore.ChangeSubscribeRequest
+	(*ChangeEvent)(nil),               // 77: kvstore.ChangeEvent
+	(*ChangeAckRequest)(nil),          // 78: kvstore.ChangeAckRequest
+	(*ChangeAckResponse)(nil),         // 79: kvstore.ChangeAckResponse
+	(*ListCursorsRequest)(nil),        // 80: kvstore.ListCursorsRequest
+	(*ConsumerCursor)(nil),            // 81: kvstore.ConsumerCursor
+	(*ListCursorsResponse)(nil),       // 82: kvstore.ListCursorsResponse
 }
 var file_proto_kvstore_proto_depIdxs = []int32{
-	0, // 0: kvstore.KeyValueStore.Set:input_type -> kvstore.SetRequest
-	2, // 1: kvstore.KeyValueStore.Get:input_type -> kvstore.GetRequest
-	4, // 2: kvstore.KeyValueStore.Delete:input_type -> kvstore.DeleteRequest
-	1, // 3: kvstore.KeyValueStore.Set:output_type -> kvstore.SetResponse
-	3, // 4: kvstore.KeyValueStore.Get:output_type -> kvstore.GetResponse
-	5, // 5: kvstore.KeyValueStore.Delete:output_type -> kvstore.DeleteResponse
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	7,  // 0: kvstore.SetRequest.version:type_name -> kvstore.VersionEntry
+	7,  // 1: kvstore.SetResponse.version:type_name -> kvstore.VersionEntry
+	7,  // 2: kvstore.GetResponse.version:type_name -> kvstore.VersionEntry
+	7,  // 3: kvstore.DeleteRequest.version:type_name -> kvstore.VersionEntry
+	7,  // 4: kvstore.DeleteResponse.version:type_name -> kvstore.VersionEntry
+	7,  // 5: kvstore.BackupEntry.version:type_name -> kvstore.VersionEntry
+	4,  // 6: kvstore.BackupEntry.crdt:type_name -> kvstore.CrdtType
+	16, // 7: kvstore.BackupChunk.entries:type_name -> kvstore.BackupEntry
+	40, // 8: kvstore.BackupChunk.schemas:type_name -> kvstore.KeySchema
+	0,  // 9: kvstore.RestoreRequest.mode:type_name -> kvstore.RestoreMode
+	16, // 10: kvstore.RestoreRequest.entries:type_name -> kvstore.BackupEntry
+	40, // 11: kvstore.RestoreRequest.schemas:type_name -> kvstore.KeySchema
+	1,  // 12: kvstore.ExportRequest.format:type_name -> kvstore.DataFormat
+	1,  // 13: kvstore.ImportRequest.format:type_name -> kvstore.DataFormat
+	23, // 14: kvstore.ImportProgress.errors:type_name -> kvstore.ImportError
+	2,  // 15: kvstore.Mutation.op:type_name -> kvstore.MutationOp
+	7,  // 16: kvstore.Mutation.version:type_name -> kvstore.VersionEntry
+	4,  // 17: kvstore.Mutation.crdt:type_name -> kvstore.CrdtType
+	29, // 18: kvstore.ListDeletedResponse.keys:type_name -> kvstore.DeletedKey
+	2,  // 19: kvstore.HistoryEntry.op:type_name -> kvstore.MutationOp
+	32, // 20: kvstore.HistoryResponse.entries:type_name -> kvstore.HistoryEntry
+	35, // 21: kvstore.QuotaUsageResponse.quotas:type_name -> kvstore.QuotaStatus
+	40, // 22: kvstore.ListSchemasResponse.schemas:type_name -> kvstore.KeySchema
+	45, // 23: kvstore.TopKeysResponse.keys:type_name -> kvstore.HotKey
+	25, // 24: kvstore.RaftEntry.mutations:type_name -> kvstore.Mutation
+	25, // 25: kvstore.RaftSnapshot.mutations:type_name -> kvstore.Mutation
+	25, // 26: kvstore.InstallSnapshotRequest.mutations:type_name -> kvstore.Mutation
+	47, // 27: kvstore.AppendEntriesRequest.entries:type_name -> kvstore.RaftEntry
+	3,  // 28: kvstore.ReplicationEvent.type:type_name -> kvstore.ReplicationEventType
+	25, // 29: kvstore.ReplicationEvent.mutations:type_name -> kvstore.Mutation
+	16, // 30: kvstore.ReplicationEvent.entries:type_name -> kvstore.BackupEntry
+	16, // 31: kvstore.MerkleLeavesResponse.entries:type_name -> kvstore.BackupEntry
+	7,  // 32: kvstore.StoreHintRequest.version:type_name -> kvstore.VersionEntry
+	4,  // 33: kvstore.CrdtUpdateRequest.type:type_name -> kvstore.CrdtType
+	5,  // 34: kvstore.CrdtUpdateRequest.op:type_name -> kvstore.CrdtOp
+	4,  // 35: kvstore.CrdtUpdateResponse.type:type_name -> kvstore.CrdtType
+	4,  // 36: kvstore.CrdtGetResponse.type:type_name -> kvstore.CrdtType
+	4,  // 37: kvstore.CrdtEntry.type:type_name -> kvstore.CrdtType
+	73, // 38: kvstore.CrdtSyncRequest.entries:type_name -> kvstore.CrdtEntry
+	73, // 39: kvstore.CrdtSyncResponse.entries:type_name -> kvstore.CrdtEntry
+	2,  // 40: kvstore.ChangeEvent.op:type_name -> kvstore.MutationOp
+	4,  // 41: kvstore.ChangeEvent.crdt:type_name -> kvstore.CrdtType
+	81, // 42: kvstore.ListCursorsResponse.consumers:type_name -> kvstore.ConsumerCursor
+	6,  // 43: kvstore.KeyValueStore.Set:input_type -> kvstore.SetRequest
+	9,  // 44: kvstore.KeyValueStore.Get:input_type -> kvstore.GetRequest
+	11, // 45: kvstore.KeyValueStore.Delete:input_type -> kvstore.DeleteRequest
+	13, // 46: kvstore.KeyValueStore.Verify:input_type -> kvstore.VerifyRequest
+	15, // 47: kvstore.KeyValueStore.Backup:input_type -> kvstore.BackupRequest
+	18, // 48: kvstore.KeyValueStore.Restore:input_type -> kvstore.RestoreRequest
+	20, // 49: kvstore.KeyValueStore.Export:input_type -> kvstore.ExportRequest
+	22, // 50: kvstore.KeyValueStore.Import:input_type -> kvstore.ImportRequest
+	26, // 51: kvstore.KeyValueStore.Undelete:input_type -> kvstore.UndeleteRequest
+	28, // 52: kvstore.KeyValueStore.ListDeleted:input_type -> kvstore.ListDeletedRequest
+	31, // 53: kvstore.KeyValueStore.History:input_type -> kvstore.HistoryRequest
+	34, // 54: kvstore.KeyValueStore.QuotaUsage:input_type -> kvstore.QuotaUsageRequest
+	37, // 55: kvstore.KeyValueStore.RegisterSchema:input_type -> kvstore.RegisterSchemaRequest
+	39, // 56: kvstore.KeyValueStore.ListSchemas:input_type -> kvstore.ListSchemasRequest
+	42, // 57: kvstore.KeyValueStore.DeleteSchema:input_type -> kvstore.DeleteSchemaRequest
+	44, // 58: kvstore.KeyValueStore.TopKeys:input_type -> kvstore.TopKeysRequest
+	69, // 59: kvstore.KeyValueStore.CrdtUpdate:input_type -> kvstore.CrdtUpdateRequest
+	71, // 60: kvstore.KeyValueStore.CrdtGet:input_type -> kvstore.CrdtGetRequest
+	74, // 61: kvstore.KeyValueStore.CrdtSync:input_type -> kvstore.CrdtSyncRequest
+	51, // 62: kvstore.Raft.RequestVote:input_type -> kvstore.VoteRequest
+	53, // 63: kvstore.Raft.AppendEntries:input_type -> kvstore.AppendEntriesRequest
+	55, // 64: kvstore.Raft.Status:input_type -> kvstore.RaftStatusRequest
+	49, // 65: kvstore.Raft.InstallSnapshot:input_type -> kvstore.InstallSnapshotRequest
+	57, // 66: kvstore.Replication.Subscribe:input_type -> kvstore.SubscribeRequest
+	59, // 67: kvstore.Replication.ReplicationStatus:input_type -> kvstore.ReplicationStatusRequest
+	61, // 68: kvstore.Replication.Promote:input_type -> kvstore.PromoteRequest
+	63, // 69: kvstore.Replication.MerkleTree:input_type -> kvstore.MerkleTreeRequest
+	65, // 70: kvstore.Replication.MerkleLeaves:input_type -> kvstore.MerkleLeavesRequest
+	67, // 71: kvstore.Replication.StoreHint:input_type -> kvstore.StoreHintRequest
+	76, // 72: kvstore.ChangeFeed.Subscribe:input_type -> kvstore.ChangeSubscribeRequest
+	78, // 73: kvstore.ChangeFeed.Ack:input_type -> kvstore.ChangeAckRequest
+	80, // 74: kvstore.ChangeFeed.ListCursors:input_type -> kvstore.ListCursorsRequest
+	8,  // 75: kvstore.KeyValueStore.Set:output_type -> kvstore.SetResponse
+	10, // 76: kvstore.KeyValueStore.Get:output_type -> kvstore.GetResponse
+	12, // 77: kvstore.KeyValueStore.Delete:output_type -> kvstore.DeleteResponse
+	14, // 78: kvstore.KeyValueStore.Verify:output_type -> kvstore.VerifyResponse
+	17, // 79: kvstore.KeyValueStore.Backup:output_type -> kvstore.BackupChunk
+	19, // 80: kvstore.KeyValueStore.Restore:output_type -> kvstore.RestoreResponse
+	21, // 81: kvstore.KeyValueStore.Export:output_type -> kvstore.ExportChunk
+	24, // 82: kvstore.KeyValueStore.Import:output_type -> kvstore.ImportProgress
+	27, // 83: kvstore.KeyValueStore.Undelete:output_type -> kvstore.UndeleteResponse
+	30, // 84: kvstore.KeyValueStore.ListDeleted:output_type -> kvstore.ListDeletedResponse
+	33, // 85: kvstore.KeyValueStore.History:output_type -> kvstore.HistoryResponse
+	36, // 86: kvstore.KeyValueStore.QuotaUsage:output_type -> kvstore.QuotaUsageResponse
+	38, // 87: kvstore.KeyValueStore.RegisterSchema:output_type -> kvstore.RegisterSchemaResponse
+	41, // 88: kvstore.KeyValueStore.ListSchemas:output_type -> kvstore.ListSchemasResponse
+	43, // 89: kvstore.KeyValueStore.DeleteSchema:output_type -> kvstore.DeleteSchemaResponse
+	46, // 90: kvstore.KeyValueStore.TopKeys:output_type -> kvstore.TopKeysResponse
+	70, // 91: kvstore.KeyValueStore.CrdtUpdate:output_type -> kvstore.CrdtUpdateResponse
+	72, // 92: kvstore.KeyValueStore.CrdtGet:output_type -> kvstore.CrdtGetResponse
+	75, // 93: kvstore.KeyValueStore.CrdtSync:output_type -> kvstore.CrdtSyncResponse
+	52, // 94: kvstore.Raft.RequestVote:output_type -> kvstore.VoteResponse
+	54, // 95: kvstore.Raft.AppendEntries:output_type -> kvstore.AppendEntriesResponse
+	56, // 96: kvstore.Raft.Status:output_type -> kvstore.RaftStatusResponse
+	50, // 97: kvstore.Raft.InstallSnapshot:output_type -> kvstore.InstallSnapshotResponse
+	58, // 98: kvstore.Replication.Subscribe:output_type -> kvstore.ReplicationEvent
+	60, // 99: kvstore.Replication.ReplicationStatus:output_type -> kvstore.ReplicationStatusResponse
+	62, // 100: kvstore.Replication.Promote:output_type -> kvstore.PromoteResponse
+	64, // 101: kvstore.Replication.MerkleTree:output_type -> kvstore.MerkleTreeResponse
+	66, // 102: kvstore.Replication.MerkleLeaves:output_type -> kvstore.MerkleLeavesResponse
+	68, // 103: kvstore.Replication.StoreHint:output_type -> kvstore.StoreHintResponse
+	77, // 104: kvstore.ChangeFeed.Subscribe:output_type -> kvstore.ChangeEvent
+	79, // 105: kvstore.ChangeFeed.Ack:output_type -> kvstore.ChangeAckResponse
+	82, // 106: kvstore.ChangeFeed.ListCursors:output_type -> kvstore.ListCursorsResponse
+	75, // [75:107] is the sub-list for method output_type
+	43, // [43:75] is the sub-list for method input_type
+	43, // [43:43] is the sub-list for extension type_name
+	43, // [43:43] is the sub-list for extension extendee
+	0,  // [0:43] is the sub-list for field type_name
 }
 
 func init() { file_proto_kvstore_proto_init() }
@@ -405,13 +5836,14 @@ func file_proto_kvstore_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_kvstore_proto_rawDesc), len(file_proto_kvstore_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   6,
+			NumEnums:      6,
+			NumMessages:   77,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   4,
 		},
 		GoTypes:           file_proto_kvstore_proto_goTypes,
 		DependencyIndexes: file_proto_kvstore_proto_depIdxs,
+		EnumInfos:         file_proto_kvstore_proto_enumTypes,
 		MessageInfos:      file_proto_kvstore_proto_msgTypes,
 	}.Build()
 	File_proto_kvstore_proto = out.File